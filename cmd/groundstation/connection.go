@@ -0,0 +1,535 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/capture"
+	"tdma-network/internal/logging"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 连接状态
+type ConnState int
+
+const (
+	STATE_DISCONNECTED ConnState = iota // 未连接
+	STATE_CONNECTING                    // 正在建立TCP连接
+	STATE_CONNECTED                     // TCP已连接，等待入网/恢复确认
+	STATE_JOINED                        // 已入网，可以发送数据
+	STATE_RECONNECTING                  // 连接断开，退避重连中
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case STATE_DISCONNECTED:
+		return "未连接"
+	case STATE_CONNECTING:
+		return "连接中"
+	case STATE_CONNECTED:
+		return "已连接"
+	case STATE_JOINED:
+		return "已入网"
+	case STATE_RECONNECTING:
+		return "重连中"
+	}
+	return "未知"
+}
+
+// 状态变迁记录
+type stateTransition struct {
+	From   ConnState
+	To     ConnState
+	Reason string
+	At     time.Time
+}
+
+// 保留的状态变迁记录条数
+const maxTransitions = 10
+
+// 发送队列上限，超出时丢弃最旧的数据
+const maxOutboxSize = 100
+
+// 切换连接状态（调用方需持有gsn.mu）
+func (gsn *GroundStationNode) setStateLocked(state ConnState, reason string) {
+	if gsn.state == state {
+		return
+	}
+	t := stateTransition{From: gsn.state, To: state, Reason: reason, At: time.Now()}
+	gsn.transitions = append(gsn.transitions, t)
+	if len(gsn.transitions) > maxTransitions {
+		gsn.transitions = gsn.transitions[len(gsn.transitions)-maxTransitions:]
+	}
+	gsn.state = state
+	gsn.log.Info("连接状态变化", "from", t.From.String(), "to", t.To.String(), "reason", reason)
+}
+
+// 切换连接状态
+func (gsn *GroundStationNode) setState(state ConnState, reason string) {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+	gsn.setStateLocked(state, reason)
+}
+
+// 获取当前连接状态
+func (gsn *GroundStationNode) State() ConnState {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+	return gsn.state
+}
+
+// 建立TCP连接并发起入网或会话恢复
+func (gsn *GroundStationNode) dial() error {
+	gsn.setState(STATE_CONNECTING, "拨号 "+gsn.address)
+
+	conn, err := gsn.openConn(gsn.address)
+	if err != nil {
+		return err
+	}
+
+	gsn.mu.Lock()
+	gsn.conn = conn
+	gsn.setStateLocked(STATE_CONNECTED, "TCP连接建立")
+	gsn.mu.Unlock()
+
+	gsn.log.Info("已连接到卫星节点", "addr", gsn.address)
+
+	// 启动接收循环
+	go gsn.receiveLoop(conn)
+
+	// 入网请求发送失败时由handleDisconnect接管重连
+	if err := gsn.join(); err != nil {
+		gsn.log.Warn("发送入网请求失败", "err", err)
+	}
+	return nil
+}
+
+// 发起入网；持有会话令牌时尝试恢复原会话
+func (gsn *GroundStationNode) join() error {
+	gsn.mu.Lock()
+	token := gsn.token
+	gsn.challengePending = token == ""
+	gsn.mu.Unlock()
+
+	if token != "" {
+		gsn.log.Debug("使用会话令牌恢复会话")
+		return gsn.sendControl(protocol.MSG_RESUME + token)
+	}
+	gsn.log.Debug("发送入网请求")
+	return gsn.sendControl(protocol.MSG_JOIN)
+}
+
+// 发送控制帧
+func (gsn *GroundStationNode) sendControl(msg string) error {
+	return gsn.writeFrame(protocol.NewTDMAFrame(0, gsn.nodeID, []byte(msg)))
+}
+
+// 写出一帧，写失败视为连接断开
+func (gsn *GroundStationNode) writeFrame(frame *protocol.TDMAFrame) error {
+	gsn.mu.Lock()
+	conn, satID, as := gsn.conn, gsn.servingSatellite, gsn.auth
+	gsn.mu.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("未连接到卫星节点")
+	}
+
+	gsn.writeMu.Lock()
+	err := gsn.writeTo(conn, satID, frame, as)
+	gsn.writeMu.Unlock()
+
+	if err != nil {
+		gsn.handleDisconnect(conn, err)
+		return fmt.Errorf("发送帧失败: %v", err)
+	}
+	return nil
+}
+
+// 在conn上写出一帧，as非nil时以该认证会话签名（握手消息除外）
+func (gsn *GroundStationNode) writeTo(conn net.Conn, satID string, frame *protocol.TDMAFrame, as *auth.Session) error {
+	// 标注绝对时隙号须在签名前，卫星丢弃未标注、超前或过期的帧
+	// 数据帧已按到达时隙标注；控制帧不受时隙限制，按发送时刻标注，到达时不会超前
+	if frame.AbsSlot == 0 {
+		now := time.Now()
+		frame.SetAbsSlot(uint64(gsn.layoutAt(now).At(now).Abs))
+	}
+	write := func(f *protocol.TDMAFrame) error {
+		gsn.capture.Frame(capture.DIR_OUTBOUND, conn, satID, f)
+		return protocol.WriteFrame(conn, f)
+	}
+	if as == nil || auth.UplinkExempt(string(frame.Data)) {
+		// 未签名的帧附加明文序号，卫星据此防重放
+		frame.SetSequence(gsn.nextSeq())
+		return write(frame)
+	}
+	return as.Send(frame, write)
+}
+
+// 下一个明文序号：发送时刻的纳秒数，且严格大于上一个序号
+// 地面站重启后序号仍大于之前发出的帧，卫星保留的防重放窗口不会拒绝新的帧
+func (gsn *GroundStationNode) nextSeq() uint64 {
+	for {
+		last := gsn.seq.Load()
+		seq := max(uint64(time.Now().UnixNano()), last+1)
+		if gsn.seq.CompareAndSwap(last, seq) {
+			return seq
+		}
+	}
+}
+
+// 处理连接断开，启动后台重连
+func (gsn *GroundStationNode) handleDisconnect(conn net.Conn, cause error) {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+
+	// 该连接已被替换或节点已停止
+	if conn == nil || gsn.conn != conn || !gsn.running {
+		return
+	}
+
+	conn.Close()
+	gsn.conn = nil
+
+	reason := "连接断开"
+	if cause != nil && cause != io.EOF {
+		reason = fmt.Sprintf("连接断开: %v", cause)
+	}
+	gsn.setStateLocked(STATE_RECONNECTING, reason)
+
+	go gsn.reconnectLoop()
+}
+
+// 指数退避重连循环
+func (gsn *GroundStationNode) reconnectLoop() {
+	for gsn.running {
+		wait := gsn.backoff.Next()
+		gsn.log.Info("等待重连", "attempt", gsn.backoff.Attempts(), "wait", wait)
+		time.Sleep(wait)
+
+		if !gsn.running {
+			return
+		}
+
+		gsn.retarget()
+		gsn.metrics.reconnects.Inc()
+		err := gsn.dial()
+		if err == nil {
+			gsn.backoff.Reset()
+			return
+		}
+		gsn.log.Warn("重连失败", "err", err)
+		gsn.setState(STATE_RECONNECTING, "重连失败")
+	}
+}
+
+// 处理入网/恢复类控制帧，返回是否已处理；signed表示帧带有通过验证的认证尾部
+func (gsn *GroundStationNode) handleControl(msg string, signed bool) bool {
+	switch {
+	case strings.HasPrefix(msg, protocol.MSG_JOIN_ACK):
+		// JOIN_ACK_<slotID>_<token>
+		parts := strings.SplitN(strings.TrimPrefix(msg, protocol.MSG_JOIN_ACK), "_", 2)
+		if len(parts) != 2 {
+			gsn.log.Warn("无效的入网确认", "msg", msg)
+			return true
+		}
+		slotID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			gsn.log.Warn("解析时隙失败", "msg", msg, "err", err)
+			return true
+		}
+		gsn.mu.Lock()
+		gsn.slotID = slotID
+		gsn.token = parts[1]
+		gsn.lastHeartbeatAck = time.Now()
+		gsn.setStateLocked(STATE_JOINED, fmt.Sprintf("入网成功，时隙 %d", slotID))
+		gsn.mu.Unlock()
+		gsn.log.Info("入网成功", logging.KEY_SLOT_ID, slotID)
+		gsn.flushQueue()
+		return true
+
+	case strings.HasPrefix(msg, protocol.MSG_RESUME_ACK):
+		slotID, err := strconv.Atoi(strings.TrimPrefix(msg, protocol.MSG_RESUME_ACK))
+		if err != nil {
+			gsn.log.Warn("解析时隙失败", "msg", msg, "err", err)
+			return true
+		}
+		gsn.mu.Lock()
+		gsn.slotID = slotID
+		gsn.lastHeartbeatAck = time.Now()
+		gsn.setStateLocked(STATE_JOINED, fmt.Sprintf("会话恢复，时隙 %d", slotID))
+		gsn.mu.Unlock()
+		gsn.log.Info("会话恢复", logging.KEY_SLOT_ID, slotID)
+		gsn.flushQueue()
+		return true
+
+	case strings.HasPrefix(msg, protocol.MSG_HEARTBEAT_ACK):
+		// HEARTBEAT_ACK_<slotID>_<发送时刻>
+		parts := strings.SplitN(strings.TrimPrefix(msg, protocol.MSG_HEARTBEAT_ACK), "_", 2)
+		slotID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			gsn.log.Warn("解析时隙失败", "msg", msg, "err", err)
+			return true
+		}
+		if len(parts) == 2 {
+			if tx, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
+				gsn.recordDelay(tx)
+			}
+		}
+		gsn.mu.Lock()
+		gsn.lastHeartbeatAck = time.Now()
+		if gsn.slotID != slotID {
+			gsn.log.Info("卫星调整时隙", "old_slot_id", gsn.slotID, logging.KEY_SLOT_ID, slotID)
+			gsn.slotID = slotID
+		}
+		gsn.mu.Unlock()
+		return true
+
+	case strings.HasPrefix(msg, protocol.MSG_SCHEDULE_CHANGE):
+		c, err := protocol.ParseScheduleChange(msg)
+		if err == nil {
+			err = gsn.scheduleLayout(c)
+		}
+		if err != nil {
+			gsn.log.Warn("无效的帧结构变更通告", "msg", msg, "err", err)
+			return true
+		}
+		gsn.log.Info("卫星通告帧结构变更", "at", c.At, "slots", c.TotalSlots, "slot_duration", c.SlotDuration, "old_slot_id", c.From, logging.KEY_SLOT_ID, c.To)
+		return true
+
+	case msg == protocol.MSG_RESUME_REJECT, msg == protocol.MSG_HEARTBEAT_REJECT,
+		msg == protocol.MSG_AUTH_REJECT+auth.REASON_NO_SESSION:
+		gsn.mu.Lock()
+		// 持有认证会话时未签名的拒绝可能是伪造的，只作提示，不丢弃会话密钥与令牌；
+		// 卫星确实丢失会话（如重启）时将持续收不到签名帧，由心跳循环超时后丢弃会话重新入网
+		if !signed && gsn.auth != nil {
+			if gsn.rejectedSince.IsZero() {
+				gsn.rejectedSince = time.Now()
+			}
+			gsn.mu.Unlock()
+			gsn.metrics.authFailures.With(auth.REASON_UNSIGNED).Inc()
+			gsn.log.Warn("忽略未签名的拒绝", "msg", msg)
+			return true
+		}
+		// 卫星没有认证会话时，已发出的每个签名帧都会被拒绝，只在第一次时重新入网
+		stale := msg == protocol.MSG_AUTH_REJECT+auth.REASON_NO_SESSION && gsn.auth == nil
+		gsn.token = ""
+		gsn.auth = nil
+		gsn.mu.Unlock()
+		if stale {
+			return true
+		}
+		gsn.log.Info("会话无效，重新入网", "msg", msg)
+		if err := gsn.join(); err != nil {
+			gsn.log.Warn("重新入网失败", "err", err)
+		}
+		return true
+
+	case strings.HasPrefix(msg, protocol.MSG_AUTH_CHALLENGE):
+		if gsn.psk == nil {
+			gsn.log.Error("卫星要求认证，但未配置密钥（-keystore）")
+			return true
+		}
+		// 挑战不签名，只接受对本站入网请求的应答，否则任何人都可以伪造挑战替换会话密钥
+		gsn.mu.Lock()
+		pending := gsn.challengePending
+		gsn.challengePending = false
+		gsn.mu.Unlock()
+		if !pending {
+			gsn.metrics.authFailures.With(auth.REASON_UNSIGNED).Inc()
+			gsn.log.Warn("忽略未请求的认证挑战")
+			return true
+		}
+		resp, as, err := auth.Respond(gsn.psk, gsn.nodeID, strings.TrimPrefix(msg, protocol.MSG_AUTH_CHALLENGE), gsn.rekey)
+		if err != nil {
+			gsn.log.Warn("响应认证挑战失败", "err", err)
+			return true
+		}
+		// 卫星用新会话签名入网确认，先切换会话再发送响应
+		gsn.mu.Lock()
+		gsn.auth = as
+		gsn.mu.Unlock()
+		gsn.log.Debug("响应认证挑战")
+		if err := gsn.sendControl(resp); err != nil {
+			gsn.log.Warn("发送认证响应失败", "err", err)
+		}
+		return true
+
+	case strings.HasPrefix(msg, protocol.MSG_JOIN_REJECT):
+		// 访问控制策略拒绝，退回未入网状态，入网请求由心跳循环定期重发（如等待时间窗口开始）
+		reason := strings.TrimPrefix(msg, protocol.MSG_JOIN_REJECT)
+		gsn.mu.Lock()
+		gsn.token = ""
+		if gsn.state == STATE_JOINED {
+			gsn.setStateLocked(STATE_CONNECTED, "访问被拒绝: "+reason)
+		}
+		gsn.mu.Unlock()
+		gsn.log.Error("入网被拒绝", "reason", reason)
+		return true
+
+	case strings.HasPrefix(msg, protocol.MSG_AUTH_REJECT):
+		// 密钥错误等无法自行恢复，入网请求由心跳循环定期重发
+		gsn.log.Error("认证被拒绝", "reason", strings.TrimPrefix(msg, protocol.MSG_AUTH_REJECT))
+		return true
+	}
+	return false
+}
+
+// 心跳循环，已入网时定期发送心跳；长时间收不到心跳确认视为连接断开
+func (gsn *GroundStationNode) heartbeatLoop() {
+	ticker := time.NewTicker(protocol.HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	timeout := protocol.HEARTBEAT_INTERVAL * protocol.HEARTBEAT_MISS_LIMIT
+	for range ticker.C {
+		if !gsn.running {
+			return
+		}
+
+		gsn.mu.Lock()
+		state, conn, lastAck := gsn.state, gsn.conn, gsn.lastHeartbeatAck
+		// 持续只收到未签名的拒绝而没有任何签名帧，视为卫星已丢失认证会话
+		lost := gsn.auth != nil && !gsn.rejectedSince.IsZero() && time.Since(gsn.rejectedSince) > timeout
+		if lost {
+			gsn.token = ""
+			gsn.auth = nil
+			gsn.rejectedSince = time.Time{}
+		}
+		gsn.mu.Unlock()
+
+		if lost {
+			gsn.log.Warn("卫星持续拒绝会话且没有签名帧，丢弃认证会话重新入网", "since", timeout)
+			if err := gsn.join(); err != nil {
+				gsn.log.Warn("重新入网失败", "err", err)
+			}
+			continue
+		}
+
+		// 入网/恢复确认可能在信道上丢失，未入网时定期重发
+		if state == STATE_CONNECTED {
+			gsn.metrics.joinRetries.Inc()
+			if err := gsn.join(); err != nil {
+				gsn.log.Warn("重发入网请求失败", "err", err)
+			}
+			continue
+		}
+		if state != STATE_JOINED {
+			continue
+		}
+		if time.Since(lastAck) > timeout {
+			gsn.metrics.heartbeatLost.Inc()
+			gsn.handleDisconnect(conn, fmt.Errorf("%v 未收到心跳确认", timeout))
+			continue
+		}
+		if err := gsn.sendControl(protocol.MSG_HEARTBEAT); err != nil {
+			gsn.log.Warn("发送心跳失败", "err", err)
+		}
+	}
+}
+
+// 数据加入发送队列
+func (gsn *GroundStationNode) enqueue(data []byte) {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+
+	if len(gsn.outbox) >= maxOutboxSize {
+		gsn.log.Warn("发送队列已满，丢弃最旧数据", "size", len(gsn.outbox))
+		gsn.metrics.queueDrops.Inc()
+		gsn.outbox = gsn.outbox[1:]
+	}
+	gsn.outbox = append(gsn.outbox, data)
+}
+
+// 在自己的时隙内发送队列中的数据
+func (gsn *GroundStationNode) flushQueue() error {
+	gsn.flushMu.Lock()
+	defer gsn.flushMu.Unlock()
+
+	// 先计算当前时隙，帧结构变更生效时会迁移本站的时隙
+	currentSlot := gsn.currentGlobalSlot()
+
+	gsn.mu.Lock()
+	state, slotID := gsn.state, gsn.slotID
+	gsn.mu.Unlock()
+
+	if state != STATE_JOINED {
+		return nil
+	}
+
+	if currentSlot != slotID {
+		return nil
+	}
+
+	// 卫星不可见时数据保留在队列中，等待下次过境
+	if !gsn.satelliteVisible(time.Now()) {
+		return nil
+	}
+
+	for {
+		gsn.mu.Lock()
+		if len(gsn.outbox) == 0 {
+			gsn.mu.Unlock()
+			return nil
+		}
+		data := gsn.outbox[0]
+		gsn.mu.Unlock()
+
+		if err := gsn.SendFrame(slotID, data); err != nil {
+			// 保留数据，待重连后重发
+			return err
+		}
+
+		gsn.mu.Lock()
+		gsn.outbox = gsn.outbox[1:]
+		gsn.mu.Unlock()
+	}
+}
+
+// 判断服务卫星是否可见
+func (gsn *GroundStationNode) satelliteVisible(t time.Time) bool {
+	gsn.mu.Lock()
+	satID := gsn.servingSatellite
+	gsn.mu.Unlock()
+
+	return gsn.visibility.Visible(satID, gsn.nodeID, t)
+}
+
+// 打印连接状态
+func (gsn *GroundStationNode) printConnectionStatus() {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+
+	fmt.Printf("连接状态: %s\n", gsn.state)
+	fmt.Printf("卫星地址: %s\n", gsn.address)
+	fmt.Printf("会话令牌: %v\n", gsn.token != "")
+	if gsn.psk != nil {
+		fmt.Printf("认证会话: %v\n", gsn.auth != nil)
+	}
+	fmt.Printf("待发送队列: %d\n", len(gsn.outbox))
+	fmt.Printf("重连次数: %d\n", gsn.backoff.Attempts())
+	if gsn.uplink != nil {
+		st := gsn.uplink.Stats()
+		fmt.Printf("上行信道: 帧 %d, 丢失 %d, 误码帧 %d (比特 %d), 乱序 %d, 传播时延 %v, 多普勒 %.1fHz\n",
+			st.Frames, st.Dropped, st.Corrupted, st.BitErrors, st.Reordered,
+			st.PropDelay.Round(time.Microsecond), st.DopplerHz)
+	}
+	if !gsn.lastHeartbeatAck.IsZero() {
+		fmt.Printf("最近心跳确认: %s\n", gsn.lastHeartbeatAck.Format("15:04:05"))
+	}
+	gsn.printHandoversLocked()
+	gsn.printTimingLocked()
+	if gsn.visibility.Constrained(gsn.servingSatellite, gsn.nodeID) {
+		now := time.Now()
+		fmt.Printf("卫星可见: %v\n", gsn.visibility.Visible(gsn.servingSatellite, gsn.nodeID, now))
+		if p, ok := gsn.visibility.NextPass(gsn.servingSatellite, gsn.nodeID, now, 24*time.Hour, 10*time.Second); ok {
+			fmt.Printf("过境窗口: %s - %s\n", p.AOS.Format(time.RFC3339), p.LOS.Format(time.RFC3339))
+		}
+	}
+	fmt.Println("状态变迁:")
+	for _, t := range gsn.transitions {
+		fmt.Printf("  %s %s -> %s (%s)\n", t.At.Format("15:04:05"), t.From, t.To, t.Reason)
+	}
+}
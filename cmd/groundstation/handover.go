@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/capture"
+	"tdma-network/internal/channel"
+	"tdma-network/internal/logging"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 服务卫星即将出境前提前该时长发起切换
+const handoverLead = 15 * time.Second
+
+// 评估候选卫星剩余可见时长的范围与步长
+const (
+	handoverLookahead = 20 * time.Minute
+	handoverStep      = 10 * time.Second
+)
+
+// 星座中的一颗卫星
+type satelliteEntry struct {
+	ID      string // 卫星节点ID，未知时为空，从收到的帧中获知
+	Address string
+}
+
+// 解析卫星列表："ID@地址:端口,ID@地址:端口"，ID可省略
+func parseSatellites(list string) ([]satelliteEntry, error) {
+	var sats []satelliteEntry
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		entry := satelliteEntry{Address: item}
+		if i := strings.Index(item, "@"); i >= 0 {
+			entry.ID, entry.Address = item[:i], item[i+1:]
+		}
+		if entry.Address == "" {
+			return nil, fmt.Errorf("卫星地址为空: %s", item)
+		}
+		sats = append(sats, entry)
+	}
+	if len(sats) == 0 {
+		return nil, fmt.Errorf("卫星列表为空")
+	}
+	return sats, nil
+}
+
+// 一次切换记录
+type handoverRecord struct {
+	From     string
+	To       string
+	At       time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// 保留的切换记录条数
+const maxHandoverRecords = 10
+
+// 切换统计
+type handoverStats struct {
+	Count    int
+	Failures int
+	Total    time.Duration
+	Records  []handoverRecord
+}
+
+// 从t开始卫星持续可见的时长（上限handoverLookahead）
+func (gsn *GroundStationNode) remainingVisibility(satID string, t time.Time) time.Duration {
+	var d time.Duration
+	for d < handoverLookahead && gsn.visibility.Visible(satID, gsn.nodeID, t.Add(d)) {
+		d += handoverStep
+	}
+	return d
+}
+
+// 选择当前可见且剩余可见时间最长的卫星，exclude为不参与选择的地址
+func (gsn *GroundStationNode) bestSatellite(exclude string) (satelliteEntry, bool) {
+	now := time.Now()
+	var best satelliteEntry
+	var bestRemaining time.Duration
+	found := false
+	for _, sat := range gsn.satellites {
+		if sat.Address == exclude {
+			continue
+		}
+		remaining := gsn.remainingVisibility(sat.ID, now)
+		if remaining == 0 {
+			continue
+		}
+		if !found || remaining > bestRemaining {
+			best, bestRemaining, found = sat, remaining, true
+		}
+	}
+	return best, found
+}
+
+// 重连前选择目标卫星，没有可见卫星时保持原目标
+func (gsn *GroundStationNode) retarget() {
+	if len(gsn.satellites) <= 1 {
+		return
+	}
+	sat, ok := gsn.bestSatellite("")
+	if !ok {
+		return
+	}
+
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+	if sat.Address != gsn.address {
+		gsn.log.Info("重连目标切换", logging.KEY_PEER, sat.ID, "addr", sat.Address)
+		gsn.address = sat.Address
+		gsn.servingSatellite = sat.ID
+		gsn.token = ""
+		gsn.auth = nil
+		gsn.rejectedSince = time.Time{}
+		gsn.delaySamples = nil
+	}
+}
+
+// 切换检测循环，服务卫星即将出境时切换到下一颗可见卫星
+func (gsn *GroundStationNode) handoverLoop() {
+	if len(gsn.satellites) <= 1 || gsn.visibility == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !gsn.running {
+			return
+		}
+
+		gsn.mu.Lock()
+		state, satID, address := gsn.state, gsn.servingSatellite, gsn.address
+		gsn.mu.Unlock()
+
+		if state != STATE_JOINED {
+			continue
+		}
+		if gsn.visibility.Visible(satID, gsn.nodeID, time.Now().Add(handoverLead)) {
+			continue
+		}
+
+		target, ok := gsn.bestSatellite(address)
+		if !ok {
+			gsn.log.Warn("卫星即将出境，但没有其他可见卫星", logging.KEY_PEER, satID)
+			continue
+		}
+		if err := gsn.handover(target); err != nil {
+			gsn.log.Warn("切换失败", logging.KEY_PEER, target.ID, "err", err)
+		}
+	}
+}
+
+// 先建后断切换：先在新卫星上入网获得时隙，再切换业务，最后释放旧卫星上的时隙
+func (gsn *GroundStationNode) handover(target satelliteEntry) (err error) {
+	start := time.Now()
+
+	gsn.mu.Lock()
+	from := gsn.servingSatellite
+	gsn.mu.Unlock()
+
+	gsn.log.Info("开始切换", "from", from, logging.KEY_PEER, target.ID, "addr", target.Address)
+	defer func() {
+		gsn.recordHandover(handoverRecord{From: from, To: target.ID, At: start, Duration: time.Since(start), Err: err})
+	}()
+
+	// 1. 在新卫星上入网
+	conn, err := gsn.openConn(target.Address)
+	if err != nil {
+		return err
+	}
+	satID, slotID, token, as, err := gsn.joinOn(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if target.ID == "" {
+		target.ID = satID
+	}
+
+	// 2. 切换业务到新连接
+	gsn.mu.Lock()
+	oldConn, oldAuth := gsn.conn, gsn.auth
+	gsn.conn = conn
+	gsn.address = target.Address
+	gsn.servingSatellite = target.ID
+	gsn.slotID = slotID
+	gsn.token = token
+	gsn.auth = as
+	gsn.rejectedSince = time.Time{}
+	gsn.pendingLayout = nil // 原卫星的变更通告不适用于新卫星，新卫星有变更时会重新通告
+	gsn.lastHeartbeatAck = time.Now()
+	gsn.delaySamples = nil
+	gsn.setStateLocked(STATE_JOINED, fmt.Sprintf("切换到卫星 %s，时隙 %d", target.ID, slotID))
+	gsn.mu.Unlock()
+
+	gsn.updateUplinkGeometry(target.ID)
+	go gsn.receiveLoop(conn)
+
+	// 3. 释放旧卫星上的时隙
+	if oldConn != nil {
+		gsn.writeMu.Lock()
+		leave := protocol.NewTDMAFrame(0, gsn.nodeID, []byte(protocol.MSG_LEAVE))
+		leaveErr := gsn.writeTo(oldConn, from, leave, oldAuth)
+		gsn.writeMu.Unlock()
+		if leaveErr != nil {
+			gsn.log.Warn("向旧卫星发送离网请求失败", logging.KEY_PEER, from, "err", leaveErr)
+		}
+		oldConn.Close()
+	}
+
+	gsn.log.Info("切换完成", "from", from, logging.KEY_PEER, target.ID, logging.KEY_SLOT_ID, slotID, "elapsed", time.Since(start))
+	gsn.flushQueue()
+	return nil
+}
+
+// 建立到指定卫星的连接（经过上行信道）
+func (gsn *GroundStationNode) openConn(address string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", address, gsn.dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接卫星节点失败: %v", err)
+	}
+	if conn, err = auth.DialTLS(conn, address, gsn.tls); err != nil {
+		return nil, err
+	}
+	if gsn.uplink != nil {
+		conn = channel.NewConn(conn, gsn.uplink)
+	}
+	return conn, nil
+}
+
+// 在尚未投入使用的连接上同步完成入网（配置了密钥时包括认证握手）
+// 返回卫星ID、时隙、会话令牌与认证会话
+func (gsn *GroundStationNode) joinOn(conn net.Conn) (string, int, string, *auth.Session, error) {
+	peer := conn.RemoteAddr().String()
+	join := protocol.NewTDMAFrame(0, gsn.nodeID, []byte(protocol.MSG_JOIN))
+	if err := gsn.writeTo(conn, peer, join, nil); err != nil {
+		return "", -1, "", nil, fmt.Errorf("发送入网请求失败: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var as *auth.Session
+	for {
+		frame, err := protocol.ReadFrame(conn)
+		if err != nil {
+			return "", -1, "", nil, fmt.Errorf("等待入网确认失败: %v", err)
+		}
+		gsn.capture.Frame(capture.DIR_INBOUND, conn, frame.GetNodeID(), frame)
+		if frame.Validate() != nil {
+			continue
+		}
+		msg := string(frame.Data)
+
+		if gsn.psk != nil {
+			switch {
+			case frame.IsAuthenticated():
+				if as == nil {
+					continue
+				}
+				if err := as.Open(frame); err != nil {
+					gsn.metrics.authFailures.With(auth.Reason(err)).Inc()
+					continue
+				}
+				msg = string(frame.Data)
+
+			case strings.HasPrefix(msg, protocol.MSG_AUTH_CHALLENGE):
+				resp, s, err := auth.Respond(gsn.psk, gsn.nodeID, strings.TrimPrefix(msg, protocol.MSG_AUTH_CHALLENGE), gsn.rekey)
+				if err != nil {
+					return "", -1, "", nil, err
+				}
+				if err := gsn.writeTo(conn, peer, protocol.NewTDMAFrame(0, gsn.nodeID, []byte(resp)), nil); err != nil {
+					return "", -1, "", nil, fmt.Errorf("发送认证响应失败: %v", err)
+				}
+				as = s
+				continue
+
+			case strings.HasPrefix(msg, protocol.MSG_AUTH_REJECT):
+				return "", -1, "", nil, fmt.Errorf("认证被拒绝: %s", strings.TrimPrefix(msg, protocol.MSG_AUTH_REJECT))
+
+			default:
+				// 配置了密钥时不接受未签名的入网确认
+				continue
+			}
+		}
+
+		if strings.HasPrefix(msg, protocol.MSG_JOIN_REJECT) {
+			return "", -1, "", nil, fmt.Errorf("入网被拒绝: %s", strings.TrimPrefix(msg, protocol.MSG_JOIN_REJECT))
+		}
+		if !strings.HasPrefix(msg, protocol.MSG_JOIN_ACK) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(msg, protocol.MSG_JOIN_ACK), "_", 2)
+		if len(parts) != 2 {
+			return "", -1, "", nil, fmt.Errorf("无效的入网确认: %s", msg)
+		}
+		slotID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return "", -1, "", nil, fmt.Errorf("解析时隙失败: %v", err)
+		}
+		return frame.GetNodeID(), slotID, parts[1], as, nil
+	}
+}
+
+// 记录切换结果
+func (gsn *GroundStationNode) recordHandover(r handoverRecord) {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+
+	if r.Err != nil {
+		gsn.handovers.Failures++
+		gsn.metrics.handovers.With("failure").Inc()
+	} else {
+		gsn.metrics.handovers.With("success").Inc()
+		gsn.handovers.Count++
+		gsn.handovers.Total += r.Duration
+	}
+	gsn.handovers.Records = append(gsn.handovers.Records, r)
+	if len(gsn.handovers.Records) > maxHandoverRecords {
+		gsn.handovers.Records = gsn.handovers.Records[len(gsn.handovers.Records)-maxHandoverRecords:]
+	}
+}
+
+// 打印切换统计（调用方需持有gsn.mu）
+func (gsn *GroundStationNode) printHandoversLocked() {
+	if len(gsn.satellites) <= 1 {
+		return
+	}
+	h := gsn.handovers
+	fmt.Printf("服务卫星: %s\n", gsn.servingSatellite)
+	fmt.Printf("切换次数: %d (失败 %d)", h.Count, h.Failures)
+	if h.Count > 0 {
+		fmt.Printf(", 平均耗时 %v", (h.Total / time.Duration(h.Count)).Round(time.Millisecond))
+	}
+	fmt.Println()
+	for _, r := range h.Records {
+		if r.Err != nil {
+			fmt.Printf("  %s %s -> %s 失败: %v\n", r.At.Format("15:04:05"), r.From, r.To, r.Err)
+		} else {
+			fmt.Printf("  %s %s -> %s 耗时 %v\n", r.At.Format("15:04:05"), r.From, r.To, r.Duration.Round(time.Millisecond))
+		}
+	}
+}
@@ -2,14 +2,26 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/capture"
+	"tdma-network/internal/channel"
+	"tdma-network/internal/config"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/metrics"
 	"tdma-network/internal/network"
+	"tdma-network/internal/orbit"
 	"tdma-network/internal/scheduler"
+	"tdma-network/internal/traffic"
 	"tdma-network/pkg/protocol"
 	"time"
 )
@@ -20,173 +32,194 @@ type GroundStationNode struct {
 	network *network.NetworkInterface
 	conn    net.Conn
 	running bool
-	slotID  int // 固定分配的slotID
+	slotID  int // 当前使用的slotID，入网后以卫星分配为准
+
+	address     string
+	mu          sync.Mutex    // 保护conn、连接状态、令牌与发送队列
+	writeMu     sync.Mutex    // 串行化帧写出
+	seq         atomic.Uint64 // 最近一个未签名帧的明文序号
+	flushMu     sync.Mutex    // 串行化队列发送
+	state       ConnState
+	transitions []stateTransition
+	token       string           // 会话令牌，用于断线后恢复
+	psk         []byte           // 预共享密钥，nil表示不认证
+	auth        *auth.Session    // 与服务卫星的认证会话，随入网握手建立
+	rekey       auth.RekeyPolicy // 会话密钥更新策略
+	tls         *tls.Config      // 到卫星连接的TLS配置，nil表示明文TCP
+	outbox      [][]byte         // 待发送数据
+	backoff     *network.Backoff
+
+	lastHeartbeatAck time.Time
+	rejectedSince    time.Time // 持有认证会话时首次收到未签名拒绝的时刻，收到签名帧后清零
+	challengePending bool      // 已发送入网请求，等待卫星的认证挑战
+
+	uplink *channel.Channel // 上行信道损伤，nil表示理想链路
+
+	visibility       *orbit.Model // 可见性模型，nil表示卫星始终可见
+	servingSatellite string       // 当前服务卫星ID，从收到的帧中获知
+
+	delaySamples  []delaySample // 心跳确认测得的单向时延
+	timingAdvance time.Duration // 定时提前量
+
+	satellites []satelliteEntry // 星座中可用的卫星
+	handovers  handoverStats
+
+	layout        scheduler.Layout         // 帧结构，须与卫星一致
+	pendingLayout *protocol.ScheduleChange // 卫星通告的尚未生效的帧结构变更
+	sendInterval  time.Duration            // 自动发送默认数据的间隔
+	dialTimeout   time.Duration            // 连接卫星的超时
+
+	sources  []*trafficSource  // 业务源，为空时按旧方式定时发送默认数据
+	received *traffic.Receiver // 其他地面站发来的业务统计
+
+	metrics *stationMetrics
+	log     *slog.Logger
+	capture *capture.Writer // 收发帧抓包，nil表示不抓包
 }
 
-// 创建新的地面站节点
-func NewGroundStationNode(nodeID string) *GroundStationNode {
-	return &GroundStationNode{
+// 地面站节点配置，由命令行、环境变量与配置文件确定
+type stationConfig struct {
+	NodeID       string
+	Satellites   []satelliteEntry
+	SlotID       int // 入网前使用的slotID，-1表示由卫星分配
+	Scheduler    scheduler.Config
+	SendInterval time.Duration // 未配置业务源时自动发送默认数据的间隔，0表示不自动发送
+	DialTimeout  time.Duration // 连接卫星的超时
+}
+
+// 默认配置
+func defaultStationConfig() stationConfig {
+	return stationConfig{SlotID: -1, Scheduler: scheduler.DefaultConfig(), SendInterval: 3 * time.Second, DialTimeout: 5 * time.Second}
+}
+
+// 检查配置是否有效
+func (c stationConfig) validate() error {
+	if c.NodeID == "" || len(c.NodeID) > len(protocol.TDMAFrame{}.NodeID) {
+		return fmt.Errorf("节点ID %q 长度应为 1-%d 字节", c.NodeID, len(protocol.TDMAFrame{}.NodeID))
+	}
+	if len(c.Satellites) == 0 {
+		return fmt.Errorf("没有配置卫星")
+	}
+	if err := c.Scheduler.Validate(); err != nil {
+		return err
+	}
+	if c.SlotID < -1 || c.SlotID >= c.Scheduler.TotalSlots {
+		return fmt.Errorf("slotID %d 超出范围 -1-%d", c.SlotID, c.Scheduler.TotalSlots-1)
+	}
+	if c.SendInterval < 0 {
+		return fmt.Errorf("自动发送间隔不能为负数")
+	}
+	if c.DialTimeout <= 0 {
+		return fmt.Errorf("连接超时须大于0")
+	}
+	return nil
+}
+
+// 按配置创建地面站节点，配置须已通过检查
+func NewGroundStationNode(cfg stationConfig) *GroundStationNode {
+	nodeID := cfg.NodeID
+	gsn := &GroundStationNode{
 		nodeID:  nodeID,
 		network: network.NewNetworkInterface(),
-		slotID:  -1,
+		slotID:  cfg.SlotID,
+		backoff: network.NewBackoff(),
+
+		satellites:   cfg.Satellites,
+		layout:       scheduler.NewLayout(cfg.Scheduler),
+		sendInterval: cfg.SendInterval,
+		dialTimeout:  cfg.DialTimeout,
+
+		received: traffic.NewReceiver(),
+		log:      logging.For("groundstation").With(logging.KEY_NODE_ID, nodeID),
 	}
+	gsn.metrics = newStationMetrics(gsn)
+	return gsn
 }
 
 // 连接到卫星节点
 func (gsn *GroundStationNode) ConnectToSatellite(address string) error {
-	conn, err := net.Dial("tcp", address)
-	if err != nil {
-		return fmt.Errorf("连接卫星节点失败: %v", err)
-	}
-
-	gsn.conn = conn
+	gsn.address = address
 	gsn.running = true
 
-	fmt.Printf("地面站节点 %s 已连接到卫星节点 %s\n", gsn.nodeID, address)
+	err := gsn.dial()
+	if err != nil {
+		gsn.running = false
+		gsn.setState(STATE_DISCONNECTED, err.Error())
+		return err
+	}
 
-	// 启动接收循环
-	go gsn.receiveLoop()
+	// 启动时隙发送循环、心跳与星间切换检测
+	go gsn.slotLoop()
+	go gsn.heartbeatLoop()
+	go gsn.handoverLoop()
 
 	return nil
 }
 
 // 断开连接
 func (gsn *GroundStationNode) Disconnect() error {
+	gsn.mu.Lock()
 	gsn.running = false
-
 	if gsn.conn != nil {
 		gsn.conn.Close()
 		gsn.conn = nil
 	}
+	gsn.setStateLocked(STATE_DISCONNECTED, "主动断开")
+	gsn.mu.Unlock()
+	gsn.capture.Close()
 
-	fmt.Printf("地面站节点 %s 已断开连接\n", gsn.nodeID)
+	gsn.log.Info("地面站节点已断开连接")
 	return nil
 }
 
 // 发送TDMA帧
 func (gsn *GroundStationNode) SendFrame(slotID int, data []byte) error {
-	if gsn.conn == nil {
-		return fmt.Errorf("未连接到卫星节点")
-	}
-
-	// 创建TDMA帧
+	// 创建TDMA帧，标注到达卫星时的绝对时隙号，卫星要求与当前时隙一致
 	frame := protocol.NewTDMAFrame(uint32(slotID), gsn.nodeID, data)
-
-	// 序列化帧
-	frameBytes, err := frame.Serialize()
-	if err != nil {
-		return fmt.Errorf("序列化帧失败: %v", err)
-	}
+	frame.SetAbsSlot(uint64(gsn.arrivalSlot().Abs))
 
 	// 发送帧
-	_, err = gsn.conn.Write(frameBytes)
+	err := gsn.writeFrame(frame)
 	if err != nil {
-		return fmt.Errorf("发送帧失败: %v", err)
+		return err
 	}
 
-	fmt.Printf("发送帧: %s\n", frame.String())
-	return nil
-}
+	gsn.mu.Lock()
+	satID := gsn.servingSatellite
+	gsn.mu.Unlock()
+	gsn.metrics.framesSent.With(satID).Inc()
 
-// 获取卫星当前时隙
-func (gsn *GroundStationNode) GetCurrentSlot() (int, error) {
-	if gsn.conn == nil {
-		log.Printf("[GetCurrentSlot] 未连接到卫星节点")
-		return -1, fmt.Errorf("未连接到卫星节点")
-	}
-	log.Printf("[GetCurrentSlot] 发送GET_CURRENT_SLOT请求")
-	frame := protocol.NewTDMAFrame(0, gsn.nodeID, []byte("GET_CURRENT_SLOT"))
-	frameBytes, err := frame.Serialize()
-	if err != nil {
-		log.Printf("[GetCurrentSlot] 序列化帧失败: %v", err)
-		return -1, fmt.Errorf("序列化帧失败: %v", err)
-	}
-	_, err = gsn.conn.Write(frameBytes)
-	if err != nil {
-		log.Printf("[GetCurrentSlot] 发送请求失败: %v", err)
-		return -1, fmt.Errorf("发送请求失败: %v", err)
-	}
-	buffer := make([]byte, 1024)
-	gsn.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
-	n, err := gsn.conn.Read(buffer)
-	if err != nil {
-		log.Printf("[GetCurrentSlot] 读取响应失败: %v", err)
-		return -1, fmt.Errorf("读取响应失败: %v", err)
-	}
-	respFrame, err := protocol.DeserializeTDMAFrame(buffer[:n])
-	if err != nil {
-		log.Printf("[GetCurrentSlot] 解析响应帧失败: %v", err)
-		return -1, fmt.Errorf("解析响应帧失败: %v", err)
-	}
-	slotStr := string(respFrame.Data)
-	log.Printf("[GetCurrentSlot] 收到响应: %s", slotStr)
-	if !strings.HasPrefix(slotStr, "CURRENT_SLOT_") {
-		log.Printf("[GetCurrentSlot] 无效的响应格式: %s", slotStr)
-		return -1, fmt.Errorf("无效的响应格式")
-	}
-	slotStr = strings.TrimPrefix(slotStr, "CURRENT_SLOT_")
-	slot, err := strconv.Atoi(slotStr)
-	if err != nil {
-		log.Printf("[GetCurrentSlot] 解析时隙失败: %v", err)
-		return -1, fmt.Errorf("解析时隙失败: %v", err)
-	}
-	log.Printf("[GetCurrentSlot] 获取到卫星当前时隙: %d", slot)
-	return slot, nil
+	gsn.log.Debug("发送帧", logging.FrameArgs(frame, logging.KEY_PEER, satID)...)
+	return nil
 }
 
 // 发送默认数据
+// 数据先进入发送队列，到达自己的时隙且已入网时才发出；断线期间数据保留在队列中
 func (gsn *GroundStationNode) SendDefaultData() error {
-	log.Printf("[SendDefaultData] 开始发送默认数据流程")
-	// 获取当前全局slotID
-	slotDuration := scheduler.DefaultSlotDuration
-	totalSlots := scheduler.DefaultTotalSlots
-	currentSlot := protocol.GetGlobalSlotID(slotDuration, totalSlots)
-	log.Printf("[SendDefaultData] 当前全局slotID: %d, 我的固定slotID: %d", currentSlot, gsn.slotID)
-	if currentSlot != gsn.slotID {
-		log.Printf("[SendDefaultData] 当前不是我的时隙，跳过发送")
-		return nil
-	}
 	defaultData := []byte(fmt.Sprintf("DEFAULT_DATA_FROM_%s_%d", gsn.nodeID, time.Now().Unix()))
-	log.Printf("[SendDefaultData] 使用时隙: %d, 数据: %s", gsn.slotID, string(defaultData))
-	err := gsn.SendFrame(gsn.slotID, defaultData)
-	if err != nil {
-		log.Printf("[SendDefaultData] 发送帧失败: %v", err)
-		return err
-	}
-	log.Printf("[SendDefaultData] 发送帧成功")
-	return nil
+	gsn.enqueue(defaultData)
+	gsn.log.Debug("默认数据加入发送队列", "current_slot_id", gsn.currentGlobalSlot(), logging.KEY_SLOT_ID, gsn.slotID)
+	return gsn.flushQueue()
 }
 
 // 接收循环
-func (gsn *GroundStationNode) receiveLoop() {
+func (gsn *GroundStationNode) receiveLoop(conn net.Conn) {
 	for gsn.running {
-		if gsn.conn == nil {
-			time.Sleep(100 * time.Millisecond)
-			continue
-		}
-
-		// 设置读取超时
-		gsn.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-
-		// 读取数据
-		buffer := make([]byte, 1024)
-		n, err := gsn.conn.Read(buffer)
+		frame, err := protocol.ReadFrame(conn)
 		if err != nil {
 			if gsn.running {
-				log.Printf("读取数据失败: %v", err)
+				gsn.log.Warn("读取数据失败", "err", err)
 			}
-			continue
-		}
-
-		// 解析TDMA帧
-		frame, err := protocol.DeserializeTDMAFrame(buffer[:n])
-		if err != nil {
-			log.Printf("解析帧失败: %v", err)
-			continue
+			gsn.handleDisconnect(conn, err)
+			return
 		}
+		gsn.capture.Frame(capture.DIR_INBOUND, conn, frame.GetNodeID(), frame)
 
-		// 跳过控制帧（如CURRENT_SLOT响应）
-		if strings.HasPrefix(string(frame.Data), "CURRENT_SLOT_") {
+		// 切换后旧连接上残留的帧不再处理
+		gsn.mu.Lock()
+		current := gsn.conn == conn
+		gsn.mu.Unlock()
+		if !current {
 			continue
 		}
 
@@ -195,14 +228,73 @@ func (gsn *GroundStationNode) receiveLoop() {
 	}
 }
 
+// 时隙发送循环，在每个时隙开始前（按定时提前量）尝试发送队列数据
+func (gsn *GroundStationNode) slotLoop() {
+	for gsn.running {
+		time.Sleep(gsn.untilNextSlot())
+
+		if err := gsn.flushQueue(); err != nil {
+			gsn.log.Warn("发送队列数据失败", "err", err)
+		}
+	}
+}
+
 // 处理接收到的帧
 func (gsn *GroundStationNode) processFrame(frame *protocol.TDMAFrame) {
-	fmt.Printf("接收帧: %s\n", frame.String())
+	gsn.log.Debug("接收帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID())...)
 
 	// 验证帧
 	err := frame.Validate()
 	if err != nil {
-		log.Printf("帧验证失败: %v", err)
+		gsn.log.Warn("帧验证失败", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID(), "err", err)...)
+		gsn.metrics.crcFailures.Inc()
+		return
+	}
+	signed := frame.IsAuthenticated()
+	if !gsn.authenticate(frame) {
+		return
+	}
+
+	// 跳过控制帧（如CURRENT_SLOT响应），认证开启时数据区在此之前是密文
+	if strings.HasPrefix(string(frame.Data), protocol.MSG_CURRENT_SLOT) {
+		return
+	}
+
+	satID := frame.GetNodeID()
+	gsn.metrics.framesReceived.With(satID).Inc()
+
+	gsn.mu.Lock()
+	changed := gsn.servingSatellite != satID
+	gsn.servingSatellite = satID
+	gsn.mu.Unlock()
+	if changed {
+		gsn.updateUplinkGeometry(satID)
+	}
+
+	// 入网/会话恢复确认
+	if gsn.handleControl(string(frame.Data), signed) {
+		return
+	}
+
+	// 其他地面站经卫星转发的数据
+	if msg := string(frame.Data); strings.HasPrefix(msg, protocol.MSG_DATA_FROM) {
+		parts := strings.SplitN(strings.TrimPrefix(msg, protocol.MSG_DATA_FROM), ":", 2)
+		if len(parts) == 2 {
+			if m, ok := traffic.Decode([]byte(parts[1])); ok {
+				gsn.received.Observe(m, time.Now())
+				gsn.log.Debug("收到业务消息", "src", parts[0], "flow", m.Flow, "seq", m.Seq,
+					"latency", time.Since(m.Timestamp).Round(time.Millisecond))
+			} else {
+				gsn.log.Info("收到转发数据", "src", parts[0], "data", parts[1])
+			}
+		}
+		return
+	} else if strings.HasPrefix(msg, protocol.MSG_DATA_UNREACHABLE) {
+		gsn.log.Warn("目的节点不可达", "dst", strings.TrimPrefix(msg, protocol.MSG_DATA_UNREACHABLE))
+		return
+	} else if strings.HasPrefix(msg, protocol.MSG_DATA_DENIED) {
+		dst, reason, _ := strings.Cut(strings.TrimPrefix(msg, protocol.MSG_DATA_DENIED), ":")
+		gsn.log.Warn("不允许发往目的节点", "dst", dst, "reason", reason)
 		return
 	}
 
@@ -214,28 +306,56 @@ func (gsn *GroundStationNode) processFrame(frame *protocol.TDMAFrame) {
 			slotStr := strings.TrimPrefix(ackData, "ACK_SLOT_")
 			slotID, err := strconv.Atoi(slotStr)
 			if err == nil {
+				gsn.mu.Lock()
 				gsn.slotID = slotID
-				fmt.Printf("收到时隙分配确认: 时隙 %d\n", slotID)
+				gsn.mu.Unlock()
+				gsn.log.Debug("收到时隙分配确认", logging.KEY_SLOT_ID, slotID)
 			}
 		}
 	} else {
 		// 处理其他类型的帧
-		fmt.Printf("收到数据: %s\n", string(frame.Data))
+		gsn.log.Info("收到数据", logging.KEY_PEER, satID, "data", string(frame.Data))
+	}
+}
+
+// 认证卫星发来的帧，返回是否继续处理；通过认证的帧去掉认证尾部
+// 配置了密钥时只接受已签名的帧，以及握手与拒绝类消息
+func (gsn *GroundStationNode) authenticate(frame *protocol.TDMAFrame) bool {
+	if gsn.psk == nil {
+		return true
+	}
+	if !frame.IsAuthenticated() && auth.DownlinkExempt(string(frame.Data)) {
+		return true
+	}
+
+	gsn.mu.Lock()
+	as := gsn.auth
+	gsn.mu.Unlock()
+
+	err := fmt.Errorf("没有认证会话")
+	reason := auth.REASON_NO_SESSION
+	if as != nil {
+		if err = as.Open(frame); err == nil {
+			gsn.mu.Lock()
+			gsn.rejectedSince = time.Time{}
+			gsn.mu.Unlock()
+			return true
+		}
+		reason = auth.Reason(err)
 	}
+	gsn.metrics.authFailures.With(reason).Inc()
+	gsn.log.Warn("认证失败，丢弃帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID(), "reason", reason, "err", err)...)
+	return false
 }
 
 // 自动发送循环
 func (gsn *GroundStationNode) autoSendLoop() {
-	log.Printf("[autoSendLoop] 自动发送循环启动")
-	ticker := time.NewTicker(3 * time.Second)
+	gsn.log.Debug("自动发送循环启动", "interval", gsn.sendInterval)
+	ticker := time.NewTicker(gsn.sendInterval)
 	defer ticker.Stop()
 	for range ticker.C {
-		log.Printf("[autoSendLoop] 定时触发发送默认数据")
-		err := gsn.SendDefaultData()
-		if err != nil {
-			log.Printf("[autoSendLoop] 发送默认数据失败: %v", err)
-		} else {
-			log.Printf("[autoSendLoop] 发送默认数据成功")
+		if err := gsn.SendDefaultData(); err != nil {
+			gsn.log.Warn("发送默认数据失败", "err", err)
 		}
 	}
 }
@@ -245,7 +365,9 @@ func (gsn *GroundStationNode) commandLoop() {
 	scanner := bufio.NewScanner(os.Stdin)
 	fmt.Println("地面站节点命令:")
 	fmt.Println("  send - 发送默认数据")
+	fmt.Println("  sendto <节点ID> <内容> - 经卫星网络发送数据到其他地面站")
 	fmt.Println("  status - 显示状态")
+	fmt.Println("  pass - 显示服务卫星下一次过境的时延曲线")
 	fmt.Println("  quit - 退出")
 
 	for gsn.running {
@@ -254,7 +376,8 @@ func (gsn *GroundStationNode) commandLoop() {
 			break
 		}
 
-		command := strings.TrimSpace(scanner.Text())
+		line := strings.TrimSpace(scanner.Text())
+		command, args, _ := strings.Cut(line, " ")
 
 		switch command {
 		case "send":
@@ -262,18 +385,27 @@ func (gsn *GroundStationNode) commandLoop() {
 			if err != nil {
 				fmt.Printf("发送失败: %v\n", err)
 			} else {
-				fmt.Println("发送成功")
+				fmt.Println("已加入发送队列")
 			}
 
+		case "sendto":
+			dst, text, ok := strings.Cut(strings.TrimSpace(args), " ")
+			if !ok || dst == "" {
+				fmt.Println("用法: sendto <节点ID> <内容>")
+				continue
+			}
+			gsn.enqueue([]byte(protocol.MSG_DATA_TO + dst + ":" + text))
+			fmt.Println("已加入发送队列")
+
 		case "status":
 			fmt.Printf("节点ID: %s\n", gsn.nodeID)
 			fmt.Printf("运行状态: %v\n", gsn.running)
 			fmt.Printf("当前时隙: %d\n", gsn.slotID)
-			if gsn.conn != nil {
-				fmt.Printf("连接状态: 已连接\n")
-			} else {
-				fmt.Printf("连接状态: 未连接\n")
-			}
+			gsn.printConnectionStatus()
+			gsn.printTraffic()
+
+		case "pass":
+			gsn.printPass()
 
 		case "quit":
 			gsn.Disconnect()
@@ -286,35 +418,181 @@ func (gsn *GroundStationNode) commandLoop() {
 }
 
 func main() {
-	log.Printf("[main] 地面站节点启动，参数: %v", os.Args)
-	if len(os.Args) < 4 {
-		fmt.Println("用法: groundstation <节点ID> <卫星地址:端口> <slotID>")
+	defaults := defaultStationConfig()
+	flag.String(config.FLAG_CONFIG, "", "配置文件，JSON或每行一个 选项名=值（默认读取TDMA_CONFIG）")
+	nodeID := flag.String("id", "", "地面站节点ID，也可作为第一个位置参数给出")
+	satelliteList := flag.String("satellites", "", "卫星列表 [卫星ID@]地址:端口[,...]，也可作为第二个位置参数给出")
+	slot := flag.Int("slot", defaults.SlotID, "入网前使用的slotID，-1表示由卫星分配，也可作为第三个位置参数给出")
+	totalSlots := flag.Int("slots", defaults.Scheduler.TotalSlots, "每个超帧的时隙数，须与卫星一致")
+	slotDuration := flag.Duration("slot-duration", defaults.Scheduler.SlotDuration, "时隙持续时间，须与卫星一致")
+	sendInterval := flag.Duration("send-interval", defaults.SendInterval, "未配置业务源时自动发送默认数据的间隔，0表示不自动发送")
+	dialTimeout := flag.Duration("dial-timeout", defaults.DialTimeout, "连接卫星的超时")
+	channelFile := flag.String("channel", "", "信道损伤配置文件(JSON)")
+	orbitFile := flag.String("orbit", "", "轨道与可见性配置文件(JSON)")
+	trafficFile := flag.String("traffic-config", "", "业务配置文件(JSON)")
+	metricsAddr := flag.String("metrics", "", "指标监听地址，如 :9101，为空不启用")
+	captureFile := flag.String("capture", "", "收发帧抓包文件(pcapng)")
+	keystoreFile := flag.String("keystore", "", "预共享密钥库(JSON)，使用其中本节点的密钥认证入网并签名、加密每帧")
+	rekeyFrames := flag.Uint64("rekey-frames", auth.DefaultRekeyPolicy.Frames, "每个方向加密该数量的帧后更新会话密钥，0表示不按帧数更新")
+	rekeyInterval := flag.Duration("rekey-interval", auth.DefaultRekeyPolicy.Interval, "会话密钥使用超过该时长后更新，0表示不按时间更新")
+	tlsCert := flag.String("tls-cert", "", "TLS客户端证书(PEM)，卫星要求客户端证书时使用")
+	tlsKey := flag.String("tls-key", "", "TLS客户端私钥(PEM)")
+	tlsCA := flag.String("tls-ca", "", "校验卫星证书的CA(PEM)，配置后到卫星的连接使用TLS 1.3")
+	tlsServerName := flag.String("tls-server-name", "", "校验卫星证书使用的名称（默认取卫星地址中的主机名）")
+	var trafficSpecs specList
+	flag.Var(&trafficSpecs, "traffic", "业务源，可重复: 类型,键=值,... 如 poisson,interval=2s,size=64")
+	logLevel := flag.String("log-level", "", "日志级别，如 info,network=debug（默认读取LOG_LEVEL）")
+	logFormat := flag.String("log-format", "", "日志格式 text|json（默认读取LOG_FORMAT）")
+	flag.Parse()
+
+	// 命令行之外的选项取自环境变量与配置文件
+	if err := config.Apply(flag.CommandLine); err != nil {
+		fmt.Printf("配置无效: %v\n", err)
+		os.Exit(1)
+	}
+	// 兼容位置参数 <节点ID> <卫星列表> <slotID>
+	if flag.NArg() > 3 {
+		fmt.Printf("多余的参数: %v\n", flag.Args()[3:])
 		os.Exit(1)
 	}
+	for i, arg := range flag.Args() {
+		switch i {
+		case 0:
+			*nodeID = arg
+		case 1:
+			*satelliteList = arg
+		case 2:
+			s, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Printf("slotID参数无效: %v\n", err)
+				os.Exit(1)
+			}
+			*slot = s
+		}
+	}
 
-	nodeID := os.Args[1]
-	satelliteAddress := os.Args[2]
-	slotID, err := strconv.Atoi(os.Args[3])
-	if err != nil {
-		fmt.Printf("slotID参数无效: %v\n", err)
+	if err := logging.Setup(*logLevel, *logFormat); err != nil {
+		fmt.Printf("日志参数无效: %v\n", err)
 		os.Exit(1)
 	}
+	logger := logging.For("groundstation")
+	logger.Debug("地面站节点启动", "args", os.Args)
 
+	if *nodeID == "" || *satelliteList == "" {
+		fmt.Println("用法: groundstation [-config 配置文件] [-slots 时隙数] [-slot-duration 时长] [-send-interval 时长] [-dial-timeout 时长] [-channel 配置文件] [-orbit 配置文件] [-traffic 业务] [-traffic-config 配置文件] [-metrics 地址] [-capture 抓包文件] [-keystore 密钥库] [-rekey-frames 帧数] [-rekey-interval 时长] [-tls-ca CA] [-tls-cert 证书 -tls-key 私钥] [-tls-server-name 名称] [-log-level 级别] [-log-format 格式] <节点ID> <[卫星ID@]地址:端口[,...]> [slotID]")
+		fmt.Println("      groundstation -id 节点ID -satellites 卫星列表 [-slot slotID] [选项...]")
+		fmt.Println("除 -config 外的选项都可以写在配置文件中，或用环境变量 TDMA_<选项名> 设置，如 TDMA_SEND_INTERVAL=1s")
+		os.Exit(1)
+	}
+
+	satellites, err := parseSatellites(*satelliteList)
+	if err != nil {
+		logging.Fatal(logger, "卫星列表参数无效", "err", err)
+	}
+	cfg := stationConfig{
+		NodeID:       *nodeID,
+		Satellites:   satellites,
+		SlotID:       *slot,
+		Scheduler:    scheduler.Config{TotalSlots: *totalSlots, SlotDuration: *slotDuration},
+		SendInterval: *sendInterval,
+		DialTimeout:  *dialTimeout,
+	}
+	if err := cfg.validate(); err != nil {
+		logging.Fatal(logger, "配置无效", "err", err)
+	}
 	// 创建地面站节点
-	groundStation := NewGroundStationNode(nodeID)
-	groundStation.slotID = slotID
-	log.Printf("[main] 创建地面站节点: %s, 固定slotID: %d", nodeID, slotID)
+	groundStation := NewGroundStationNode(cfg)
+
+	if *channelFile != "" {
+		channels, err := channel.LoadConfig(*channelFile)
+		if err != nil {
+			logging.Fatal(logger, "加载信道配置失败", "err", err)
+		}
+		groundStation.uplink = channel.New(channels.Link(cfg.NodeID))
+		logger.Info("上行信道", "config", fmt.Sprintf("%+v", channels.Link(cfg.NodeID)))
+	}
+	if *orbitFile != "" {
+		groundStation.visibility, err = orbit.LoadModel(*orbitFile)
+		if err != nil {
+			logging.Fatal(logger, "加载可见性配置失败", "err", err)
+		}
+	}
+	var trafficCfgs []traffic.Config
+	if *trafficFile != "" {
+		trafficCfgs, err = traffic.LoadConfig(*trafficFile)
+		if err != nil {
+			logging.Fatal(logger, "加载业务配置失败", "err", err)
+		}
+	}
+	for _, spec := range trafficSpecs {
+		cfg, err := traffic.ParseSpec(spec)
+		if err != nil {
+			logging.Fatal(logger, "业务参数无效", "spec", spec, "err", err)
+		}
+		trafficCfgs = append(trafficCfgs, cfg)
+	}
+	if err := groundStation.addTraffic(trafficCfgs); err != nil {
+		logging.Fatal(logger, "创建业务源失败", "err", err)
+	}
+	logger.Info("创建地面站节点", logging.KEY_NODE_ID, cfg.NodeID, logging.KEY_SLOT_ID, cfg.SlotID, "slots", cfg.Scheduler.TotalSlots, "slot_duration", cfg.Scheduler.SlotDuration)
+
+	if *keystoreFile != "" {
+		keys, err := auth.LoadKeystore(*keystoreFile)
+		if err != nil {
+			logging.Fatal(logger, "加载密钥库失败", "err", err)
+		}
+		psk, ok := keys.Key(cfg.NodeID)
+		if !ok {
+			logging.Fatal(logger, "密钥库中没有本节点的密钥", "file", *keystoreFile)
+		}
+		groundStation.psk = psk
+		groundStation.rekey = auth.RekeyPolicy{Frames: *rekeyFrames, Interval: *rekeyInterval}
+		logger.Info("启用节点认证", "file", *keystoreFile)
+	}
+
+	if *tlsCA != "" || *tlsCert != "" || *tlsKey != "" {
+		cfg := auth.TLSConfig{Cert: *tlsCert, Key: *tlsKey, CA: *tlsCA, ServerName: *tlsServerName}
+		groundStation.tls, err = cfg.Client()
+		if err != nil {
+			logging.Fatal(logger, "TLS配置无效", "err", err)
+		}
+		logger.Info("启用TLS", "ca", *tlsCA, "client_cert", *tlsCert != "")
+	}
+
+	if *captureFile != "" {
+		groundStation.capture, err = capture.Create(*captureFile, cfg.NodeID)
+		if err != nil {
+			logging.Fatal(logger, "创建抓包文件失败", "err", err)
+		}
+		groundStation.capture.SetLayout(groundStation.layout)
+		logger.Info("抓包", "file", *captureFile)
+	}
+	if *metricsAddr != "" {
+		if _, err := metrics.Serve(*metricsAddr, groundStation.metrics.registry); err != nil {
+			logging.Fatal(logger, "启动指标服务失败", "err", err)
+		}
+		logger.Info("指标服务", "url", "http://"+*metricsAddr+"/metrics")
+	}
 
-	// 连接到卫星节点
-	err = groundStation.ConnectToSatellite(satelliteAddress)
+	// 连接到当前可见的卫星节点
+	target, ok := groundStation.bestSatellite("")
+	if !ok {
+		target = satellites[0]
+	}
+	groundStation.servingSatellite = target.ID
+	err = groundStation.ConnectToSatellite(target.Address)
 	if err != nil {
-		log.Fatalf("[main] 连接卫星节点失败: %v", err)
+		logging.Fatal(logger, "连接卫星节点失败", "err", err)
 	}
-	log.Printf("[main] 已连接到卫星节点: %s", satelliteAddress)
 
-	// 启动自动发送循环
-	go groundStation.autoSendLoop()
-	log.Printf("[main] 自动发送循环已启动")
+	// 启动业务源，未配置时启动自动发送循环
+	if len(groundStation.sources) > 0 {
+		groundStation.startTraffic()
+	} else {
+		if cfg.SendInterval > 0 {
+			go groundStation.autoSendLoop()
+		}
+	}
 
 	// 启动命令行交互
 	groundStation.commandLoop()
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"tdma-network/internal/metrics"
+)
+
+// 地面站节点指标
+type stationMetrics struct {
+	registry *metrics.Registry
+
+	framesReceived *metrics.CounterVec // 按卫星
+	framesSent     *metrics.CounterVec // 按卫星
+	crcFailures    *metrics.Counter
+	authFailures   *metrics.CounterVec // 按原因
+	queueDrops     *metrics.Counter
+	joinRetries    *metrics.Counter
+	reconnects     *metrics.Counter
+	heartbeatLost  *metrics.Counter
+	handovers      *metrics.CounterVec // 按结果
+}
+
+// 创建并注册地面站节点指标
+func newStationMetrics(gsn *GroundStationNode) *stationMetrics {
+	r := metrics.NewRegistry()
+	m := &stationMetrics{
+		registry: r,
+
+		framesReceived: r.CounterVec("tdma_frames_received_total", "通过校验的下行帧数", "node_id"),
+		framesSent:     r.CounterVec("tdma_frames_sent_total", "发出的上行数据帧数", "node_id"),
+		crcFailures:    r.Counter("tdma_crc_failures_total", "校验失败的帧数"),
+		authFailures:   r.CounterVec("tdma_auth_failures_total", "认证失败而丢弃的下行帧数", "reason"),
+		queueDrops:     r.Counter("tdma_queue_drops_total", "发送队列已满而丢弃的数据数"),
+		joinRetries:    r.Counter("tdma_join_retransmissions_total", "未收到确认而重发的入网/恢复请求数"),
+		reconnects:     r.Counter("tdma_reconnects_total", "重连尝试次数"),
+		heartbeatLost:  r.Counter("tdma_heartbeat_timeouts_total", "心跳确认超时次数"),
+		handovers:      r.CounterVec("tdma_handovers_total", "星间切换次数", "result"),
+	}
+
+	r.GaugeFunc("tdma_queue_depth", "发送队列中待发送的数据数", func() float64 {
+		gsn.mu.Lock()
+		defer gsn.mu.Unlock()
+		return float64(len(gsn.outbox))
+	})
+	r.GaugeFunc("tdma_slot_id", "当前使用的时隙，未分配为-1", func() float64 {
+		gsn.mu.Lock()
+		defer gsn.mu.Unlock()
+		return float64(gsn.slotID)
+	})
+	r.GaugeFunc("tdma_joined", "是否已入网(1/0)", func() float64 {
+		if gsn.State() == STATE_JOINED {
+			return 1
+		}
+		return 0
+	})
+	r.GaugeFunc("tdma_timing_advance_seconds", "定时提前量", func() float64 {
+		gsn.mu.Lock()
+		defer gsn.mu.Unlock()
+		return gsn.timingAdvance.Seconds()
+	})
+	return m
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/orbit"
+	"tdma-network/internal/scheduler"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 保留的时延测量样本数
+const maxDelaySamples = 32
+
+// 一次单向时延测量
+type delaySample struct {
+	At    time.Time
+	Delay time.Duration
+}
+
+// 根据卫星发送时刻记录一次单向时延测量，并更新定时提前量
+func (gsn *GroundStationNode) recordDelay(txUnixNano int64) {
+	now := time.Now()
+	delay := now.Sub(time.Unix(0, txUnixNano))
+	if delay < 0 {
+		delay = 0
+	}
+
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+
+	gsn.delaySamples = append(gsn.delaySamples, delaySample{At: now, Delay: delay})
+	if len(gsn.delaySamples) > maxDelaySamples {
+		gsn.delaySamples = gsn.delaySamples[len(gsn.delaySamples)-maxDelaySamples:]
+	}
+	// 上行与下行传播时延近似相等，提前该时长发送使帧落入卫星侧的目标时隙
+	gsn.timingAdvance = delay
+}
+
+// 时延漂移率（每秒时延变化），由最早与最新样本估计
+func (gsn *GroundStationNode) delayDrift() time.Duration {
+	if len(gsn.delaySamples) < 2 {
+		return 0
+	}
+	first, last := gsn.delaySamples[0], gsn.delaySamples[len(gsn.delaySamples)-1]
+	span := last.At.Sub(first.At).Seconds()
+	if span <= 0 {
+		return 0
+	}
+	return time.Duration(float64(last.Delay-first.Delay) / span)
+}
+
+// 获取当前定时提前量
+func (gsn *GroundStationNode) getTimingAdvance() time.Duration {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+	return gsn.timingAdvance
+}
+
+// 获取全局统一时钟下的当前时隙（按卫星侧到达时刻计算，已计入定时提前量）
+func (gsn *GroundStationNode) currentGlobalSlot() int {
+	return gsn.arrivalSlot().Slot
+}
+
+// 现在发出的帧到达卫星时所在的时隙，含绝对时隙号
+func (gsn *GroundStationNode) arrivalSlot() scheduler.SlotTime {
+	arrival := time.Now().Add(gsn.getTimingAdvance())
+	return gsn.layoutAt(arrival).At(arrival)
+}
+
+// 距下一次发送时机（时隙边界减去定时提前量）的等待时间
+func (gsn *GroundStationNode) untilNextSlot() time.Duration {
+	arrival := time.Now().Add(gsn.getTimingAdvance())
+	return gsn.layoutAt(arrival).At(arrival).End.Sub(arrival)
+}
+
+// 卫星侧t时刻的帧结构，到达已通告变更的生效时刻时切换
+// 届时仍使用通告中原时隙的改用新时隙，新时隙为-1时等待心跳确认重新分配
+func (gsn *GroundStationNode) layoutAt(t time.Time) scheduler.Layout {
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+
+	c := gsn.pendingLayout
+	if c == nil || t.Before(c.At) {
+		return gsn.layout
+	}
+	gsn.layout = gsn.layout.Next(scheduler.Config{TotalSlots: c.TotalSlots, SlotDuration: c.SlotDuration}, c.At)
+	gsn.pendingLayout = nil
+	gsn.capture.SetLayout(gsn.layout)
+	if gsn.slotID == c.From && c.To != c.From {
+		gsn.log.Info("帧结构变更迁移时隙", "old_slot_id", c.From, logging.KEY_SLOT_ID, c.To)
+		gsn.slotID = c.To
+	}
+	gsn.log.Info("帧结构变更生效", "slots", c.TotalSlots, "slot_duration", c.SlotDuration)
+	return gsn.layout
+}
+
+// 记录卫星通告的帧结构变更
+func (gsn *GroundStationNode) scheduleLayout(c protocol.ScheduleChange) error {
+	cfg := scheduler.Config{TotalSlots: c.TotalSlots, SlotDuration: c.SlotDuration}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	gsn.mu.Lock()
+	defer gsn.mu.Unlock()
+	if !gsn.layout.IsSuperframeBoundary(c.At) {
+		return fmt.Errorf("生效时刻 %s 不是超帧边界", c.At.Format(time.RFC3339Nano))
+	}
+	gsn.pendingLayout = &c
+	return nil
+}
+
+// 服务卫星变化时更新上行几何传播模型
+func (gsn *GroundStationNode) updateUplinkGeometry(satID string) {
+	if gsn.uplink == nil {
+		return
+	}
+	cfg := gsn.uplink.Config()
+	if !cfg.Geometry {
+		return
+	}
+	if link, ok := gsn.visibility.Link(satID, gsn.nodeID, cfg.CarrierHz); ok {
+		gsn.uplink.SetPropagation(link)
+	} else {
+		gsn.uplink.SetPropagation(nil)
+	}
+}
+
+// 打印链路定时状态（调用方需持有gsn.mu）
+func (gsn *GroundStationNode) printTimingLocked() {
+	if len(gsn.delaySamples) == 0 {
+		return
+	}
+	last := gsn.delaySamples[len(gsn.delaySamples)-1]
+	fmt.Printf("传播时延: %v (漂移 %v/s)\n", last.Delay.Round(time.Microsecond), gsn.delayDrift().Round(time.Nanosecond))
+	fmt.Printf("定时提前量: %v\n", gsn.timingAdvance.Round(time.Microsecond))
+}
+
+// 打印服务卫星下一次过境的时延曲线
+func (gsn *GroundStationNode) printPass() {
+	gsn.mu.Lock()
+	satID := gsn.servingSatellite
+	gsn.mu.Unlock()
+
+	var carrier float64
+	if gsn.uplink != nil {
+		carrier = gsn.uplink.Config().CarrierHz
+	}
+	link, ok := gsn.visibility.Link(satID, gsn.nodeID, carrier)
+	if !ok {
+		fmt.Printf("未配置卫星 %s 与本站的轨道几何\n", satID)
+		return
+	}
+	p, ok := gsn.visibility.NextPass(satID, gsn.nodeID, time.Now(), 24*time.Hour, 10*time.Second)
+	if !ok {
+		fmt.Println("24小时内没有过境")
+		return
+	}
+	orbit.PrintCurve(link.Curve(p, 30*time.Second))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"tdma-network/internal/traffic"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 可重复的 -traffic 参数
+type specList []string
+
+func (l *specList) String() string     { return strings.Join(*l, " ") }
+func (l *specList) Set(s string) error { *l = append(*l, s); return nil }
+
+// 一个业务源
+type trafficSource struct {
+	cfg  traffic.Config
+	flow string
+	gen  traffic.Generator
+	seq  uint64
+	sent atomic.Int64
+	done atomic.Bool
+}
+
+// 按配置添加业务源
+func (gsn *GroundStationNode) addTraffic(cfgs []traffic.Config) error {
+	for _, cfg := range cfgs {
+		gen, err := traffic.New(cfg)
+		if err != nil {
+			return err
+		}
+		gsn.sources = append(gsn.sources, &trafficSource{
+			cfg:  cfg,
+			flow: cfg.FlowName(len(gsn.sources)),
+			gen:  gen,
+		})
+	}
+	return nil
+}
+
+// 启动全部业务源
+func (gsn *GroundStationNode) startTraffic() {
+	for _, src := range gsn.sources {
+		go gsn.trafficLoop(src)
+	}
+}
+
+// 业务发送循环，消息加入发送队列后在自己的时隙内发出
+func (gsn *GroundStationNode) trafficLoop(src *trafficSource) {
+	gsn.log.Info("业务源启动", "flow", src.flow, "type", src.cfg.Type)
+	for gsn.running {
+		wait, payload, ok := src.gen.Next()
+		if !ok {
+			src.done.Store(true)
+			gsn.log.Info("业务源发送完毕", "flow", src.flow, "sent", src.sent.Load())
+			return
+		}
+		time.Sleep(wait)
+
+		src.seq++
+		msg := traffic.Message{
+			Source:    gsn.nodeID,
+			Flow:      src.flow,
+			Seq:       src.seq,
+			Timestamp: time.Now(),
+			Payload:   payload,
+		}
+		data := msg.Encode()
+		if src.cfg.Destination != "" {
+			data = append([]byte(protocol.MSG_DATA_TO+src.cfg.Destination+":"), data...)
+		}
+		gsn.enqueue(data)
+		src.sent.Add(1)
+	}
+}
+
+// 打印业务发送与接收统计
+func (gsn *GroundStationNode) printTraffic() {
+	if len(gsn.sources) > 0 {
+		fmt.Println("业务源:")
+		for _, src := range gsn.sources {
+			dest := src.cfg.Destination
+			if dest == "" {
+				dest = "卫星"
+			}
+			state := "运行中"
+			if src.done.Load() {
+				state = "已结束"
+			}
+			fmt.Printf("  %s (%s) -> %s: 已产生 %d 条, %s\n", src.flow, src.cfg.Type, dest, src.sent.Load(), state)
+		}
+	}
+	gsn.received.Print()
+}
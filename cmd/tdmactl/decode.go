@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"tdma-network/pkg/protocol"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 帧内各字段的偏移
+const (
+	offSlotID     = 8
+	offNodeID     = 12
+	offLength     = 44
+	offFragmentID = 48
+	offTotalFrags = 52
+	offFragIndex  = 54
+	offFlags      = 56
+	offAbsSlot    = 58
+	offData       = protocol.FRAME_PREFIX_LEN
+)
+
+// 数据区显示的最大字节数
+const maxDataShown = 64
+
+// decode子命令，返回输入中是否没有发现问题
+func runDecode(args []string) (bool, error) {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	isBase64 := fs.Bool("base64", false, "输入为base64")
+	file := fs.String("file", "", "从二进制文件读取")
+	fs.Parse(args)
+
+	var data []byte
+	var err error
+	switch {
+	case *file != "":
+		data, err = os.ReadFile(*file)
+		if err != nil {
+			return false, fmt.Errorf("读取文件失败: %v", err)
+		}
+	default:
+		text := strings.Join(fs.Args(), "")
+		if text == "" {
+			in, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return false, fmt.Errorf("读取标准输入失败: %v", err)
+			}
+			text = string(in)
+		}
+		data, err = parseText(text, *isBase64)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return decodeStream(os.Stdout, data), nil
+}
+
+// 解析十六进制或base64文本，忽略空白、0x前缀与冒号分隔
+func parseText(text string, isBase64 bool) ([]byte, error) {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("无效的base64: %v", err)
+		}
+		return data, nil
+	}
+
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+	text = strings.ReplaceAll(text, ":", "")
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("无效的十六进制: %v", err)
+	}
+	return data, nil
+}
+
+// 逐帧解析字节流并打印，返回是否没有发现问题
+func decodeStream(w io.Writer, data []byte) bool {
+	var frames, problems int
+	report := func(format string, args ...any) {
+		problems++
+		fmt.Fprintf(w, "  ! "+format+"\n", args...)
+	}
+
+	off := 0
+	for off < len(data) {
+		i := bytes.Index(data[off:], protocol.FRAME_HEADER[:])
+		switch {
+		case i < 0 && frames == 0 && off == 0:
+			// 没有任何有效帧头，按单帧解析以显示各字段
+			fmt.Fprintf(w, "偏移 %d: 未找到帧头，按单帧解析\n", off)
+		case i < 0:
+			fmt.Fprintf(w, "偏移 %d:\n", off)
+			report("末尾 %d 字节不含帧头: %s", len(data)-off, preview(data[off:]))
+			off = len(data)
+			continue
+		case i > 0:
+			fmt.Fprintf(w, "偏移 %d:\n", off)
+			report("无效的帧头，跳过 %d 字节: %s", i, preview(data[off:off+i]))
+			off += i
+		}
+
+		frames++
+		n, found := inspectFrame(w, frames, data, off)
+		problems += found
+		off += n
+	}
+
+	if len(data) == 0 {
+		fmt.Fprintln(w, "输入为空")
+		return false
+	}
+	fmt.Fprintf(w, "共 %d 帧, %d 字节, 发现问题 %d 处\n", frames, len(data), problems)
+	return problems == 0
+}
+
+// 解析并打印data[off:]处的一帧，返回消耗的字节数与发现的问题数
+// Length超出剩余数据时只消耗帧头，从其后重新寻找帧头
+func inspectFrame(w io.Writer, index int, data []byte, off int) (int, int) {
+	buf := data[off:]
+	problems := 0
+	problem := func(format string, args ...any) {
+		problems++
+		fmt.Fprintf(w, "  ! "+format+"\n", args...)
+	}
+
+	minLen := protocol.FRAME_PREFIX_LEN + protocol.FRAME_SUFFIX_LEN
+	if len(buf) < minLen {
+		fmt.Fprintf(w, "帧 #%d 偏移 %d\n", index, off)
+		problem("帧长度不足: 剩余 %d 字节，最短帧 %d 字节", len(buf), minLen)
+		return len(buf), problems
+	}
+
+	length := binary.BigEndian.Uint32(buf[offLength:])
+	total := minLen + int(length)
+	if uint64(minLen)+uint64(length) > uint64(len(buf)) {
+		fmt.Fprintf(w, "帧 #%d 偏移 %d\n", index, off)
+		printPrefix(w, off, buf)
+		problem("长度不匹配: Length为 %d，剩余数据只能容纳 %d 字节", length, len(buf)-minLen)
+		if i := bytes.Index(buf[offData:], protocol.FRAME_FOOTER[:]); i >= 4 {
+			problem("在偏移 %d 找到帧尾，按此Length应为 %d", off+offData+i, i-4)
+		}
+		return len(protocol.FRAME_HEADER), problems
+	}
+
+	fmt.Fprintf(w, "帧 #%d 偏移 %d，共 %d 字节\n", index, off, total)
+	frame, err := protocol.DeserializeTDMAFrame(buf[:total])
+	if err != nil {
+		problem("反序列化失败: %v", err)
+		return total, problems
+	}
+
+	printPrefix(w, off, buf)
+	fmt.Fprintf(w, "  %6d  %-11s %s\n", off+offData, "Data", describeData(frame.Data))
+	crcOff := offData + int(length)
+	fmt.Fprintf(w, "  %6d  %-11s 0x%08X\n", off+crcOff, "CRC", frame.CRC)
+	fmt.Fprintf(w, "  %6d  %-11s %x\n", off+crcOff+4, "Footer", frame.Footer[:])
+
+	if frame.Header != protocol.FRAME_HEADER {
+		problem("无效的帧头: %x，应为 %x", frame.Header[:], protocol.FRAME_HEADER[:])
+	}
+	if frame.Footer != protocol.FRAME_FOOTER {
+		problem("无效的帧尾: %x，应为 %x", frame.Footer[:], protocol.FRAME_FOOTER[:])
+		if i := bytes.Index(buf[offData:], protocol.FRAME_FOOTER[:]); i >= 4 {
+			problem("在偏移 %d 找到帧尾，按此Length应为 %d", off+offData+i, i-4)
+		}
+	}
+	if expected := frame.CalculateCRC(); expected != frame.CRC {
+		problem("CRC不匹配: 期望 0x%08X，实际 0x%08X", expected, frame.CRC)
+	}
+	if err := frame.Validate(); err != nil && problems == 0 {
+		problem("校验失败: %v", err)
+	}
+	if problems == 0 {
+		fmt.Fprintln(w, "  校验通过")
+	}
+	return total, problems
+}
+
+// 打印数据区之前的固定字段
+func printPrefix(w io.Writer, off int, buf []byte) {
+	flags := binary.BigEndian.Uint16(buf[offFlags:])
+	nodeID := strings.TrimRight(string(buf[offNodeID:offLength]), "\x00")
+
+	fmt.Fprintf(w, "  %6s  %-11s %s\n", "偏移", "字段", "值")
+	fmt.Fprintf(w, "  %6d  %-11s %x\n", off, "Header", buf[:offSlotID])
+	fmt.Fprintf(w, "  %6d  %-11s %d\n", off+offSlotID, "SlotID", binary.BigEndian.Uint32(buf[offSlotID:]))
+	fmt.Fprintf(w, "  %6d  %-11s %q\n", off+offNodeID, "NodeID", nodeID)
+	fmt.Fprintf(w, "  %6d  %-11s %d\n", off+offLength, "Length", binary.BigEndian.Uint32(buf[offLength:]))
+	fmt.Fprintf(w, "  %6d  %-11s %d\n", off+offFragmentID, "FragmentID", binary.BigEndian.Uint32(buf[offFragmentID:]))
+	fmt.Fprintf(w, "  %6d  %-11s %d\n", off+offTotalFrags, "TotalFrags", binary.BigEndian.Uint16(buf[offTotalFrags:]))
+	fmt.Fprintf(w, "  %6d  %-11s %d\n", off+offFragIndex, "FragIndex", binary.BigEndian.Uint16(buf[offFragIndex:]))
+	fmt.Fprintf(w, "  %6d  %-11s 0x%04X %s\n", off+offFlags, "Flags", flags, flagNames(flags))
+	fmt.Fprintf(w, "  %6d  %-11s %d\n", off+offAbsSlot, "AbsSlot", binary.BigEndian.Uint64(buf[offAbsSlot:]))
+}
+
+// 标志位名称
+func flagNames(flags uint16) string {
+	var names []string
+	for _, f := range []struct {
+		bit  uint16
+		name string
+	}{
+		{protocol.FLAG_FRAGMENT, "FRAGMENT"},
+		{protocol.FLAG_FIRST_FRAG, "FIRST"},
+		{protocol.FLAG_LAST_FRAG, "LAST"},
+		{protocol.FLAG_NEED_ACK, "NEED_ACK"},
+		{protocol.FLAG_AUTH, "AUTH"},
+		{protocol.FLAG_ENCRYPTED, "ENCRYPTED"},
+		{protocol.FLAG_SEQ, "SEQ"},
+	} {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// 数据区：可打印文本加引号显示，否则显示十六进制
+func describeData(data []byte) string {
+	if len(data) == 0 {
+		return "(空)"
+	}
+	if utf8.Valid(data) && !bytes.ContainsFunc(data, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		return fmt.Sprintf("%q (%d 字节)", data, len(data))
+	}
+	return fmt.Sprintf("%s (%d 字节)", preview(data), len(data))
+}
+
+// 十六进制预览，过长时截断
+func preview(data []byte) string {
+	if len(data) > maxDataShown {
+		return fmt.Sprintf("%x...", data[:maxDataShown])
+	}
+	return fmt.Sprintf("%x", data)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"tdma-network/pkg/protocol"
+	"testing"
+)
+
+func testFrame(t *testing.T, slotID uint32, nodeID, data string) []byte {
+	t.Helper()
+	f := protocol.NewTDMAFrame(slotID, nodeID, []byte(data))
+	f.SetAbsSlot(176534000)
+	raw, err := f.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	return raw
+}
+
+// 输出中以 "!" 开头的问题行
+func problemLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if rest, ok := strings.CutPrefix(line, "  ! "); ok {
+			lines = append(lines, rest)
+		}
+	}
+	return lines
+}
+
+func TestDecodeStream(t *testing.T) {
+	good := testFrame(t, 3, "GS1", "DATA_TO:GS2:hello")
+	second := testFrame(t, 4, "GS2", "HEARTBEAT")
+	third := testFrame(t, 5, "SAT_A", "ACK_SLOT_5")
+	dataLen := len("DATA_TO:GS2:hello")
+
+	// 数据区被改动，CRC与内容不符
+	corrupted := bytes.Clone(good)
+	corrupted[offData] ^= 0xFF
+	f, err := protocol.DeserializeTDMAFrame(corrupted)
+	if err != nil {
+		t.Fatalf("DeserializeTDMAFrame: %v", err)
+	}
+	crcMismatch := fmt.Sprintf("CRC不匹配: 期望 0x%08X，实际 0x%08X", f.CalculateCRC(), f.CRC)
+
+	// Length比实际数据多5字节
+	tooLong := bytes.Clone(good)
+	binary.BigEndian.PutUint32(tooLong[offLength:], uint32(dataLen+5))
+
+	// 帧头被改动，整个输入中找不到帧头；CRC覆盖帧头，同样不匹配
+	badHeader := bytes.Clone(good)
+	badHeader[0] = 0x00
+	if f, err = protocol.DeserializeTDMAFrame(badHeader); err != nil {
+		t.Fatalf("DeserializeTDMAFrame: %v", err)
+	}
+	headerCRC := fmt.Sprintf("CRC不匹配: 期望 0x%08X，实际 0x%08X", f.CalculateCRC(), f.CRC)
+
+	tests := []struct {
+		name     string
+		input    []byte
+		ok       bool
+		problems []string
+		summary  string
+	}{
+		{"valid frame", good, true, nil, "共 1 帧"},
+		{"several frames", bytes.Join([][]byte{good, second, third}, nil), true, nil, "共 3 帧"},
+		{"garbage before header", append([]byte{0xde, 0xad, 0xbe, 0xef}, good...), false,
+			[]string{"无效的帧头，跳过 4 字节: deadbeef"}, "共 1 帧"},
+		{"no header", badHeader, false,
+			[]string{fmt.Sprintf("无效的帧头: %x，应为 %x", badHeader[:8], protocol.FRAME_HEADER[:]), headerCRC}, "共 1 帧"},
+		{"crc mismatch", corrupted, false, []string{crcMismatch}, "共 1 帧"},
+		{"length mismatch", tooLong, false, []string{
+			fmt.Sprintf("长度不匹配: Length为 %d，剩余数据只能容纳 %d 字节", dataLen+5, dataLen),
+			fmt.Sprintf("在偏移 %d 找到帧尾，按此Length应为 %d", offData+dataLen+4, dataLen),
+			// 只跳过帧头后重新寻找帧头，其后没有其他帧
+			fmt.Sprintf("末尾 %d 字节不含帧头: %s", len(tooLong)-8, preview(tooLong[8:])),
+		}, "共 1 帧"},
+		{"trailing bytes", append(bytes.Clone(good), 0x01, 0x02), false,
+			[]string{"末尾 2 字节不含帧头: 0102"}, "共 1 帧"},
+		{"problem between frames", bytes.Join([][]byte{good, corrupted, second}, nil), false,
+			[]string{crcMismatch}, "共 3 帧"},
+		{"truncated frame", good[:offData], false,
+			[]string{fmt.Sprintf("帧长度不足: 剩余 %d 字节，最短帧 %d 字节", offData, protocol.FRAME_PREFIX_LEN+protocol.FRAME_SUFFIX_LEN)}, "共 1 帧"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ok := decodeStream(&buf, tt.input)
+			out := buf.String()
+			if ok != tt.ok {
+				t.Fatalf("decodeStream = %v, want %v\n%s", ok, tt.ok, out)
+			}
+			got := problemLines(out)
+			if strings.Join(got, "\n") != strings.Join(tt.problems, "\n") {
+				t.Fatalf("problems:\n%s\nwant:\n%s\noutput:\n%s", strings.Join(got, "\n"), strings.Join(tt.problems, "\n"), out)
+			}
+			wantSummary := fmt.Sprintf("%s, %d 字节, 发现问题 %d 处", tt.summary, len(tt.input), len(tt.problems))
+			if !strings.Contains(out, wantSummary) {
+				t.Fatalf("output lacks summary %q:\n%s", wantSummary, out)
+			}
+			if tt.ok && strings.Count(out, "校验通过") != strings.Count(out, "帧 #") {
+				t.Fatalf("not every frame passed validation:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestDecodeStreamEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if decodeStream(&buf, nil) {
+		t.Fatal("empty input reported as valid")
+	}
+	if !strings.Contains(buf.String(), "输入为空") {
+		t.Fatalf("output = %q", buf.String())
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"tdma-network/pkg/protocol"
+)
+
+// encode子命令
+func runEncode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	slotID := fs.Uint("slot", 0, "时隙")
+	nodeID := fs.String("node", "", "节点ID")
+	data := fs.String("data", "", "数据区内容")
+	dataHex := fs.String("data-hex", "", "数据区内容（十六进制），优先于 -data")
+	fragmentID := fs.Uint("frag-id", 0, "分片ID")
+	totalFrags := fs.Uint("total-frags", 1, "总分片数")
+	fragIndex := fs.Uint("frag-index", 0, "分片索引")
+	flags := fs.String("flags", "0", "标志位，如 0x0003")
+	absSlot := fs.Uint64("abs-slot", 0, "绝对时隙号，0表示未标注")
+	seq := fs.Uint64("seq", 0, "明文序号，非0时在数据区末尾附加序号尾部")
+	crc := fs.String("crc", "", "覆盖CRC字段（如 0xDEADBEEF），用于构造错误帧；默认按内容计算")
+	format := fs.String("format", "hex", "输出格式 hex|base64|raw")
+	fs.Parse(args)
+
+	payload := []byte(*data)
+	if *dataHex != "" {
+		var err error
+		payload, err = hex.DecodeString(*dataHex)
+		if err != nil {
+			return fmt.Errorf("无效的 -data-hex: %v", err)
+		}
+	}
+	if len(*nodeID) > 32 {
+		return fmt.Errorf("节点ID超过32字节")
+	}
+	flagValue, err := strconv.ParseUint(*flags, 0, 16)
+	if err != nil {
+		return fmt.Errorf("无效的 -flags: %v", err)
+	}
+	if *totalFrags > 0xFFFF || *fragIndex > 0xFFFF || *slotID > 0xFFFFFFFF || *fragmentID > 0xFFFFFFFF {
+		return fmt.Errorf("字段取值超出范围")
+	}
+
+	frame := protocol.NewTDMAFrame(uint32(*slotID), *nodeID, payload)
+	frame.FragmentID = uint32(*fragmentID)
+	frame.TotalFrags = uint16(*totalFrags)
+	frame.FragIndex = uint16(*fragIndex)
+	frame.Flags = uint16(flagValue)
+	frame.AbsSlot = *absSlot
+	frame.CRC = frame.CalculateCRC()
+	if *seq != 0 {
+		frame.SetSequence(*seq)
+	}
+	if *crc != "" {
+		v, err := strconv.ParseUint(*crc, 0, 32)
+		if err != nil {
+			return fmt.Errorf("无效的 -crc: %v", err)
+		}
+		frame.CRC = uint32(v)
+	}
+
+	raw, err := frame.Serialize()
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "hex":
+		fmt.Println(hex.EncodeToString(raw))
+	case "base64":
+		fmt.Println(base64.StdEncoding.EncodeToString(raw))
+	case "raw":
+		_, err = os.Stdout.Write(raw)
+	default:
+		return fmt.Errorf("未知的输出格式: %s", *format)
+	}
+	return err
+}
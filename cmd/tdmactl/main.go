@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Println("用法: tdmactl <命令> [参数]")
+	fmt.Println("命令:")
+	fmt.Println("  decode [-base64] [-file 文件] [数据] - 解析并校验TDMA帧，数据默认为十六进制，省略时从标准输入读取")
+	fmt.Println("  encode [-slot 时隙] [-node 节点ID] [-data 内容] ... - 按参数生成TDMA帧")
+	fmt.Println("使用 tdmactl <命令> -h 查看命令参数")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "decode":
+		var ok bool
+		ok, err = runDecode(os.Args[2:])
+		if err == nil && !ok {
+			os.Exit(1)
+		}
+	case "encode":
+		err = runEncode(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Printf("未知命令: %s\n", os.Args[1])
+		usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/satellite"
+)
+
+func main() {
+	duration := flag.Duration("duration", 0, "覆盖场景中的仿真时长")
+	seed := flag.Int64("seed", 0, "覆盖场景中的随机种子")
+	logLevel := flag.String("log-level", "", "日志级别，如 info,isl=debug（默认读取LOG_LEVEL，未设置时只输出错误）")
+	logFormat := flag.String("log-format", "", "日志格式 text|json（默认读取LOG_FORMAT）")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Println("用法: tdmasim [-duration 时长] [-seed 种子] [-log-level 级别] <场景文件>")
+		os.Exit(1)
+	}
+
+	// 默认只输出错误日志，以免卫星的运行日志淹没报告
+	if *logLevel == "" && os.Getenv("LOG_LEVEL") == "" {
+		*logLevel = "error"
+	}
+	if err := logging.Setup(*logLevel, *logFormat); err != nil {
+		fmt.Printf("日志参数无效: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := satellite.Simulate(flag.Arg(0), *duration, *seed); err != nil {
+		logging.Fatal(logging.For("tdmasim"), "仿真失败", "err", err)
+	}
+}
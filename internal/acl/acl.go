@@ -0,0 +1,230 @@
+package acl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 拒绝原因，同时用作 JOIN_REJECT_<原因>、DATA_DENIED 与指标标签
+const (
+	REASON_NOT_ALLOWED    = "not_allowed"    // 策略中没有匹配该节点的条目
+	REASON_OUTSIDE_WINDOW = "outside_window" // 不在允许的时间窗口内
+	REASON_SLOT_QUOTA     = "slot_quota"     // 超过时隙数上限
+	REASON_PRIORITY       = "priority"       // 超过优先级上限
+	REASON_DESTINATION    = "destination"    // 不允许发往该目的节点
+)
+
+// 访问被拒绝
+type Error struct {
+	Reason string
+	msg    string
+}
+
+func (e *Error) Error() string { return e.msg }
+
+func deny(reason, format string, args ...any) error {
+	return &Error{Reason: reason, msg: fmt.Sprintf(format, args...)}
+}
+
+// 拒绝的原因，err不是访问被拒绝时返回空串
+func Reason(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ""
+}
+
+// 每日时间窗口（UTC），结束早于开始时跨越午夜
+type Window struct {
+	Start, End time.Duration // 自零点起的偏移
+}
+
+// 解析 "HH:MM-HH:MM"
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("时间窗口格式应为 HH:MM-HH:MM: %q", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return Window{}, err
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("时间窗口为空: %q", s)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("无效的时刻 %q: %v", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// t是否处于窗口内
+func (w Window) Contains(t time.Time) bool {
+	t = t.UTC()
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	if w.Start < w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// 策略条目，数值字段为0表示不限制
+type Entry struct {
+	Node         string   `json:"node"`                   // 节点ID或通配模式（path.Match语法，如 GS*）
+	MaxSlots     int      `json:"max_slots,omitempty"`    // 同时持有的时隙数上限
+	MaxPriority  int      `json:"max_priority,omitempty"` // 优先级上限
+	Windows      []string `json:"windows,omitempty"`      // 允许接入的每日时间窗口（UTC），为空表示全天
+	Destinations []string `json:"destinations,omitempty"` // 允许发往的目的节点或通配模式，为空表示不限制
+
+	windows []Window
+}
+
+// 访问控制策略，按顺序匹配第一条条目，没有匹配的节点一律拒绝
+//
+//	{
+//	  "nodes": [
+//	    {"node": "GS1", "max_slots": 2, "max_priority": 5, "windows": ["06:00-18:00"], "destinations": ["GS2", "GS3"]},
+//	    {"node": "GS*", "max_slots": 1, "max_priority": 1}
+//	  ]
+//	}
+type Policy struct {
+	Nodes []Entry `json:"nodes"`
+}
+
+// 加载策略文件
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取访问控制策略失败: %v", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("解析访问控制策略失败: %v", err)
+	}
+
+	for i := range p.Nodes {
+		e := &p.Nodes[i]
+		if e.Node == "" {
+			return nil, fmt.Errorf("nodes[%d]: 缺少node", i)
+		}
+		if _, err := path.Match(e.Node, ""); err != nil {
+			return nil, fmt.Errorf("nodes[%d]: 无效的节点模式 %q", i, e.Node)
+		}
+		if e.MaxSlots < 0 || e.MaxPriority < 0 {
+			return nil, fmt.Errorf("nodes[%d]: 上限不能为负数", i)
+		}
+		for _, s := range e.Windows {
+			w, err := ParseWindow(s)
+			if err != nil {
+				return nil, fmt.Errorf("nodes[%d]: %v", i, err)
+			}
+			e.windows = append(e.windows, w)
+		}
+		for _, d := range e.Destinations {
+			if _, err := path.Match(d, ""); err != nil {
+				return nil, fmt.Errorf("nodes[%d]: 无效的目的节点模式 %q", i, d)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// 匹配节点的条目
+func (p *Policy) Lookup(nodeID string) (*Entry, bool) {
+	for i := range p.Nodes {
+		if ok, _ := path.Match(p.Nodes[i].Node, nodeID); ok {
+			return &p.Nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+// 节点在now时刻能否接入
+func (p *Policy) Check(nodeID string, now time.Time) error {
+	if p == nil {
+		return nil
+	}
+	e, ok := p.Lookup(nodeID)
+	if !ok {
+		return deny(REASON_NOT_ALLOWED, "访问控制策略不允许节点 %s", nodeID)
+	}
+	if !e.InWindow(now) {
+		return deny(REASON_OUTSIDE_WINDOW, "节点 %s 不在允许的时间窗口 %v 内", nodeID, e.Windows)
+	}
+	return nil
+}
+
+// 节点以priority优先级持有slots个时隙是否允许
+// slots为0时只检查节点与优先级，不检查时间窗口
+func (p *Policy) Admit(nodeID string, priority, slots int, now time.Time) error {
+	if p == nil {
+		return nil
+	}
+	e, ok := p.Lookup(nodeID)
+	if !ok {
+		return deny(REASON_NOT_ALLOWED, "访问控制策略不允许节点 %s", nodeID)
+	}
+	if e.MaxPriority > 0 && priority > e.MaxPriority {
+		return deny(REASON_PRIORITY, "节点 %s 优先级 %d 超过上限 %d", nodeID, priority, e.MaxPriority)
+	}
+	if slots == 0 {
+		return nil
+	}
+	if !e.InWindow(now) {
+		return deny(REASON_OUTSIDE_WINDOW, "节点 %s 不在允许的时间窗口 %v 内", nodeID, e.Windows)
+	}
+	if e.MaxSlots > 0 && slots > e.MaxSlots {
+		return deny(REASON_SLOT_QUOTA, "节点 %s 时隙数 %d 超过上限 %d", nodeID, slots, e.MaxSlots)
+	}
+	return nil
+}
+
+// 节点能否向dst发送数据
+func (p *Policy) AllowDestination(nodeID, dst string) error {
+	if p == nil {
+		return nil
+	}
+	e, ok := p.Lookup(nodeID)
+	if !ok {
+		return deny(REASON_NOT_ALLOWED, "访问控制策略不允许节点 %s", nodeID)
+	}
+	if len(e.Destinations) == 0 {
+		return nil
+	}
+	for _, pattern := range e.Destinations {
+		if ok, _ := path.Match(pattern, dst); ok {
+			return nil
+		}
+	}
+	return deny(REASON_DESTINATION, "节点 %s 不允许发往 %s", nodeID, dst)
+}
+
+// t是否处于条目的任一时间窗口内
+func (e *Entry) InWindow(t time.Time) bool {
+	if len(e.windows) == 0 {
+		return true
+	}
+	for _, w := range e.windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadTestPolicy(t *testing.T, content string) (*Policy, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "acl.json")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入策略文件失败: %v", err)
+	}
+	return LoadPolicy(file)
+}
+
+// 某日UTC的hh:mm
+func at(hh, mm int) time.Time {
+	return time.Date(2026, 10, 18, hh, mm, 0, 0, time.UTC)
+}
+
+func TestWindowContains(t *testing.T) {
+	tests := []struct {
+		window string
+		t      time.Time
+		want   bool
+	}{
+		{"06:00-18:00", at(6, 0), true},
+		{"06:00-18:00", at(12, 30), true},
+		{"06:00-18:00", at(17, 59), true},
+		{"06:00-18:00", at(18, 0), false},
+		{"06:00-18:00", at(5, 59), false},
+		// 跨越午夜
+		{"22:00-02:00", at(23, 0), true},
+		{"22:00-02:00", at(0, 0), true},
+		{"22:00-02:00", at(1, 59), true},
+		{"22:00-02:00", at(2, 0), false},
+		{"22:00-02:00", at(12, 0), false},
+		// 按UTC判断
+		{"06:00-18:00", time.Date(2026, 10, 18, 4, 0, 0, 0, time.FixedZone("UTC+8", 8*3600)), false},
+		{"06:00-18:00", time.Date(2026, 10, 18, 16, 0, 0, 0, time.FixedZone("UTC+8", 8*3600)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window+"@"+tt.t.Format("15:04Z07"), func(t *testing.T) {
+			w, err := ParseWindow(tt.window)
+			if err != nil {
+				t.Fatalf("ParseWindow: %v", err)
+			}
+			if got := w.Contains(tt.t); got != tt.want {
+				t.Fatalf("Contains = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, s := range []string{"", "06:00", "06:00-06:00", "6-18", "06:00-24:00", "ab:cd-18:00"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestLoadPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", `{"nodes": [`},
+		{"missing node", `{"nodes": [{"max_slots": 1}]}`},
+		{"bad node pattern", `{"nodes": [{"node": "GS["}]}`},
+		{"negative quota", `{"nodes": [{"node": "GS1", "max_slots": -1}]}`},
+		{"bad window", `{"nodes": [{"node": "GS1", "windows": ["06:00"]}]}`},
+		{"bad destination pattern", `{"nodes": [{"node": "GS1", "destinations": ["GS["]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadTestPolicy(t, tt.content); err == nil {
+				t.Fatalf("LoadPolicy succeeded, want error")
+			}
+		})
+	}
+}
+
+const testPolicy = `{
+  "nodes": [
+    {"node": "GS1", "max_slots": 2, "max_priority": 5, "windows": ["06:00-18:00"], "destinations": ["GS2", "RELAY*"]},
+    {"node": "NIGHT", "windows": ["22:00-02:00", "12:00-13:00"]},
+    {"node": "GS*", "max_slots": 1, "max_priority": 1}
+  ]
+}`
+
+func TestPolicyDecisions(t *testing.T) {
+	p, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		decide func(p *Policy) error
+		want   string
+	}{
+		// 节点匹配：按顺序匹配第一条，没有匹配的节点一律拒绝
+		{"exact node", func(p *Policy) error { return p.Check("GS1", at(12, 0)) }, ""},
+		{"pattern node", func(p *Policy) error { return p.Check("GS7", at(3, 0)) }, ""},
+		{"unlisted node", func(p *Policy) error { return p.Check("SAT9", at(12, 0)) }, REASON_NOT_ALLOWED},
+		{"unlisted node admit", func(p *Policy) error { return p.Admit("SAT9", 0, 1, at(12, 0)) }, REASON_NOT_ALLOWED},
+		{"unlisted node destination", func(p *Policy) error { return p.AllowDestination("SAT9", "GS1") }, REASON_NOT_ALLOWED},
+
+		// 时间窗口
+		{"outside window", func(p *Policy) error { return p.Check("GS1", at(19, 0)) }, REASON_OUTSIDE_WINDOW},
+		{"across midnight", func(p *Policy) error { return p.Check("NIGHT", at(1, 0)) }, ""},
+		{"second window", func(p *Policy) error { return p.Check("NIGHT", at(12, 30)) }, ""},
+		{"between windows", func(p *Policy) error { return p.Check("NIGHT", at(20, 0)) }, REASON_OUTSIDE_WINDOW},
+		{"admit outside window", func(p *Policy) error { return p.Admit("GS1", 1, 1, at(19, 0)) }, REASON_OUTSIDE_WINDOW},
+		{"priority only ignores window", func(p *Policy) error { return p.Admit("GS1", 1, 0, at(19, 0)) }, ""},
+
+		// 时隙数上限
+		{"within quota", func(p *Policy) error { return p.Admit("GS1", 0, 2, at(12, 0)) }, ""},
+		{"over quota", func(p *Policy) error { return p.Admit("GS1", 0, 3, at(12, 0)) }, REASON_SLOT_QUOTA},
+		{"pattern quota", func(p *Policy) error { return p.Admit("GS7", 0, 2, at(12, 0)) }, REASON_SLOT_QUOTA},
+		{"unlimited quota", func(p *Policy) error { return p.Admit("NIGHT", 100, 100, at(23, 0)) }, ""},
+
+		// 优先级上限
+		{"within priority", func(p *Policy) error { return p.Admit("GS1", 5, 1, at(12, 0)) }, ""},
+		{"over priority", func(p *Policy) error { return p.Admit("GS1", 6, 1, at(12, 0)) }, REASON_PRIORITY},
+		{"over priority without slots", func(p *Policy) error { return p.Admit("GS7", 2, 0, at(12, 0)) }, REASON_PRIORITY},
+
+		// 目的节点
+		{"allowed destination", func(p *Policy) error { return p.AllowDestination("GS1", "GS2") }, ""},
+		{"destination pattern", func(p *Policy) error { return p.AllowDestination("GS1", "RELAY3") }, ""},
+		{"denied destination", func(p *Policy) error { return p.AllowDestination("GS1", "GS3") }, REASON_DESTINATION},
+		{"unrestricted destination", func(p *Policy) error { return p.AllowDestination("GS7", "GS3") }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decide(p)
+			if got := Reason(err); got != tt.want || (err == nil) != (tt.want == "") {
+				t.Fatalf("decision = %v (reason %q), want reason %q", err, got, tt.want)
+			}
+		})
+	}
+}
+
+// 没有策略时一律允许
+func TestNilPolicyAllowsAll(t *testing.T) {
+	var p *Policy
+	if err := p.Check("ANY", at(0, 0)); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if err := p.Admit("ANY", 100, 100, at(0, 0)); err != nil {
+		t.Fatalf("Admit: %v", err)
+	}
+	if err := p.AllowDestination("ANY", "OTHER"); err != nil {
+		t.Fatalf("AllowDestination: %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 认证失败原因，同时用作 AUTH_REJECT_<原因> 与指标标签
+const (
+	REASON_UNKNOWN_NODE  = "unknown_node"  // 密钥库中没有该节点
+	REASON_NO_CHALLENGE  = "no_challenge"  // 响应没有对应的挑战或挑战已过期
+	REASON_BAD_RESPONSE  = "bad_response"  // 挑战响应错误
+	REASON_NO_SESSION    = "no_session"    // 节点没有已认证的会话
+	REASON_UNSIGNED      = "unsigned"      // 帧没有认证尾部
+	REASON_BAD_TAG       = "bad_tag"       // 认证标签错误
+	REASON_REPLAY        = "replay"        // 序号重复或落后于防重放窗口（重放）
+	REASON_UNENCRYPTED   = "unencrypted"   // 签名帧的数据区未加密
+	REASON_DECRYPT       = "decrypt"       // 解密失败
+	REASON_CERT_MISMATCH = "cert_mismatch" // 帧的节点ID与连接的客户端证书主体不一致
+)
+
+// 握手参数
+const (
+	NonceLen         = 16               // 挑战与响应随机数长度（字节）
+	ChallengeTimeout = 10 * time.Second // 挑战的有效期
+)
+
+// 认证失败
+type Error struct {
+	Reason string
+	msg    string
+}
+
+func (e *Error) Error() string { return e.msg }
+
+func fail(reason, format string, args ...any) error {
+	return &Error{Reason: reason, msg: fmt.Sprintf(format, args...)}
+}
+
+// 认证失败的原因，err不是认证失败时返回空串
+func Reason(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ""
+}
+
+// 地面站可以不签名发送的消息：握手消息
+func UplinkExempt(msg string) bool {
+	return msg == protocol.MSG_JOIN || strings.HasPrefix(msg, protocol.MSG_AUTH_RESPONSE)
+}
+
+// 卫星可以不签名发送的消息：握手消息，以及卫星没有节点会话时的拒绝
+func DownlinkExempt(msg string) bool {
+	return strings.HasPrefix(msg, protocol.MSG_AUTH_CHALLENGE) || strings.HasPrefix(msg, protocol.MSG_AUTH_REJECT) ||
+		msg == protocol.MSG_RESUME_REJECT || msg == protocol.MSG_HEARTBEAT_REJECT
+}
+
+// 生成随机数
+func NewNonce() ([]byte, error) {
+	nonce := make([]byte, NonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+	return nonce, nil
+}
+
+// 握手内容：双方随机数与X25519公钥
+type handshake struct {
+	challenge, nonce []byte // 卫星与地面站的随机数
+	satPub, gsPub    []byte // 卫星与地面站的X25519公钥
+}
+
+// 挑战响应MAC，证明地面站持有预共享密钥，同时保护双方公钥不被替换
+func (h handshake) responseMAC(psk []byte, nodeID string) []byte {
+	return h.derive(psk, "TDMA-AUTH-RESPONSE", nodeID)
+}
+
+// 会话密钥，由ECDH共享密钥派生，预共享密钥对握手内容的MAC作为salt
+func (h handshake) sessionKeys(psk []byte, nodeID string, secret []byte) sessionKeys {
+	return deriveKeys(secret, h.derive(psk, "TDMA-AUTH-SESSION", nodeID))
+}
+
+func (h handshake) derive(psk []byte, label, nodeID string) []byte {
+	mac := hmac.New(sha256.New, psk)
+	mac.Write([]byte(label))
+	mac.Write([]byte{0})
+	mac.Write([]byte(nodeID))
+	mac.Write([]byte{0})
+	for _, b := range [][]byte{h.challenge, h.nonce, h.satPub, h.gsPub} {
+		mac.Write(b)
+	}
+	return mac.Sum(nil)
+}
+
+// 解析以"_"分隔的十六进制字段
+func decodeFields(s string, n int) ([][]byte, bool) {
+	parts := strings.Split(s, "_")
+	if len(parts) != n {
+		return nil, false
+	}
+	fields := make([][]byte, n)
+	for i, p := range parts {
+		b, err := hex.DecodeString(p)
+		if err != nil {
+			return nil, false
+		}
+		fields[i] = b
+	}
+	return fields, true
+}
+
+// 地面站响应卫星的挑战（AUTH_CHALLENGE_之后的部分），返回AUTH_RESPONSE消息与新的认证会话
+func Respond(psk []byte, nodeID, challenge string, policy RekeyPolicy) (string, *Session, error) {
+	fields, ok := decodeFields(challenge, 2)
+	if !ok || len(fields[0]) != NonceLen {
+		return "", nil, fmt.Errorf("无效的挑战: %q", challenge)
+	}
+	nonce, err := NewNonce()
+	if err != nil {
+		return "", nil, err
+	}
+	priv, err := newECDHKey()
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := sharedSecret(priv, fields[1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	h := handshake{challenge: fields[0], nonce: nonce, satPub: fields[1], gsPub: priv.PublicKey().Bytes()}
+	msg := protocol.MSG_AUTH_RESPONSE + hex.EncodeToString(h.nonce) + "_" + hex.EncodeToString(h.gsPub) +
+		"_" + hex.EncodeToString(h.responseMAC(psk, nodeID))
+	keys := h.sessionKeys(psk, nodeID, secret)
+	return msg, newSession(keys.mac, "uplink", keys.uplink, "downlink", keys.downlink, policy, clock.Real{}), nil
+}
+
+// 一端的认证会话：签名密钥、两个方向的加密状态、发送序号与接收序号的防重放窗口
+// 序号从1开始，每个方向独立递增
+type Session struct {
+	macKey []byte
+	policy RekeyPolicy
+	clock  clock.Clock
+
+	sendMu  sync.Mutex
+	sendSeq uint64
+	send    *cipherState
+
+	recvMu  sync.Mutex
+	recvWin replayWindow
+	recv    *cipherState
+}
+
+func newSession(macKey []byte, sendName string, sendKey []byte, recvName string, recvKey []byte, policy RekeyPolicy, clk clock.Clock) *Session {
+	now := clk.Now()
+	return &Session{
+		macKey: macKey,
+		policy: policy,
+		clock:  clk,
+		send:   newCipherState(sendName, sendKey, now),
+		recv:   newCipherState(recvName, recvKey, now),
+	}
+}
+
+// 以下一个序号加密frame的数据区并附加认证尾部，然后调用write写出
+// 持锁写出，保证序号按写出顺序递增
+func (s *Session) Send(frame *protocol.TDMAFrame, write func(*protocol.TDMAFrame) error) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.sendSeq++
+	s.send.seal(frame, s.sendSeq, s.policy, s.clock.Now())
+	frame.Sign(s.macKey, s.sendSeq)
+	return write(frame)
+}
+
+// 校验frame的认证尾部与序号并解密，成功时frame还原为明文帧
+// 序号重复或落后已接受的最大序号达到ReplayWindow视为重放，窗口内乱序到达的帧可以接受
+func (s *Session) Open(frame *protocol.TDMAFrame) error {
+	if !frame.IsAuthenticated() {
+		return fail(REASON_UNSIGNED, "帧没有认证尾部")
+	}
+
+	s.recvMu.Lock()
+	defer s.recvMu.Unlock()
+
+	seq, err := frame.Verify(s.macKey)
+	if err != nil {
+		return fail(REASON_BAD_TAG, "%v", err)
+	}
+	if err := s.recvWin.check(seq); err != nil {
+		return fail(REASON_REPLAY, "%v", err)
+	}
+	if !frame.IsEncrypted() {
+		return fail(REASON_UNENCRYPTED, "帧数据区未加密")
+	}
+	if err := s.recv.open(frame, seq, s.clock.Now()); err != nil {
+		return fail(REASON_DECRYPT, "%v", err)
+	}
+	s.recvWin.accept(seq)
+	return nil
+}
+
+// 待完成的挑战
+type challenge struct {
+	nonce []byte
+	key   *ecdh.PrivateKey // 卫星的临时X25519私钥
+	at    time.Time
+}
+
+// 卫星端认证：按密钥库发起挑战、校验响应并维护各节点的认证会话
+// nil表示不要求认证
+type Authenticator struct {
+	keys   *Keystore
+	clock  clock.Clock
+	policy RekeyPolicy
+
+	mu         sync.Mutex
+	challenges map[string]challenge // 节点ID|对端地址 -> 挑战
+	sessions   map[string]*Session  // 节点ID -> 认证会话
+}
+
+// 创建使用密钥库keys的认证器
+func NewAuthenticator(keys *Keystore, clk clock.Clock) *Authenticator {
+	return &Authenticator{
+		keys:       keys,
+		clock:      clk,
+		policy:     DefaultRekeyPolicy,
+		challenges: make(map[string]challenge),
+		sessions:   make(map[string]*Session),
+	}
+}
+
+// 设置之后建立的会话的密钥更新策略
+func (a *Authenticator) SetRekeyPolicy(p RekeyPolicy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.policy = p
+}
+
+// 为来自remote的节点入网请求生成挑战，返回AUTH_CHALLENGE消息
+// 同一连接上重复入网时覆盖之前的挑战
+func (a *Authenticator) Challenge(nodeID, remote string) (string, error) {
+	if _, ok := a.keys.Key(nodeID); !ok {
+		return "", fail(REASON_UNKNOWN_NODE, "密钥库中没有节点 %s", nodeID)
+	}
+	nonce, err := NewNonce()
+	if err != nil {
+		return "", err
+	}
+	priv, err := newECDHKey()
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.clock.Now()
+	for k, c := range a.challenges {
+		if now.Sub(c.at) > ChallengeTimeout {
+			delete(a.challenges, k)
+		}
+	}
+	a.challenges[nodeID+"|"+remote] = challenge{nonce: nonce, key: priv, at: now}
+	return protocol.MSG_AUTH_CHALLENGE + hex.EncodeToString(nonce) + "_" + hex.EncodeToString(priv.PublicKey().Bytes()), nil
+}
+
+// 校验来自remote的挑战响应（AUTH_RESPONSE_之后的部分），成功时为节点建立新的认证会话
+// 每个挑战只能使用一次
+func (a *Authenticator) Complete(nodeID, remote, response string) error {
+	psk, ok := a.keys.Key(nodeID)
+	if !ok {
+		return fail(REASON_UNKNOWN_NODE, "密钥库中没有节点 %s", nodeID)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	k := nodeID + "|" + remote
+	c, ok := a.challenges[k]
+	delete(a.challenges, k)
+	if !ok || a.clock.Now().Sub(c.at) > ChallengeTimeout {
+		return fail(REASON_NO_CHALLENGE, "没有待完成的挑战")
+	}
+
+	fields, ok := decodeFields(response, 3)
+	if !ok || len(fields[0]) != NonceLen {
+		return fail(REASON_BAD_RESPONSE, "无效的挑战响应")
+	}
+	h := handshake{challenge: c.nonce, nonce: fields[0], satPub: c.key.PublicKey().Bytes(), gsPub: fields[1]}
+	if !hmac.Equal(fields[2], h.responseMAC(psk, nodeID)) {
+		return fail(REASON_BAD_RESPONSE, "挑战响应错误")
+	}
+	secret, err := sharedSecret(c.key, h.gsPub)
+	if err != nil {
+		return fail(REASON_BAD_RESPONSE, "%v", err)
+	}
+
+	keys := h.sessionKeys(psk, nodeID, secret)
+	a.sessions[nodeID] = newSession(keys.mac, "downlink", keys.downlink, "uplink", keys.uplink, a.policy, a.clock)
+	return nil
+}
+
+// 校验并解密节点发来的帧
+func (a *Authenticator) Open(nodeID string, frame *protocol.TDMAFrame) error {
+	if _, ok := a.keys.Key(nodeID); !ok {
+		return fail(REASON_UNKNOWN_NODE, "密钥库中没有节点 %s", nodeID)
+	}
+	s := a.Session(nodeID)
+	if s == nil {
+		if !frame.IsAuthenticated() {
+			return fail(REASON_UNSIGNED, "帧没有认证尾部")
+		}
+		return fail(REASON_NO_SESSION, "节点没有已认证的会话")
+	}
+	return s.Open(frame)
+}
+
+// 节点的认证会话，没有时返回nil
+func (a *Authenticator) Session(nodeID string) *Session {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sessions[nodeID]
+}
+
+// 删除节点的认证会话（会话结束时调用）
+func (a *Authenticator) Remove(nodeID string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, nodeID)
+}
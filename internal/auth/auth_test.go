@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"strings"
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+var (
+	testPSK  = []byte("0123456789abcdef0123456789abcdef")
+	testNode = "GS1"
+)
+
+func newTestAuthenticator() (*Authenticator, *clock.Virtual) {
+	clk := clock.NewVirtual(protocol.TDMA_EPOCH.Add(time.Hour))
+	ks := &Keystore{keys: map[string][]byte{testNode: testPSK}}
+	return NewAuthenticator(ks, clk), clk
+}
+
+// 完成一次握手，返回卫星端认证器与地面站端会话
+func establish(t *testing.T, policy RekeyPolicy) (*Authenticator, *clock.Virtual, *Session) {
+	t.Helper()
+	a, clk := newTestAuthenticator()
+	a.SetRekeyPolicy(policy)
+	challenge, err := a.Challenge(testNode, "remote")
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	resp, gs, err := Respond(testPSK, testNode, strings.TrimPrefix(challenge, protocol.MSG_AUTH_CHALLENGE), policy)
+	if err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	if err := a.Complete(testNode, "remote", strings.TrimPrefix(resp, protocol.MSG_AUTH_RESPONSE)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	return a, clk, gs
+}
+
+// 用会话s签名并加密一帧
+func seal(t *testing.T, s *Session, data string) *protocol.TDMAFrame {
+	t.Helper()
+	var out *protocol.TDMAFrame
+	frame := protocol.NewTDMAFrame(1, testNode, []byte(data))
+	frame.SetAbsSlot(1000)
+	if err := s.Send(frame, func(f *protocol.TDMAFrame) error { out = f; return nil }); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	return out
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		name   string
+		psk    []byte
+		node   string
+		remote string // 响应所在连接
+		wait   time.Duration
+		reason string
+	}{
+		{"ok", testPSK, testNode, "remote", 0, ""},
+		{"wrong key", []byte("fedcba9876543210fedcba9876543210"), testNode, "remote", 0, REASON_BAD_RESPONSE},
+		{"other connection", testPSK, testNode, "other", 0, REASON_NO_CHALLENGE},
+		{"challenge expired", testPSK, testNode, "remote", ChallengeTimeout + time.Second, REASON_NO_CHALLENGE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, clk := newTestAuthenticator()
+			challenge, err := a.Challenge(tt.node, "remote")
+			if err != nil {
+				t.Fatalf("Challenge: %v", err)
+			}
+			resp, _, err := Respond(tt.psk, tt.node, strings.TrimPrefix(challenge, protocol.MSG_AUTH_CHALLENGE), DefaultRekeyPolicy)
+			if err != nil {
+				t.Fatalf("Respond: %v", err)
+			}
+			clk.Run(clk.Now().Add(tt.wait))
+			err = a.Complete(tt.node, tt.remote, strings.TrimPrefix(resp, protocol.MSG_AUTH_RESPONSE))
+			if got := Reason(err); got != tt.reason || (err == nil) != (tt.reason == "") {
+				t.Fatalf("Complete = %v (reason %q), want reason %q", err, got, tt.reason)
+			}
+			if (a.Session(tt.node) != nil) != (tt.reason == "") {
+				t.Fatalf("session established = %v", a.Session(tt.node) != nil)
+			}
+		})
+	}
+}
+
+func TestChallengeSingleUse(t *testing.T) {
+	a, _ := newTestAuthenticator()
+	if _, err := a.Challenge("GS9", "remote"); Reason(err) != REASON_UNKNOWN_NODE {
+		t.Fatalf("Challenge(unknown node) = %v, want %s", err, REASON_UNKNOWN_NODE)
+	}
+	challenge, err := a.Challenge(testNode, "remote")
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	resp, _, err := Respond(testPSK, testNode, strings.TrimPrefix(challenge, protocol.MSG_AUTH_CHALLENGE), DefaultRekeyPolicy)
+	if err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	resp = strings.TrimPrefix(resp, protocol.MSG_AUTH_RESPONSE)
+	if err := a.Complete(testNode, "remote", resp); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if err := a.Complete(testNode, "remote", resp); Reason(err) != REASON_NO_CHALLENGE {
+		t.Fatalf("second Complete = %v, want %s", err, REASON_NO_CHALLENGE)
+	}
+}
+
+// 认证尾部或其覆盖的任何内容被篡改的帧都被拒绝
+func TestSessionOpenRejectsTamperedTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(f *protocol.TDMAFrame)
+		reason string
+	}{
+		{"unmodified", func(f *protocol.TDMAFrame) {}, ""},
+		{"tag", func(f *protocol.TDMAFrame) { f.Data[len(f.Data)-1] ^= 1 }, REASON_BAD_TAG},
+		{"sequence", func(f *protocol.TDMAFrame) { f.Data[len(f.Data)-protocol.AUTH_TRAILER_LEN] ^= 1 }, REASON_BAD_TAG},
+		{"payload", func(f *protocol.TDMAFrame) { f.Data[protocol.KEY_EPOCH_LEN] ^= 1 }, REASON_BAD_TAG},
+		{"slot id", func(f *protocol.TDMAFrame) { f.SlotID++ }, REASON_BAD_TAG},
+		{"node id", func(f *protocol.TDMAFrame) { f.NodeID[0] ^= 1 }, REASON_BAD_TAG},
+		{"abs slot", func(f *protocol.TDMAFrame) { f.AbsSlot++ }, REASON_BAD_TAG},
+		{"fragment", func(f *protocol.TDMAFrame) { f.FragIndex++ }, REASON_BAD_TAG},
+		{"truncated trailer", func(f *protocol.TDMAFrame) { f.Data = f.Data[:protocol.AUTH_TRAILER_LEN-1] }, REASON_BAD_TAG},
+		{"auth flag cleared", func(f *protocol.TDMAFrame) { f.Flags &^= protocol.FLAG_AUTH }, REASON_UNSIGNED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, gs := establish(t, DefaultRekeyPolicy)
+			frame := seal(t, gs, protocol.MSG_HEARTBEAT)
+			tt.tamper(frame)
+			err := a.Open(testNode, frame)
+			if got := Reason(err); got != tt.reason || (err == nil) != (tt.reason == "") {
+				t.Fatalf("Open = %v (reason %q), want reason %q", err, got, tt.reason)
+			}
+			if err == nil && string(frame.Data) != protocol.MSG_HEARTBEAT {
+				t.Fatalf("opened data = %q", frame.Data)
+			}
+		})
+	}
+}
+
+// 另一个会话的密钥签名的帧被拒绝
+func TestSessionOpenRejectsOtherSession(t *testing.T) {
+	a, _, _ := establish(t, DefaultRekeyPolicy)
+	_, _, other := establish(t, DefaultRekeyPolicy)
+	if err := a.Open(testNode, seal(t, other, protocol.MSG_HEARTBEAT)); Reason(err) != REASON_BAD_TAG {
+		t.Fatalf("Open = %v, want %s", err, REASON_BAD_TAG)
+	}
+	if err := a.Open("GS2", seal(t, other, protocol.MSG_HEARTBEAT)); Reason(err) != REASON_UNKNOWN_NODE {
+		t.Fatalf("Open(unknown node) = %v, want %s", err, REASON_UNKNOWN_NODE)
+	}
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"tdma-network/internal/logging"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+var logger = logging.For("auth")
+
+// 密钥更新策略，字段为零表示不按该条件更新
+type RekeyPolicy struct {
+	Frames   uint64        // 每个方向用同一密钥加密该数量的帧后更新
+	Interval time.Duration // 密钥使用超过该时长后更新
+}
+
+// 默认密钥更新策略
+var DefaultRekeyPolicy = RekeyPolicy{Frames: 10000, Interval: 10 * time.Minute}
+
+// 接收方最多向前追赶的密钥代数
+const maxEpochSkip = 16
+
+// 会话密钥：签名密钥与两个方向的初始加密密钥
+type sessionKeys struct {
+	mac, uplink, downlink []byte
+}
+
+// 生成X25519临时密钥
+func newECDHKey() (*ecdh.PrivateKey, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成X25519密钥失败: %v", err)
+	}
+	return priv, nil
+}
+
+// 用本端私钥与对端公钥计算共享密钥
+func sharedSecret(priv *ecdh.PrivateKey, peer []byte) ([]byte, error) {
+	pub, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, fmt.Errorf("无效的X25519公钥: %v", err)
+	}
+	secret, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("ECDH失败: %v", err)
+	}
+	return secret, nil
+}
+
+// 由ECDH共享密钥派生会话密钥
+// salt由预共享密钥对握手内容计算，没有预共享密钥的中间人无法得到会话密钥
+func deriveKeys(secret, salt []byte) sessionKeys {
+	prk := hkdfExtract(salt, secret)
+	return sessionKeys{
+		mac:      hkdfExpand(prk, "tdma mac", 32),
+		uplink:   hkdfExpand(prk, "tdma uplink", 32),
+		downlink: hkdfExpand(prk, "tdma downlink", 32),
+	}
+}
+
+// HKDF-SHA256 (RFC 5869)
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+func hkdfExpand(prk []byte, info string, n int) []byte {
+	var out, block []byte
+	for i := byte(1); len(out) < n; i++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(block)
+		mac.Write([]byte(info))
+		mac.Write([]byte{i})
+		block = mac.Sum(nil)
+		out = append(out, block...)
+	}
+	return out[:n]
+}
+
+// 下一代加密密钥，旧密钥不能由新密钥推出
+func nextKey(key []byte) []byte {
+	return hkdfExpand(key, "tdma rekey", len(key))
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err) // 密钥长度固定为32字节
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// 一个方向的加密状态
+type cipherState struct {
+	name  string // 方向，用于日志
+	key   []byte
+	epoch uint32
+	aead  cipher.AEAD
+	prev  cipher.AEAD // 上一代密钥，接收方用于解密更新前发出的帧
+
+	frames uint64    // 本代密钥已加密的帧数
+	since  time.Time // 本代密钥的启用时刻
+}
+
+func newCipherState(name string, key []byte, now time.Time) *cipherState {
+	return &cipherState{name: name, key: key, aead: newAEAD(key), since: now}
+}
+
+// 切换到下一代密钥
+func (c *cipherState) advance(now time.Time) {
+	c.key = nextKey(c.key)
+	c.epoch++
+	c.prev, c.aead = c.aead, newAEAD(c.key)
+	c.frames, c.since = 0, now
+	logger.Debug("更新会话密钥", "direction", c.name, "epoch", c.epoch)
+}
+
+// 按策略更新密钥后加密帧
+func (c *cipherState) seal(frame *protocol.TDMAFrame, seq uint64, policy RekeyPolicy, now time.Time) {
+	if (policy.Frames > 0 && c.frames >= policy.Frames) || (policy.Interval > 0 && now.Sub(c.since) >= policy.Interval) {
+		c.advance(now)
+	}
+	frame.Encrypt(c.aead, c.epoch, seq)
+	c.frames++
+}
+
+// 解密帧，对端已更新密钥时跟随更新
+func (c *cipherState) open(frame *protocol.TDMAFrame, seq uint64, now time.Time) error {
+	epoch, ok := frame.KeyEpoch()
+	if !ok {
+		return fmt.Errorf("帧没有加密数据")
+	}
+	switch {
+	case epoch == c.epoch:
+		return frame.Decrypt(c.aead, seq)
+
+	case epoch+1 == c.epoch && c.prev != nil:
+		return frame.Decrypt(c.prev, seq)
+
+	case epoch > c.epoch && epoch-c.epoch <= maxEpochSkip:
+		// 跳过多代时，上一代密钥是epoch-1代而不是当前这一代
+		prevKey, key := c.key, nextKey(c.key)
+		for e := c.epoch + 1; e < epoch; e++ {
+			prevKey, key = key, nextKey(key)
+		}
+		aead := newAEAD(key)
+		if err := frame.Decrypt(aead, seq); err != nil {
+			return err
+		}
+		prev := c.aead
+		if epoch > c.epoch+1 {
+			prev = newAEAD(prevKey)
+		}
+		c.prev, c.aead, c.key, c.epoch = prev, aead, key, epoch
+		c.frames, c.since = 0, now
+		logger.Debug("跟随对端更新会话密钥", "direction", c.name, "epoch", epoch)
+		return nil
+	}
+	return fmt.Errorf("密钥代数 %d 超出范围（当前 %d）", epoch, c.epoch)
+}
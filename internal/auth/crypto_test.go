@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"encoding/binary"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+// 去掉认证尾部，修改数据区后用key重新签名，模拟持有签名密钥但没有加密密钥的篡改
+func resign(f *protocol.TDMAFrame, key []byte, mutate func(data []byte) []byte) {
+	n := len(f.Data) - protocol.AUTH_TRAILER_LEN
+	seq := binary.BigEndian.Uint64(f.Data[n:])
+	f.Data = mutate(f.Data[:n])
+	f.Flags &^= protocol.FLAG_AUTH
+	f.Sign(key, seq)
+}
+
+// 签名正确但密文被篡改或未加密的帧都被拒绝
+func TestSessionOpenRejectsTamperedCiphertext(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(f *protocol.TDMAFrame, macKey []byte)
+		reason string
+	}{
+		{"ciphertext", func(f *protocol.TDMAFrame, k []byte) {
+			resign(f, k, func(d []byte) []byte { d[protocol.KEY_EPOCH_LEN] ^= 1; return d })
+		}, REASON_DECRYPT},
+		{"gcm tag", func(f *protocol.TDMAFrame, k []byte) {
+			resign(f, k, func(d []byte) []byte { d[len(d)-1] ^= 1; return d })
+		}, REASON_DECRYPT},
+		{"truncated ciphertext", func(f *protocol.TDMAFrame, k []byte) {
+			resign(f, k, func(d []byte) []byte { return d[:len(d)-1] })
+		}, REASON_DECRYPT},
+		{"key epoch", func(f *protocol.TDMAFrame, k []byte) {
+			resign(f, k, func(d []byte) []byte { d[protocol.KEY_EPOCH_LEN-1] ^= 1; return d })
+		}, REASON_DECRYPT},
+		{"plaintext", func(f *protocol.TDMAFrame, k []byte) {
+			f.Flags &^= protocol.FLAG_ENCRYPTED
+			resign(f, k, func([]byte) []byte { return []byte(protocol.MSG_HEARTBEAT) })
+		}, REASON_UNENCRYPTED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, gs := establish(t, DefaultRekeyPolicy)
+			frame := seal(t, gs, protocol.MSG_HEARTBEAT)
+			tt.mutate(frame, gs.macKey)
+			if err := a.Open(testNode, frame); Reason(err) != tt.reason {
+				t.Fatalf("Open = %v, want %s", err, tt.reason)
+			}
+
+			// 解密失败的序号不计入防重放窗口，之后的正常帧照常接受
+			if err := a.Open(testNode, seal(t, gs, protocol.MSG_HEARTBEAT)); err != nil {
+				t.Fatalf("next frame: %v", err)
+			}
+		})
+	}
+}
+
+// 发送方按帧数更新密钥代数，接收方跟随更新，并能解密上一代密钥加密的迟到帧
+func TestKeyEpochRollover(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames uint64 // 每代密钥加密的帧数
+		sent   int
+		open   []int    // 按此顺序接收的帧
+		want   []string // 每帧的认证失败原因
+	}{
+		{"in order", 2, 6, []int{0, 1, 2, 3, 4, 5}, []string{"", "", "", "", "", ""}},
+		{"previous epoch after rollover", 2, 4, []int{2, 0, 3, 1}, []string{"", "", "", ""}},
+		{"two epochs back", 2, 5, []int{4, 0, 3}, []string{"", REASON_DECRYPT, ""}},
+		{"catch up to skip limit", 1, maxEpochSkip + 1, []int{maxEpochSkip}, []string{""}},
+		{"beyond skip limit", 1, maxEpochSkip + 2, []int{maxEpochSkip + 1, 0}, []string{REASON_DECRYPT, ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := RekeyPolicy{Frames: tt.frames}
+			a, _, gs := establish(t, policy)
+			var sent []*protocol.TDMAFrame
+			for i := 0; i < tt.sent; i++ {
+				f := seal(t, gs, protocol.MSG_HEARTBEAT)
+				epoch, _ := f.KeyEpoch()
+				if want := uint32(uint64(i) / tt.frames); epoch != want {
+					t.Fatalf("frame %d sealed with epoch %d, want %d", i, epoch, want)
+				}
+				sent = append(sent, f)
+			}
+			for i, idx := range tt.open {
+				err := a.Open(testNode, sent[idx])
+				if got := Reason(err); got != tt.want[i] {
+					t.Fatalf("open frame %d: %v (reason %q), want %q", idx, err, got, tt.want[i])
+				}
+				if err == nil && string(sent[idx].Data) != protocol.MSG_HEARTBEAT {
+					t.Fatalf("frame %d decrypted to %q", idx, sent[idx].Data)
+				}
+			}
+		})
+	}
+}
+
+// 密钥使用超过策略时长后，下一帧使用新一代密钥
+func TestKeyEpochRolloverByInterval(t *testing.T) {
+	a, clk, gs := establish(t, RekeyPolicy{Interval: time.Minute})
+	sat := a.Session(testNode)
+
+	for i, wait := range []time.Duration{0, 30 * time.Second, 30 * time.Second, 59 * time.Second, time.Minute} {
+		clk.Run(clk.Now().Add(wait))
+		f := seal(t, sat, protocol.MSG_HEARTBEAT_ACK)
+		epoch, _ := f.KeyEpoch()
+		if want := []uint32{0, 0, 1, 1, 2}[i]; epoch != want {
+			t.Fatalf("frame %d sealed with epoch %d, want %d", i, epoch, want)
+		}
+		if err := gs.Open(f); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+	}
+}
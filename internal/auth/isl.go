@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"tdma-network/pkg/protocol"
+)
+
+// 一次星间链路认证握手，双方用发起方卫星的预共享密钥互相证明身份
+// MAC覆盖双方卫星ID与随机数，每次建链的随机数不同，截获的响应不能在其他链路上重放
+type ISLHandshake struct {
+	psk              []byte
+	dialer, acceptor string // 发起方与接收方卫星ID
+	challenge, nonce []byte // 接收方与发起方的随机数
+}
+
+// 接收方为发起方dialer生成挑战，返回握手状态与 ISL_CHALLENGE 消息
+func (a *Authenticator) AcceptISL(dialer, acceptor string) (*ISLHandshake, string, error) {
+	psk, ok := a.keys.Key(dialer)
+	if !ok {
+		return nil, "", fail(REASON_UNKNOWN_NODE, "密钥库中没有卫星 %s", dialer)
+	}
+	challenge, err := NewNonce()
+	if err != nil {
+		return nil, "", err
+	}
+	h := &ISLHandshake{psk: psk, dialer: dialer, acceptor: acceptor, challenge: challenge}
+	return h, protocol.MSG_ISL_CHALLENGE + hex.EncodeToString(challenge), nil
+}
+
+// 接收方校验发起方的响应（ISL_RESPONSE_之后的部分），返回 ISL_ACCEPT 消息
+func (h *ISLHandshake) Verify(response string) (string, error) {
+	fields, ok := decodeFields(response, 2)
+	if !ok || len(fields[0]) != NonceLen {
+		return "", fail(REASON_BAD_RESPONSE, "无效的星间链路认证响应")
+	}
+	h.nonce = fields[0]
+	if !hmac.Equal(fields[1], h.mac("TDMA-ISL-RESPONSE")) {
+		return "", fail(REASON_BAD_RESPONSE, "卫星 %s 的认证响应错误", h.dialer)
+	}
+	return protocol.MSG_ISL_ACCEPT + hex.EncodeToString(h.mac("TDMA-ISL-ACCEPT")), nil
+}
+
+// 发起方响应接收方acceptor的挑战（ISL_CHALLENGE_之后的部分），返回握手状态与 ISL_RESPONSE 消息
+func (a *Authenticator) RespondISL(dialer, acceptor, challenge string) (*ISLHandshake, string, error) {
+	psk, ok := a.keys.Key(dialer)
+	if !ok {
+		return nil, "", fail(REASON_UNKNOWN_NODE, "密钥库中没有本星 %s 的密钥", dialer)
+	}
+	fields, ok := decodeFields(challenge, 1)
+	if !ok || len(fields[0]) != NonceLen {
+		return nil, "", fail(REASON_NO_CHALLENGE, "无效的星间链路挑战: %q", challenge)
+	}
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, "", err
+	}
+	h := &ISLHandshake{psk: psk, dialer: dialer, acceptor: acceptor, challenge: fields[0], nonce: nonce}
+	return h, protocol.MSG_ISL_RESPONSE + hex.EncodeToString(nonce) + "_" + hex.EncodeToString(h.mac("TDMA-ISL-RESPONSE")), nil
+}
+
+// 发起方校验接收方的确认（ISL_ACCEPT_之后的部分）
+func (h *ISLHandshake) Confirm(accept string) error {
+	fields, ok := decodeFields(accept, 1)
+	if !ok || !hmac.Equal(fields[0], h.mac("TDMA-ISL-ACCEPT")) {
+		return fail(REASON_BAD_RESPONSE, "卫星 %s 的认证确认错误", h.acceptor)
+	}
+	return nil
+}
+
+// 链路密钥，握手成功后双方用它为链路上的每一帧附加认证尾部
+// 由预共享密钥与双方随机数导出，每条链路不同
+func (h *ISLHandshake) LinkKey() []byte {
+	return hkdfExpand(h.mac("TDMA-ISL-LINK"), "tdma isl mac", 32)
+}
+
+func (h *ISLHandshake) mac(label string) []byte {
+	mac := hmac.New(sha256.New, h.psk)
+	for _, s := range []string{label, h.dialer, h.acceptor} {
+		mac.Write([]byte(s))
+		mac.Write([]byte{0})
+	}
+	mac.Write(h.challenge)
+	mac.Write(h.nonce)
+	return mac.Sum(nil)
+}
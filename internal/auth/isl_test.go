@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
+	"testing"
+)
+
+var testSatPSK = []byte("fedcba9876543210fedcba9876543210")
+
+// 持有keys中密钥的卫星端认证器
+func newISLAuthenticator(keys map[string][]byte) *Authenticator {
+	return NewAuthenticator(&Keystore{keys: keys}, clock.Real{})
+}
+
+func TestISLHandshake(t *testing.T) {
+	otherPSK := []byte("00000000000000000000000000000000")
+	tests := []struct {
+		name string
+		// 发起方SAT_A与接收方SAT_B的密钥库
+		dialerKeys, acceptorKeys map[string][]byte
+		// 篡改握手消息
+		tamperResponse func(string) string
+		tamperAccept   func(string) string
+		acceptErr      string // AcceptISL或Verify的失败原因
+		respondErr     string // RespondISL的失败原因
+		confirmErr     string // Confirm的失败原因
+	}{
+		{name: "ok",
+			dialerKeys: map[string][]byte{"SAT_A": testSatPSK}, acceptorKeys: map[string][]byte{"SAT_A": testSatPSK}},
+		{name: "dialer unknown to acceptor",
+			dialerKeys: map[string][]byte{"SAT_A": testSatPSK}, acceptorKeys: map[string][]byte{"SAT_C": testSatPSK},
+			acceptErr: REASON_UNKNOWN_NODE},
+		{name: "dialer without own key",
+			dialerKeys: map[string][]byte{}, acceptorKeys: map[string][]byte{"SAT_A": testSatPSK},
+			respondErr: REASON_UNKNOWN_NODE},
+		{name: "wrong key",
+			dialerKeys: map[string][]byte{"SAT_A": otherPSK}, acceptorKeys: map[string][]byte{"SAT_A": testSatPSK},
+			acceptErr: REASON_BAD_RESPONSE},
+		{name: "tampered response",
+			dialerKeys: map[string][]byte{"SAT_A": testSatPSK}, acceptorKeys: map[string][]byte{"SAT_A": testSatPSK},
+			tamperResponse: func(s string) string { return flipHex(s) }, acceptErr: REASON_BAD_RESPONSE},
+		{name: "malformed response",
+			dialerKeys: map[string][]byte{"SAT_A": testSatPSK}, acceptorKeys: map[string][]byte{"SAT_A": testSatPSK},
+			tamperResponse: func(string) string { return "zz" }, acceptErr: REASON_BAD_RESPONSE},
+		{name: "tampered accept",
+			dialerKeys: map[string][]byte{"SAT_A": testSatPSK}, acceptorKeys: map[string][]byte{"SAT_A": testSatPSK},
+			tamperAccept: func(s string) string { return flipHex(s) }, confirmErr: REASON_BAD_RESPONSE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer, acceptor := newISLAuthenticator(tt.dialerKeys), newISLAuthenticator(tt.acceptorKeys)
+
+			hb, challenge, err := acceptor.AcceptISL("SAT_A", "SAT_B")
+			if tt.acceptErr == REASON_UNKNOWN_NODE {
+				if Reason(err) != tt.acceptErr {
+					t.Fatalf("AcceptISL = %v, want reason %s", err, tt.acceptErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AcceptISL: %v", err)
+			}
+
+			ha, response, err := dialer.RespondISL("SAT_A", "SAT_B", strings.TrimPrefix(challenge, protocol.MSG_ISL_CHALLENGE))
+			if tt.respondErr != "" {
+				if Reason(err) != tt.respondErr {
+					t.Fatalf("RespondISL = %v, want reason %s", err, tt.respondErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RespondISL: %v", err)
+			}
+
+			response = strings.TrimPrefix(response, protocol.MSG_ISL_RESPONSE)
+			if tt.tamperResponse != nil {
+				response = tt.tamperResponse(response)
+			}
+			accept, err := hb.Verify(response)
+			if tt.acceptErr != "" {
+				if Reason(err) != tt.acceptErr {
+					t.Fatalf("Verify = %v, want reason %s", err, tt.acceptErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			accept = strings.TrimPrefix(accept, protocol.MSG_ISL_ACCEPT)
+			if tt.tamperAccept != nil {
+				accept = tt.tamperAccept(accept)
+			}
+			err = ha.Confirm(accept)
+			if tt.confirmErr != "" {
+				if Reason(err) != tt.confirmErr {
+					t.Fatalf("Confirm = %v, want reason %s", err, tt.confirmErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Confirm: %v", err)
+			}
+			// 双方导出相同的链路密钥，且与握手MAC不同
+			if !bytes.Equal(ha.LinkKey(), hb.LinkKey()) {
+				t.Fatalf("link keys differ")
+			}
+			if bytes.Equal(ha.LinkKey(), ha.mac("TDMA-ISL-ACCEPT")) {
+				t.Fatalf("link key equals the accept MAC")
+			}
+		})
+	}
+}
+
+// 每次建链导出的链路密钥不同
+func TestISLLinkKeyPerLink(t *testing.T) {
+	keys := map[string][]byte{"SAT_A": testSatPSK}
+	dialer, acceptor := newISLAuthenticator(keys), newISLAuthenticator(keys)
+
+	link := func() []byte {
+		t.Helper()
+		hb, challenge, err := acceptor.AcceptISL("SAT_A", "SAT_B")
+		if err != nil {
+			t.Fatalf("AcceptISL: %v", err)
+		}
+		_, response, err := dialer.RespondISL("SAT_A", "SAT_B", strings.TrimPrefix(challenge, protocol.MSG_ISL_CHALLENGE))
+		if err != nil {
+			t.Fatalf("RespondISL: %v", err)
+		}
+		if _, err := hb.Verify(strings.TrimPrefix(response, protocol.MSG_ISL_RESPONSE)); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		return hb.LinkKey()
+	}
+	if bytes.Equal(link(), link()) {
+		t.Fatalf("two links derived the same key")
+	}
+}
+
+// 截获的响应不能用于另一次建链，也不能冒充其他接收方
+func TestISLResponseBoundToChallenge(t *testing.T) {
+	keys := map[string][]byte{"SAT_A": testSatPSK}
+	dialer, acceptor := newISLAuthenticator(keys), newISLAuthenticator(keys)
+
+	_, first, err := acceptor.AcceptISL("SAT_A", "SAT_B")
+	if err != nil {
+		t.Fatalf("AcceptISL: %v", err)
+	}
+	_, response, err := dialer.RespondISL("SAT_A", "SAT_B", strings.TrimPrefix(first, protocol.MSG_ISL_CHALLENGE))
+	if err != nil {
+		t.Fatalf("RespondISL: %v", err)
+	}
+	response = strings.TrimPrefix(response, protocol.MSG_ISL_RESPONSE)
+
+	second, _, err := acceptor.AcceptISL("SAT_A", "SAT_B")
+	if err != nil {
+		t.Fatalf("AcceptISL: %v", err)
+	}
+	if _, err := second.Verify(response); Reason(err) != REASON_BAD_RESPONSE {
+		t.Fatalf("replayed response on a new challenge: Verify = %v", err)
+	}
+
+	// 同一挑战下，为SAT_B计算的响应对SAT_C无效
+	hc, _, err := acceptor.AcceptISL("SAT_A", "SAT_C")
+	if err != nil {
+		t.Fatalf("AcceptISL: %v", err)
+	}
+	hc.challenge = append([]byte(nil), second.challenge...)
+	_, forB, err := dialer.RespondISL("SAT_A", "SAT_B", hex.EncodeToString(second.challenge))
+	if err != nil {
+		t.Fatalf("RespondISL: %v", err)
+	}
+	if _, err := hc.Verify(strings.TrimPrefix(forB, protocol.MSG_ISL_RESPONSE)); Reason(err) != REASON_BAD_RESPONSE {
+		t.Fatalf("response for another acceptor: Verify = %v", err)
+	}
+}
+
+// 翻转十六进制串的最后一位
+func flipHex(s string) string {
+	last := s[len(s)-1]
+	if last == '0' {
+		return s[:len(s)-1] + "1"
+	}
+	return s[:len(s)-1] + "0"
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// 预共享密钥的最小长度（字节）
+const MinKeyLen = 16
+
+// 密钥库：节点ID -> 预共享密钥
+// 文件格式，密钥为十六进制：
+//
+//	{
+//	  "keys": {
+//	    "GS1": "8f1e2d...",
+//	    "GS2": "03a9c4..."
+//	  }
+//	}
+type Keystore struct {
+	keys map[string][]byte
+}
+
+type keystoreFile struct {
+	Keys map[string]string `json:"keys"`
+}
+
+// 加载密钥库文件
+func LoadKeystore(path string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥库失败: %v", err)
+	}
+
+	var kf keystoreFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("解析密钥库失败: %v", err)
+	}
+
+	ks := &Keystore{keys: make(map[string][]byte, len(kf.Keys))}
+	for nodeID, s := range kf.Keys {
+		key, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("keys.%s: 密钥不是有效的十六进制: %v", nodeID, err)
+		}
+		if len(key) < MinKeyLen {
+			return nil, fmt.Errorf("keys.%s: 密钥长度 %d 字节，至少需要 %d 字节", nodeID, len(key), MinKeyLen)
+		}
+		ks.keys[nodeID] = key
+	}
+	return ks, nil
+}
+
+// 节点的预共享密钥
+func (ks *Keystore) Key(nodeID string) ([]byte, bool) {
+	if ks == nil {
+		return nil, false
+	}
+	key, ok := ks.keys[nodeID]
+	return key, ok
+}
+
+// 密钥库中的节点ID，按字典序
+func (ks *Keystore) Nodes() []string {
+	if ks == nil {
+		return nil
+	}
+	nodes := make([]string, 0, len(ks.keys))
+	for nodeID := range ks.keys {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// TLS握手超时
+const HandshakeTimeout = 10 * time.Second
+
+// TLS传输配置，证书、私钥与CA均为PEM文件
+type TLSConfig struct {
+	Cert string // 本端证书
+	Key  string // 本端私钥
+	CA   string // 校验对端证书的CA，客户端未配置时使用系统根证书；服务端校验客户端证书时必须配置
+
+	VerifyClient bool   // 服务端：要求并校验客户端证书
+	ServerName   string // 客户端：校验服务端证书使用的名称，为空时取连接地址中的主机名
+}
+
+// 是否启用TLS
+func (c TLSConfig) Enabled() bool {
+	return c.Cert != "" || c.CA != ""
+}
+
+// 服务端配置，只允许TLS 1.3
+// 校验客户端证书时必须指定CA，否则任何公共CA签发的证书都能通过校验
+func (c TLSConfig) Server() (*tls.Config, error) {
+	if c.Cert == "" || c.Key == "" {
+		return nil, fmt.Errorf("TLS服务端需要证书与私钥")
+	}
+	if c.VerifyClient && c.CA == "" {
+		return nil, fmt.Errorf("校验客户端证书需要指定CA，不能使用系统根证书")
+	}
+	cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("加载证书失败: %v", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+	}
+	if c.VerifyClient {
+		pool, err := c.caPool()
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// 客户端配置，只允许TLS 1.3；配置了证书时向服务端出示
+func (c TLSConfig) Client() (*tls.Config, error) {
+	pool, err := c.caPool()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		RootCAs:    pool,
+		ServerName: c.ServerName,
+	}
+	if c.Cert != "" || c.Key != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("加载证书失败: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// CA证书池，未配置CA时返回nil（使用系统根证书）
+func (c TLSConfig) caPool() (*x509.CertPool, error) {
+	if c.CA == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(c.CA)
+	if err != nil {
+		return nil, fmt.Errorf("读取CA证书失败: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA文件中没有有效的证书: %s", c.CA)
+	}
+	return pool, nil
+}
+
+// 在conn上建立TLS客户端连接并完成握手，cfg为nil时原样返回conn
+// cfg未指定ServerName时取address中的主机名
+func DialTLS(conn net.Conn, address string, cfg *tls.Config) (net.Conn, error) {
+	if cfg == nil {
+		return conn, nil
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		cfg = cfg.Clone()
+		cfg.ServerName = host
+	}
+	tc := tls.Client(conn, cfg)
+	tc.SetDeadline(time.Now().Add(HandshakeTimeout))
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS握手失败: %v", err)
+	}
+	tc.SetDeadline(time.Time{})
+	return tc, nil
+}
+
+// 完成服务端握手，返回客户端证书主体的CN（没有客户端证书时为空）
+func AcceptTLS(tc *tls.Conn) (string, error) {
+	tc.SetDeadline(time.Now().Add(HandshakeTimeout))
+	if err := tc.Handshake(); err != nil {
+		return "", fmt.Errorf("TLS握手失败: %v", err)
+	}
+	tc.SetDeadline(time.Time{})
+
+	certs := tc.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", nil
+	}
+	return certs[0].Subject.CommonName, nil
+}
+
+// 校验帧的节点ID与连接的证书主体一致
+func MatchCertNode(certNode, nodeID string) error {
+	if certNode != nodeID {
+		return fail(REASON_CERT_MISMATCH, "证书主体 %q 与节点ID %q 不一致", certNode, nodeID)
+	}
+	return nil
+}
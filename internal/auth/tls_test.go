@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试用CA，签发的证书写入dir
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string // CA证书PEM文件
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "TDMA Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{dir: t.TempDir(), cert: cert, key: key}
+	ca.file = ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+// 签发主体CN为cn的证书，返回证书与私钥文件
+func (ca *testCA) issue(t *testing.T, cn string, dnsNames ...string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca.write(t, cn+".pem", "CERTIFICATE", der), ca.write(t, cn+".key", "EC PRIVATE KEY", keyDER)
+}
+
+func (ca *testCA) write(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(ca.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 在本地TCP连接上完成一次TLS握手，返回服务端取出的证书CN与双方的错误
+func tlsHandshake(t *testing.T, server, client *tls.Config) (certNode string, serverErr, clientErr error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		node string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		node, err := AcceptTLS(tls.Server(conn, server))
+		done <- result{node, err}
+	}()
+
+	raw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, clientErr := DialTLS(raw, "sat.test:8080", client)
+	if clientErr == nil {
+		// TLS 1.3中服务端在客户端握手完成后才校验客户端证书，读取以等待服务端的结果
+		conn.SetReadDeadline(time.Now().Add(HandshakeTimeout))
+		conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	r := <-done
+	return r.node, r.err, clientErr
+}
+
+func TestServerRequiresCAToVerifyClients(t *testing.T) {
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, "SAT_A", "sat.test")
+
+	if _, err := (TLSConfig{Cert: cert, Key: key, VerifyClient: true}).Server(); err == nil {
+		t.Fatalf("Server without CA accepted VerifyClient")
+	}
+	if _, err := (TLSConfig{Cert: cert, Key: key, CA: ca.file, VerifyClient: true}).Server(); err != nil {
+		t.Fatalf("Server with CA: %v", err)
+	}
+	if _, err := (TLSConfig{Cert: cert, Key: key}).Server(); err != nil {
+		t.Fatalf("Server without client verification: %v", err)
+	}
+}
+
+func TestAcceptTLS(t *testing.T) {
+	ca := newTestCA(t)
+	satCert, satKey := ca.issue(t, "SAT_A", "sat.test")
+	gsCert, gsKey := ca.issue(t, "GS1")
+	server, err := TLSConfig{Cert: satCert, Key: satKey, CA: ca.file, VerifyClient: true}.Server()
+	if err != nil {
+		t.Fatalf("Server: %v", err)
+	}
+	// 另一CA签发的同名证书
+	rogueCert, rogueKey := newTestCA(t).issue(t, "GS1")
+
+	tests := []struct {
+		name       string
+		client     TLSConfig
+		maxVersion uint16
+		wantNode   string
+		wantErr    bool
+	}{
+		{name: "client certificate", client: TLSConfig{CA: ca.file, Cert: gsCert, Key: gsKey}, wantNode: "GS1"},
+		{name: "no client certificate", client: TLSConfig{CA: ca.file}, wantErr: true},
+		{name: "untrusted client certificate", client: TLSConfig{CA: ca.file, Cert: rogueCert, Key: rogueKey}, wantErr: true},
+		{name: "TLS 1.2", client: TLSConfig{CA: ca.file, Cert: gsCert, Key: gsKey}, maxVersion: tls.VersionTLS12, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.client.Client()
+			if err != nil {
+				t.Fatalf("Client: %v", err)
+			}
+			if tt.maxVersion != 0 {
+				client.MinVersion = tls.VersionTLS12
+				client.MaxVersion = tt.maxVersion
+			}
+			node, serverErr, _ := tlsHandshake(t, server, client)
+			if tt.wantErr {
+				if serverErr == nil {
+					t.Fatalf("handshake succeeded with node %q", node)
+				}
+				return
+			}
+			if serverErr != nil {
+				t.Fatalf("AcceptTLS: %v", serverErr)
+			}
+			if node != tt.wantNode {
+				t.Fatalf("node = %q, want %q", node, tt.wantNode)
+			}
+		})
+	}
+}
+
+func TestMatchCertNode(t *testing.T) {
+	if err := MatchCertNode("GS1", "GS1"); err != nil {
+		t.Fatalf("MatchCertNode(GS1, GS1) = %v", err)
+	}
+	for _, nodeID := range []string{"GS2", "", "GS1 "} {
+		if got := Reason(MatchCertNode("GS1", nodeID)); got != REASON_CERT_MISMATCH {
+			t.Errorf("MatchCertNode(GS1, %q) reason = %q, want %q", nodeID, got, REASON_CERT_MISMATCH)
+		}
+	}
+	// 没有客户端证书的连接不匹配任何节点
+	if got := Reason(MatchCertNode("", "GS1")); got != REASON_CERT_MISMATCH {
+		t.Errorf("MatchCertNode(\"\", GS1) reason = %q, want %q", got, REASON_CERT_MISMATCH)
+	}
+}
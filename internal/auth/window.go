@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"tdma-network/pkg/protocol"
+)
+
+// 防重放窗口大小：比已接受的最大序号小不超过该值的序号可以乱序到达
+const ReplayWindow = 64
+
+// 接收序号的滑动防重放窗口
+// top为已接受的最大序号，bitmap第i位表示序号top-i已接受
+type replayWindow struct {
+	top    uint64
+	bitmap uint64
+}
+
+// 检查序号能否接受，不修改窗口
+func (w *replayWindow) check(seq uint64) error {
+	if seq == 0 {
+		return fmt.Errorf("序号为0")
+	}
+	if seq > w.top {
+		return nil
+	}
+	diff := w.top - seq
+	if diff >= ReplayWindow {
+		return fmt.Errorf("序号 %d 落后已接收的 %d 超过窗口 %d", seq, w.top, ReplayWindow)
+	}
+	if w.bitmap&(1<<diff) != 0 {
+		return fmt.Errorf("序号 %d 已接收", seq)
+	}
+	return nil
+}
+
+// 记录已接受的序号，调用前须通过check
+func (w *replayWindow) accept(seq uint64) {
+	if seq > w.top {
+		shift := seq - w.top
+		if shift >= ReplayWindow {
+			w.bitmap = 0
+		} else {
+			w.bitmap <<= shift
+		}
+		w.bitmap |= 1
+		w.top = seq
+		return
+	}
+	w.bitmap |= 1 << (w.top - seq)
+}
+
+// 明文运行时各节点的防重放窗口，不需要密钥库
+// 帧的序号在明文序号尾部（protocol.FLAG_SEQ）中，没有密钥保护，只能识别原样重发的帧
+// 窗口在节点会话结束后保留，重发会话结束前截获的帧同样被拒绝
+type Sequencer struct {
+	mu      sync.Mutex
+	windows map[string]*replayWindow // 节点ID -> 接收窗口
+}
+
+// 创建明文防重放窗口
+func NewSequencer() *Sequencer {
+	return &Sequencer{windows: make(map[string]*replayWindow)}
+}
+
+// 校验节点发来的帧的序号尾部，成功时去掉尾部
+// 没有序号尾部的帧视为未签名，序号重复或落后于窗口视为重放
+func (s *Sequencer) Open(nodeID string, frame *protocol.TDMAFrame) error {
+	if !frame.IsSequenced() {
+		return fail(REASON_UNSIGNED, "帧没有序号尾部")
+	}
+	seq, err := frame.TakeSequence()
+	if err != nil {
+		return fail(REASON_UNSIGNED, "%v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.windows[nodeID]
+	if !ok {
+		w = &replayWindow{}
+		s.windows[nodeID] = w
+	}
+	if err := w.check(seq); err != nil {
+		return fail(REASON_REPLAY, "%v", err)
+	}
+	w.accept(seq)
+	return nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"tdma-network/pkg/protocol"
+	"testing"
+)
+
+func TestReplayWindow(t *testing.T) {
+	type step struct {
+		seq uint64
+		ok  bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"zero", []step{{0, false}}},
+		{"in order", []step{{1, true}, {2, true}, {3, true}}},
+		{"duplicate", []step{{1, true}, {2, true}, {2, false}, {1, false}}},
+		{"reordered within window", []step{{10, true}, {5, true}, {9, true}, {5, false}, {11, true}}},
+		{"oldest in window", []step{{100, true}, {100 - ReplayWindow + 1, true}, {100 - ReplayWindow + 1, false}}},
+		{"out of window", []step{{100, true}, {100 - ReplayWindow, false}, {1, false}}},
+		// 跳跃超过窗口时位图清零，跳跃前接受的序号落在窗口之外
+		{"jump past window", []step{{1, true}, {200, true}, {1, false}, {199, true}, {200, false}}},
+		{"jump within window keeps history", []step{{1, true}, {2, true}, {40, true}, {2, false}, {3, true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w replayWindow
+			for i, s := range tt.steps {
+				err := w.check(s.seq)
+				if (err == nil) != s.ok {
+					t.Fatalf("step %d: check(%d) = %v, want ok=%v", i, s.seq, err, s.ok)
+				}
+				if err == nil {
+					w.accept(s.seq)
+				}
+			}
+		})
+	}
+}
+
+// 会话按序号防重放：重复与超出窗口的帧以replay拒绝，窗口内乱序的帧照常解密
+func TestSessionOpenReplay(t *testing.T) {
+	a, _, gs := establish(t, DefaultRekeyPolicy)
+	var sent [][]byte
+	for i := 0; i < ReplayWindow+2; i++ {
+		raw, err := seal(t, gs, protocol.MSG_HEARTBEAT).Serialize()
+		if err != nil {
+			t.Fatalf("Serialize: %v", err)
+		}
+		sent = append(sent, raw)
+	}
+
+	// 序号为下标+1
+	tests := []struct {
+		name   string
+		idx    int
+		reason string
+	}{
+		{"first", 0, ""},
+		{"duplicate", 0, REASON_REPLAY},
+		{"skip ahead", ReplayWindow, ""},
+		{"reordered within window", 5, ""},
+		{"reordered duplicate", 5, REASON_REPLAY},
+		{"advance window", ReplayWindow + 1, ""},
+		{"oldest in window", 2, ""},
+		// 从未接受过，但落后最大序号达到窗口大小
+		{"out of window", 1, REASON_REPLAY},
+		{"newest duplicate", ReplayWindow + 1, REASON_REPLAY},
+	}
+	for _, tt := range tests {
+		frame, err := protocol.DeserializeTDMAFrame(sent[tt.idx])
+		if err != nil {
+			t.Fatalf("%s: Deserialize: %v", tt.name, err)
+		}
+		err = a.Open(testNode, frame)
+		if got := Reason(err); got != tt.reason || (err == nil) != (tt.reason == "") {
+			t.Fatalf("%s: Open(seq %d) = %v (reason %q), want reason %q", tt.name, tt.idx+1, err, got, tt.reason)
+		}
+	}
+}
+
+// 明文运行时按节点的序号尾部防重放，各节点的窗口相互独立
+func TestSequencerOpen(t *testing.T) {
+	frame := func(nodeID string, seq uint64) *protocol.TDMAFrame {
+		f := protocol.NewTDMAFrame(0, nodeID, []byte(protocol.MSG_HEARTBEAT))
+		if seq > 0 {
+			f.SetSequence(seq)
+		}
+		return f
+	}
+	tests := []struct {
+		name   string
+		node   string
+		seq    uint64 // 0表示不带序号尾部
+		reason string
+	}{
+		{"first", "GS1", 1000, ""},
+		{"duplicate", "GS1", 1000, REASON_REPLAY},
+		{"other node same sequence", "GS2", 1000, ""},
+		{"reordered within window", "GS1", 990, ""},
+		{"advance", "GS1", 2000, ""},
+		{"out of window", "GS1", 1500, REASON_REPLAY},
+		{"unsequenced", "GS1", 0, REASON_UNSIGNED},
+	}
+	s := NewSequencer()
+	for _, tt := range tests {
+		f := frame(tt.node, tt.seq)
+		err := s.Open(tt.node, f)
+		if got := Reason(err); got != tt.reason || (err == nil) != (tt.reason == "") {
+			t.Fatalf("%s: Open = %v (reason %q), want reason %q", tt.name, err, got, tt.reason)
+		}
+		if err == nil && (f.IsSequenced() || string(f.Data) != protocol.MSG_HEARTBEAT || f.Validate() != nil) {
+			t.Fatalf("%s: opened frame flags %#x data %q", tt.name, f.Flags, f.Data)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/scheduler"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 帧方向，取值与pcapng epb_flags的方向位相同
+type Direction uint8
+
+const (
+	DIR_INBOUND  Direction = 1 // 接收
+	DIR_OUTBOUND Direction = 2 // 发送
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DIR_INBOUND:
+		return "接收"
+	case DIR_OUTBOUND:
+		return "发送"
+	}
+	return "未知"
+}
+
+// 抓包格式常量
+// 每个分组为80字节的抓包头加上完整的TDMA帧，格式见README“抓包”一节
+const (
+	LINKTYPE_USER0 = 147
+	HEADER_VERSION = 1
+	HEADER_LEN     = 80
+)
+
+// pcapng块类型与选项
+const (
+	blockSHB = 0x0A0D0D0A
+	blockIDB = 0x00000001
+	blockEPB = 0x00000006
+
+	byteOrderMagic = 0x1A2B3C4D
+
+	optEnd      = 0
+	optComment  = 1
+	optUserAppl = 4 // shb_userappl
+	optIfName   = 2 // if_name
+	optTsResol  = 9 // if_tsresol
+	optEPBFlags = 2 // epb_flags
+)
+
+var logger = logging.For("capture")
+
+// 抓包写入器，nil表示不抓包
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	nodeID string
+	failed bool
+	conns  map[string]uint32 // 本端地址|对端地址 -> 连接编号，按首次出现顺序从1开始
+	layout scheduler.Layout  // 计算抓包头中当前时隙的帧结构
+}
+
+// 创建抓包文件
+func Create(path, nodeID string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建抓包文件失败: %v", err)
+	}
+	cw, err := NewWriter(f, nodeID)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cw.closer = f
+	return cw, nil
+}
+
+// 创建抓包写入器并写入文件头
+func NewWriter(w io.Writer, nodeID string) (*Writer, error) {
+	cw := &Writer{w: w, nodeID: nodeID, conns: make(map[string]uint32), layout: scheduler.NewLayout(scheduler.DefaultConfig())}
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // 主版本
+	binary.LittleEndian.PutUint16(shb[6:], 0) // 次版本
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	shb = appendOption(shb, optUserAppl, []byte("tdma-network"))
+	shb = appendOption(shb, optEnd, nil)
+	if err := cw.writeBlock(blockSHB, shb); err != nil {
+		return nil, err
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], LINKTYPE_USER0)
+	binary.LittleEndian.PutUint32(idb[4:], 0) // 不截断
+	idb = appendOption(idb, optIfName, []byte(nodeID))
+	idb = appendOption(idb, optTsResol, []byte{9})
+	idb = appendOption(idb, optEnd, nil)
+	if err := cw.writeBlock(blockIDB, idb); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// 设置帧结构，须与节点的调度器一致
+func (cw *Writer) SetLayout(l scheduler.Layout) {
+	if cw == nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.layout = l
+}
+
+// 记录在conn上收发的一帧，peer为对端节点ID或地址；写入失败后停止抓包，不影响收发
+func (cw *Writer) Frame(dir Direction, conn net.Conn, peer string, frame *protocol.TDMAFrame) {
+	if cw == nil {
+		return
+	}
+	raw, err := frame.Serialize()
+	if err != nil {
+		return
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.failed {
+		return
+	}
+	var connID uint32
+	if conn != nil {
+		// 按地址区分连接，同一TCP连接经过信道或加锁包装后仍为同一编号
+		key := conn.LocalAddr().String() + "|" + conn.RemoteAddr().String()
+		if connID = cw.conns[key]; connID == 0 {
+			connID = uint32(len(cw.conns) + 1)
+			cw.conns[key] = connID
+		}
+	}
+
+	if peer == "" && conn != nil {
+		peer = conn.RemoteAddr().String()
+	}
+
+	now := time.Now()
+	current := cw.layout.Slot(now)
+
+	hdr := make([]byte, HEADER_LEN)
+	hdr[0] = HEADER_VERSION
+	hdr[1] = byte(dir)
+	binary.BigEndian.PutUint16(hdr[2:], HEADER_LEN)
+	binary.BigEndian.PutUint32(hdr[4:], uint32(current))
+	binary.BigEndian.PutUint32(hdr[8:], connID)
+	copy(hdr[16:48], cw.nodeID)
+	copy(hdr[48:80], peer)
+	data := append(hdr, raw...)
+
+	src, dst := cw.nodeID, peer
+	if dir == DIR_INBOUND {
+		src, dst = peer, cw.nodeID
+	}
+	comment := fmt.Sprintf("%s %s -> %s 时隙 %d (当前 %d)", dir, src, dst, frame.SlotID, current)
+
+	ts := uint64(now.UnixNano())
+	epb := make([]byte, 20, 20+len(data)+64)
+	binary.LittleEndian.PutUint32(epb[0:], 0) // 接口ID
+	binary.LittleEndian.PutUint32(epb[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(epb[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(epb[12:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(epb[16:], uint32(len(data)))
+	epb = append(epb, pad(data)...)
+	var flags [4]byte
+	binary.LittleEndian.PutUint32(flags[:], uint32(dir))
+	epb = appendOption(epb, optEPBFlags, flags[:])
+	epb = appendOption(epb, optComment, []byte(comment))
+	epb = appendOption(epb, optEnd, nil)
+
+	if err := cw.writeBlock(blockEPB, epb); err != nil {
+		cw.failed = true
+		logger.Warn("写入抓包文件失败，停止抓包", logging.KEY_NODE_ID, cw.nodeID, "err", err)
+	}
+}
+
+// 关闭抓包文件
+func (cw *Writer) Close() error {
+	if cw == nil || cw.closer == nil {
+		return nil
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.failed = true
+	return cw.closer.Close()
+}
+
+// 写入一个块：类型、总长度、内容、总长度
+func (cw *Writer) writeBlock(blockType uint32, body []byte) error {
+	total := uint32(12 + len(body))
+	buf := make([]byte, 0, total)
+	buf = binary.LittleEndian.AppendUint32(buf, blockType)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	buf = append(buf, body...)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	_, err := cw.w.Write(buf)
+	return err
+}
+
+// 追加一个选项，值补齐到4字节
+func appendOption(buf []byte, code uint16, value []byte) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, code)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(value)))
+	return append(buf, pad(value)...)
+}
+
+// 补齐到4字节边界
+func pad(b []byte) []byte {
+	if n := len(b) % 4; n != 0 {
+		return append(b[:len(b):len(b)], make([]byte, 4-n)...)
+	}
+	return b
+}
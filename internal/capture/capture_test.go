@@ -0,0 +1,252 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"tdma-network/internal/scheduler"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+type testAddr string
+
+func (a testAddr) Network() string { return "tcp" }
+func (a testAddr) String() string  { return string(a) }
+
+// 只提供地址的连接，抓包按地址区分连接
+type testConn struct {
+	net.Conn
+	local, remote string
+}
+
+func (c testConn) LocalAddr() net.Addr  { return testAddr(c.local) }
+func (c testConn) RemoteAddr() net.Addr { return testAddr(c.remote) }
+
+type block struct {
+	typ  uint32
+	body []byte
+}
+
+// 按pcapng块结构切分，检查首尾长度一致且按4字节对齐
+func readBlocks(t *testing.T, data []byte) []block {
+	t.Helper()
+	var blocks []block
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("%d trailing bytes", len(data))
+		}
+		typ := binary.LittleEndian.Uint32(data[0:])
+		total := binary.LittleEndian.Uint32(data[4:])
+		if total%4 != 0 || total < 12 || int(total) > len(data) {
+			t.Fatalf("block 0x%08x: invalid total length %d", typ, total)
+		}
+		if trailer := binary.LittleEndian.Uint32(data[total-4:]); trailer != total {
+			t.Fatalf("block 0x%08x: trailing length %d, want %d", typ, trailer, total)
+		}
+		blocks = append(blocks, block{typ: typ, body: data[8 : total-4]})
+		data = data[total:]
+	}
+	return blocks
+}
+
+// 解析选项，检查每个值补齐到4字节且以opt_endofopt结束
+func readOptions(t *testing.T, b []byte) map[uint16][]byte {
+	t.Helper()
+	opts := make(map[uint16][]byte)
+	for {
+		if len(b) < 4 {
+			t.Fatal("options not terminated by opt_endofopt")
+		}
+		code := binary.LittleEndian.Uint16(b[0:])
+		n := int(binary.LittleEndian.Uint16(b[2:]))
+		if code == optEnd {
+			if n != 0 || len(b) != 4 {
+				t.Fatalf("opt_endofopt length %d with %d bytes after it", n, len(b)-4)
+			}
+			return opts
+		}
+		padded := (n + 3) &^ 3
+		if 4+padded > len(b) {
+			t.Fatalf("option %d: length %d exceeds block", code, n)
+		}
+		if pad := b[4+n : 4+padded]; !bytes.Equal(pad, make([]byte, len(pad))) {
+			t.Fatalf("option %d: non-zero padding %x", code, pad)
+		}
+		opts[code] = b[4 : 4+n]
+		b = b[4+padded:]
+	}
+}
+
+func TestWriterBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewWriter(&buf, "SAT_A")
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	// 时隙足够长，抓包时刻的全局时隙在测试期间不变
+	layout := scheduler.NewLayout(scheduler.Config{TotalSlots: 8, SlotDuration: time.Hour})
+	cw.SetLayout(layout)
+
+	gs1 := testConn{local: "10.0.0.1:8080", remote: "10.0.0.2:5000"}
+	gs2 := testConn{local: "10.0.0.1:8080", remote: "10.0.0.3:5000"}
+	frames := []struct {
+		dir               Direction
+		conn              net.Conn
+		peer              string
+		frame             *protocol.TDMAFrame
+		connID            uint32
+		wantPeer, comment string
+	}{
+		// 数据长度不是4的倍数，分组数据需要补齐
+		{DIR_INBOUND, gs1, "GS1", protocol.NewTDMAFrame(3, "GS1", []byte("HEARTBEAT")), 1, "GS1", "接收 GS1 -> SAT_A 时隙 3"},
+		{DIR_OUTBOUND, gs1, "GS1", protocol.NewTDMAFrame(3, "SAT_A", []byte("HEARTBEAT_ACK_3")), 1, "GS1", "发送 SAT_A -> GS1 时隙 3"},
+		// 对端未知时记录地址
+		{DIR_INBOUND, gs2, "", protocol.NewTDMAFrame(0, "GS2", []byte("JOIN")), 2, "10.0.0.3:5000", "接收 10.0.0.3:5000 -> SAT_A 时隙 0"},
+	}
+	before := time.Now()
+	for _, f := range frames {
+		cw.Frame(f.dir, f.conn, f.peer, f.frame)
+	}
+	after := time.Now()
+
+	blocks := readBlocks(t, buf.Bytes())
+	if len(blocks) != 2+len(frames) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), 2+len(frames))
+	}
+
+	shb := blocks[0]
+	if shb.typ != blockSHB {
+		t.Fatalf("first block type 0x%08x, want SHB", shb.typ)
+	}
+	if magic := binary.LittleEndian.Uint32(shb.body[0:]); magic != byteOrderMagic {
+		t.Fatalf("byte-order magic 0x%08x", magic)
+	}
+	if major, minor := binary.LittleEndian.Uint16(shb.body[4:]), binary.LittleEndian.Uint16(shb.body[6:]); major != 1 || minor != 0 {
+		t.Fatalf("version %d.%d, want 1.0", major, minor)
+	}
+	if sectionLen := binary.LittleEndian.Uint64(shb.body[8:]); sectionLen != ^uint64(0) {
+		t.Fatalf("section length %d, want -1", sectionLen)
+	}
+	if appl := readOptions(t, shb.body[16:])[optUserAppl]; string(appl) != "tdma-network" {
+		t.Fatalf("shb_userappl = %q", appl)
+	}
+
+	idb := blocks[1]
+	if idb.typ != blockIDB {
+		t.Fatalf("second block type 0x%08x, want IDB", idb.typ)
+	}
+	if linkType := binary.LittleEndian.Uint16(idb.body[0:]); linkType != LINKTYPE_USER0 {
+		t.Fatalf("link type %d, want %d", linkType, LINKTYPE_USER0)
+	}
+	if snapLen := binary.LittleEndian.Uint32(idb.body[4:]); snapLen != 0 {
+		t.Fatalf("snaplen %d, want 0", snapLen)
+	}
+	opts := readOptions(t, idb.body[8:])
+	if name := opts[optIfName]; string(name) != "SAT_A" {
+		t.Fatalf("if_name = %q, want SAT_A", name)
+	}
+	if res := opts[optTsResol]; !bytes.Equal(res, []byte{9}) {
+		t.Fatalf("if_tsresol = %v, want [9]", res)
+	}
+
+	for i, f := range frames {
+		t.Run(fmt.Sprintf("packet %d", i), func(t *testing.T) {
+			epb := blocks[2+i]
+			if epb.typ != blockEPB {
+				t.Fatalf("block type 0x%08x, want EPB", epb.typ)
+			}
+			raw, err := f.frame.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantLen := HEADER_LEN + len(raw)
+
+			if ifID := binary.LittleEndian.Uint32(epb.body[0:]); ifID != 0 {
+				t.Fatalf("interface ID %d, want 0", ifID)
+			}
+			ts := int64(binary.LittleEndian.Uint32(epb.body[4:]))<<32 | int64(binary.LittleEndian.Uint32(epb.body[8:]))
+			if at := time.Unix(0, ts); at.Before(before) || at.After(after) {
+				t.Fatalf("timestamp %v outside [%v, %v]", at, before, after)
+			}
+			capLen := int(binary.LittleEndian.Uint32(epb.body[12:]))
+			origLen := int(binary.LittleEndian.Uint32(epb.body[16:]))
+			if capLen != wantLen || origLen != wantLen {
+				t.Fatalf("captured/original length %d/%d, want %d", capLen, origLen, wantLen)
+			}
+			padded := (capLen + 3) &^ 3
+			data := epb.body[20 : 20+capLen]
+			if pad := epb.body[20+capLen : 20+padded]; !bytes.Equal(pad, make([]byte, len(pad))) {
+				t.Fatalf("non-zero packet padding %x", pad)
+			}
+
+			// 抓包头，偏移与 tools/wireshark/tdma.lua 一致
+			hdr := data[:HEADER_LEN]
+			if hdr[0] != HEADER_VERSION || Direction(hdr[1]) != f.dir {
+				t.Fatalf("version %d direction %d, want %d %d", hdr[0], hdr[1], HEADER_VERSION, f.dir)
+			}
+			if hdrLen := binary.BigEndian.Uint16(hdr[2:]); hdrLen != HEADER_LEN {
+				t.Fatalf("header length %d, want %d", hdrLen, HEADER_LEN)
+			}
+			current := int(binary.BigEndian.Uint32(hdr[4:]))
+			if current != layout.Slot(before) && current != layout.Slot(after) {
+				t.Fatalf("current slot %d, want %d", current, layout.Slot(before))
+			}
+			if connID := binary.BigEndian.Uint32(hdr[8:]); connID != f.connID {
+				t.Fatalf("connection %d, want %d", connID, f.connID)
+			}
+			if !bytes.Equal(hdr[12:16], make([]byte, 4)) {
+				t.Fatalf("reserved bytes %x", hdr[12:16])
+			}
+			if local := string(bytes.TrimRight(hdr[16:48], "\x00")); local != "SAT_A" {
+				t.Fatalf("local node %q, want SAT_A", local)
+			}
+			if peer := string(bytes.TrimRight(hdr[48:80], "\x00")); peer != f.wantPeer {
+				t.Fatalf("peer %q, want %q", peer, f.wantPeer)
+			}
+			if !bytes.Equal(data[HEADER_LEN:], raw) {
+				t.Fatal("packet does not end with the serialized frame")
+			}
+
+			opts := readOptions(t, epb.body[20+padded:])
+			if flags := opts[optEPBFlags]; len(flags) != 4 || binary.LittleEndian.Uint32(flags) != uint32(f.dir) {
+				t.Fatalf("epb_flags = %x, want direction %d", flags, f.dir)
+			}
+			wantComment := fmt.Sprintf("%s (当前 %d)", f.comment, current)
+			if comment := string(opts[optComment]); comment != wantComment {
+				t.Fatalf("comment %q, want %q", comment, wantComment)
+			}
+		})
+	}
+}
+
+// 写入失败后停止抓包，不再写入
+func TestWriterStopsAfterWriteError(t *testing.T) {
+	w := &failingWriter{}
+	cw, err := NewWriter(w, "SAT_A")
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.fail = true
+	frame := protocol.NewTDMAFrame(1, "GS1", []byte("JOIN"))
+	cw.Frame(DIR_INBOUND, nil, "GS1", frame)
+	cw.Frame(DIR_INBOUND, nil, "GS1", frame)
+	if w.attempts != 1 {
+		t.Fatalf("%d writes after the first failure, want 1", w.attempts)
+	}
+}
+
+type failingWriter struct {
+	fail     bool
+	attempts int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if !w.fail {
+		return len(b), nil
+	}
+	w.attempts++
+	return 0, fmt.Errorf("disk full")
+}
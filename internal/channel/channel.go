@@ -0,0 +1,208 @@
+package channel
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
+)
+
+// 丢包模型
+const (
+	LOSS_NONE      = "none"      // 不丢包
+	LOSS_BERNOULLI = "bernoulli" // 独立随机丢包
+	LOSS_GILBERT   = "gilbert"   // Gilbert-Elliott两状态突发丢包
+)
+
+// 信道参数
+type Config struct {
+	LossModel string  `json:"loss_model"`
+	LossProb  float64 `json:"loss_prob"` // bernoulli: 每帧丢失概率
+
+	// gilbert: 好/坏两状态马尔可夫链，每帧转移一次
+	GoodToBad float64 `json:"good_to_bad"` // 好->坏转移概率
+	BadToGood float64 `json:"bad_to_good"` // 坏->好转移概率
+	LossGood  float64 `json:"loss_good"`   // 好状态下丢失概率
+	LossBad   float64 `json:"loss_bad"`    // 坏状态下丢失概率
+
+	BER float64 `json:"ber"` // 误比特率，作用于数据区与CRC
+
+	Delay  Duration `json:"delay"`  // 固定传播时延
+	Jitter Duration `json:"jitter"` // 时延抖动，在[-Jitter, +Jitter]内均匀分布
+
+	ReorderProb  float64  `json:"reorder_prob"`  // 乱序概率
+	ReorderDelay Duration `json:"reorder_delay"` // 乱序帧额外时延，使后续帧先到达
+
+	// 几何时延：启用后按卫星轨道与地面站位置计算随时间变化的传播时延，叠加在Delay之上
+	Geometry  bool    `json:"geometry"`
+	CarrierHz float64 `json:"carrier_hz"` // 载波频率，用于报告多普勒频移
+
+	Seed int64 `json:"seed"` // 随机种子，0表示按时间取种
+}
+
+// 随时间变化的传播特性
+type Propagation interface {
+	Delay(t time.Time) time.Duration
+	Doppler(t time.Time) float64
+}
+
+// 信道统计
+type Stats struct {
+	Frames    int64 // 进入信道的帧数
+	Dropped   int64 // 丢失帧数
+	Corrupted int64 // 含误码的帧数
+	BitErrors int64 // 翻转的比特数
+	Reordered int64 // 被延后乱序的帧数
+
+	PropDelay time.Duration // 最近一帧的几何传播时延
+	DopplerHz float64       // 最近一帧的多普勒频移
+}
+
+// 单向信道
+type Channel struct {
+	mu    sync.Mutex
+	cfg   Config
+	rng   *rand.Rand
+	bad   bool // gilbert当前是否处于坏状态
+	stats Stats
+	prop  Propagation
+	clock clock.Clock
+}
+
+// 创建新的信道
+func New(cfg Config) *Channel {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Channel{
+		cfg:   cfg,
+		rng:   rand.New(rand.NewSource(seed)),
+		clock: clock.Real{},
+	}
+}
+
+// 设置时钟，几何时延按该时钟的当前时刻计算
+func (c *Channel) SetClock(clk clock.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clk
+}
+
+// 获取信道参数
+func (c *Channel) Config() Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg
+}
+
+// 更新信道参数，保留随机数状态
+func (c *Channel) SetConfig(cfg Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+}
+
+// 设置几何传播模型，nil表示不使用几何时延
+func (c *Channel) SetPropagation(p Propagation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prop = p
+}
+
+// 获取信道统计
+func (c *Channel) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// 让一帧通过信道
+// 返回可能含误码的帧副本与投递时延；dropped为true表示该帧丢失
+func (c *Channel) Transmit(frame []byte) (out []byte, delay time.Duration, dropped bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Frames++
+
+	if c.lose() {
+		c.stats.Dropped++
+		return nil, 0, true
+	}
+
+	out = make([]byte, len(frame))
+	copy(out, frame)
+	if flips := c.corrupt(out); flips > 0 {
+		c.stats.Corrupted++
+		c.stats.BitErrors += int64(flips)
+	}
+
+	return out, c.delay(), false
+}
+
+// 按丢包模型判定是否丢失
+func (c *Channel) lose() bool {
+	switch c.cfg.LossModel {
+	case LOSS_BERNOULLI:
+		return c.rng.Float64() < c.cfg.LossProb
+
+	case LOSS_GILBERT:
+		if c.bad {
+			if c.rng.Float64() < c.cfg.BadToGood {
+				c.bad = false
+			}
+		} else if c.rng.Float64() < c.cfg.GoodToBad {
+			c.bad = true
+		}
+		if c.bad {
+			return c.rng.Float64() < c.cfg.LossBad
+		}
+		return c.rng.Float64() < c.cfg.LossGood
+	}
+	return false
+}
+
+// 按误比特率翻转数据区与CRC中的比特，返回翻转数
+// 帧头、长度等成帧字段视为受前向纠错保护，避免破坏TCP流上的帧同步
+func (c *Channel) corrupt(frame []byte) int {
+	if c.cfg.BER <= 0 {
+		return 0
+	}
+
+	start := protocol.FRAME_PREFIX_LEN
+	end := len(frame) - 8 // 不含Footer
+	flips := 0
+	for i := start; i < end; i++ {
+		for bit := uint(0); bit < 8; bit++ {
+			if c.rng.Float64() < c.cfg.BER {
+				frame[i] ^= 1 << bit
+				flips++
+			}
+		}
+	}
+	return flips
+}
+
+// 计算投递时延
+func (c *Channel) delay() time.Duration {
+	d := time.Duration(c.cfg.Delay)
+	if c.prop != nil {
+		now := c.clock.Now()
+		c.stats.PropDelay = c.prop.Delay(now)
+		c.stats.DopplerHz = c.prop.Doppler(now)
+		d += c.stats.PropDelay
+	}
+	if c.cfg.Jitter > 0 {
+		d += time.Duration((c.rng.Float64()*2 - 1) * float64(c.cfg.Jitter))
+	}
+	if c.cfg.ReorderProb > 0 && c.rng.Float64() < c.cfg.ReorderProb {
+		d += time.Duration(c.cfg.ReorderDelay)
+		c.stats.Reordered++
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
@@ -0,0 +1,241 @@
+package channel
+
+import (
+	"bytes"
+	"math"
+	"math/bits"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+const testSeed = 42
+
+func testFrame(t *testing.T) []byte {
+	t.Helper()
+	f := protocol.NewTDMAFrame(3, "GS1", bytes.Repeat([]byte("DATA_TO:GS2:hello "), 8))
+	f.SetAbsSlot(176534000)
+	raw, err := f.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	return raw
+}
+
+func TestLossRate(t *testing.T) {
+	const n = 20000
+	tests := []struct {
+		name string
+		cfg  Config
+		want float64 // 稳态丢包率
+	}{
+		{"none", Config{}, 0},
+		{"bernoulli 10%", Config{LossModel: LOSS_BERNOULLI, LossProb: 0.1}, 0.1},
+		{"bernoulli 50%", Config{LossModel: LOSS_BERNOULLI, LossProb: 0.5}, 0.5},
+		// 坏状态稳态概率 g/(g+b) = 0.05/0.25 = 0.2，丢包率 0.8*0.01 + 0.2*0.8 = 0.168
+		{"gilbert", Config{LossModel: LOSS_GILBERT, GoodToBad: 0.05, BadToGood: 0.2, LossGood: 0.01, LossBad: 0.8}, 0.168},
+		{"gilbert lossless good state", Config{LossModel: LOSS_GILBERT, GoodToBad: 0.01, BadToGood: 0.09, LossBad: 1}, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Seed = testSeed
+			ch := New(tt.cfg)
+			frame := testFrame(t)
+			for i := 0; i < n; i++ {
+				ch.Transmit(frame)
+			}
+			st := ch.Stats()
+			if st.Frames != n {
+				t.Fatalf("Frames = %d, want %d", st.Frames, n)
+			}
+			if got := float64(st.Dropped) / n; math.Abs(got-tt.want) > 0.02 {
+				t.Fatalf("loss rate = %.4f, want %.4f±0.02", got, tt.want)
+			}
+		})
+	}
+}
+
+// 丢包模式（而不只是比例）由种子决定
+func TestLossPatternDeterministic(t *testing.T) {
+	cfg := Config{LossModel: LOSS_GILBERT, GoodToBad: 0.05, BadToGood: 0.2, LossGood: 0.01, LossBad: 0.8, Seed: testSeed}
+	pattern := func(cfg Config) []bool {
+		ch := New(cfg)
+		frame := testFrame(t)
+		out := make([]bool, 1000)
+		for i := range out {
+			_, _, out[i] = ch.Transmit(frame)
+		}
+		return out
+	}
+	a, b := pattern(cfg), pattern(cfg)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("frame %d: dropped %v vs %v with the same seed", i, a[i], b[i])
+		}
+	}
+	cfg.Seed++
+	if c := pattern(cfg); equalBools(a, c) {
+		t.Fatalf("different seeds produced the same loss pattern")
+	}
+}
+
+func equalBools(a, b []bool) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Gilbert-Elliott丢包成串出现，同样丢包率下平均连续丢包长度明显大于独立丢包
+func TestGilbertLossIsBursty(t *testing.T) {
+	meanBurst := func(cfg Config) float64 {
+		cfg.Seed = testSeed
+		ch := New(cfg)
+		frame := testFrame(t)
+		bursts, lost, prev := 0, 0, false
+		for i := 0; i < 20000; i++ {
+			_, _, dropped := ch.Transmit(frame)
+			if dropped {
+				lost++
+				if !prev {
+					bursts++
+				}
+			}
+			prev = dropped
+		}
+		return float64(lost) / float64(bursts)
+	}
+	bernoulli := meanBurst(Config{LossModel: LOSS_BERNOULLI, LossProb: 0.1})
+	gilbert := meanBurst(Config{LossModel: LOSS_GILBERT, GoodToBad: 0.01, BadToGood: 0.09, LossBad: 1})
+	if gilbert < 3*bernoulli {
+		t.Fatalf("mean burst length gilbert %.2f, bernoulli %.2f, want gilbert much burstier", gilbert, bernoulli)
+	}
+}
+
+// 误码只翻转数据区与CRC中的比特，含误码的帧都无法通过CRC校验
+func TestBitErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ber  float64
+	}{
+		{"none", 0},
+		{"low", 1e-4},
+		{"high", 1e-2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := New(Config{BER: tt.ber, Seed: testSeed})
+			frame := testFrame(t)
+			dataStart, footerStart := protocol.FRAME_PREFIX_LEN, len(frame)-8
+
+			var flipped, corrupted int64
+			for i := 0; i < 2000; i++ {
+				out, _, dropped := ch.Transmit(frame)
+				if dropped {
+					t.Fatalf("frame dropped without a loss model")
+				}
+				n := 0
+				for j := range frame {
+					d := bits.OnesCount8(out[j] ^ frame[j])
+					if d > 0 && (j < dataStart || j >= footerStart) {
+						t.Fatalf("bit error at byte %d outside data and CRC", j)
+					}
+					n += d
+				}
+				if n == 0 {
+					continue
+				}
+				flipped += int64(n)
+				corrupted++
+
+				f, err := protocol.DeserializeTDMAFrame(out)
+				if err != nil {
+					t.Fatalf("corrupted frame no longer parses: %v", err)
+				}
+				if err := f.Validate(); err == nil || err.Error() != "CRC校验失败" {
+					t.Fatalf("Validate = %v, want CRC failure", err)
+				}
+			}
+
+			st := ch.Stats()
+			if st.BitErrors != flipped || st.Corrupted != corrupted {
+				t.Fatalf("stats %d bits / %d frames, counted %d / %d", st.BitErrors, st.Corrupted, flipped, corrupted)
+			}
+			bitsExposed := float64(2000 * (footerStart - dataStart) * 8)
+			if want := tt.ber * bitsExposed; math.Abs(float64(flipped)-want) > 4*math.Sqrt(want)+1 {
+				t.Fatalf("flipped %d bits, want about %.0f", flipped, want)
+			}
+		})
+	}
+}
+
+// 输入帧不被修改
+func TestTransmitCopiesFrame(t *testing.T) {
+	ch := New(Config{BER: 0.5, Seed: testSeed})
+	frame := testFrame(t)
+	orig := append([]byte(nil), frame...)
+	ch.Transmit(frame)
+	if !bytes.Equal(frame, orig) {
+		t.Fatalf("Transmit modified its input")
+	}
+}
+
+func TestDelay(t *testing.T) {
+	ms := func(n int) Duration { return Duration(time.Duration(n) * time.Millisecond) }
+	tests := []struct {
+		name      string
+		cfg       Config
+		lo, hi    time.Duration
+		reordered bool // 是否应有帧被延后
+	}{
+		{"none", Config{}, 0, 0, false},
+		{"fixed", Config{Delay: ms(20)}, 20 * time.Millisecond, 20 * time.Millisecond, false},
+		{"jitter", Config{Delay: ms(20), Jitter: ms(5)}, 15 * time.Millisecond, 25 * time.Millisecond, false},
+		// 抖动超过固定时延时截断为0
+		{"jitter clamps at zero", Config{Delay: ms(2), Jitter: ms(10)}, 0, 12 * time.Millisecond, false},
+		{"reorder", Config{Delay: ms(20), ReorderProb: 0.3, ReorderDelay: ms(100)}, 20 * time.Millisecond, 120 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Seed = testSeed
+			ch := New(tt.cfg)
+			frame := testFrame(t)
+			var late int64
+			for i := 0; i < 1000; i++ {
+				_, d, _ := ch.Transmit(frame)
+				if d < tt.lo || d > tt.hi {
+					t.Fatalf("delay %v outside [%v, %v]", d, tt.lo, tt.hi)
+				}
+				if tt.reordered && d == time.Duration(tt.cfg.Delay+tt.cfg.ReorderDelay) {
+					late++
+				}
+			}
+			st := ch.Stats()
+			if st.Reordered != late {
+				t.Fatalf("Reordered = %d, %d frames delayed by reorder_delay", st.Reordered, late)
+			}
+			if tt.reordered && math.Abs(float64(late)/1000-tt.cfg.ReorderProb) > 0.05 {
+				t.Fatalf("reorder rate %.3f, want %.2f", float64(late)/1000, tt.cfg.ReorderProb)
+			}
+		})
+	}
+}
+
+// 几何时延叠加在固定时延之上
+type fixedPropagation struct{ d time.Duration }
+
+func (p fixedPropagation) Delay(time.Time) time.Duration { return p.d }
+func (p fixedPropagation) Doppler(time.Time) float64     { return -1200 }
+
+func TestPropagationDelay(t *testing.T) {
+	ch := New(Config{Delay: Duration(time.Millisecond), Seed: testSeed})
+	ch.SetPropagation(fixedPropagation{8 * time.Millisecond})
+	if _, d, _ := ch.Transmit(testFrame(t)); d != 9*time.Millisecond {
+		t.Fatalf("delay = %v, want 9ms", d)
+	}
+	if st := ch.Stats(); st.PropDelay != 8*time.Millisecond || st.DopplerHz != -1200 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,113 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 支持"50ms"形式的JSON时长
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("时长应为字符串，如\"50ms\": %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// 信道配置文件
+// links按地面站节点ID配置单条链路，未列出的链路使用default
+//
+//	{
+//	  "default": {"delay": "20ms"},
+//	  "links": {
+//	    "GS1": {"loss_model": "gilbert", "good_to_bad": 0.01, "bad_to_good": 0.3, "loss_bad": 0.8, "ber": 1e-5}
+//	  }
+//	}
+type FileConfig struct {
+	Default Config            `json:"default"`
+	Links   map[string]Config `json:"links"`
+}
+
+// 加载信道配置文件
+func LoadConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取信道配置失败: %v", err)
+	}
+
+	var fc FileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("解析信道配置失败: %v", err)
+	}
+
+	if err := fc.Validate(); err != nil {
+		return nil, err
+	}
+	return &fc, nil
+}
+
+// 检查全部链路参数
+func (fc *FileConfig) Validate() error {
+	if err := fc.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for name, cfg := range fc.Links {
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("links.%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// 获取链路参数
+func (fc *FileConfig) Link(nodeID string) Config {
+	if fc == nil {
+		return Config{}
+	}
+	if cfg, ok := fc.Links[nodeID]; ok {
+		return cfg
+	}
+	return fc.Default
+}
+
+// 检查参数合法性
+func (c Config) Validate() error {
+	switch c.LossModel {
+	case "", LOSS_NONE, LOSS_BERNOULLI, LOSS_GILBERT:
+	default:
+		return fmt.Errorf("未知的丢包模型: %s", c.LossModel)
+	}
+
+	probs := map[string]float64{
+		"loss_prob":    c.LossProb,
+		"good_to_bad":  c.GoodToBad,
+		"bad_to_good":  c.BadToGood,
+		"loss_good":    c.LossGood,
+		"loss_bad":     c.LossBad,
+		"ber":          c.BER,
+		"reorder_prob": c.ReorderProb,
+	}
+	for name, p := range probs {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%s 应在[0,1]内: %v", name, p)
+		}
+	}
+
+	if c.Delay < 0 || c.Jitter < 0 || c.ReorderDelay < 0 {
+		return fmt.Errorf("时延参数不能为负")
+	}
+	return nil
+}
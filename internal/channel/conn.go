@@ -0,0 +1,168 @@
+package channel
+
+import (
+	"container/heap"
+	"net"
+	"sync"
+	"time"
+)
+
+// 经过信道损伤的连接
+// 每次Write视为一个完整帧，对其施加丢包、误码与时延后再写入底层连接；读方向不做处理
+type Conn struct {
+	net.Conn
+	ch *Channel
+
+	mu     sync.Mutex
+	queue  pendingQueue
+	seq    uint64
+	closed bool
+	err    error // 异步投递的写错误，下次Write时返回
+	wake   chan struct{}
+}
+
+// 包装连接
+func NewConn(conn net.Conn, ch *Channel) *Conn {
+	c := &Conn{
+		Conn: conn,
+		ch:   ch,
+		wake: make(chan struct{}, 1),
+	}
+	go c.deliverLoop()
+	return c
+}
+
+// 获取信道
+func (c *Conn) Channel() *Channel {
+	return c.ch
+}
+
+// 写入一帧
+func (c *Conn) Write(b []byte) (int, error) {
+	out, delay, dropped := c.ch.Transmit(b)
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return 0, err
+	}
+	if dropped {
+		c.mu.Unlock()
+		return len(b), nil
+	}
+
+	// 无时延且没有排队中的帧时直接写出
+	if delay == 0 && len(c.queue) == 0 {
+		c.mu.Unlock()
+		if _, err := c.Conn.Write(out); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	c.seq++
+	heap.Push(&c.queue, &pending{at: time.Now().Add(delay), seq: c.seq, data: out})
+	c.mu.Unlock()
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return len(b), nil
+}
+
+// 关闭连接，丢弃尚未投递的帧
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.queue = nil
+	c.mu.Unlock()
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return c.Conn.Close()
+}
+
+// 按投递时间顺序写出排队的帧
+func (c *Conn) deliverLoop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return
+		}
+
+		var wait time.Duration = -1
+		if len(c.queue) > 0 {
+			next := c.queue[0]
+			if d := time.Until(next.at); d > 0 {
+				wait = d
+			} else {
+				heap.Pop(&c.queue)
+				c.mu.Unlock()
+
+				if _, err := c.Conn.Write(next.data); err != nil {
+					c.mu.Lock()
+					c.err = err
+					c.mu.Unlock()
+				}
+				continue
+			}
+		}
+		c.mu.Unlock()
+
+		if wait < 0 {
+			<-c.wake
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-c.wake:
+		}
+	}
+}
+
+// 待投递帧
+type pending struct {
+	at   time.Time
+	seq  uint64
+	data []byte
+}
+
+// 按投递时间排序的小顶堆，时间相同时保持写入顺序
+type pendingQueue []*pending
+
+func (q pendingQueue) Len() int { return len(q) }
+
+func (q pendingQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+
+func (q pendingQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pendingQueue) Push(x any) { *q = append(*q, x.(*pending)) }
+
+func (q *pendingQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,65 @@
+package channel
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// 经信道写出一组帧（每帧一字节），返回对端读到的前n帧
+// cfgs[i]为写第i帧时的信道参数
+func deliveryOrder(t *testing.T, cfgs []Config, n int) []byte {
+	t.Helper()
+	local, remote := net.Pipe()
+	ch := New(Config{Seed: testSeed})
+	conn := NewConn(local, ch)
+	defer conn.Close()
+
+	got := make(chan []byte, 1)
+	go func() {
+		var out []byte
+		buf := make([]byte, 1)
+		remote.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for len(out) < n {
+			if _, err := remote.Read(buf); err != nil {
+				break
+			}
+			out = append(out, buf[0])
+		}
+		got <- out
+	}()
+
+	for i, cfg := range cfgs {
+		cfg.Seed = testSeed
+		ch.SetConfig(cfg)
+		if _, err := conn.Write([]byte{byte('A' + i)}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	return <-got
+}
+
+func TestConnDeliveryOrder(t *testing.T) {
+	ms := func(n int) Duration { return Duration(time.Duration(n) * time.Millisecond) }
+	late := Config{Delay: ms(10), ReorderProb: 1, ReorderDelay: ms(60)}
+	tests := []struct {
+		name string
+		cfgs []Config
+		want string
+	}{
+		{"no delay", []Config{{}, {}, {}}, "ABC"},
+		{"same delay keeps write order", []Config{{Delay: ms(20)}, {Delay: ms(20)}, {Delay: ms(20)}}, "ABC"},
+		{"reordered frame arrives last", []Config{late, {Delay: ms(10)}, {Delay: ms(10)}}, "BCA"},
+		{"middle frame reordered", []Config{{Delay: ms(10)}, late, {Delay: ms(10)}}, "ACB"},
+		// 有帧排队时无时延的帧也进入队列，按到期时刻先于排队的帧投递
+		{"undelayed frame overtakes queued one", []Config{{Delay: ms(20)}, {}}, "BA"},
+		{"dropped frame", []Config{{}, {LossModel: LOSS_BERNOULLI, LossProb: 1}, {}}, "AC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(deliveryOrder(t, tt.cfgs, len(tt.want))); got != tt.want {
+				t.Fatalf("delivered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
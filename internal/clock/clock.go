@@ -0,0 +1,130 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// 时钟接口，调度器、会话与信道通过它获取时间，便于在虚拟时间下运行
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// 定时器
+type Timer interface {
+	Stop() bool
+}
+
+// 系统时钟
+type Real struct{}
+
+func (Real) Now() time.Time                            { return time.Now() }
+func (Real) Since(t time.Time) time.Duration           { return time.Since(t) }
+func (Real) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// 离散事件虚拟时钟
+// 时间只在Run中推进到下一个事件，事件回调在调用Run的协程中依次执行
+type Virtual struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	events eventQueue
+}
+
+// 创建新的虚拟时钟
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+func (v *Virtual) Since(t time.Time) time.Duration {
+	return v.Now().Sub(t)
+}
+
+// 在虚拟时间d之后执行f，同一时刻的事件按登记顺序执行
+func (v *Virtual) AfterFunc(d time.Duration, f func()) Timer {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if d < 0 {
+		d = 0
+	}
+	v.seq++
+	e := &event{at: v.now.Add(d), seq: v.seq, fn: f}
+	heap.Push(&v.events, e)
+	return e
+}
+
+// 依次执行until之前（含）的全部事件，最后将时间推进到until，返回执行的事件数
+func (v *Virtual) Run(until time.Time) int {
+	n := 0
+	for {
+		v.mu.Lock()
+		if len(v.events) == 0 || v.events[0].at.After(until) {
+			if v.now.Before(until) {
+				v.now = until
+			}
+			v.mu.Unlock()
+			return n
+		}
+		e := heap.Pop(&v.events).(*event)
+		v.now = e.at
+		fire := !e.stopped
+		e.stopped = true
+		v.mu.Unlock()
+
+		if fire {
+			e.fn()
+			n++
+		}
+	}
+}
+
+// 待执行的事件数
+func (v *Virtual) Pending() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.events)
+}
+
+type event struct {
+	at      time.Time
+	seq     uint64
+	fn      func()
+	stopped bool
+}
+
+// 取消事件，事件已执行或已取消时返回false
+func (e *event) Stop() bool {
+	if e.stopped {
+		return false
+	}
+	e.stopped = true
+	return true
+}
+
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x any)   { *q = append(*q, x.(*event)) }
+func (q *eventQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 环境变量前缀，选项 slot-duration 对应环境变量 TDMA_SLOT_DURATION
+const ENV_PREFIX = "TDMA_"
+
+// 指定配置文件的选项名，配置文件与环境变量中不能再设置该项
+const FLAG_CONFIG = "config"
+
+// 配置项的一个取值及其来源，来源用于错误信息
+type setting struct {
+	text   string
+	source string
+}
+
+// 配置文件中的配置项，键为命令行选项名，可重复的选项有多个取值
+type settings map[string][]setting
+
+// 读取配置文件，.json 文件为扁平的JSON对象，其他文件为每行一个 键=值
+// 键为命令行选项名，"_" 与 "-" 等价；数组值用于可重复的选项
+//
+//	# satellite.conf
+//	id = SAT_A
+//	port = 8080
+//	slot-duration = 500ms
+func load(file string) (settings, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if strings.HasSuffix(file, ".json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return parseJSON(file, data)
+	}
+	return parseKeyValue(file, data)
+}
+
+func parseJSON(file string, data []byte) (settings, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", file, err)
+	}
+
+	values := make(settings, len(raw))
+	for key, msg := range raw {
+		name := normalize(key)
+		source := fmt.Sprintf("配置文件 %s", file)
+
+		var list []json.RawMessage
+		if err := json.Unmarshal(msg, &list); err != nil {
+			list = []json.RawMessage{msg}
+		}
+		for _, item := range list {
+			text, err := jsonScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: 配置项 %s: %v", source, key, err)
+			}
+			values[name] = append(values[name], setting{text: text, source: source})
+		}
+	}
+	return values, nil
+}
+
+// JSON的字符串、数字与布尔值转为选项的文本形式
+func jsonScalar(msg json.RawMessage) (string, error) {
+	var v any
+	if err := json.Unmarshal(msg, &v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strings.TrimSpace(string(msg)), nil
+	}
+	return "", fmt.Errorf("只支持字符串、数字、布尔值及其数组: %s", msg)
+}
+
+func parseKeyValue(file string, data []byte) (settings, error) {
+	values := make(settings)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		source := fmt.Sprintf("配置文件 %s 第%d行", file, line)
+
+		key, val, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s: 格式应为 键=值", source)
+		}
+		val = strings.TrimSpace(val)
+		if uq, err := strconv.Unquote(val); err == nil {
+			val = uq
+		}
+		name := normalize(key)
+		values[name] = append(values[name], setting{text: val, source: source})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	return values, nil
+}
+
+func normalize(key string) string {
+	return strings.ReplaceAll(strings.TrimSpace(key), "_", "-")
+}
+
+// 选项对应的环境变量名
+func EnvName(name string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// 按 命令行 > 环境变量 > 配置文件 > 默认值 的优先级设置fs中的选项，fs须已解析命令行
+// 配置文件由 -config 选项或环境变量 TDMA_CONFIG 指定，未指定时只读取环境变量
+// 配置文件中未知的配置项与无效的取值都返回错误
+func Apply(fs *flag.FlagSet) error {
+	return NewLoader(fs).Apply()
+}
+
+// 配置加载器，记录命令行给出的选项，可多次从环境变量与配置文件重新加载其余选项
+type Loader struct {
+	fs       *flag.FlagSet
+	explicit map[string]bool // 命令行给出的选项，重新加载时保持不变
+}
+
+// 创建配置加载器，fs须已解析命令行且尚未设置其他选项
+func NewLoader(fs *flag.FlagSet) *Loader {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	return &Loader{fs: fs, explicit: explicit}
+}
+
+// 按 Apply 的优先级设置选项，命令行未给出的选项先恢复默认值，
+// 因此从配置文件中删除的配置项重新加载后回到默认值
+// 出错时部分选项可能已被修改
+func (l *Loader) Apply() error {
+	return l.apply(l.fs)
+}
+
+// 按同样的优先级设置另一个FlagSet中的同名选项，不修改加载器的FlagSet，
+// 因此可以在其他协程读取选项时重新加载。fs只需定义关心的不可重复选项且尚未设置，
+// 命令行给出的选项取命令行的值，配置文件中的配置项仍按加载器的FlagSet检查是否已知
+func (l *Loader) ApplyTo(fs *flag.FlagSet) error {
+	return l.apply(fs)
+}
+
+func (l *Loader) apply(fs *flag.FlagSet) error {
+	var file string
+	if f := l.fs.Lookup(FLAG_CONFIG); f != nil {
+		file = f.Value.String()
+	}
+	if file == "" {
+		file = os.Getenv(EnvName(FLAG_CONFIG))
+	}
+
+	var values settings
+	if file != "" {
+		var err error
+		if values, err = load(file); err != nil {
+			return err
+		}
+	}
+	for name, list := range values {
+		if name == FLAG_CONFIG {
+			return fmt.Errorf("%s: 配置文件中不能设置 %s", list[0].source, FLAG_CONFIG)
+		}
+		if l.fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: 未知的配置项 %q", list[0].source, name)
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == FLAG_CONFIG {
+			return
+		}
+		if l.explicit[f.Name] {
+			if fs != l.fs {
+				if e := fs.Set(f.Name, l.fs.Lookup(f.Name).Value.String()); e != nil {
+					err = fmt.Errorf("命令行: 配置项 %s 的值无效: %v", f.Name, e)
+				}
+			}
+			return
+		}
+		if f.Value.String() != f.DefValue {
+			if e := fs.Set(f.Name, f.DefValue); e != nil {
+				err = fmt.Errorf("恢复配置项 %s 的默认值失败: %v", f.Name, e)
+				return
+			}
+		}
+		list := values[f.Name]
+		if env, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			list = []setting{{text: env, source: "环境变量 " + EnvName(f.Name)}}
+		}
+		for _, v := range list {
+			if e := fs.Set(f.Name, v.text); e != nil {
+				err = fmt.Errorf("%s: 配置项 %s 的值 %q 无效: %v", v.source, f.Name, v.text, e)
+				return
+			}
+		}
+	})
+	return err
+}
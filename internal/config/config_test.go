@@ -0,0 +1,186 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试用的选项：与卫星的同名选项相同
+type testFlags struct {
+	fs           *flag.FlagSet
+	id           *string
+	slots        *int
+	slotDuration *time.Duration
+}
+
+func newTestFlags(args ...string) (*testFlags, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := &testFlags{
+		fs:           fs,
+		id:           fs.String("id", "SAT_DEFAULT", ""),
+		slots:        fs.Int("slots", 10, ""),
+		slotDuration: fs.Duration("slot-duration", time.Second, ""),
+	}
+	fs.String(FLAG_CONFIG, "", "")
+	return f, fs.Parse(args)
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	return file
+}
+
+func TestApplyPrecedence(t *testing.T) {
+	const file = "id = SAT_FILE\nslots = 16\nslot-duration = 250ms\n"
+	tests := []struct {
+		name     string
+		file     string // 配置文件内容，为空时不指定配置文件
+		env      map[string]string
+		args     []string
+		id       string
+		slots    int
+		duration time.Duration
+	}{
+		{"defaults", "", nil, nil, "SAT_DEFAULT", 10, time.Second},
+		{"file over default", file, nil, nil, "SAT_FILE", 16, 250 * time.Millisecond},
+		{"env over file", file, map[string]string{"TDMA_SLOTS": "20", "TDMA_ID": "SAT_ENV"}, nil, "SAT_ENV", 20, 250 * time.Millisecond},
+		{"env without file", "", map[string]string{"TDMA_SLOT_DURATION": "500ms"}, nil, "SAT_DEFAULT", 10, 500 * time.Millisecond},
+		{"flag over env and file", file, map[string]string{"TDMA_SLOTS": "20"}, []string{"-slots", "4"}, "SAT_FILE", 4, 250 * time.Millisecond},
+		// 命令行给出的值即使等于默认值也不被覆盖
+		{"flag equal to default", file, map[string]string{"TDMA_SLOTS": "20"}, []string{"-slots", "10"}, "SAT_FILE", 10, 250 * time.Millisecond},
+		{"json file", `{"id": "SAT_JSON", "slots": 12, "slot_duration": "2s"}`, nil, nil, "SAT_JSON", 12, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				name := "satellite.conf"
+				if strings.HasPrefix(tt.file, "{") {
+					name = "satellite.json"
+				}
+				args = append([]string{"-config", writeConfig(t, name, tt.file)}, args...)
+			}
+			f, err := newTestFlags(args...)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := NewLoader(f.fs).Apply(); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if *f.id != tt.id || *f.slots != tt.slots || *f.slotDuration != tt.duration {
+				t.Fatalf("got id=%s slots=%d slot-duration=%v, want %s %d %v", *f.id, *f.slots, *f.slotDuration, tt.id, tt.slots, tt.duration)
+			}
+		})
+	}
+}
+
+// 配置文件也可以用环境变量 TDMA_CONFIG 指定
+func TestApplyConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvName(FLAG_CONFIG), writeConfig(t, "satellite.conf", "slots = 7\n"))
+	f, err := newTestFlags()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := Apply(f.fs); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if *f.slots != 7 {
+		t.Fatalf("slots = %d, want 7", *f.slots)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name, file, want string
+	}{
+		{"unknown key", "slots = 8\ncolour = red\n", "未知的配置项"},
+		{"invalid value", "slots = 8\nslot-duration = soon\n", "第2行"},
+		{"config in file", "config = other.conf\n", "不能设置"},
+		{"missing equals", "slots 8\n", "格式应为"},
+		{"json object value", `{"slots": {"n": 8}}`, "只支持"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newTestFlags("-config", writeConfig(t, "satellite.conf", tt.file))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := NewLoader(f.fs).Apply(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Apply = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// 重新加载时配置文件中删除的配置项恢复默认值，命令行给出的选项保持不变
+func TestLoaderReload(t *testing.T) {
+	file := writeConfig(t, "satellite.conf", "id = SAT_FILE\nslots = 16\nslot-duration = 250ms\n")
+	f, err := newTestFlags("-config", file, "-id", "SAT_FLAG")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	loader := NewLoader(f.fs)
+	if err := loader.Apply(); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if err := os.WriteFile(file, []byte("slots = 32\n"), 0o644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if err := loader.Apply(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if *f.id != "SAT_FLAG" || *f.slots != 32 || *f.slotDuration != time.Second {
+		t.Fatalf("after reload id=%s slots=%d slot-duration=%v", *f.id, *f.slots, *f.slotDuration)
+	}
+}
+
+// ApplyTo只设置新的FlagSet，加载器的选项保持不变
+func TestLoaderApplyTo(t *testing.T) {
+	file := writeConfig(t, "satellite.conf", "id = SAT_FILE\nslots = 16\n")
+	f, err := newTestFlags("-config", file, "-slot-duration", "100ms")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	loader := NewLoader(f.fs)
+	if err := loader.Apply(); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if err := os.WriteFile(file, []byte("id = SAT_FILE\nslots = 32\n"), 0o644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	t.Setenv("TDMA_SLOTS", "64")
+
+	// 只定义关心的选项，配置文件中的其他配置项仍然有效
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	slots := fs.Int("slots", 10, "")
+	slotDuration := fs.Duration("slot-duration", time.Second, "")
+	if err := loader.ApplyTo(fs); err != nil {
+		t.Fatalf("ApplyTo: %v", err)
+	}
+	if *slots != 64 || *slotDuration != 100*time.Millisecond {
+		t.Fatalf("ApplyTo: slots=%d slot-duration=%v, want 64 100ms", *slots, *slotDuration)
+	}
+	if *f.slots != 16 || *f.id != "SAT_FILE" || *f.slotDuration != 100*time.Millisecond {
+		t.Fatalf("loader flags modified: id=%s slots=%d slot-duration=%v", *f.id, *f.slots, *f.slotDuration)
+	}
+
+	if err := os.WriteFile(file, []byte("colour = red\n"), 0o644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if err := loader.ApplyTo(flag.NewFlagSet("reload", flag.ContinueOnError)); err == nil {
+		t.Fatalf("ApplyTo accepted an unknown key")
+	}
+}
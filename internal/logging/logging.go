@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"tdma-network/pkg/protocol"
+)
+
+// 统一的日志字段名
+const (
+	KEY_COMPONENT = "pkg"
+	KEY_NODE_ID   = "node_id"
+	KEY_SLOT_ID   = "slot_id"
+	KEY_FRAME_ID  = "frame_id"
+	KEY_PEER      = "peer"
+	KEY_ABS_SLOT  = "abs_slot"
+)
+
+// 日志格式
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+var (
+	mu        sync.Mutex
+	root      atomic.Pointer[slog.Handler] // 实际输出的处理器，Setup前为标准错误上的文本格式
+	fallback  = slog.LevelInfo             // 未单独配置的组件使用的级别
+	overrides = map[string]slog.Level{}
+	levels    = map[string]*slog.LevelVar{} // 各组件当前级别，For创建后随Setup更新
+)
+
+func init() {
+	h := newRootHandler(os.Stderr, FORMAT_TEXT)
+	root.Store(&h)
+}
+
+// 创建输出处理器；级别过滤由组件处理器完成，这里放行所有级别
+func newRootHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if format == FORMAT_JSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// 配置日志级别与格式
+// level形如 "info,network=debug,isl=warn"：不带组件名的一项为默认级别；
+// 为空时读取环境变量 LOG_LEVEL，设置了 DEBUG（且不为0）时默认级别为debug。
+// format为 text 或 json，为空时读取 LOG_FORMAT，默认text。
+func Setup(level, format string) error {
+	if level == "" {
+		level = os.Getenv("LOG_LEVEL")
+	}
+	if format == "" {
+		format = os.Getenv("LOG_FORMAT")
+	}
+	if format == "" {
+		format = FORMAT_TEXT
+	}
+	if format != FORMAT_TEXT && format != FORMAT_JSON {
+		return fmt.Errorf("未知的日志格式: %s", format)
+	}
+
+	def := slog.LevelInfo
+	if d := os.Getenv("DEBUG"); d != "" && d != "0" {
+		def = slog.LevelDebug
+	}
+	def, perComponent, err := ParseLevels(level, def)
+	if err != nil {
+		return err
+	}
+
+	h := newRootHandler(os.Stderr, format)
+	root.Store(&h)
+
+	mu.Lock()
+	fallback = def
+	overrides = perComponent
+	for name, lv := range levels {
+		lv.Set(levelLocked(name))
+	}
+	mu.Unlock()
+
+	slog.SetDefault(For("main"))
+	return nil
+}
+
+// 解析级别配置，def为未指定默认级别时使用的级别
+func ParseLevels(spec string, def slog.Level) (slog.Level, map[string]slog.Level, error) {
+	perComponent := map[string]slog.Level{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			name, value = "", item
+		}
+		var lv slog.Level
+		if err := lv.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return 0, nil, fmt.Errorf("无效的日志级别 %q: %v", item, err)
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			def = lv
+		} else {
+			perComponent[name] = lv
+		}
+	}
+	return def, perComponent, nil
+}
+
+// 组件的配置级别（调用方需持有mu）
+func levelLocked(component string) slog.Level {
+	if lv, ok := overrides[component]; ok {
+		return lv
+	}
+	return fallback
+}
+
+// 获取组件的日志记录器，级别可通过Setup按组件名单独配置
+func For(component string) *slog.Logger {
+	mu.Lock()
+	lv, ok := levels[component]
+	if !ok {
+		lv = new(slog.LevelVar)
+		lv.Set(levelLocked(component))
+		levels[component] = lv
+	}
+	mu.Unlock()
+
+	h := &handler{level: lv}
+	return slog.New(h.WithAttrs([]slog.Attr{slog.String(KEY_COMPONENT, component)}))
+}
+
+// 调整组件的日志级别
+func SetLevel(component string, level slog.Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	overrides[component] = level
+	if lv, ok := levels[component]; ok {
+		lv.Set(level)
+	}
+}
+
+// 记录错误并退出
+func Fatal(l *slog.Logger, msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+// 帧的公共日志字段，args附加在其后
+func FrameArgs(f *protocol.TDMAFrame, args ...any) []any {
+	return append([]any{KEY_SLOT_ID, f.SlotID, KEY_ABS_SLOT, f.AbsSlot, KEY_FRAME_ID, f.FragmentID, "len", len(f.Data)}, args...)
+}
+
+// 按组件级别过滤的处理器
+// 输出处理器在Setup时可能被替换，属性与分组记录下来在输出时再应用
+type handler struct {
+	level *slog.LevelVar
+	ops   []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	out := *root.Load()
+	for _, op := range h.ops {
+		out = op(out)
+	}
+	return out.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{level: h.level, ops: append(ops, op)}
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 环形缓冲中的一条日志
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   string // 记录自身的字段，形如 "peer=GS1 err=..."
+}
+
+// 最近日志的环形缓冲，供终端界面显示
+type Ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// 创建容量为size的环形缓冲
+func NewRing(size int) *Ring {
+	return &Ring{entries: make([]Entry, size)}
+}
+
+// 返回包装next的处理器，min及以上级别的记录同时写入缓冲
+// 写入缓冲不受next级别的限制
+func (r *Ring) Wrap(next slog.Handler, min slog.Level) slog.Handler {
+	return &ringHandler{ring: r, next: next, min: min}
+}
+
+// 缓冲中的日志，按时间从旧到新
+func (r *Ring) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Entry(nil), r.entries[:r.next]...)
+	}
+	return append(append([]Entry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+func (r *Ring) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+type ringHandler struct {
+	ring *Ring
+	next slog.Handler
+	min  slog.Level
+}
+
+func (h *ringHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.min || h.next.Enabled(ctx, level)
+}
+
+func (h *ringHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level >= h.min {
+		var attrs []string
+		rec.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a.Key+"="+a.Value.String())
+			return true
+		})
+		h.ring.add(Entry{Time: rec.Time, Level: rec.Level, Message: rec.Message, Attrs: strings.Join(attrs, " ")})
+	}
+	if !h.next.Enabled(ctx, rec.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+// 通过With附加的公共字段（组件、节点ID）只传给next，不写入缓冲
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ringHandler{ring: h.ring, next: h.next.WithAttrs(attrs), min: h.min}
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	return &ringHandler{ring: h.ring, next: h.next.WithGroup(name), min: h.min}
+}
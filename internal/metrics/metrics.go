@@ -0,0 +1,355 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 指标类型
+const (
+	TYPE_COUNTER = "counter"
+	TYPE_GAUGE   = "gauge"
+)
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// 指标注册表，按Prometheus文本格式输出
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+	onScrape []func()
+}
+
+// 创建新的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// 同名的一组指标
+type family struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	fn     func() float64 // 非nil时为采集时计算的无标签指标
+
+	mu     sync.Mutex
+	series map[string]*value // 编码后的标签 -> 值
+}
+
+// 浮点值，支持并发更新
+type value struct {
+	labels []string
+	bits   atomic.Uint64
+}
+
+func (v *value) load() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) set(x float64) {
+	v.bits.Store(math.Float64bits(x))
+}
+
+// 注册指标族，名称非法或重复注册属于编程错误，直接panic
+func (r *Registry) register(name, help, typ string, labels []string, fn func() float64) *family {
+	if !metricNameRE.MatchString(name) {
+		panic(fmt.Sprintf("非法的指标名: %q", name))
+	}
+	for _, l := range labels {
+		if !labelNameRE.MatchString(l) || strings.HasPrefix(l, "__") {
+			panic(fmt.Sprintf("指标 %s 的标签名非法: %q", name, l))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.families[name]; ok {
+		panic(fmt.Sprintf("指标 %s 重复注册", name))
+	}
+	f := &family{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		fn:     fn,
+		series: make(map[string]*value),
+	}
+	r.families[name] = f
+	return f
+}
+
+// 获取标签值对应的序列，不存在时创建
+func (f *family) with(labelValues []string) *value {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("指标 %s 需要 %d 个标签值，实际 %d 个", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	v, ok := f.series[key]
+	if !ok {
+		v = &value{labels: append([]string(nil), labelValues...)}
+		f.series[key] = v
+	}
+	return v
+}
+
+// 计数器，只增不减
+type Counter struct{ v *value }
+
+// 加1
+func (c *Counter) Inc() { c.v.add(1) }
+
+// 增加delta，负数被忽略
+func (c *Counter) Add(delta float64) {
+	if delta > 0 {
+		c.v.add(delta)
+	}
+}
+
+// 当前值
+func (c *Counter) Value() float64 { return c.v.load() }
+
+// 带标签的计数器
+type CounterVec struct{ f *family }
+
+// 获取标签值对应的计数器
+func (c *CounterVec) With(labelValues ...string) *Counter {
+	return &Counter{v: c.f.with(labelValues)}
+}
+
+// 仪表，可任意设置
+type Gauge struct{ v *value }
+
+func (g *Gauge) Set(x float64)     { g.v.set(x) }
+func (g *Gauge) Add(delta float64) { g.v.add(delta) }
+func (g *Gauge) Inc()              { g.v.add(1) }
+func (g *Gauge) Dec()              { g.v.add(-1) }
+func (g *Gauge) Value() float64    { return g.v.load() }
+
+// 带标签的仪表
+type GaugeVec struct{ f *family }
+
+// 获取标签值对应的仪表
+func (g *GaugeVec) With(labelValues ...string) *Gauge {
+	return &Gauge{v: g.f.with(labelValues)}
+}
+
+// 注册无标签计数器
+func (r *Registry) Counter(name, help string) *Counter {
+	return &Counter{v: r.register(name, help, TYPE_COUNTER, nil, nil).with(nil)}
+}
+
+// 注册带标签的计数器
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: r.register(name, help, TYPE_COUNTER, labels, nil)}
+}
+
+// 注册无标签仪表
+func (r *Registry) Gauge(name, help string) *Gauge {
+	return &Gauge{v: r.register(name, help, TYPE_GAUGE, nil, nil).with(nil)}
+}
+
+// 注册带标签的仪表
+func (r *Registry) GaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: r.register(name, help, TYPE_GAUGE, labels, nil)}
+}
+
+// 注册在采集时计算的仪表
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, TYPE_GAUGE, nil, fn)
+}
+
+// 注册采集前执行的回调，用于按当前状态更新仪表
+func (r *Registry) OnScrape(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onScrape = append(r.onScrape, fn)
+}
+
+// 按Prometheus文本格式(0.0.4)输出全部指标，指标族与序列均按名称排序
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.collect() {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// 指标的一个序列
+type Sample struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// 获取全部指标序列，顺序与WriteText相同
+// 非有限值（NaN、Inf）无法用JSON表示，不包含在内
+func (r *Registry) Samples() []Sample {
+	var samples []Sample
+	for _, f := range r.collect() {
+		if f.fn != nil {
+			if v := f.fn(); !math.IsNaN(v) && !math.IsInf(v, 0) {
+				samples = append(samples, Sample{Name: f.name, Type: f.typ, Value: v})
+			}
+			continue
+		}
+		for _, s := range f.sortedSeries() {
+			v := s.load()
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			labels := make(map[string]string, len(f.labels))
+			for i, l := range f.labels {
+				labels[l] = s.labels[i]
+			}
+			samples = append(samples, Sample{Name: f.name, Type: f.typ, Labels: labels, Value: v})
+		}
+	}
+	return samples
+}
+
+// 执行采集钩子，返回按名称排序的指标族
+func (r *Registry) collect() []*family {
+	r.mu.Lock()
+	hooks := append([]func(){}, r.onScrape...)
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	return families
+}
+
+// 按标签值排序的序列
+func (f *family) sortedSeries() []*value {
+	f.mu.Lock()
+	series := make([]*value, 0, len(f.series))
+	for _, v := range f.series {
+		series = append(series, v)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(series, func(i, j int) bool {
+		a, b := series[i].labels, series[j].labels
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return series
+}
+
+// 输出一个指标族
+func (f *family) write(w *bufio.Writer) {
+	if f.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	if f.fn != nil {
+		fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.fn()))
+		return
+	}
+
+	for _, v := range f.sortedSeries() {
+		w.WriteString(f.name)
+		if len(f.labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range f.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, `%s="%s"`, l, escapeLabel(v.labels[i]))
+			}
+			w.WriteByte('}')
+		}
+		fmt.Fprintf(w, " %s\n", formatValue(v.load()))
+	}
+}
+
+// HELP中转义反斜杠与换行
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// 标签值中转义反斜杠、双引号与换行
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// 格式化数值
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// 文本格式的Content-Type
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// 返回输出指标的HTTP处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", CONTENT_TYPE)
+		r.WriteText(w)
+	})
+}
+
+// 在addr上启动HTTP服务，提供 /metrics
+func Serve(addr string, r *Registry) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("启动指标服务失败: %v", err)
+	}
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	return srv, nil
+}
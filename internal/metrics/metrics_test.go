@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	return sb.String()
+}
+
+func TestWriteTextFormat(t *testing.T) {
+	r := NewRegistry()
+	frames := r.CounterVec("tdma_frames_received_total", "Frames received per node.", "node_id")
+	frames.With("GS2").Add(3)
+	frames.With("GS1").Inc()
+	r.Counter("tdma_crc_failures_total", "Frames failing CRC.").Add(2)
+	r.Gauge("tdma_queue_depth", "Frames waiting in the outbox.").Set(7)
+	r.GaugeFunc("tdma_slot_utilisation", "Fraction of slots assigned.", func() float64 { return 0.25 })
+
+	want := `# HELP tdma_crc_failures_total Frames failing CRC.
+# TYPE tdma_crc_failures_total counter
+tdma_crc_failures_total 2
+# HELP tdma_frames_received_total Frames received per node.
+# TYPE tdma_frames_received_total counter
+tdma_frames_received_total{node_id="GS1"} 1
+tdma_frames_received_total{node_id="GS2"} 3
+# HELP tdma_queue_depth Frames waiting in the outbox.
+# TYPE tdma_queue_depth gauge
+tdma_queue_depth 7
+# HELP tdma_slot_utilisation Fraction of slots assigned.
+# TYPE tdma_slot_utilisation gauge
+tdma_slot_utilisation 0.25
+`
+	if got := render(t, r); got != want {
+		t.Errorf("exposition mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMultipleLabelsAndEscaping(t *testing.T) {
+	r := NewRegistry()
+	g := r.GaugeVec("tdma_sessions", "Sessions by state.\nSecond line with \\ backslash.", "state", "peer")
+	g.With("ACTIVE", `a"b\c`+"\nd").Set(1)
+
+	got := render(t, r)
+	for _, line := range []string{
+		`# HELP tdma_sessions Sessions by state.\nSecond line with \\ backslash.`,
+		`# TYPE tdma_sessions gauge`,
+		`tdma_sessions{state="ACTIVE",peer="a\"b\\c\nd"} 1`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestSpecialValues(t *testing.T) {
+	r := NewRegistry()
+	g := r.GaugeVec("v", "", "k")
+	g.With("inf").Set(math.Inf(1))
+	g.With("ninf").Set(math.Inf(-1))
+	g.With("nan").Set(math.NaN())
+	g.With("small").Set(1e-9)
+
+	want := `# TYPE v gauge
+v{k="inf"} +Inf
+v{k="nan"} NaN
+v{k="ninf"} -Inf
+v{k="small"} 1e-09
+`
+	if got := render(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	r := NewRegistry()
+	frames := r.CounterVec("frames_total", "", "node_id")
+	frames.With("GS2").Add(3)
+	frames.With("GS1").Inc()
+	r.GaugeFunc("util", "", func() float64 { return 0.5 })
+	r.GaugeVec("v", "", "k").With("nan").Set(math.NaN())
+
+	got := r.Samples()
+	want := []Sample{
+		{Name: "frames_total", Type: TYPE_COUNTER, Labels: map[string]string{"node_id": "GS1"}, Value: 1},
+		{Name: "frames_total", Type: TYPE_COUNTER, Labels: map[string]string{"node_id": "GS2"}, Value: 3},
+		{Name: "util", Type: TYPE_GAUGE, Value: 0.5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Type != w.Type || g.Value != w.Value || len(g.Labels) != len(w.Labels) || g.Labels["node_id"] != w.Labels["node_id"] {
+			t.Errorf("sample %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestCounterIgnoresNegative(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("c_total", "")
+	c.Add(5)
+	c.Add(-3)
+	if c.Value() != 5 {
+		t.Errorf("counter = %v, want 5", c.Value())
+	}
+}
+
+func TestOnScrape(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("peers", "")
+	n := 0
+	r.OnScrape(func() { n++; g.Set(float64(n)) })
+
+	render(t, r)
+	if got := render(t, r); !strings.Contains(got, "peers 2\n") {
+		t.Errorf("scrape hook not applied:\n%s", got)
+	}
+}
+
+func TestInvalidRegistrationPanics(t *testing.T) {
+	cases := map[string]func(r *Registry){
+		"bad metric name": func(r *Registry) { r.Counter("1bad", "") },
+		"bad label name":  func(r *Registry) { r.CounterVec("ok", "", "bad-label") },
+		"reserved label":  func(r *Registry) { r.CounterVec("ok", "", "__name") },
+		"duplicate": func(r *Registry) {
+			r.Counter("dup", "")
+			r.Gauge("dup", "")
+		},
+		"label count": func(r *Registry) { r.CounterVec("ok", "", "a").With("x", "y") },
+	}
+	for name, fn := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			fn(NewRegistry())
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("tdma_frames_sent_total", "Frames sent.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != CONTENT_TYPE {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "tdma_frames_sent_total 1\n") {
+		t.Errorf("body:\n%s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package network
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 指数退避（带随机抖动），可并发使用
+type Backoff struct {
+	Initial    time.Duration // 首次重试等待时间
+	Max        time.Duration // 等待时间上限（含抖动）
+	Multiplier float64       // 每次失败后的增长倍数
+	Jitter     float64       // 抖动比例，0.2表示±20%
+
+	mu      sync.Mutex
+	attempt int
+}
+
+// 创建默认参数的退避器
+func NewBackoff() *Backoff {
+	return &Backoff{
+		Initial:    500 * time.Millisecond,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// 获取下一次重试的等待时间
+func (b *Backoff) Next() time.Duration {
+	b.mu.Lock()
+	attempt := b.attempt
+	b.attempt++
+	b.mu.Unlock()
+
+	d := float64(b.Initial)
+	for i := 0; i < attempt; i++ {
+		d *= b.Multiplier
+		if d >= float64(b.Max) {
+			d = float64(b.Max)
+			break
+		}
+	}
+
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (rand.Float64()*2 - 1)
+	}
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	return time.Duration(d)
+}
+
+// 获取已重试次数
+func (b *Backoff) Attempts() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempt
+}
+
+// 连接成功后重置
+func (b *Backoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempt = 0
+}
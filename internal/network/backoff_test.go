@@ -0,0 +1,87 @@
+package network
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowthAndCap(t *testing.T) {
+	b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := b.Next(); got != w*time.Millisecond {
+			t.Fatalf("attempt %d: Next = %v, want %v", i, got, w*time.Millisecond)
+		}
+	}
+	if got := b.Attempts(); got != len(want) {
+		t.Fatalf("Attempts = %d, want %d", got, len(want))
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int // 之前已失败的次数
+		lo, hi   time.Duration
+	}{
+		// 基准100ms，±20%
+		{"first", 0, 80 * time.Millisecond, 120 * time.Millisecond},
+		{"third", 2, 320 * time.Millisecond, 480 * time.Millisecond},
+		// 基准达到上限，抖动后不超过上限
+		{"capped", 10, 800 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < 200; i++ {
+				b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: 0.2}
+				for j := 0; j < tt.attempts; j++ {
+					b.Next()
+				}
+				d := b.Next()
+				if d < tt.lo || d > tt.hi {
+					t.Fatalf("Next = %v, want within [%v, %v]", d, tt.lo, tt.hi)
+				}
+				seen[d] = true
+			}
+			if len(seen) < 2 {
+				t.Fatalf("jitter produced a single value %v", seen)
+			}
+		})
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	for i := 0; i < 5; i++ {
+		b.Next()
+	}
+	b.Reset()
+	if got := b.Attempts(); got != 0 {
+		t.Fatalf("Attempts after Reset = %d", got)
+	}
+	if got := b.Next(); got != 100*time.Millisecond {
+		t.Fatalf("Next after Reset = %v, want %v", got, 100*time.Millisecond)
+	}
+}
+
+// 重连循环与状态打印并发访问退避器（配合 -race 运行）
+func TestBackoffConcurrentUse(t *testing.T) {
+	b := NewBackoff()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Next()
+				b.Attempts()
+				if j%10 == 0 {
+					b.Reset()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
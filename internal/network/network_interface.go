@@ -5,9 +5,13 @@ import (
 	"net"
 	"sync"
 	"time"
+	"tdma-network/internal/channel"
+	"tdma-network/internal/logging"
 	"tdma-network/pkg/protocol"
 )
 
+var logger = logging.For("network")
+
 // 连接状态
 type ConnectionStatus struct {
 	Connected bool
@@ -23,6 +27,7 @@ type NetworkInterface struct {
 	mu           sync.RWMutex
 	timeout      time.Duration
 	fragmentTimeout time.Duration
+	channel      *channel.Channel // 发送方向的信道损伤，nil表示理想链路
 }
 
 // 创建新的网络接口
@@ -42,12 +47,15 @@ func (ni *NetworkInterface) Connect(target string) error {
 	if err != nil {
 		return fmt.Errorf("连接失败: %v", err)
 	}
+	if ni.channel != nil {
+		conn = channel.NewConn(conn, ni.channel)
+	}
 	
 	ni.conn = conn
 	ni.address = target
 	ni.connected = true
 	
-	fmt.Printf("已连接到 %s\n", target)
+	logger.Info("已连接", logging.KEY_PEER, target)
 	return nil
 }
 
@@ -62,7 +70,7 @@ func (ni *NetworkInterface) Disconnect() error {
 	}
 	
 	ni.connected = false
-	fmt.Printf("已断开连接\n")
+	logger.Info("已断开连接", logging.KEY_PEER, ni.address)
 	return nil
 }
 
@@ -87,7 +95,7 @@ func (ni *NetworkInterface) SendFrame(frame *protocol.TDMAFrame, target string)
 		return fmt.Errorf("发送失败: %v", err)
 	}
 	
-	fmt.Printf("发送帧: %s\n", frame.String())
+	logger.Debug("发送帧", logging.FrameArgs(frame, logging.KEY_NODE_ID, frame.GetNodeID(), logging.KEY_PEER, target)...)
 	return nil
 }
 
@@ -154,7 +162,7 @@ func (ni *NetworkInterface) ReceiveFrame() (*protocol.TDMAFrame, error) {
 		return nil, fmt.Errorf("帧验证失败: %v", err)
 	}
 	
-	fmt.Printf("接收帧: %s\n", frame.String())
+	logger.Debug("接收帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID())...)
 	return frame, nil
 }
 
@@ -179,6 +187,14 @@ func (ni *NetworkInterface) SetTimeout(timeout time.Duration) error {
 	return nil
 }
 
+// 设置发送方向的信道损伤，下次Connect时生效
+func (ni *NetworkInterface) SetChannel(ch *channel.Channel) {
+	ni.mu.Lock()
+	defer ni.mu.Unlock()
+	
+	ni.channel = ch
+}
+
 // 设置分片超时
 func (ni *NetworkInterface) SetFragmentTimeout(timeout time.Duration) error {
 	ni.mu.Lock()
@@ -0,0 +1,114 @@
+package orbit
+
+import (
+	"math"
+	"time"
+)
+
+// 物理常量
+const (
+	EARTH_RADIUS_KM = 6371.0       // 球形地球平均半径
+	EARTH_MU        = 398600.4418  // 地球引力常数 km^3/s^2
+	EARTH_OMEGA     = 7.2921159e-5 // 地球自转角速度 rad/s
+	LIGHT_SPEED_KM  = 299792.458   // 光速 km/s
+	deg             = math.Pi / 180
+)
+
+// 三维向量(km)
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func (a Vec3) Sub(b Vec3) Vec3 {
+	return Vec3{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+func (a Vec3) Dot(b Vec3) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func (a Vec3) Norm() float64 {
+	return math.Sqrt(a.Dot(a))
+}
+
+// 圆轨道参数
+// 升交点赤经在历元时刻相对格林尼治子午线度量
+type Orbit struct {
+	AltitudeKm     float64   `json:"altitude_km"`
+	InclinationDeg float64   `json:"inclination_deg"`
+	RAANDeg        float64   `json:"raan_deg"`
+	PhaseDeg       float64   `json:"phase_deg"` // 历元时刻的纬度幅角
+	Epoch          time.Time `json:"epoch"`
+}
+
+// 轨道周期
+func (o Orbit) Period() time.Duration {
+	r := EARTH_RADIUS_KM + o.AltitudeKm
+	return time.Duration(2 * math.Pi * math.Sqrt(r*r*r/EARTH_MU) * float64(time.Second))
+}
+
+// 计算t时刻卫星在地固坐标系中的位置
+func (o Orbit) PositionECEF(t time.Time) Vec3 {
+	r := EARTH_RADIUS_KM + o.AltitudeKm
+	n := math.Sqrt(EARTH_MU / (r * r * r))
+	dt := t.Sub(o.Epoch).Seconds()
+
+	u := o.PhaseDeg*deg + n*dt
+	inc := o.InclinationDeg * deg
+	raan := o.RAANDeg * deg
+
+	// 惯性系位置
+	x := r * (math.Cos(raan)*math.Cos(u) - math.Sin(raan)*math.Sin(u)*math.Cos(inc))
+	y := r * (math.Sin(raan)*math.Cos(u) + math.Cos(raan)*math.Sin(u)*math.Cos(inc))
+	z := r * math.Sin(u) * math.Sin(inc)
+
+	// 扣除地球自转
+	theta := EARTH_OMEGA * dt
+	return Vec3{
+		X: x*math.Cos(theta) + y*math.Sin(theta),
+		Y: -x*math.Sin(theta) + y*math.Cos(theta),
+		Z: z,
+	}
+}
+
+// 地面站位置
+type Station struct {
+	LatDeg          float64 `json:"lat_deg"`
+	LonDeg          float64 `json:"lon_deg"`
+	AltM            float64 `json:"alt_m"`
+	MinElevationDeg float64 `json:"min_elevation_deg"`
+}
+
+// 地面站地固坐标
+func (s Station) ECEF() Vec3 {
+	r := EARTH_RADIUS_KM + s.AltM/1000
+	lat, lon := s.LatDeg*deg, s.LonDeg*deg
+	return Vec3{
+		X: r * math.Cos(lat) * math.Cos(lon),
+		Y: r * math.Cos(lat) * math.Sin(lon),
+		Z: r * math.Sin(lat),
+	}
+}
+
+// 站星几何关系
+type LookAngle struct {
+	ElevationDeg float64
+	RangeKm      float64
+	RangeRate    float64 // 距离变化率 km/s，正值表示远离
+}
+
+// 计算t时刻地面站对卫星的仰角、斜距与距离变化率
+func Look(o Orbit, s Station, t time.Time) LookAngle {
+	gs := s.ECEF()
+	d := o.PositionECEF(t).Sub(gs)
+	rng := d.Norm()
+
+	// 以1秒差分估计距离变化率
+	next := o.PositionECEF(t.Add(time.Second)).Sub(gs).Norm()
+
+	return LookAngle{
+		ElevationDeg: math.Asin(d.Dot(gs)/(rng*gs.Norm())) / deg,
+		RangeKm:      rng,
+		RangeRate:    next - rng,
+	}
+}
@@ -0,0 +1,77 @@
+package orbit
+
+import (
+	"fmt"
+	"time"
+)
+
+// 站星链路，基于几何关系计算传播时延与多普勒频移
+type Link struct {
+	Orbit     Orbit
+	Station   Station
+	CarrierHz float64 // 载波频率，用于计算多普勒频移
+}
+
+// 获取卫星与地面站之间的链路，任一方未配置轨道/位置时返回false
+func (m *Model) Link(satID, nodeID string, carrierHz float64) (Link, bool) {
+	if m == nil {
+		return Link{}, false
+	}
+	o, ok := m.Satellites[satID]
+	if !ok {
+		return Link{}, false
+	}
+	st, ok := m.Stations[nodeID]
+	if !ok {
+		return Link{}, false
+	}
+	return Link{Orbit: o, Station: st, CarrierHz: carrierHz}, true
+}
+
+// t时刻的单向传播时延
+func (l Link) Delay(t time.Time) time.Duration {
+	rng := Look(l.Orbit, l.Station, t).RangeKm
+	return time.Duration(rng / LIGHT_SPEED_KM * float64(time.Second))
+}
+
+// t时刻的多普勒频移(Hz)，卫星接近时为正
+func (l Link) Doppler(t time.Time) float64 {
+	rate := Look(l.Orbit, l.Station, t).RangeRate
+	return -rate / LIGHT_SPEED_KM * l.CarrierHz
+}
+
+// 时延曲线上的一点
+type CurvePoint struct {
+	Time         time.Time
+	ElevationDeg float64
+	RangeKm      float64
+	Delay        time.Duration
+	DopplerHz    float64
+}
+
+// 以step为步长计算一次过境内的时延曲线
+func (l Link) Curve(p Pass, step time.Duration) []CurvePoint {
+	var points []CurvePoint
+	for t := p.AOS; !t.After(p.LOS); t = t.Add(step) {
+		la := Look(l.Orbit, l.Station, t)
+		points = append(points, CurvePoint{
+			Time:         t,
+			ElevationDeg: la.ElevationDeg,
+			RangeKm:      la.RangeKm,
+			Delay:        time.Duration(la.RangeKm / LIGHT_SPEED_KM * float64(time.Second)),
+			DopplerHz:    -la.RangeRate / LIGHT_SPEED_KM * l.CarrierHz,
+		})
+	}
+	return points
+}
+
+// 打印时延曲线
+func PrintCurve(points []CurvePoint) {
+	fmt.Printf("=== 过境时延曲线 ===\n")
+	fmt.Printf("%-10s %8s %10s %12s %12s\n", "时间", "仰角(°)", "斜距(km)", "时延", "多普勒(Hz)")
+	for _, p := range points {
+		fmt.Printf("%-10s %8.2f %10.1f %12v %12.1f\n",
+			p.Time.Format("15:04:05"), p.ElevationDeg, p.RangeKm, p.Delay.Round(time.Microsecond), p.DopplerHz)
+	}
+	fmt.Printf("==================\n")
+}
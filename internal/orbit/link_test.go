@@ -0,0 +1,138 @@
+package orbit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const testCarrierHz = 2.2e9 // S波段
+
+// 仰角el处的斜距（球形地球）
+func slantRange(altKm, elDeg float64) float64 {
+	r := EARTH_RADIUS_KM + altKm
+	e := elDeg * deg
+	return math.Sqrt(r*r-math.Pow(EARTH_RADIUS_KM*math.Cos(e), 2)) - EARTH_RADIUS_KM*math.Sin(e)
+}
+
+func TestLinkDelayFromRange(t *testing.T) {
+	l := Link{Orbit: testOrbit, Station: testStation, CarrierHz: testCarrierHz}
+	tests := []struct {
+		name string
+		at   time.Duration // 相对过顶时刻
+	}{
+		{"overhead", 0},
+		{"one minute before", -time.Minute},
+		{"three minutes after", 3 * time.Minute},
+		{"low elevation", -4 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := testEpoch.Add(tt.at)
+			la := Look(testOrbit, testStation, at)
+			// 斜距与仰角满足球面几何关系
+			if want := slantRange(testOrbit.AltitudeKm, la.ElevationDeg); math.Abs(la.RangeKm-want) > 0.5 {
+				t.Fatalf("range %.2fkm at elevation %.2f°, want %.2fkm", la.RangeKm, la.ElevationDeg, want)
+			}
+			// 时延 = 斜距 / 光速
+			want := time.Duration(la.RangeKm / LIGHT_SPEED_KM * float64(time.Second))
+			if got := l.Delay(at); got != want {
+				t.Fatalf("Delay = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// 过顶时斜距等于轨道高度，时延最小
+	overhead := l.Delay(testEpoch)
+	if want := time.Duration(testOrbit.AltitudeKm / LIGHT_SPEED_KM * float64(time.Second)); (overhead - want).Abs() > time.Microsecond {
+		t.Fatalf("overhead delay %v, want %v", overhead, want)
+	}
+	if l.Delay(testEpoch.Add(-2*time.Minute)) <= overhead || l.Delay(testEpoch.Add(2*time.Minute)) <= overhead {
+		t.Fatalf("delay away from zenith should exceed the overhead delay %v", overhead)
+	}
+}
+
+func TestLinkDopplerSign(t *testing.T) {
+	l := Link{Orbit: testOrbit, Station: testStation, CarrierHz: testCarrierHz}
+	// 多普勒频移上限 v/c·f，v为轨道速度
+	v := math.Sqrt(EARTH_MU / (EARTH_RADIUS_KM + testOrbit.AltitudeKm))
+	limit := v / LIGHT_SPEED_KM * testCarrierHz
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		sign int // 1 接近（正频移），-1 远离，0 过顶附近
+	}{
+		{"approaching", -3 * time.Minute, 1},
+		{"just before zenith", -30 * time.Second, 1},
+		{"zenith", 0, 0},
+		{"just after zenith", 30 * time.Second, -1},
+		{"receding", 3 * time.Minute, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := testEpoch.Add(tt.at)
+			la := Look(testOrbit, testStation, at)
+			hz := l.Doppler(at)
+			if math.Abs(hz) > limit {
+				t.Fatalf("Doppler %.0fHz exceeds v/c·f = %.0fHz", hz, limit)
+			}
+			// 距离缩短（变化率为负）时频移为正
+			if want := -la.RangeRate / LIGHT_SPEED_KM * testCarrierHz; hz != want {
+				t.Fatalf("Doppler = %.1fHz, want %.1fHz", hz, want)
+			}
+			switch {
+			case tt.sign > 0 && (hz <= 0 || la.RangeRate >= 0):
+				t.Fatalf("approaching: Doppler %.1fHz, range rate %.3fkm/s", hz, la.RangeRate)
+			case tt.sign < 0 && (hz >= 0 || la.RangeRate <= 0):
+				t.Fatalf("receding: Doppler %.1fHz, range rate %.3fkm/s", hz, la.RangeRate)
+			case tt.sign == 0 && math.Abs(hz) > limit/10:
+				t.Fatalf("zenith: Doppler %.1fHz, want near 0", hz)
+			}
+		})
+	}
+}
+
+// 一次过境的曲线：时延先减后增，多普勒由正变负且只过零一次
+func TestLinkCurve(t *testing.T) {
+	m := testModel()
+	p, ok := m.NextPass("SAT1", "GS1", testEpoch, time.Hour, time.Second)
+	if !ok {
+		t.Fatalf("no pass")
+	}
+	l, ok := m.Link("SAT1", "GS1", testCarrierHz)
+	if !ok {
+		t.Fatalf("Link not found")
+	}
+	if _, ok := m.Link("SAT1", "GS9", testCarrierHz); ok {
+		t.Fatalf("Link found for a station without a position")
+	}
+
+	points := l.Curve(p, 10*time.Second)
+	if len(points) < 2 || !points[0].Time.Equal(p.AOS) || points[len(points)-1].Time.After(p.LOS) {
+		t.Fatalf("curve covers %d points from %v", len(points), points[0].Time)
+	}
+	crossings := 0
+	for i, pt := range points {
+		if pt.Delay != l.Delay(pt.Time) || pt.DopplerHz != l.Doppler(pt.Time) {
+			t.Fatalf("point %d disagrees with Delay/Doppler", i)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := points[i-1]
+		if pt.DopplerHz > prev.DopplerHz {
+			t.Fatalf("Doppler increased at %v: %.1f -> %.1f", pt.Time, prev.DopplerHz, pt.DopplerHz)
+		}
+		if (prev.DopplerHz > 0) != (pt.DopplerHz > 0) {
+			crossings++
+			// 过零时时延最小
+			if pt.Delay > points[0].Delay || prev.Delay > points[len(points)-1].Delay {
+				t.Fatalf("Doppler crosses zero away from minimum range")
+			}
+		}
+	}
+	if crossings != 1 || points[0].DopplerHz <= 0 || points[len(points)-1].DopplerHz >= 0 {
+		t.Fatalf("Doppler crosses zero %d times, from %.1f to %.1f", crossings, points[0].DopplerHz, points[len(points)-1].DopplerHz)
+	}
+}
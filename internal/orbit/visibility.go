@@ -0,0 +1,133 @@
+package orbit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// 过境窗口
+type Pass struct {
+	AOS time.Time `json:"aos"` // 入境（信号获取）
+	LOS time.Time `json:"los"` // 出境（信号丢失）
+}
+
+// 是否处于窗口内
+func (p Pass) Contains(t time.Time) bool {
+	return !t.Before(p.AOS) && t.Before(p.LOS)
+}
+
+// 可见性模型
+// 对每个(卫星, 地面站)组合，优先使用导入的过境计划，其次使用轨道预报；
+// 两者都未配置的组合视为始终可见
+//
+//	{
+//	  "satellites": {"SATELLITE_001": {"altitude_km": 550, "inclination_deg": 53, "epoch": "2024-01-01T00:00:00Z"}},
+//	  "stations":   {"GS1": {"lat_deg": 39.9, "lon_deg": 116.4, "min_elevation_deg": 10}},
+//	  "passes":     {"SATELLITE_001": {"GS2": [{"aos": "2024-01-01T00:10:00Z", "los": "2024-01-01T00:20:00Z"}]}}
+//	}
+type Model struct {
+	Satellites map[string]Orbit             `json:"satellites"`
+	Stations   map[string]Station           `json:"stations"`
+	Passes     map[string]map[string][]Pass `json:"passes"`
+}
+
+// 加载可见性配置文件
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取可见性配置失败: %v", err)
+	}
+
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析可见性配置失败: %v", err)
+	}
+
+	for satID, o := range m.Satellites {
+		if o.AltitudeKm <= 0 {
+			return nil, fmt.Errorf("卫星 %s 轨道高度无效: %v", satID, o.AltitudeKm)
+		}
+	}
+	for satID, stations := range m.Passes {
+		for nodeID, passes := range stations {
+			for i, p := range passes {
+				if !p.LOS.After(p.AOS) {
+					return nil, fmt.Errorf("卫星 %s 地面站 %s 第 %d 个过境窗口无效", satID, nodeID, i)
+				}
+			}
+			sort.Slice(passes, func(i, j int) bool { return passes[i].AOS.Before(passes[j].AOS) })
+		}
+	}
+	return &m, nil
+}
+
+// 是否为该组合配置了可见性约束
+func (m *Model) Constrained(satID, nodeID string) bool {
+	if m == nil {
+		return false
+	}
+	if _, ok := m.Passes[satID][nodeID]; ok {
+		return true
+	}
+	_, hasOrbit := m.Satellites[satID]
+	_, hasStation := m.Stations[nodeID]
+	return hasOrbit && hasStation
+}
+
+// 判断t时刻地面站是否可见卫星
+func (m *Model) Visible(satID, nodeID string, t time.Time) bool {
+	if !m.Constrained(satID, nodeID) {
+		return true
+	}
+	if passes, ok := m.Passes[satID][nodeID]; ok {
+		for _, p := range passes {
+			if p.Contains(t) {
+				return true
+			}
+		}
+		return false
+	}
+	st := m.Stations[nodeID]
+	return Look(m.Satellites[satID], st, t).ElevationDeg >= st.MinElevationDeg
+}
+
+// 查找从t开始horizon时长内的当前或下一个过境窗口
+// 轨道预报以step为步长搜索，窗口边界精度为step
+func (m *Model) NextPass(satID, nodeID string, t time.Time, horizon, step time.Duration) (Pass, bool) {
+	if !m.Constrained(satID, nodeID) {
+		return Pass{}, false
+	}
+	if passes, ok := m.Passes[satID][nodeID]; ok {
+		for _, p := range passes {
+			if p.LOS.After(t) && p.AOS.Before(t.Add(horizon)) {
+				return p, true
+			}
+		}
+		return Pass{}, false
+	}
+
+	end := t.Add(horizon)
+	cur := t
+	for cur.Before(end) && !m.Visible(satID, nodeID, cur) {
+		cur = cur.Add(step)
+	}
+	if !cur.Before(end) {
+		return Pass{}, false
+	}
+
+	// 当前已在窗口内时向前回溯AOS
+	aos := cur
+	if cur.Equal(t) {
+		for m.Visible(satID, nodeID, aos.Add(-step)) && t.Sub(aos) < horizon {
+			aos = aos.Add(-step)
+		}
+	}
+	los := cur
+	for los.Before(end) && m.Visible(satID, nodeID, los) {
+		los = los.Add(step)
+	}
+	return Pass{AOS: aos, LOS: los}, true
+}
@@ -0,0 +1,174 @@
+package orbit
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// 历元时刻位于(0°, 0°)正上方的赤道圆轨道与该处的地面站
+var (
+	testOrbit   = Orbit{AltitudeKm: 550, Epoch: testEpoch}
+	testStation = Station{MinElevationDeg: 10}
+)
+
+func testModel() *Model {
+	return &Model{
+		Satellites: map[string]Orbit{"SAT1": testOrbit},
+		Stations:   map[string]Station{"GS1": testStation, "GS3": {LatDeg: 60, MinElevationDeg: 10}},
+		Passes: map[string]map[string][]Pass{"SAT1": {"GS2": {
+			{AOS: testEpoch.Add(10 * time.Minute), LOS: testEpoch.Add(20 * time.Minute)},
+			{AOS: testEpoch.Add(100 * time.Minute), LOS: testEpoch.Add(110 * time.Minute)},
+		}}},
+	}
+}
+
+func TestVisible(t *testing.T) {
+	m := testModel()
+	tests := []struct {
+		name        string
+		sat, node   string
+		t           time.Time
+		constrained bool
+		want        bool
+	}{
+		{"unconstrained station", "SAT1", "GS9", testEpoch, false, true},
+		{"unknown satellite", "SAT9", "GS1", testEpoch, false, true},
+		{"overhead", "SAT1", "GS1", testEpoch, true, true},
+		{"below horizon", "SAT1", "GS1", testEpoch.Add(testOrbit.Period() / 2), true, false},
+		// 赤道轨道最高只能到约纬度20°处的地平线
+		{"out of reach", "SAT1", "GS3", testEpoch, true, false},
+		{"before imported pass", "SAT1", "GS2", testEpoch.Add(10*time.Minute - time.Nanosecond), true, false},
+		{"at AOS", "SAT1", "GS2", testEpoch.Add(10 * time.Minute), true, true},
+		{"at LOS", "SAT1", "GS2", testEpoch.Add(20 * time.Minute), true, false},
+		{"second pass", "SAT1", "GS2", testEpoch.Add(105 * time.Minute), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Constrained(tt.sat, tt.node); got != tt.constrained {
+				t.Fatalf("Constrained = %v, want %v", got, tt.constrained)
+			}
+			if got := m.Visible(tt.sat, tt.node, tt.t); got != tt.want {
+				t.Fatalf("Visible = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var nilModel *Model
+	if !nilModel.Visible("SAT1", "GS1", testEpoch) {
+		t.Fatalf("nil model should treat every station as visible")
+	}
+}
+
+func TestNextPassImported(t *testing.T) {
+	m := testModel()
+	first, second := m.Passes["SAT1"]["GS2"][0], m.Passes["SAT1"]["GS2"][1]
+	tests := []struct {
+		name    string
+		from    time.Duration
+		horizon time.Duration
+		want    Pass
+		ok      bool
+	}{
+		{"before first", 0, time.Hour, first, true},
+		{"inside first", 15 * time.Minute, time.Hour, first, true},
+		{"between passes", 30 * time.Minute, 2 * time.Hour, second, true},
+		{"horizon too short", 30 * time.Minute, time.Hour, Pass{}, false},
+		{"after all passes", 3 * time.Hour, time.Hour, Pass{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.NextPass("SAT1", "GS2", testEpoch.Add(tt.from), tt.horizon, time.Second)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("NextPass = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// 按轨道预报搜索的窗口边界与仰角门限一致，相邻两次过境相隔一个会合周期
+func TestNextPassPredicted(t *testing.T) {
+	m := testModel()
+	step := time.Second
+
+	p, ok := m.NextPass("SAT1", "GS1", testEpoch, 3*time.Hour, step)
+	if !ok || !p.Contains(testEpoch) {
+		t.Fatalf("NextPass at epoch = %v, %v, want a pass containing the epoch", p, ok)
+	}
+	checkBoundary := func(p Pass) {
+		t.Helper()
+		if !m.Visible("SAT1", "GS1", p.AOS) || m.Visible("SAT1", "GS1", p.AOS.Add(-step)) {
+			t.Fatalf("AOS %v is not the start of visibility", p.AOS)
+		}
+		if m.Visible("SAT1", "GS1", p.LOS) || !m.Visible("SAT1", "GS1", p.LOS.Add(-step)) {
+			t.Fatalf("LOS %v is not the end of visibility", p.LOS)
+		}
+		for _, at := range []time.Time{p.AOS, p.LOS.Add(-step)} {
+			if el := Look(testOrbit, testStation, at).ElevationDeg; el < 10 || el > 10.2 {
+				t.Fatalf("elevation at pass edge %v = %.3f°, want just above 10°", at, el)
+			}
+		}
+	}
+	checkBoundary(p)
+	// 550km轨道在10°仰角门限下过顶过境约8分钟
+	if d := p.LOS.Sub(p.AOS); d < 6*time.Minute || d > 10*time.Minute {
+		t.Fatalf("pass duration %v, want about 8 minutes", d)
+	}
+
+	next, ok := m.NextPass("SAT1", "GS1", p.LOS, 3*time.Hour, step)
+	if !ok || !next.AOS.After(p.LOS) {
+		t.Fatalf("next pass = %v, %v", next, ok)
+	}
+	checkBoundary(next)
+	// 顺行赤道轨道相对地面站的会合周期 2π/(n-ωE)
+	period := testOrbit.Period().Seconds()
+	synodic := time.Duration(1 / (1/period - EARTH_OMEGA/(2*math.Pi)) * float64(time.Second))
+	if d := next.AOS.Sub(p.AOS) - synodic; d < -2*step || d > 2*step {
+		t.Fatalf("passes %v apart, want synodic period %v", next.AOS.Sub(p.AOS), synodic)
+	}
+
+	if _, ok := m.NextPass("SAT1", "GS3", testEpoch, 3*time.Hour, time.Minute); ok {
+		t.Fatalf("station out of reach has a pass")
+	}
+	if _, ok := m.NextPass("SAT1", "GS9", testEpoch, time.Hour, time.Minute); ok {
+		t.Fatalf("unconstrained station has a pass")
+	}
+}
+
+func TestLoadModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"valid", `{"satellites": {"SAT1": {"altitude_km": 550}},
+			"passes": {"SAT1": {"GS2": [{"aos": "2024-01-01T02:00:00Z", "los": "2024-01-01T02:10:00Z"},
+			                            {"aos": "2024-01-01T01:00:00Z", "los": "2024-01-01T01:10:00Z"}]}}}`, true},
+		{"invalid json", `{"satellites": `, false},
+		{"zero altitude", `{"satellites": {"SAT1": {"altitude_km": 0}}}`, false},
+		{"empty pass", `{"passes": {"SAT1": {"GS2": [{"aos": "2024-01-01T01:00:00Z", "los": "2024-01-01T01:00:00Z"}]}}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "visibility.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("写入配置失败: %v", err)
+			}
+			m, err := LoadModel(file)
+			if (err == nil) != tt.ok {
+				t.Fatalf("LoadModel = %v, want ok=%v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			// 过境窗口按AOS排序
+			if passes := m.Passes["SAT1"]["GS2"]; !passes[0].AOS.Before(passes[1].AOS) {
+				t.Fatalf("passes not sorted: %v", passes)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"sync"
+	"tdma-network/internal/clock"
+	"tdma-network/internal/logging"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 回放日志记录类型
+const (
+	ENTRY_START     = "start"     // 卫星节点启动，NodeID为卫星ID，Time为调度器启动时刻，附带影响调度的配置
+	ENTRY_RECV      = "recv"      // 地面站连接上收到的帧
+	ENTRY_SEND      = "send"      // 发往地面站连接的响应帧，NodeID为目的节点
+	ENTRY_CLOSE     = "close"     // 地面站连接断开
+	ENTRY_ISL       = "isl"       // 星间链路收到的帧，NodeID为邻居卫星
+	ENTRY_ISL_DOWN  = "isl_down"  // 星间链路中断
+	ENTRY_SCHEDULE  = "schedule"  // 调度表变化后的快照
+	ENTRY_VIOLATION = "violation" // 认证阶段发现的安全违规（帧本身未记录），NodeID为违规节点，Kind为违规类型
+	ENTRY_LAYOUT    = "layout"    // 计划帧结构变更，At为生效时刻，Slots与SlotDuration为新帧结构
+)
+
+// 回放日志中的一条记录，日志每行为一条记录的JSON
+type Entry struct {
+	Time     time.Time      `json:"time"`
+	Type     string         `json:"type"`
+	Conn     uint32         `json:"conn,omitempty"`   // 连接编号，按首次收到帧的顺序从1开始
+	Remote   string         `json:"remote,omitempty"` // 连接对端地址
+	NodeID   string         `json:"node_id,omitempty"`
+	Frame    []byte         `json:"frame,omitempty"` // 序列化后的完整帧
+	Schedule map[int]string `json:"schedule,omitempty"`
+	Kind     string         `json:"kind,omitempty"`
+
+	// 启动与帧结构变更记录中的配置，早期的日志中为0
+	At            *time.Time    `json:"at,omitempty"`
+	Slots         int           `json:"slots,omitempty"`
+	SlotDuration  time.Duration `json:"slot_duration,omitempty"`
+	ResumeTimeout time.Duration `json:"resume_timeout,omitempty"`
+}
+
+var logger = logging.For("replay")
+
+// 回放日志记录器，nil表示不记录
+type Recorder struct {
+	mu       sync.Mutex
+	clock    clock.Clock // 记录时刻取自节点的时钟，虚拟时间下运行时与回放的时间轴一致
+	w        io.Writer
+	closer   io.Closer
+	failed   bool
+	nextConn uint32
+	conns    map[string]uint32 // 本端地址|对端地址 -> 连接编号
+	schedule map[int]string    // 最近记录的调度表
+}
+
+// 创建回放日志文件，记录时刻取自clk
+func Create(path string, clk clock.Clock) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建回放日志失败: %v", err)
+	}
+	r := NewRecorder(f, clk)
+	r.closer = f
+	return r, nil
+}
+
+// 创建写入w的记录器，记录时刻取自clk
+func NewRecorder(w io.Writer, clk clock.Clock) *Recorder {
+	return &Recorder{clock: clk, w: w, conns: make(map[string]uint32)}
+}
+
+// 记录卫星节点启动，at为调度器的启动时刻，回放时按记录的时隙配置与会话保留时长重建节点
+func (r *Recorder) Start(nodeID string, at time.Time, slots int, slotDuration, resumeTimeout time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: at, Type: ENTRY_START, NodeID: nodeID, Slots: slots, SlotDuration: slotDuration, ResumeTimeout: resumeTimeout})
+}
+
+// 记录地面站连接上收到的一帧
+func (r *Recorder) Recv(conn net.Conn, frame *protocol.TDMAFrame) {
+	if r == nil {
+		return
+	}
+	raw, err := frame.Serialize()
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_RECV, Conn: r.connID(conn, true), Remote: conn.RemoteAddr().String(), Frame: raw})
+}
+
+// 记录发往nodeID的响应帧
+func (r *Recorder) Send(conn net.Conn, nodeID string, frame *protocol.TDMAFrame) {
+	if r == nil {
+		return
+	}
+	raw, err := frame.Serialize()
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_SEND, Conn: r.connID(conn, true), NodeID: nodeID, Frame: raw})
+}
+
+// 记录连接断开，没有收发过帧的连接不记录
+func (r *Recorder) Closed(conn net.Conn) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.connID(conn, false)
+	if id == 0 {
+		return
+	}
+	delete(r.conns, connKey(conn))
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_CLOSE, Conn: id})
+}
+
+// 记录星间链路上收到的一帧
+func (r *Recorder) ISL(peerID string, frame *protocol.TDMAFrame) {
+	if r == nil {
+		return
+	}
+	raw, err := frame.Serialize()
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_ISL, NodeID: peerID, Frame: raw})
+}
+
+// 记录星间链路中断
+func (r *Recorder) ISLDown(peerID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_ISL_DOWN, NodeID: peerID})
+}
+
+// 记录地面站连接上nodeID的一次安全违规
+func (r *Recorder) Violation(conn net.Conn, nodeID, kind string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_VIOLATION, Conn: r.connID(conn, true), Remote: conn.RemoteAddr().String(), NodeID: nodeID, Kind: kind})
+}
+
+// 记录计划的帧结构变更，变更生效后的调度表由快照记录
+func (r *Recorder) Layout(at time.Time, slots int, slotDuration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_LAYOUT, At: &at, Slots: slots, SlotDuration: slotDuration})
+}
+
+// 调度表与上次记录不同时记录快照
+func (r *Recorder) Schedule(schedule map[int]string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.schedule != nil && maps.Equal(r.schedule, schedule) {
+		return
+	}
+	r.schedule = maps.Clone(schedule)
+	r.write(Entry{Time: r.clock.Now(), Type: ENTRY_SCHEDULE, Schedule: schedule})
+}
+
+// 关闭回放日志
+func (r *Recorder) Close() error {
+	if r == nil || r.closer == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = true
+	return r.closer.Close()
+}
+
+// 连接编号，create为false时未知连接返回0（调用方需持有mu）
+func (r *Recorder) connID(conn net.Conn, create bool) uint32 {
+	key := connKey(conn)
+	id, ok := r.conns[key]
+	if !ok && create {
+		r.nextConn++
+		id = r.nextConn
+		r.conns[key] = id
+	}
+	return id
+}
+
+// 按地址区分连接，同一TCP连接经过信道或加锁包装后仍为同一连接
+func connKey(conn net.Conn) string {
+	return conn.LocalAddr().String() + "|" + conn.RemoteAddr().String()
+}
+
+// 每条记录单独写入，进程异常退出时日志只丢失最后一条；写入失败后停止记录（调用方需持有mu）
+func (r *Recorder) write(e Entry) {
+	if r.failed {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		r.failed = true
+		logger.Warn("写入回放日志失败，停止记录", "err", err)
+	}
+}
+
+// 读取回放日志
+// 最后一行不完整（记录时进程异常退出）时忽略该行
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开回放日志失败: %v", err)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// 从r读取回放日志
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取回放日志失败: %v", err)
+		}
+		eof := err == io.EOF
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var e Entry
+			if err := json.Unmarshal(line, &e); err != nil {
+				if eof {
+					logger.Warn("忽略回放日志末尾不完整的记录", "line", lineNo)
+					break
+				}
+				return nil, fmt.Errorf("回放日志第 %d 行格式错误: %v", lineNo, err)
+			}
+			entries = append(entries, e)
+		}
+		if eof {
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,287 @@
+package routing
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 链路状态通告：卫星的星间邻居与当前接入的地面站
+type LSA struct {
+	Origin    string
+	Seq       uint64
+	Neighbors map[string]int // 邻居卫星 -> 链路代价
+	Stations  []string
+}
+
+// 编码为 "origin;seq;邻居=代价,...;地面站,..."
+func (l LSA) Encode() string {
+	var neighbors []string
+	for n, cost := range l.Neighbors {
+		neighbors = append(neighbors, fmt.Sprintf("%s=%d", n, cost))
+	}
+	sort.Strings(neighbors)
+	return fmt.Sprintf("%s;%d;%s;%s", l.Origin, l.Seq, strings.Join(neighbors, ","), strings.Join(l.Stations, ","))
+}
+
+// 解码链路状态通告
+func DecodeLSA(s string) (LSA, error) {
+	parts := strings.Split(s, ";")
+	if len(parts) != 4 {
+		return LSA{}, fmt.Errorf("无效的链路状态通告: %s", s)
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return LSA{}, fmt.Errorf("无效的序号: %v", err)
+	}
+
+	l := LSA{Origin: parts[0], Seq: seq, Neighbors: make(map[string]int)}
+	if parts[2] != "" {
+		for _, item := range strings.Split(parts[2], ",") {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				return LSA{}, fmt.Errorf("无效的邻居项: %s", item)
+			}
+			cost, err := strconv.Atoi(kv[1])
+			if err != nil || cost <= 0 {
+				return LSA{}, fmt.Errorf("无效的链路代价: %s", item)
+			}
+			l.Neighbors[kv[0]] = cost
+		}
+	}
+	if parts[3] != "" {
+		l.Stations = strings.Split(parts[3], ",")
+	}
+	return l, nil
+}
+
+// 路由表项
+type Route struct {
+	Satellite string // 目的地面站接入的卫星
+	NextHop   string // 下一跳卫星，目的卫星为本星时为空
+	Cost      int
+}
+
+// 链路状态路由表
+type Table struct {
+	mu     sync.RWMutex
+	self   string
+	seq    uint64
+	lsdb   map[string]LSA
+	recvAt map[string]time.Time
+	routes map[string]Route  // 目的卫星 -> 路由
+	onSat  map[string]string // 地面站 -> 接入卫星
+}
+
+// 创建路由表
+func NewTable(self string) *Table {
+	t := &Table{
+		self:   self,
+		seq:    uint64(time.Now().UnixNano()), // 重启后的通告序号大于重启前的
+		lsdb:   make(map[string]LSA),
+		recvAt: make(map[string]time.Time),
+	}
+	t.recompute()
+	return t
+}
+
+// 更新本星的链路状态，返回新的通告
+func (t *Table) SetLocal(neighbors map[string]int, stations []string) LSA {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	sorted := append([]string(nil), stations...)
+	sort.Strings(sorted)
+	l := LSA{Origin: t.self, Seq: t.seq, Neighbors: neighbors, Stations: sorted}
+	t.lsdb[t.self] = l
+	t.recvAt[t.self] = time.Now()
+	t.recompute()
+	return l
+}
+
+// 获取本星当前的链路状态通告
+func (t *Table) Local() LSA {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.lsdb[t.self]
+}
+
+// 获取链路状态数据库中的全部通告，用于与新邻居同步
+func (t *Table) All() []LSA {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	all := make([]LSA, 0, len(t.lsdb))
+	for _, l := range t.lsdb {
+		all = append(all, l)
+	}
+	return all
+}
+
+// 接收其他卫星的通告，序号更新时返回true（需要继续泛洪）
+func (t *Table) Update(l LSA) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if l.Origin == t.self {
+		return false
+	}
+	if old, ok := t.lsdb[l.Origin]; ok && l.Seq <= old.Seq {
+		return false
+	}
+	t.lsdb[l.Origin] = l
+	t.recvAt[l.Origin] = time.Now()
+	t.recompute()
+	return true
+}
+
+// 删除超过maxAge未刷新的通告，返回被删除的卫星
+func (t *Table) Expire(maxAge time.Duration) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var expired []string
+	for origin, at := range t.recvAt {
+		if origin != t.self && time.Since(at) > maxAge {
+			expired = append(expired, origin)
+			delete(t.lsdb, origin)
+			delete(t.recvAt, origin)
+		}
+	}
+	if len(expired) > 0 {
+		t.recompute()
+	}
+	return expired
+}
+
+// 查找到达地面站的路由
+func (t *Table) Lookup(station string) (Route, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	sat, ok := t.onSat[station]
+	if !ok {
+		return Route{}, false
+	}
+	r, ok := t.routes[sat]
+	return r, ok
+}
+
+// 地面站接入的卫星是否可经邻居neighbor到达（不经过本星），用于校验邻居转发来的数据的源节点
+// 与路由计算相同，只使用双向链路；不要求经neighbor的路径是最短路径
+func (t *Table) ReachableVia(station, neighbor string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	seen := map[string]bool{t.self: true, neighbor: true}
+	queue := []string{neighbor}
+	for len(queue) > 0 {
+		sat := queue[0]
+		queue = queue[1:]
+		if slices.Contains(t.lsdb[sat].Stations, station) {
+			return true
+		}
+		for n := range t.lsdb[sat].Neighbors {
+			if _, ok := t.lsdb[n].Neighbors[sat]; !ok || seen[n] {
+				continue
+			}
+			seen[n] = true
+			queue = append(queue, n)
+		}
+	}
+	return false
+}
+
+// 获取到各卫星的路由
+func (t *Table) Routes() map[string]Route {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	routes := make(map[string]Route, len(t.routes))
+	for k, v := range t.routes {
+		routes[k] = v
+	}
+	return routes
+}
+
+// 获取地面站接入关系
+func (t *Table) Stations() map[string]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	stations := make(map[string]string, len(t.onSat))
+	for k, v := range t.onSat {
+		stations[k] = v
+	}
+	return stations
+}
+
+// 基于链路状态数据库以Dijkstra算法重新计算路由（调用方需持有锁）
+// 只使用双方通告中都包含对方的双向链路
+func (t *Table) recompute() {
+	dist := map[string]int{t.self: 0}
+	first := map[string]string{t.self: ""}
+	pq := &distQueue{{node: t.self}}
+
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(distItem)
+		if cur.dist > dist[cur.node] {
+			continue
+		}
+		for n, cost := range t.lsdb[cur.node].Neighbors {
+			if _, ok := t.lsdb[n].Neighbors[cur.node]; !ok {
+				continue
+			}
+			nd := cur.dist + cost
+			if d, ok := dist[n]; ok && d <= nd {
+				continue
+			}
+			dist[n] = nd
+			if cur.node == t.self {
+				first[n] = n
+			} else {
+				first[n] = first[cur.node]
+			}
+			heap.Push(pq, distItem{node: n, dist: nd})
+		}
+	}
+
+	t.routes = make(map[string]Route, len(dist))
+	for sat, d := range dist {
+		t.routes[sat] = Route{Satellite: sat, NextHop: first[sat], Cost: d}
+	}
+
+	// 地面站可能短暂出现在多颗卫星的通告中（切换期间），取代价最小者
+	t.onSat = make(map[string]string)
+	for sat := range dist {
+		for _, st := range t.lsdb[sat].Stations {
+			if cur, ok := t.onSat[st]; !ok || dist[sat] < dist[cur] {
+				t.onSat[st] = sat
+			}
+		}
+	}
+}
+
+type distItem struct {
+	node string
+	dist int
+}
+
+type distQueue []distItem
+
+func (q distQueue) Len() int           { return len(q) }
+func (q distQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q distQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *distQueue) Push(x any)        { *q = append(*q, x.(distItem)) }
+func (q *distQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
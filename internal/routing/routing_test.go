@@ -0,0 +1,205 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLSAEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		lsa  LSA
+		enc  string
+	}{
+		{"full", LSA{Origin: "SAT_A", Seq: 7, Neighbors: map[string]int{"SAT_C": 2, "SAT_B": 1}, Stations: []string{"GS1", "GS2"}},
+			"SAT_A;7;SAT_B=1,SAT_C=2;GS1,GS2"},
+		{"no neighbors", LSA{Origin: "SAT_A", Seq: 1, Neighbors: map[string]int{}, Stations: []string{"GS1"}}, "SAT_A;1;;GS1"},
+		{"empty", LSA{Origin: "SAT_A", Seq: 2, Neighbors: map[string]int{}}, "SAT_A;2;;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lsa.Encode(); got != tt.enc {
+				t.Fatalf("Encode = %q, want %q", got, tt.enc)
+			}
+			got, err := DecodeLSA(tt.enc)
+			if err != nil {
+				t.Fatalf("DecodeLSA: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.lsa) {
+				t.Fatalf("DecodeLSA = %+v, want %+v", got, tt.lsa)
+			}
+		})
+	}
+}
+
+func TestDecodeLSAErrors(t *testing.T) {
+	for _, s := range []string{
+		"SAT_A;1;",           // 字段数不对
+		"SAT_A;x;;",          // 序号无效
+		"SAT_A;1;SAT_B;",     // 邻居项缺少代价
+		"SAT_A;1;SAT_B=0;",   // 代价须为正
+		"SAT_A;1;SAT_B=-1;",  // 代价须为正
+		"SAT_A;1;SAT_B=abc;", // 代价不是整数
+	} {
+		if _, err := DecodeLSA(s); err == nil {
+			t.Errorf("DecodeLSA(%q) accepted", s)
+		}
+	}
+}
+
+// 链路 A-B-C 与 A-D-C，B路径代价更低；D只单向通告与C的链路
+func testTable(t *testing.T) *Table {
+	t.Helper()
+	tbl := NewTable("SAT_A")
+	tbl.SetLocal(map[string]int{"SAT_B": 1, "SAT_D": 1}, []string{"GS1"})
+	for _, l := range []LSA{
+		{Origin: "SAT_B", Seq: 1, Neighbors: map[string]int{"SAT_A": 1, "SAT_C": 1}, Stations: []string{"GS2"}},
+		{Origin: "SAT_C", Seq: 1, Neighbors: map[string]int{"SAT_B": 1}, Stations: []string{"GS3"}},
+		{Origin: "SAT_D", Seq: 1, Neighbors: map[string]int{"SAT_A": 1, "SAT_C": 1}, Stations: []string{"GS4"}},
+	} {
+		if !tbl.Update(l) {
+			t.Fatalf("Update(%s) = false", l.Origin)
+		}
+	}
+	return tbl
+}
+
+func TestLookup(t *testing.T) {
+	tbl := testTable(t)
+	tests := []struct {
+		station string
+		want    Route
+		ok      bool
+	}{
+		{"GS1", Route{Satellite: "SAT_A", NextHop: "", Cost: 0}, true},
+		{"GS2", Route{Satellite: "SAT_B", NextHop: "SAT_B", Cost: 1}, true},
+		{"GS3", Route{Satellite: "SAT_C", NextHop: "SAT_B", Cost: 2}, true},
+		{"GS4", Route{Satellite: "SAT_D", NextHop: "SAT_D", Cost: 1}, true},
+		{"GS9", Route{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.station, func(t *testing.T) {
+			got, ok := tbl.Lookup(tt.station)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("Lookup = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// 只使用双方都通告了的链路：C未通告与D的链路，经D到C不可用
+func TestOneWayLinkIgnored(t *testing.T) {
+	tbl := testTable(t)
+	// B失去与C的链路，C只剩与D的单向链路，不可达
+	if !tbl.Update(LSA{Origin: "SAT_B", Seq: 2, Neighbors: map[string]int{"SAT_A": 1}, Stations: []string{"GS2"}}) {
+		t.Fatalf("Update rejected a newer LSA")
+	}
+	if r, ok := tbl.Lookup("GS3"); ok {
+		t.Fatalf("GS3 reachable via %+v over a one-way link", r)
+	}
+	// C通告与D的链路后经D可达
+	tbl.Update(LSA{Origin: "SAT_C", Seq: 2, Neighbors: map[string]int{"SAT_D": 1}, Stations: []string{"GS3"}})
+	if r, ok := tbl.Lookup("GS3"); !ok || r.NextHop != "SAT_D" || r.Cost != 2 {
+		t.Fatalf("Lookup(GS3) = %+v, %v, want via SAT_D", r, ok)
+	}
+}
+
+// 经邻居转发来的数据，源地面站须接入经该邻居可达的卫星
+func TestReachableVia(t *testing.T) {
+	tbl := testTable(t)
+	tests := []struct {
+		station, neighbor string
+		want              bool
+	}{
+		{"GS2", "SAT_B", true},
+		{"GS3", "SAT_B", true},
+		{"GS3", "SAT_D", false}, // C未通告与D的链路
+		{"GS4", "SAT_D", true},
+		{"GS4", "SAT_B", false}, // B到D须经过本星
+		{"GS1", "SAT_B", false}, // 接入本星的地面站
+		{"GS9", "SAT_B", false},
+	}
+	for _, tt := range tests {
+		if got := tbl.ReachableVia(tt.station, tt.neighbor); got != tt.want {
+			t.Errorf("ReachableVia(%s, %s) = %v, want %v", tt.station, tt.neighbor, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateSequence(t *testing.T) {
+	tbl := testTable(t)
+	tests := []struct {
+		name string
+		lsa  LSA
+		want bool
+	}{
+		{"same sequence", LSA{Origin: "SAT_B", Seq: 1, Neighbors: map[string]int{"SAT_A": 1}}, false},
+		{"older sequence", LSA{Origin: "SAT_B", Seq: 0, Neighbors: map[string]int{"SAT_A": 1}}, false},
+		{"own origin", LSA{Origin: "SAT_A", Seq: 1 << 62, Neighbors: map[string]int{}}, false},
+		{"newer sequence", LSA{Origin: "SAT_B", Seq: 5, Neighbors: map[string]int{"SAT_A": 1, "SAT_C": 1}, Stations: []string{"GS5"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tbl.Update(tt.lsa); got != tt.want {
+				t.Fatalf("Update = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// 旧通告未改变路由表，新通告生效
+	if r, ok := tbl.Lookup("GS5"); !ok || r.Satellite != "SAT_B" {
+		t.Fatalf("Lookup(GS5) = %+v, %v", r, ok)
+	}
+	if _, ok := tbl.Lookup("GS2"); ok {
+		t.Fatalf("GS2 still routed after SAT_B withdrew it")
+	}
+	if l := tbl.Local(); l.Origin != "SAT_A" || l.Neighbors["SAT_B"] != 1 {
+		t.Fatalf("own LSA overwritten: %+v", l)
+	}
+}
+
+// 地面站同时出现在两颗卫星的通告中时取代价较小者
+func TestStationOnTwoSatellites(t *testing.T) {
+	tbl := testTable(t)
+	tbl.Update(LSA{Origin: "SAT_C", Seq: 2, Neighbors: map[string]int{"SAT_B": 1}, Stations: []string{"GS3", "GS2"}})
+	if sat := tbl.Stations()["GS2"]; sat != "SAT_B" {
+		t.Fatalf("GS2 on %s, want the nearer SAT_B", sat)
+	}
+}
+
+func TestSetLocalSequence(t *testing.T) {
+	tbl := NewTable("SAT_A")
+	first := tbl.SetLocal(map[string]int{"SAT_B": 1}, []string{"GS2", "GS1"})
+	second := tbl.SetLocal(map[string]int{"SAT_B": 1}, nil)
+	if second.Seq <= first.Seq {
+		t.Fatalf("sequence did not increase: %d -> %d", first.Seq, second.Seq)
+	}
+	if !reflect.DeepEqual(first.Stations, []string{"GS1", "GS2"}) {
+		t.Fatalf("stations not sorted: %v", first.Stations)
+	}
+	// 重启后的序号大于重启前的，邻居据此接受新的通告
+	if restarted := NewTable("SAT_A").SetLocal(nil, nil); restarted.Seq <= second.Seq {
+		t.Fatalf("sequence after restart %d <= %d", restarted.Seq, second.Seq)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	tbl := testTable(t)
+	if expired := tbl.Expire(time.Hour); len(expired) != 0 {
+		t.Fatalf("Expire(1h) = %v", expired)
+	}
+	time.Sleep(time.Millisecond)
+	expired := tbl.Expire(0)
+	if len(expired) != 3 {
+		t.Fatalf("Expire(0) = %v, want the three remote LSAs", expired)
+	}
+	if _, ok := tbl.Lookup("GS2"); ok {
+		t.Fatalf("route to GS2 survived expiry")
+	}
+	if r, ok := tbl.Lookup("GS1"); !ok || r.Satellite != "SAT_A" {
+		t.Fatalf("own LSA expired: %+v, %v", r, ok)
+	}
+	if len(tbl.All()) != 1 {
+		t.Fatalf("LSDB has %d entries after expiry", len(tbl.All()))
+	}
+}
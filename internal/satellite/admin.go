@@ -0,0 +1,498 @@
+package satellite
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/scheduler"
+	"tdma-network/internal/session"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 管理接口请求体的最大长度
+const adminMaxBody = 64 << 10
+
+// 管理接口，查询类接口无需认证，修改类接口需要Bearer令牌
+type adminServer struct {
+	sn    *SatelliteNode
+	token string // 为空时禁用修改类接口
+}
+
+// 在addr上启动管理接口
+func (sn *SatelliteNode) serveAdmin(addr, token string) (*http.Server, error) {
+	a := &adminServer{sn: sn, token: token}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("启动管理接口失败: %v", err)
+	}
+	srv := &http.Server{Handler: a.handler(), ReadHeaderTimeout: 5 * time.Second}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+// 管理接口的路由
+func (a *adminServer) handler() http.Handler {
+	sn := a.sn
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", a.get(a.status))
+	mux.HandleFunc("/api/schedule", a.get(a.schedule))
+	mux.HandleFunc("/api/sessions", a.get(a.sessions))
+	mux.HandleFunc("/api/metrics", a.get(a.metrics))
+	mux.HandleFunc("/api/security", a.get(a.security))
+	mux.Handle("/metrics", sn.metrics.registry.Handler())
+	mux.HandleFunc("/api/slots/allocate", a.post(a.allocate))
+	mux.HandleFunc("/api/slots/release", a.post(a.release))
+	mux.HandleFunc("/api/nodes/priority", a.post(a.priority))
+	mux.HandleFunc("/api/nodes/kick", a.post(a.kick))
+	mux.HandleFunc("/api/security/release", a.post(a.unquarantine))
+	mux.HandleFunc("/api/reload", a.post(a.reload))
+	return mux
+}
+
+// 接口错误，带HTTP状态码
+type apiError struct {
+	code int
+	msg  string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &apiError{code: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...any) error {
+	return &apiError{code: http.StatusNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
+// 查询接口
+func (a *adminServer) get(h func() (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, &apiError{code: http.StatusMethodNotAllowed, msg: "只支持GET"})
+			return
+		}
+		v, err := h()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+// 修改接口，校验令牌并解析JSON请求体
+func (a *adminServer) post(h func(body []byte) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, &apiError{code: http.StatusMethodNotAllowed, msg: "只支持POST"})
+			return
+		}
+		if err := a.authorize(r); err != nil {
+			a.sn.log.Warn("管理接口拒绝请求", "path", r.URL.Path, "remote", r.RemoteAddr, "err", err)
+			if e, ok := err.(*apiError); ok && e.code == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="tdma-admin"`)
+			}
+			writeError(w, err)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, adminMaxBody))
+		if err != nil {
+			writeError(w, badRequest("读取请求失败: %v", err))
+			return
+		}
+
+		v, err := h(body)
+		if err != nil {
+			a.sn.log.Warn("管理操作失败", "path", r.URL.Path, "remote", r.RemoteAddr, "err", err)
+			writeError(w, err)
+			return
+		}
+		a.sn.log.Info("管理操作", "path", r.URL.Path, "remote", r.RemoteAddr)
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+// 校验Authorization: Bearer令牌
+func (a *adminServer) authorize(r *http.Request) error {
+	if a.token == "" {
+		return &apiError{code: http.StatusForbidden, msg: "未配置管理令牌，修改类接口已禁用"}
+	}
+	auth := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return &apiError{code: http.StatusUnauthorized, msg: "缺少Bearer令牌"}
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.token)) != 1 {
+		return &apiError{code: http.StatusUnauthorized, msg: "令牌无效"}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if e, ok := err.(*apiError); ok {
+		code = e.code
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// 解析请求体，拒绝未知字段
+func decodeBody(body []byte, v any) error {
+	if len(body) == 0 {
+		return badRequest("请求体为空")
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("无效的请求: %v", err)
+	}
+	return nil
+}
+
+// 节点状态
+type statusResponse struct {
+	NodeID       string    `json:"node_id"`
+	Running      bool      `json:"running"`
+	CurrentSlot  int       `json:"current_slot"`
+	GlobalSlot   int64     `json:"global_slot"` // 绝对时隙号
+	Superframe   int64     `json:"superframe"`
+	SlotStart    time.Time `json:"slot_start"`
+	SlotEnd      time.Time `json:"slot_end"`
+	TotalSlots   int       `json:"total_slots"`
+	SlotDuration string    `json:"slot_duration"`
+	Assigned     int       `json:"assigned"`
+	Utilisation  float64   `json:"utilisation"`
+	Sessions     int       `json:"sessions"`
+	ISLPeers     int       `json:"isl_peers"`
+	Routes       int       `json:"routes"`
+
+	PendingLayout *layoutChangeResponse `json:"pending_layout,omitempty"`
+}
+
+// 尚未生效的帧结构变更
+type layoutChangeResponse struct {
+	At           time.Time   `json:"at"`
+	TotalSlots   int         `json:"total_slots"`
+	SlotDuration string      `json:"slot_duration"`
+	Remap        map[int]int `json:"remap,omitempty"` // 已分配时隙 -> 新时隙，-1表示将失去时隙
+}
+
+func newLayoutChange(ch scheduler.Change) *layoutChangeResponse {
+	return &layoutChangeResponse{
+		At:           ch.Layout.Epoch,
+		TotalSlots:   ch.Layout.TotalSlots,
+		SlotDuration: ch.Layout.SlotDuration.String(),
+		Remap:        ch.Remap,
+	}
+}
+
+func (a *adminServer) status() (any, error) {
+	sn := a.sn
+	total := sn.scheduler.TotalSlots()
+	assigned := len(sn.scheduler.GetSchedule())
+	current := sn.scheduler.Current()
+	var pending *layoutChangeResponse
+	if ch, ok := sn.scheduler.PendingChange(); ok {
+		pending = newLayoutChange(ch)
+	}
+	return statusResponse{
+		NodeID:       sn.nodeID,
+		Running:      sn.running,
+		CurrentSlot:  current.Slot,
+		GlobalSlot:   current.Abs,
+		Superframe:   current.Superframe,
+		SlotStart:    current.Start,
+		SlotEnd:      current.End,
+		TotalSlots:   total,
+		SlotDuration: sn.scheduler.SlotDuration().String(),
+		Assigned:     assigned,
+		Utilisation:  float64(assigned) / float64(total),
+		Sessions:     len(sn.sessions.List()),
+		ISLPeers:     len(sn.peerList()),
+		Routes:       len(sn.routes.Routes()),
+
+		PendingLayout: pending,
+	}, nil
+}
+
+// 调度表中的一个时隙
+type slotResponse struct {
+	SlotID    int        `json:"slot_id"`
+	Status    string     `json:"status"`
+	NodeID    string     `json:"node_id,omitempty"`
+	Priority  *int       `json:"priority,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+}
+
+func (a *adminServer) schedule() (any, error) {
+	priorities := a.sn.scheduler.GetPriorities()
+	slots := a.sn.scheduler.GetSlots()
+	resp := make([]slotResponse, 0, len(slots))
+	for _, s := range slots {
+		r := slotResponse{SlotID: s.SlotID, Status: s.Status, NodeID: s.NodeID}
+		if s.NodeID != "" {
+			if p, ok := priorities[s.NodeID]; ok {
+				r.Priority = &p
+			}
+			start := s.StartTime
+			r.StartTime = &start
+		}
+		resp = append(resp, r)
+	}
+	return resp, nil
+}
+
+// 地面站会话
+type sessionResponse struct {
+	NodeID        string    `json:"node_id"`
+	SlotID        int       `json:"slot_id"`
+	State         string    `json:"state"`
+	RemoteAddr    string    `json:"remote_addr"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastSeen      time.Time `json:"last_seen"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Heartbeats    int64     `json:"heartbeats"`
+}
+
+func (a *adminServer) sessions() (any, error) {
+	list := a.sn.sessions.List()
+	resp := make([]sessionResponse, 0, len(list))
+	for _, s := range list {
+		resp = append(resp, sessionResponse{
+			NodeID:        s.NodeID,
+			SlotID:        s.SlotID,
+			State:         s.State,
+			RemoteAddr:    s.RemoteAddr,
+			CreatedAt:     s.CreatedAt,
+			LastSeen:      s.LastSeen,
+			LastHeartbeat: s.LastHeartbeat,
+			Heartbeats:    s.Heartbeats,
+		})
+	}
+	return resp, nil
+}
+
+func (a *adminServer) metrics() (any, error) {
+	return a.sn.metrics.registry.Samples(), nil
+}
+
+// 强制分配时隙，省略slot_id时按正常流程为节点分配
+func (a *adminServer) allocate(body []byte) (any, error) {
+	var req struct {
+		SlotID *int   `json:"slot_id"`
+		NodeID string `json:"node_id"`
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+	if req.NodeID == "" {
+		return nil, badRequest("缺少node_id")
+	}
+
+	var slotID int
+	var err error
+	if req.SlotID == nil {
+		slotID, err = a.sn.allocateForNode(req.NodeID)
+		a.sn.recordSchedule()
+		if err == nil {
+			a.sn.notifySlot(req.NodeID)
+		}
+	} else {
+		slotID = *req.SlotID
+		err = a.sn.assignSlot(slotID, req.NodeID)
+	}
+	if errors.Is(err, scheduler.ErrSlotTaken) {
+		return nil, &apiError{code: http.StatusConflict, msg: err.Error()}
+	}
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return map[string]any{"slot_id": slotID, "node_id": req.NodeID}, nil
+}
+
+// 释放时隙
+func (a *adminServer) release(body []byte) (any, error) {
+	var req struct {
+		SlotID *int `json:"slot_id"`
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+	if req.SlotID == nil {
+		return nil, badRequest("缺少slot_id")
+	}
+	nodeID, err := a.sn.releaseSlot(*req.SlotID)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return map[string]any{"slot_id": *req.SlotID, "node_id": nodeID}, nil
+}
+
+// 修改节点优先级
+func (a *adminServer) priority(body []byte) (any, error) {
+	var req struct {
+		NodeID   string `json:"node_id"`
+		Priority *int   `json:"priority"`
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Priority == nil {
+		return nil, badRequest("缺少priority")
+	}
+	if err := a.sn.scheduler.UpdatePriority(req.NodeID, *req.Priority); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return map[string]any{"node_id": req.NodeID, "priority": *req.Priority}, nil
+}
+
+// 踢出节点
+func (a *adminServer) kick(body []byte) (any, error) {
+	var req struct {
+		NodeID string `json:"node_id"`
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+	if req.NodeID == "" {
+		return nil, badRequest("缺少node_id")
+	}
+	if !a.sn.kickNode(req.NodeID) {
+		return nil, notFound("节点 %s 没有会话或连接", req.NodeID)
+	}
+	return map[string]any{"node_id": req.NodeID}, nil
+}
+
+// 有违规记录的节点及其隔离状态
+func (a *adminServer) security() (any, error) {
+	return a.sn.guard.List(), nil
+}
+
+// 提前解除节点的隔离
+func (a *adminServer) unquarantine(body []byte) (any, error) {
+	var req struct {
+		NodeID string `json:"node_id"`
+	}
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+	if req.NodeID == "" {
+		return nil, badRequest("缺少node_id")
+	}
+	if !a.sn.guard.Release(req.NodeID) {
+		return nil, notFound("节点 %s 未被隔离", req.NodeID)
+	}
+	a.sn.log.Info("解除隔离", logging.KEY_PEER, req.NodeID)
+	return map[string]any{"node_id": req.NodeID}, nil
+}
+
+// 重新加载配置文件
+func (a *adminServer) reload(body []byte) (any, error) {
+	ch, err := a.sn.reloadConfig()
+	if err != nil {
+		return nil, &apiError{code: http.StatusUnprocessableEntity, msg: err.Error()}
+	}
+	resp := map[string]any{"channel": a.sn.channelFile, "orbit": a.sn.orbitFile, "acl": a.sn.aclFile}
+	if ch != nil {
+		resp["layout"] = newLayoutChange(*ch)
+	}
+	return resp, nil
+}
+
+// 将空闲时隙强制分配给节点，节点原先持有的时隙随之释放，节点已有会话时以该时隙续约
+func (sn *SatelliteNode) assignSlot(slotID int, nodeID string) error {
+	released, err := sn.scheduler.AssignTimeSlot(slotID, nodeID)
+	if err != nil {
+		return err
+	}
+	for _, old := range released {
+		sn.slotLost(nodeID, old)
+	}
+	sn.sessions.SetSlot(nodeID, slotID)
+	sn.log.Info("强制分配时隙", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID, "released", released)
+	sn.recordSchedule()
+	sn.notifySlot(nodeID)
+	return nil
+}
+
+// 释放时隙，返回原归属节点
+// 节点的会话仍然有效，地面站收到通知后按仍持有的时隙发送，没有时隙时重新入网
+func (sn *SatelliteNode) releaseSlot(slotID int) (string, error) {
+	nodeID := sn.scheduler.GetSchedule()[slotID]
+	if err := sn.scheduler.ReleaseTimeSlot(slotID); err != nil {
+		return "", err
+	}
+	sn.slotLost(nodeID, slotID)
+	sn.log.Info("释放时隙", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	sn.recordSchedule()
+	sn.notifySlot(nodeID)
+	return nodeID, nil
+}
+
+// 通知接入本星的地面站时隙变更：仍持有时隙时发送时隙确认，
+// 否则发送 HEARTBEAT_REJECT，地面站据此重新入网
+func (sn *SatelliteNode) notifySlot(nodeID string) {
+	if nodeID == "" {
+		return
+	}
+	conn, ok := sn.stationConn(nodeID)
+	if !ok {
+		return
+	}
+	held := -1
+	for slotID, owner := range sn.scheduler.GetSchedule() {
+		if owner == nodeID && (held < 0 || slotID < held) {
+			held = slotID
+		}
+	}
+	if held < 0 {
+		sn.reply(conn, nodeID, 0, protocol.MSG_HEARTBEAT_REJECT)
+		return
+	}
+	sn.reply(conn, nodeID, held, fmt.Sprintf("%s%d", protocol.MSG_ACK_SLOT, held))
+}
+
+// 踢出节点：删除会话、释放时隙并断开其连接，返回节点是否存在
+// 节点之后可以重新入网
+func (sn *SatelliteNode) kickNode(nodeID string) bool {
+	slotID := -1
+	sess, hasSession := sn.sessions.Remove(nodeID)
+	if hasSession {
+		slotID = sess.SlotID
+	}
+	sn.auth.Remove(nodeID)
+	sn.releaseNodeSlots(nodeID)
+	conn, connected := sn.stationConn(nodeID)
+	if connected {
+		conn.Close()
+	}
+	if !hasSession && !connected {
+		return false
+	}
+
+	sn.log.Info("踢出节点", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	sn.sessions.Publish(session.Event{Type: session.EVENT_KICKED, NodeID: nodeID, SlotID: slotID, Time: sn.clock.Now()})
+	sn.recordSchedule()
+	return true
+}
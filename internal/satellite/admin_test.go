@@ -0,0 +1,214 @@
+package satellite
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"tdma-network/pkg/protocol"
+	"testing"
+)
+
+const testAdminToken = "s3cret"
+
+// 以令牌token启动管理接口
+func newTestAdmin(t *testing.T, sn *SatelliteNode, token string) *httptest.Server {
+	t.Helper()
+	a := &adminServer{sn: sn, token: token}
+	srv := httptest.NewServer(a.handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// 发送请求，auth为空时不带Authorization头，返回状态码与解码后的响应
+func adminRequest(t *testing.T, srv *httptest.Server, method, path, auth, body string) (int, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	var v map[string]any
+	json.NewDecoder(resp.Body).Decode(&v)
+	return resp.StatusCode, v
+}
+
+func TestAdminAuthorize(t *testing.T) {
+	const body = `{"node_id":"GS1"}`
+	tests := []struct {
+		name  string
+		token string // 卫星配置的令牌
+		auth  string // 请求的Authorization头
+		want  int
+	}{
+		{"missing token", testAdminToken, "", http.StatusUnauthorized},
+		{"wrong scheme", testAdminToken, "Basic " + testAdminToken, http.StatusUnauthorized},
+		{"wrong token", testAdminToken, "Bearer nope", http.StatusUnauthorized},
+		{"correct token", testAdminToken, "Bearer " + testAdminToken, http.StatusOK},
+		{"no token configured", "", "Bearer " + testAdminToken, http.StatusForbidden},
+		{"no token configured without header", "", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn, _ := newTestSatellite(t)
+			srv := newTestAdmin(t, sn, tt.token)
+
+			code, resp := adminRequest(t, srv, http.MethodPost, "/api/slots/allocate", tt.auth, body)
+			if code != tt.want {
+				t.Fatalf("status = %d (%v), want %d", code, resp, tt.want)
+			}
+			allocated := false
+			for _, owner := range sn.scheduler.GetSchedule() {
+				allocated = allocated || owner == "GS1"
+			}
+			if allocated != (tt.want == http.StatusOK) {
+				t.Fatalf("allocated = %v after status %d", allocated, code)
+			}
+		})
+	}
+}
+
+func TestAdminMutatingEndpointsForbiddenWithoutToken(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	srv := newTestAdmin(t, sn, "")
+
+	for _, path := range []string{
+		"/api/slots/allocate",
+		"/api/slots/release",
+		"/api/nodes/priority",
+		"/api/nodes/kick",
+		"/api/security/release",
+		"/api/reload",
+	} {
+		if code, resp := adminRequest(t, srv, http.MethodPost, path, "Bearer "+testAdminToken, `{}`); code != http.StatusForbidden {
+			t.Errorf("POST %s: status = %d (%v), want 403", path, code, resp)
+		}
+	}
+}
+
+func TestAdminReadOnlyEndpointsWithoutToken(t *testing.T) {
+	for _, token := range []string{"", testAdminToken} {
+		sn, _ := newTestSatellite(t)
+		srv := newTestAdmin(t, sn, token)
+
+		for _, path := range []string{"/api/status", "/api/schedule", "/api/sessions", "/api/metrics", "/api/security", "/metrics"} {
+			resp, err := srv.Client().Get(srv.URL + path)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("token %q: GET %s: status = %d, want 200", token, path, resp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestAdminAssignSlot(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	srv := newTestAdmin(t, sn, testAdminToken)
+	bearer := "Bearer " + testAdminToken
+
+	conn := newTestConn("GS1")
+	old := joinStation(t, sn, conn, "GS1")
+	target := (old + 1) % sn.scheduler.TotalSlots()
+
+	// 改派到空闲时隙，原时隙随之释放
+	body := `{"slot_id":` + strconv.Itoa(target) + `,"node_id":"GS1"}`
+	if code, resp := adminRequest(t, srv, http.MethodPost, "/api/slots/allocate", bearer, body); code != http.StatusOK {
+		t.Fatalf("allocate: status = %d (%v)", code, resp)
+	}
+	schedule := sn.scheduler.GetSchedule()
+	if owner, ok := schedule[old]; ok {
+		t.Fatalf("old slot %d still held by %s", old, owner)
+	}
+	if schedule[target] != "GS1" {
+		t.Fatalf("slot %d owner = %q, want GS1", target, schedule[target])
+	}
+	if sess, _ := sn.sessions.Get("GS1"); sess.SlotID != target {
+		t.Fatalf("session slot = %d, want %d", sess.SlotID, target)
+	}
+	// 地面站立即收到新的时隙
+	if replies, want := takeReplies(conn), protocol.MSG_ACK_SLOT+strconv.Itoa(target); len(replies) != 1 || replies[0] != want {
+		t.Fatalf("GS1 received %q, want %q", replies, want)
+	}
+
+	// 时隙已归属其他节点时拒绝，双方的时隙都不变
+	other := newTestConn("GS2")
+	held := joinStation(t, sn, other, "GS2")
+	body = `{"slot_id":` + strconv.Itoa(held) + `,"node_id":"GS1"}`
+	if code, resp := adminRequest(t, srv, http.MethodPost, "/api/slots/allocate", bearer, body); code != http.StatusConflict {
+		t.Fatalf("allocate taken slot: status = %d (%v), want 409", code, resp)
+	}
+	schedule = sn.scheduler.GetSchedule()
+	if schedule[held] != "GS2" || schedule[target] != "GS1" {
+		t.Fatalf("schedule changed after conflict: %v", schedule)
+	}
+}
+
+// 释放时隙后通知地面站：没有其他时隙时发送心跳拒绝，地面站据此重新入网
+func TestAdminReleaseSlotNotifiesStation(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	srv := newTestAdmin(t, sn, testAdminToken)
+
+	conn := newTestConn("GS1")
+	slotID := joinStation(t, sn, conn, "GS1")
+	body := `{"slot_id":` + strconv.Itoa(slotID) + `}`
+	code, resp := adminRequest(t, srv, http.MethodPost, "/api/slots/release", "Bearer "+testAdminToken, body)
+	if code != http.StatusOK || resp["node_id"] != "GS1" {
+		t.Fatalf("release: status = %d (%v)", code, resp)
+	}
+	if replies := takeReplies(conn); len(replies) != 1 || replies[0] != protocol.MSG_HEARTBEAT_REJECT {
+		t.Fatalf("GS1 received %q, want %q", replies, protocol.MSG_HEARTBEAT_REJECT)
+	}
+
+	// 释放空闲时隙时没有地面站需要通知
+	if code, resp := adminRequest(t, srv, http.MethodPost, "/api/slots/release", "Bearer "+testAdminToken, body); code != http.StatusOK {
+		t.Fatalf("release free slot: status = %d (%v)", code, resp)
+	}
+	if replies := takeReplies(conn); len(replies) != 0 {
+		t.Fatalf("GS1 received %q after releasing a free slot", replies)
+	}
+}
+
+func TestAdminReloadReportsFiles(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	srv := newTestAdmin(t, sn, testAdminToken)
+
+	code, resp := adminRequest(t, srv, http.MethodPost, "/api/reload", "Bearer "+testAdminToken, "")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d (%v)", code, resp)
+	}
+	for _, key := range []string{"channel", "orbit", "acl"} {
+		if _, ok := resp[key]; !ok {
+			t.Errorf("response %v lacks %q", resp, key)
+		}
+	}
+}
+
+func TestAdminReloadInvalidConfig(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	srv := newTestAdmin(t, sn, testAdminToken)
+
+	sn.channelFile = filepath.Join(t.TempDir(), "channel.json")
+	if err := os.WriteFile(sn.channelFile, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	code, resp := adminRequest(t, srv, http.MethodPost, "/api/reload", "Bearer "+testAdminToken, "")
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d (%v), want 422", code, resp)
+	}
+	if msg, _ := resp["error"].(string); !strings.Contains(msg, "加载信道配置失败") {
+		t.Fatalf("error = %q, want the loader's message", msg)
+	}
+}
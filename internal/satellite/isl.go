@@ -0,0 +1,708 @@
+package satellite
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"tdma-network/internal/acl"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/capture"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/network"
+	"tdma-network/internal/routing"
+	"tdma-network/internal/session"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 配置的星间链路邻居
+type peerEntry struct {
+	ID      string // 邻居卫星ID，可省略，建链后获知；省略ID的邻居发起的链路不被接受
+	Address string
+}
+
+// 解析邻居列表："ID@地址:端口,ID@地址:端口"，ID可省略
+func parsePeers(list string) ([]peerEntry, error) {
+	var peers []peerEntry
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		entry := peerEntry{Address: item}
+		if i := strings.Index(item, "@"); i >= 0 {
+			entry.ID, entry.Address = item[:i], item[i+1:]
+		}
+		if entry.Address == "" {
+			return nil, fmt.Errorf("邻居地址为空: %s", item)
+		}
+		peers = append(peers, entry)
+	}
+	return peers, nil
+}
+
+// 一条在线的星间链路
+type peer struct {
+	id      string
+	conn    net.Conn
+	dialed  bool // 是否由本星发起
+	since   time.Time
+	writeMu sync.Mutex
+	capture *capture.Writer
+
+	// 握手导出的链路密钥，链路上的每一帧都附加认证尾部；未认证的链路为nil
+	key     []byte
+	sendSeq uint64 // 受writeMu保护
+	recvSeq uint64 // 只由处理该链路的协程访问
+}
+
+// 在星间链路上发送消息
+func (p *peer) send(self, data string) error {
+	frame := protocol.NewTDMAFrame(0, self, []byte(data))
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if p.key != nil {
+		p.sendSeq++
+		frame.Sign(p.key, p.sendSeq)
+	}
+	p.capture.Frame(capture.DIR_OUTBOUND, p.conn, p.id, frame)
+	return protocol.WriteFrame(p.conn, frame)
+}
+
+// 校验并去掉链路密钥的认证尾部，序号须大于上一帧；未认证的链路不校验
+func (p *peer) open(frame *protocol.TDMAFrame) error {
+	if p.key == nil {
+		return nil
+	}
+	seq, err := frame.Verify(p.key)
+	if err != nil {
+		return rejectISL(islDropBadMAC, "%v", err)
+	}
+	if seq <= p.recvSeq {
+		return rejectISL(islDropReplay, "序号 %d 不大于上一帧的 %d", seq, p.recvSeq)
+	}
+	p.recvSeq = seq
+	return nil
+}
+
+// 写操作加锁的连接，地面站的连接会同时被处理协程与转发数据的协程写入
+type lockedConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *lockedConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+// 记录地面站当前所在的连接，用于投递转发来的数据
+func (sn *SatelliteNode) attachStation(nodeID string, conn net.Conn) {
+	sn.stationsMu.Lock()
+	defer sn.stationsMu.Unlock()
+	sn.stations[nodeID] = conn
+}
+
+// 连接断开时移除地面站，已在其他连接上重新接入时不做处理
+func (sn *SatelliteNode) detachStation(nodeID string, conn net.Conn) {
+	sn.stationsMu.Lock()
+	defer sn.stationsMu.Unlock()
+	if sn.stations[nodeID] == conn {
+		delete(sn.stations, nodeID)
+	}
+}
+
+// 获取地面站所在连接
+func (sn *SatelliteNode) stationConn(nodeID string) (net.Conn, bool) {
+	sn.stationsMu.Lock()
+	defer sn.stationsMu.Unlock()
+	conn, ok := sn.stations[nodeID]
+	return conn, ok
+}
+
+// 主动连接配置的邻居，链路中断后按退避间隔重连
+func (sn *SatelliteNode) dialLoop(entry peerEntry) {
+	backoff := network.NewBackoff()
+	for sn.running {
+		if entry.ID != "" && sn.getPeer(entry.ID) != nil {
+			// 对方已主动建链
+			time.Sleep(protocol.ISL_HELLO_INTERVAL)
+			continue
+		}
+
+		conn, err := net.DialTimeout("tcp", entry.Address, 5*time.Second)
+		if err == nil {
+			conn, err = auth.DialTLS(conn, entry.Address, sn.tlsClient)
+		}
+		if err != nil {
+			delay := backoff.Next()
+			sn.islLog.Debug("连接邻居失败", "addr", entry.Address, "err", err, "retry", delay.Round(time.Millisecond))
+			time.Sleep(delay)
+			continue
+		}
+
+		if err := sn.islWrite(conn, entry.Address, protocol.MSG_ISL_HELLO); err != nil {
+			conn.Close()
+			time.Sleep(backoff.Next())
+			continue
+		}
+		id, key, err := sn.dialISL(conn, entry.ID)
+		if err != nil {
+			sn.islLog.Warn("星间链路认证失败", "addr", entry.Address, logging.KEY_PEER, id, "err", err)
+			conn.Close()
+			time.Sleep(backoff.Next())
+			continue
+		}
+		backoff.Reset()
+		id = sn.serveISL(conn, id, key, true)
+		if entry.ID == "" {
+			entry.ID = id
+		}
+		time.Sleep(backoff.Next())
+	}
+}
+
+// 拒绝星间链路的原因，作为 tdma_isl_rejected_total 的 reason 标签，认证失败时为认证失败原因
+const (
+	islRejectNotPeer   = "not_peer"  // 对端不在邻居列表中
+	islRejectHandshake = "handshake" // 握手消息不符合流程或读写失败
+	islRejectStation   = "station"   // 地面站连接上的星间链路消息
+)
+
+// 丢弃已建立链路上的帧的原因，作为 tdma_isl_dropped_total 的 reason 标签
+const (
+	islDropBadMAC    = "bad_mac"   // 认证尾部缺失或与链路密钥不符
+	islDropReplay    = "replay"    // 认证尾部的序号未递增
+	islDropMalformed = "malformed" // 消息格式错误
+	islDropSource    = "source"    // 转发数据的源地面站不能经该邻居到达
+)
+
+// 拒绝建链的错误
+type islReject struct {
+	reason string
+	msg    string
+}
+
+func (e *islReject) Error() string { return e.msg }
+
+func rejectISL(reason, format string, args ...any) error {
+	return &islReject{reason: reason, msg: fmt.Sprintf(format, args...)}
+}
+
+// 拒绝建链的原因
+func islRejectReason(err error) string {
+	if reason := auth.Reason(err); reason != "" {
+		return reason
+	}
+	if e, ok := err.(*islReject); ok {
+		return e.reason
+	}
+	return islRejectHandshake
+}
+
+// 是否为邻居列表中给出ID的卫星
+func (sn *SatelliteNode) configuredPeer(id string) bool {
+	for _, entry := range sn.peerEntries {
+		if entry.ID != "" && entry.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// 在尚未登记的星间链路上发送握手消息
+func (sn *SatelliteNode) islWrite(conn net.Conn, to, data string) error {
+	frame := protocol.NewTDMAFrame(0, sn.nodeID, []byte(data))
+	sn.capture.Frame(capture.DIR_OUTBOUND, conn, to, frame)
+	return protocol.WriteFrame(conn, frame)
+}
+
+// 读取握手中对端的下一帧，超过ISL_DEAD_INTERVAL未收到或帧无效时返回错误
+func (sn *SatelliteNode) islRead(conn net.Conn) (*protocol.TDMAFrame, error) {
+	conn.SetReadDeadline(time.Now().Add(protocol.ISL_DEAD_INTERVAL))
+	frame, err := protocol.ReadFrame(conn)
+	if err != nil {
+		return nil, fmt.Errorf("读取握手消息失败: %v", err)
+	}
+	sn.capture.Frame(capture.DIR_INBOUND, conn, frame.GetNodeID(), frame)
+	if err := frame.Validate(); err != nil {
+		return nil, fmt.Errorf("握手消息验证失败: %v", err)
+	}
+	return frame, nil
+}
+
+// 处理其他卫星发起的星间链路，first为其ISL_HELLO；对端身份确认后处理该链路直至中断
+func (sn *SatelliteNode) acceptPeer(conn net.Conn, first *protocol.TDMAFrame, certNode string) {
+	id, key, err := sn.acceptISL(conn, first, certNode)
+	if err != nil {
+		reason := islRejectReason(err)
+		sn.metrics.islRejected.With(reason).Inc()
+		sn.islLog.Warn("拒绝星间链路", logging.KEY_PEER, id, "addr", conn.RemoteAddr().String(), "reason", reason, "err", err)
+		conn.Close()
+		return
+	}
+	sn.serveISL(conn, id, key, false)
+}
+
+// 确认发起建链的对端身份，返回邻居卫星ID与链路密钥
+// 只接受邻居列表中给出ID的卫星：配置了密钥库时用发起方的预共享密钥挑战响应，
+// 否则校验了客户端证书时要求证书主体与卫星ID一致，两者都没有时不认证。
+// 只有挑战响应认证的链路有链路密钥，证书认证的链路由TLS保护
+func (sn *SatelliteNode) acceptISL(conn net.Conn, first *protocol.TDMAFrame, certNode string) (string, []byte, error) {
+	id := first.GetNodeID()
+	if err := first.Validate(); err != nil {
+		return id, nil, fmt.Errorf("握手消息验证失败: %v", err)
+	}
+	if string(first.Data) != protocol.MSG_ISL_HELLO {
+		return id, nil, fmt.Errorf("建链的第一帧不是 %s", protocol.MSG_ISL_HELLO)
+	}
+	if !sn.configuredPeer(id) {
+		return id, nil, rejectISL(islRejectNotPeer, "卫星 %s 不在邻居列表中", id)
+	}
+
+	if sn.auth == nil {
+		if certNode != "" {
+			return id, nil, auth.MatchCertNode(certNode, id)
+		}
+		sn.islLog.Warn("未配置密钥库，星间链路未认证", logging.KEY_PEER, id)
+		return id, nil, nil
+	}
+
+	h, challenge, err := sn.auth.AcceptISL(id, sn.nodeID)
+	if err != nil {
+		return id, nil, err
+	}
+	if err := sn.islWrite(conn, id, challenge); err != nil {
+		return id, nil, err
+	}
+	frame, err := sn.islRead(conn)
+	if err != nil {
+		return id, nil, err
+	}
+	response, ok := strings.CutPrefix(string(frame.Data), protocol.MSG_ISL_RESPONSE)
+	if !ok || frame.GetNodeID() != id {
+		return id, nil, fmt.Errorf("期望 %s 的认证响应，收到 %s 的 %q", id, frame.GetNodeID(), frame.Data)
+	}
+	accept, err := h.Verify(response)
+	if err != nil {
+		return id, nil, err
+	}
+	if err := sn.islWrite(conn, id, accept); err != nil {
+		return id, nil, err
+	}
+	return id, h.LinkKey(), nil
+}
+
+// 本星发起建链后确认对端身份，返回邻居卫星ID与链路密钥，expect为邻居列表中的ID（可省略）
+// 配置了密钥库时响应对端的挑战并校验其确认；未配置时不认证，省略的ID从对端的第一帧获知
+func (sn *SatelliteNode) dialISL(conn net.Conn, expect string) (string, []byte, error) {
+	if sn.auth == nil {
+		return expect, nil, nil
+	}
+
+	frame, err := sn.islRead(conn)
+	if err != nil {
+		return expect, nil, err
+	}
+	id := frame.GetNodeID()
+	if expect != "" && id != expect {
+		return id, nil, fmt.Errorf("对端为卫星 %s，邻居列表中为 %s", id, expect)
+	}
+	challenge, ok := strings.CutPrefix(string(frame.Data), protocol.MSG_ISL_CHALLENGE)
+	if !ok {
+		return id, nil, fmt.Errorf("期望认证挑战，收到 %q", frame.Data)
+	}
+	h, response, err := sn.auth.RespondISL(sn.nodeID, id, challenge)
+	if err != nil {
+		return id, nil, err
+	}
+	if err := sn.islWrite(conn, id, response); err != nil {
+		return id, nil, err
+	}
+	if frame, err = sn.islRead(conn); err != nil {
+		return id, nil, err
+	}
+	accept, ok := strings.CutPrefix(string(frame.Data), protocol.MSG_ISL_ACCEPT)
+	if !ok || frame.GetNodeID() != id {
+		return id, nil, fmt.Errorf("期望 %s 的认证确认，收到 %s 的 %q", id, frame.GetNodeID(), frame.Data)
+	}
+	if err := h.Confirm(accept); err != nil {
+		return id, nil, err
+	}
+	return id, h.LinkKey(), nil
+}
+
+// 处理一条身份已确认的星间链路直至中断，返回邻居卫星ID
+// peerID为空时（未认证的链路省略了邻居ID）从对端的第一帧获知，之后只接受该卫星的帧；
+// key不为nil时丢弃认证尾部校验失败的帧
+func (sn *SatelliteNode) serveISL(conn net.Conn, peerID string, key []byte, dialed bool) string {
+	defer conn.Close()
+
+	var p *peer
+	defer func() {
+		if p != nil {
+			sn.removePeer(p)
+		}
+	}()
+	if peerID != "" {
+		if p = sn.addPeer(peerID, conn, dialed, key); p == nil {
+			return peerID
+		}
+	}
+
+	for sn.running {
+		conn.SetReadDeadline(time.Now().Add(protocol.ISL_DEAD_INTERVAL))
+		frame, err := protocol.ReadFrame(conn)
+		if err != nil {
+			if p != nil {
+				sn.islLog.Warn("星间链路中断", logging.KEY_PEER, p.id, "err", err)
+			}
+			break
+		}
+		sn.capture.Frame(capture.DIR_INBOUND, conn, frame.GetNodeID(), frame)
+		if err := frame.Validate(); err != nil {
+			sn.islLog.Warn("帧验证失败", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID(), "err", err)...)
+			continue
+		}
+
+		if p == nil {
+			peerID = frame.GetNodeID()
+			if p = sn.addPeer(peerID, conn, dialed, key); p == nil {
+				break
+			}
+		}
+		if frame.GetNodeID() != p.id {
+			sn.islLog.Warn("星间链路上的节点ID与邻居不一致，丢弃帧", logging.FrameArgs(frame, logging.KEY_PEER, p.id)...)
+			continue
+		}
+		if err := p.open(frame); err != nil {
+			sn.dropISL(p, islRejectReason(err), err)
+			continue
+		}
+		sn.metrics.islFrames.With(p.id).Inc()
+		sn.record.ISL(p.id, frame)
+		sn.handleISL(p, string(frame.Data))
+	}
+
+	return peerID
+}
+
+// 登记新的星间链路，双方同时发起时保留ID较小一方发起的链路
+func (sn *SatelliteNode) addPeer(id string, conn net.Conn, dialed bool, key []byte) *peer {
+	if id == "" || id == sn.nodeID {
+		sn.islLog.Warn("无效的邻居ID", logging.KEY_PEER, id)
+		return nil
+	}
+
+	p := &peer{id: id, conn: conn, dialed: dialed, since: time.Now(), capture: sn.capture, key: key}
+
+	sn.peersMu.Lock()
+	if old, ok := sn.peers[id]; ok {
+		preferDialed := sn.nodeID < id
+		if old.dialed == preferDialed {
+			sn.peersMu.Unlock()
+			sn.islLog.Debug("星间链路已存在，关闭重复链路", logging.KEY_PEER, id)
+			return nil
+		}
+		old.conn.Close()
+	}
+	sn.peers[id] = p
+	sn.peersMu.Unlock()
+
+	sn.islLog.Info("建立星间链路", logging.KEY_PEER, id, "addr", conn.RemoteAddr().String())
+
+	if !dialed {
+		if err := p.send(sn.nodeID, protocol.MSG_ISL_HELLO); err != nil {
+			sn.islLog.Warn("发送HELLO失败", logging.KEY_PEER, id, "err", err)
+		}
+	}
+	// 同步链路状态数据库
+	for _, l := range sn.routes.All() {
+		if err := p.send(sn.nodeID, protocol.MSG_ISL_LSA+l.Encode()); err != nil {
+			sn.islLog.Warn("同步链路状态失败", logging.KEY_PEER, id, "err", err)
+			break
+		}
+	}
+	sn.advertise(false)
+	return p
+}
+
+// 移除中断的星间链路并重新计算路由
+func (sn *SatelliteNode) removePeer(p *peer) {
+	sn.peersMu.Lock()
+	removed := sn.peers[p.id] == p
+	if removed {
+		delete(sn.peers, p.id)
+	}
+	sn.peersMu.Unlock()
+
+	if removed {
+		sn.islLog.Info("星间链路已移除", logging.KEY_PEER, p.id)
+		sn.record.ISLDown(p.id)
+		sn.advertise(false)
+	}
+}
+
+// 获取邻居链路
+func (sn *SatelliteNode) getPeer(id string) *peer {
+	sn.peersMu.Lock()
+	defer sn.peersMu.Unlock()
+	return sn.peers[id]
+}
+
+// 获取全部邻居链路
+func (sn *SatelliteNode) peerList() []*peer {
+	sn.peersMu.Lock()
+	defer sn.peersMu.Unlock()
+
+	peers := make([]*peer, 0, len(sn.peers))
+	for _, p := range sn.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// 处理星间链路消息
+func (sn *SatelliteNode) handleISL(from *peer, data string) {
+	switch {
+	case data == protocol.MSG_ISL_HELLO:
+		// 读超时已刷新，无需处理
+
+	case strings.HasPrefix(data, protocol.MSG_ISL_LSA):
+		l, err := routing.DecodeLSA(strings.TrimPrefix(data, protocol.MSG_ISL_LSA))
+		if err != nil {
+			sn.dropISL(from, islDropMalformed, err)
+			return
+		}
+		if sn.routes.Update(l) {
+			sn.flood(l, from.id)
+		}
+
+	case strings.HasPrefix(data, protocol.MSG_ISL_DATA):
+		src, dst, ttl, payload, err := decodeISLData(strings.TrimPrefix(data, protocol.MSG_ISL_DATA))
+		if err != nil {
+			sn.dropISL(from, islDropMalformed, err)
+			return
+		}
+		// 邻居只能转发经它可达的地面站发出的数据
+		if !sn.routes.ReachableVia(src, from.id) {
+			sn.dropISL(from, islDropSource, fmt.Errorf("源地面站 %s 不能经邻居到达", src))
+			return
+		}
+		if err := sn.forward(src, dst, ttl, payload); err != nil {
+			sn.islLog.Warn("转发失败", "src", src, "dst", dst, "err", err)
+		}
+
+	default:
+		sn.islLog.Warn("未知的星间链路消息", logging.KEY_PEER, from.id, "data", data)
+	}
+}
+
+// 丢弃邻居发来的帧
+func (sn *SatelliteNode) dropISL(from *peer, reason string, err error) {
+	sn.metrics.islDropped.With(reason).Inc()
+	sn.islLog.Warn("丢弃星间链路帧", logging.KEY_PEER, from.id, "reason", reason, "err", err)
+}
+
+// 编码转发数据（ISL_DATA:之后的部分）：<剩余跳数>:<源节点长度>:<目的节点长度>:<源节点><目的节点><内容>
+// 节点ID按长度切分，可以包含任意字符
+func encodeISLData(src, dst string, ttl int, payload string) string {
+	return fmt.Sprintf("%d:%d:%d:%s%s%s", ttl, len(src), len(dst), src, dst, payload)
+}
+
+// 解码转发数据
+func decodeISLData(s string) (src, dst string, ttl int, payload string, err error) {
+	parts := strings.SplitN(s, ":", 4)
+	if len(parts) != 4 {
+		return "", "", 0, "", fmt.Errorf("无效的转发数据: %q", s)
+	}
+	var n [3]int
+	for i := range n {
+		if n[i], err = strconv.Atoi(parts[i]); err != nil || n[i] < 0 {
+			return "", "", 0, "", fmt.Errorf("无效的转发数据头: %q", s)
+		}
+	}
+	rest := parts[3]
+	if n[1] == 0 || n[2] == 0 || n[1]+n[2] > len(rest) {
+		return "", "", 0, "", fmt.Errorf("节点ID长度 %d、%d 无效", n[1], n[2])
+	}
+	return rest[:n[1]], rest[n[1] : n[1]+n[2]], n[0], rest[n[1]+n[2]:], nil
+}
+
+// 向除exclude外的全部邻居泛洪链路状态通告
+func (sn *SatelliteNode) flood(l routing.LSA, exclude string) {
+	msg := protocol.MSG_ISL_LSA + l.Encode()
+	for _, p := range sn.peerList() {
+		if p.id == exclude {
+			continue
+		}
+		if err := p.send(sn.nodeID, msg); err != nil {
+			sn.islLog.Warn("发送链路状态失败", logging.KEY_PEER, p.id, "err", err)
+		}
+	}
+}
+
+// 当前接入本星的地面站（会话未断开）
+func (sn *SatelliteNode) attachedStations() []string {
+	var stations []string
+	for _, sess := range sn.sessions.List() {
+		if sess.State != session.STATE_DETACHED {
+			stations = append(stations, sess.NodeID)
+		}
+	}
+	return stations
+}
+
+// 更新并泛洪本星的链路状态，内容未变化且不强制刷新时不发送
+func (sn *SatelliteNode) advertise(force bool) {
+	neighbors := make(map[string]int)
+	for _, p := range sn.peerList() {
+		neighbors[p.id] = 1
+	}
+	stations := sn.attachedStations()
+	sort.Strings(stations)
+
+	sn.advertiseMu.Lock()
+	defer sn.advertiseMu.Unlock()
+
+	if !force && sameLocal(sn.routes.Local(), neighbors, stations) {
+		return
+	}
+	sn.flood(sn.routes.SetLocal(neighbors, stations), "")
+}
+
+// 判断本星通告内容是否与当前状态一致
+func sameLocal(l routing.LSA, neighbors map[string]int, stations []string) bool {
+	if l.Origin == "" || len(l.Neighbors) != len(neighbors) || len(l.Stations) != len(stations) {
+		return false
+	}
+	for n, cost := range neighbors {
+		if l.Neighbors[n] != cost {
+			return false
+		}
+	}
+	for i := range stations {
+		if l.Stations[i] != stations[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 星间链路维护循环：保活、刷新本星通告、删除过期通告
+func (sn *SatelliteNode) islLoop() {
+	ticker := time.NewTicker(protocol.ISL_HELLO_INTERVAL)
+	defer ticker.Stop()
+
+	lastRefresh := time.Now()
+	for range ticker.C {
+		if !sn.running {
+			return
+		}
+		for _, p := range sn.peerList() {
+			if err := p.send(sn.nodeID, protocol.MSG_ISL_HELLO); err != nil {
+				sn.islLog.Warn("发送HELLO失败", logging.KEY_PEER, p.id, "err", err)
+			}
+		}
+		if time.Since(lastRefresh) >= protocol.ISL_REFRESH_INTERVAL {
+			sn.advertise(true)
+			lastRefresh = time.Now()
+		}
+		for _, origin := range sn.routes.Expire(protocol.ISL_LSA_MAX_AGE) {
+			sn.islLog.Info("链路状态通告已过期", "origin", origin)
+		}
+	}
+}
+
+// 处理地面站发往其他地面站的数据
+func (sn *SatelliteNode) handleDataTo(src, data string, conn net.Conn) {
+	parts := strings.SplitN(strings.TrimPrefix(data, protocol.MSG_DATA_TO), ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		sn.log.Warn("无效的转发数据", logging.KEY_PEER, src, "data", data)
+		return
+	}
+	dst, payload := parts[0], parts[1]
+	if err := sn.accessPolicy().AllowDestination(src, dst); err != nil {
+		reason := acl.Reason(err)
+		sn.metrics.aclDenials.With(reason).Inc()
+		sn.log.Warn("访问被拒绝", logging.KEY_PEER, src, "dst", dst, "reason", reason, "err", err)
+		sn.reply(conn, src, 0, protocol.MSG_DATA_DENIED+dst+":"+reason)
+		return
+	}
+	if err := sn.forward(src, dst, protocol.ISL_MAX_HOPS, payload); err != nil {
+		sn.log.Info("转发失败", "src", src, "dst", dst, "err", err)
+		sn.metrics.unreachable.Inc()
+		sn.reply(conn, src, 0, protocol.MSG_DATA_UNREACHABLE+dst)
+	}
+}
+
+// 将数据投递给接入本星的目的地面站，或沿最短路径转发给下一跳卫星
+func (sn *SatelliteNode) forward(src, dst string, ttl int, payload string) error {
+	if conn, ok := sn.stationConn(dst); ok {
+		slotID := 0
+		if sess, ok := sn.sessions.Get(dst); ok {
+			slotID = sess.SlotID
+		}
+		sn.islLog.Debug("投递", "src", src, "dst", dst)
+		sn.reply(conn, dst, slotID, protocol.MSG_DATA_FROM+src+":"+payload)
+		return nil
+	}
+
+	route, ok := sn.routes.Lookup(dst)
+	if !ok || route.NextHop == "" {
+		return fmt.Errorf("目的节点 %s 不可达", dst)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("超过最大跳数")
+	}
+	p := sn.getPeer(route.NextHop)
+	if p == nil {
+		return fmt.Errorf("下一跳卫星 %s 链路不存在", route.NextHop)
+	}
+
+	sn.islLog.Debug("转发", "src", src, "dst", dst, "next_hop", route.NextHop, "satellite", route.Satellite)
+	sn.metrics.forwarded.Inc()
+	return p.send(sn.nodeID, protocol.MSG_ISL_DATA+encodeISLData(src, dst, ttl-1, payload))
+}
+
+// 地面站接入变化时更新链路状态
+func (sn *SatelliteNode) stationEvent(e session.Event) {
+	switch e.Type {
+	case session.EVENT_JOINED, session.EVENT_RESUMED, session.EVENT_DETACHED,
+		session.EVENT_DEAD, session.EVENT_EXPIRED, session.EVENT_LEFT, session.EVENT_KICKED, session.EVENT_QUARANTINED:
+		sn.advertise(false)
+	}
+}
+
+// 打印星间链路与路由表
+func (sn *SatelliteNode) printRoutes() {
+	fmt.Println("星间链路:")
+	for _, p := range sn.peerList() {
+		fmt.Printf("  %s: %s, 建立于 %s\n", p.id, p.conn.RemoteAddr(), p.since.Format(time.RFC3339))
+	}
+
+	fmt.Println("路由表:")
+	routes := sn.routes.Routes()
+	sats := make([]string, 0, len(routes))
+	for sat := range routes {
+		sats = append(sats, sat)
+	}
+	sort.Strings(sats)
+	for _, sat := range sats {
+		r := routes[sat]
+		nextHop := r.NextHop
+		if nextHop == "" {
+			nextHop = "本星"
+		}
+		fmt.Printf("  卫星 %s: 下一跳 %s, 代价 %d\n", sat, nextHop, r.Cost)
+	}
+
+	fmt.Println("地面站接入:")
+	for station, sat := range sn.routes.Stations() {
+		fmt.Printf("  %s -> %s\n", station, sat)
+	}
+}
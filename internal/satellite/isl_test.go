@@ -0,0 +1,401 @@
+package satellite
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+// 测试用的卫星密钥库，格式与 -keystore 文件相同
+func testKeystore(t *testing.T, keys string) *auth.Keystore {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(file, []byte(`{"keys": {`+keys+`}}`), 0o600); err != nil {
+		t.Fatalf("写入密钥库失败: %v", err)
+	}
+	ks, err := auth.LoadKeystore(file)
+	if err != nil {
+		t.Fatalf("LoadKeystore: %v", err)
+	}
+	return ks
+}
+
+const (
+	satAKey   = `"SAT_A": "000102030405060708090a0b0c0d0e0f"`
+	wrongAKey = `"SAT_A": "ffffffffffffffffffffffffffffffff"`
+)
+
+func writeISL(conn net.Conn, nodeID, data string) error {
+	return protocol.WriteFrame(conn, protocol.NewTDMAFrame(0, nodeID, []byte(data)))
+}
+
+// 以SAT_A的身份响应挑战，keys为SAT_A自己的密钥库
+func respondAsSatA(t *testing.T, keys *auth.Keystore) func(net.Conn) {
+	return func(conn net.Conn) {
+		frame, err := protocol.ReadFrame(conn)
+		if err != nil {
+			return
+		}
+		challenge, ok := strings.CutPrefix(string(frame.Data), protocol.MSG_ISL_CHALLENGE)
+		if !ok {
+			return
+		}
+		_, response, err := auth.NewAuthenticator(keys, clock.Real{}).RespondISL("SAT_A", frame.GetNodeID(), challenge)
+		if err != nil {
+			t.Errorf("RespondISL: %v", err)
+			return
+		}
+		writeISL(conn, "SAT_A", response)
+		protocol.ReadFrame(conn)
+	}
+}
+
+// 对端发起的星间链路：只接受邻居列表中能证明身份的卫星
+func TestAcceptISL(t *testing.T) {
+	tests := []struct {
+		name     string
+		keystore string // 接收方的密钥库，为空表示不认证
+		certNode string // 校验过的客户端证书主体
+		hello    string // 发起方第一帧的节点ID
+		remote   func(t *testing.T) func(net.Conn)
+		reason   string // 拒绝原因，为空表示接受
+	}{
+		{name: "authenticated peer", keystore: satAKey, hello: "SAT_A",
+			remote: func(t *testing.T) func(net.Conn) { return respondAsSatA(t, testKeystore(t, satAKey)) }},
+		{name: "unauthenticated ISL hello is refused", keystore: satAKey, hello: "SAT_A",
+			remote: func(t *testing.T) func(net.Conn) {
+				// 不响应挑战，直接发送伪造的链路状态通告
+				return func(conn net.Conn) {
+					protocol.ReadFrame(conn)
+					writeISL(conn, "SAT_A", protocol.MSG_ISL_LSA+"SAT_X;1;;GS1")
+				}
+			},
+			reason: islRejectHandshake},
+		{name: "wrong key", keystore: satAKey, hello: "SAT_A",
+			remote: func(t *testing.T) func(net.Conn) { return respondAsSatA(t, testKeystore(t, wrongAKey)) },
+			reason: auth.REASON_BAD_RESPONSE},
+		{name: "not a configured peer", keystore: satAKey, hello: "SAT_X", reason: islRejectNotPeer},
+		{name: "station ID", keystore: satAKey + `, "GS1": "000102030405060708090a0b0c0d0e0f"`, hello: "GS1", reason: islRejectNotPeer},
+		{name: "peer missing from keystore", keystore: `"SAT_C": "000102030405060708090a0b0c0d0e0f"`, hello: "SAT_A", reason: auth.REASON_UNKNOWN_NODE},
+		{name: "unauthenticated configured peer", hello: "SAT_A"},
+		{name: "unauthenticated unlisted peer", hello: "SAT_X", reason: islRejectNotPeer},
+		{name: "certificate matches", certNode: "SAT_A", hello: "SAT_A"},
+		{name: "certificate mismatch", certNode: "SAT_B", hello: "SAT_A", reason: auth.REASON_CERT_MISMATCH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn, _ := newTestSatellite(t)
+			sn.peerEntries = []peerEntry{{ID: "SAT_A", Address: "127.0.0.1:1"}, {Address: "127.0.0.1:2"}}
+			if tt.keystore != "" {
+				sn.auth = auth.NewAuthenticator(testKeystore(t, tt.keystore), sn.clock)
+			}
+
+			local, remote := net.Pipe()
+			defer local.Close()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				defer remote.Close()
+				if tt.remote != nil {
+					tt.remote(t)(remote)
+				}
+			}()
+
+			hello := protocol.NewTDMAFrame(0, tt.hello, []byte(protocol.MSG_ISL_HELLO))
+			id, _, err := sn.acceptISL(local, hello, tt.certNode)
+			local.Close()
+			<-done
+
+			if tt.reason == "" {
+				if err != nil || id != tt.hello {
+					t.Fatalf("acceptISL = %q, %v, want %s accepted", id, err, tt.hello)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("acceptISL accepted %s", id)
+			}
+			if reason := islRejectReason(err); reason != tt.reason {
+				t.Fatalf("rejected with %q (%v), want %q", reason, err, tt.reason)
+			}
+		})
+	}
+}
+
+// 两颗卫星完成相互认证的建链握手
+func TestISLHandshakeBetweenSatellites(t *testing.T) {
+	tests := []struct {
+		name         string
+		dialerKeys   string
+		expect       string // 发起方邻居列表中的ID
+		dialerErr    bool
+		acceptReason string
+	}{
+		{"ok", satAKey, "SAT_B", false, ""},
+		{"ID omitted", satAKey, "", false, ""},
+		{"wrong acceptor", satAKey, "SAT_C", true, islRejectHandshake},
+		{"wrong key", wrongAKey, "SAT_B", true, auth.REASON_BAD_RESPONSE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestSatellite(t)
+			a.nodeID = "SAT_A"
+			a.auth = auth.NewAuthenticator(testKeystore(t, tt.dialerKeys), a.clock)
+			b, _ := newTestSatellite(t)
+			b.nodeID = "SAT_B"
+			b.auth = auth.NewAuthenticator(testKeystore(t, satAKey), b.clock)
+			b.peerEntries = []peerEntry{{ID: "SAT_A", Address: "127.0.0.1:1"}}
+
+			dialer, acceptor := net.Pipe()
+			type result struct {
+				id  string
+				key []byte
+				err error
+			}
+			accepted := make(chan result, 1)
+			go func() {
+				defer acceptor.Close()
+				first, err := protocol.ReadFrame(acceptor)
+				if err != nil {
+					accepted <- result{err: err}
+					return
+				}
+				id, key, err := b.acceptISL(acceptor, first, "")
+				accepted <- result{id, key, err}
+			}()
+
+			if err := a.islWrite(dialer, "SAT_B", protocol.MSG_ISL_HELLO); err != nil {
+				t.Fatalf("islWrite: %v", err)
+			}
+			id, key, err := a.dialISL(dialer, tt.expect)
+			dialer.Close()
+			r := <-accepted
+
+			if tt.dialerErr != (err != nil) {
+				t.Fatalf("dialISL = %q, %v", id, err)
+			}
+			if tt.acceptReason == "" {
+				if r.err != nil || r.id != "SAT_A" || id != "SAT_B" {
+					t.Fatalf("acceptISL = %q, %v; dialISL = %q", r.id, r.err, id)
+				}
+				if key == nil || !bytes.Equal(key, r.key) {
+					t.Fatalf("link keys differ: %x, %x", key, r.key)
+				}
+				return
+			}
+			// 发起方发现对端不符时中止握手，接收方读取响应失败
+			if r.err == nil || islRejectReason(r.err) != tt.acceptReason {
+				t.Fatalf("acceptISL = %v, want reason %s", r.err, tt.acceptReason)
+			}
+		})
+	}
+}
+
+// 地面站连接上的星间链路消息被丢弃，不影响路由；未列出的节点不能把连接变为星间链路
+func TestStationConnectionRejectsISL(t *testing.T) {
+	tests := []struct {
+		name   string
+		join   bool // 先以地面站身份入网
+		reason string
+	}{
+		{"after join", true, islRejectStation},
+		{"as first frame", false, islRejectNotPeer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn, _ := newTestSatellite(t)
+			sn.peerEntries = []peerEntry{{ID: "SAT_A", Address: "127.0.0.1:1"}}
+			local, remote := net.Pipe()
+			done := make(chan struct{})
+			go func() {
+				sn.handleConnection(local)
+				close(done)
+			}()
+			// 丢弃卫星的响应
+			go func() {
+				for {
+					if _, err := protocol.ReadFrame(remote); err != nil {
+						return
+					}
+				}
+			}()
+
+			stamp := func(data string) *protocol.TDMAFrame {
+				f := protocol.NewTDMAFrame(0, "GS1", []byte(data))
+				f.SetAbsSlot(uint64(sn.scheduler.Current().Abs))
+				return f
+			}
+			if tt.join {
+				if err := protocol.WriteFrame(remote, stamp(protocol.MSG_JOIN)); err != nil {
+					t.Fatalf("WriteFrame: %v", err)
+				}
+			}
+			for _, msg := range []string{protocol.MSG_ISL_HELLO, protocol.MSG_ISL_LSA + "SAT_X;1;;GS2"} {
+				if err := protocol.WriteFrame(remote, stamp(msg)); err != nil {
+					break
+				}
+			}
+			remote.Close()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("handleConnection did not return")
+			}
+
+			if n := sn.metrics.islRejected.With(tt.reason).Value(); n == 0 {
+				t.Fatalf("no %s rejection counted", tt.reason)
+			}
+			if _, ok := sn.routes.Lookup("GS2"); ok || len(sn.peerList()) != 0 {
+				t.Fatalf("forged ISL message accepted: peers %d", len(sn.peerList()))
+			}
+		})
+	}
+}
+
+var testLinkKey = bytes.Repeat([]byte{0x5a}, 32)
+
+// 在本地管道上以链路密钥key服务来自SAT_A的星间链路，返回SAT_A一侧用于发送的链路
+func serveTestISL(t *testing.T, sn *SatelliteNode, key []byte) *peer {
+	t.Helper()
+	local, remote := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sn.serveISL(local, "SAT_A", key, false)
+	}()
+	// 丢弃卫星发给SAT_A的帧
+	go func() {
+		for {
+			if _, err := protocol.ReadFrame(remote); err != nil {
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		remote.Close()
+		<-done
+	})
+	return &peer{id: sn.nodeID, conn: remote, key: key}
+}
+
+// 发送一帧HELLO，写入完成时卫星已处理完之前的帧
+func syncISL(t *testing.T, a *peer) {
+	t.Helper()
+	if err := a.send("SAT_A", protocol.MSG_ISL_HELLO); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+// 认证的链路上只接受用链路密钥签名且序号递增的帧
+func TestISLFramesAuthenticated(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	a := serveTestISL(t, sn, testLinkKey)
+
+	if err := a.send("SAT_A", protocol.MSG_ISL_LSA+"SAT_A;1;SAT_T=1;GS1"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	syncISL(t, a)
+	if r, ok := sn.routes.Lookup("GS1"); !ok || r.NextHop != "SAT_A" {
+		t.Fatalf("Lookup(GS1) = %+v, %v, want via SAT_A", r, ok)
+	}
+
+	forged := func(seq uint64, key []byte, station string) *protocol.TDMAFrame {
+		f := protocol.NewTDMAFrame(0, "SAT_A", []byte(protocol.MSG_ISL_LSA+"SAT_A;9;SAT_T=1;"+station))
+		if key != nil {
+			f.Sign(key, seq)
+		}
+		return f
+	}
+	tests := []struct {
+		name   string
+		frame  *protocol.TDMAFrame
+		reason string
+	}{
+		{"unsigned", forged(0, nil, "GS2"), islDropBadMAC},
+		{"wrong key", forged(100, bytes.Repeat([]byte{1}, 32), "GS2"), islDropBadMAC},
+		{"replayed sequence", forged(1, testLinkKey, "GS2"), islDropReplay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := sn.metrics.islDropped.With(tt.reason).Value()
+			if err := protocol.WriteFrame(a.conn, tt.frame); err != nil {
+				t.Fatalf("WriteFrame: %v", err)
+			}
+			syncISL(t, a)
+			if got := sn.metrics.islDropped.With(tt.reason).Value(); got != before+1 {
+				t.Fatalf("%s drops = %v, want %v", tt.reason, got, before+1)
+			}
+			if _, ok := sn.routes.Lookup("GS2"); ok {
+				t.Fatalf("forged LSA accepted")
+			}
+		})
+	}
+}
+
+// 转发数据的节点ID按长度切分，源地面站须经发来数据的邻居可达
+func TestISLDataSource(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	a := serveTestISL(t, sn, testLinkKey)
+	if err := a.send("SAT_A", protocol.MSG_ISL_LSA+"SAT_A;1;SAT_T=1;GS1"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	conn := newTestConn("GS:2")
+	sn.attachStation("GS:2", conn)
+
+	tests := []struct {
+		name   string
+		data   string
+		want   string // 投递给GS:2的消息，为空表示丢弃
+		reason string
+	}{
+		{"delivered", encodeISLData("GS1", "GS:2", 3, "a:b"), protocol.MSG_DATA_FROM + "GS1:a:b", ""},
+		{"source not behind peer", encodeISLData("GS9", "GS:2", 3, "x"), "", islDropSource},
+		{"colon-separated fields", "GS1:GS:2:3:x", "", islDropMalformed},
+		{"length past end", "3:3:9:GS1GS:2", "", islDropMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before float64
+			if tt.reason != "" {
+				before = sn.metrics.islDropped.With(tt.reason).Value()
+			}
+			if err := a.send("SAT_A", protocol.MSG_ISL_DATA+tt.data); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			syncISL(t, a)
+
+			replies := takeReplies(conn)
+			if tt.want != "" {
+				if len(replies) != 1 || replies[0] != tt.want {
+					t.Fatalf("GS:2 received %q, want %q", replies, tt.want)
+				}
+				return
+			}
+			if len(replies) != 0 {
+				t.Fatalf("dropped data delivered: %q", replies)
+			}
+			if got := sn.metrics.islDropped.With(tt.reason).Value(); got != before+1 {
+				t.Fatalf("%s drops = %v, want %v", tt.reason, got, before+1)
+			}
+		})
+	}
+}
+
+func TestDecodeISLData(t *testing.T) {
+	src, dst, ttl, payload, err := decodeISLData(encodeISLData("GS:1", "GS_2", 7, "x:y"))
+	if err != nil || src != "GS:1" || dst != "GS_2" || ttl != 7 || payload != "x:y" {
+		t.Fatalf("decodeISLData = %q, %q, %d, %q, %v", src, dst, ttl, payload, err)
+	}
+	for _, s := range []string{"", "1:2:3", "1:0:3:GS_2", "1:-1:3:GS_2", "x:3:3:GS1GS2"} {
+		if _, _, _, _, err := decodeISLData(s); err == nil {
+			t.Errorf("decodeISLData(%q) succeeded", s)
+		}
+	}
+}
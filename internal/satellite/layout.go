@@ -0,0 +1,119 @@
+package satellite
+
+import (
+	"net"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/scheduler"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 重新加载时隙配置，配置变化时计划在changeLead之后的第一个超帧边界切换帧结构
+// 返回计划的变更，配置未变化或不支持热更新时返回nil
+func (sn *SatelliteNode) reloadLayout() (*scheduler.Change, error) {
+	if sn.schedulerConfig == nil {
+		return nil, nil
+	}
+	cfg, err := sn.schedulerConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 已计划同样的变更时不重复计划，已计划其他变更时由PlanChange报错
+	if ch, ok := sn.scheduler.PendingChange(); ok {
+		if ch.Layout.Config == cfg {
+			return &ch, nil
+		}
+	} else if sn.scheduler.Config() == cfg {
+		return nil, nil
+	}
+
+	now := sn.clock.Now()
+	at := sn.scheduler.Layout().NextSuperframe(now.Add(sn.changeLead))
+	ch, err := sn.planLayout(cfg, at)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// 计划在at时刻切换到cfg的帧结构，向接入的地面站通告，并在生效时刻迁移时隙
+func (sn *SatelliteNode) planLayout(cfg scheduler.Config, at time.Time) (scheduler.Change, error) {
+	ch, err := sn.scheduler.PlanChange(cfg, at)
+	if err != nil {
+		return ch, err
+	}
+	sn.record.Layout(at, cfg.TotalSlots, cfg.SlotDuration)
+	sn.log.Info("计划帧结构变更", "at", at, "slots", cfg.TotalSlots, "slot_duration", cfg.SlotDuration, "remap", ch.Remap)
+
+	for _, nodeID := range sn.attachedStations() {
+		if conn, ok := sn.stationConn(nodeID); ok {
+			sn.announceLayout(conn, nodeID, ch)
+		}
+	}
+	sn.clock.AfterFunc(at.Sub(sn.clock.Now()), sn.applyLayout)
+	return ch, nil
+}
+
+// 向地面站通告帧结构变更及其时隙在新帧结构中的位置
+func (sn *SatelliteNode) announceLayout(conn net.Conn, nodeID string, ch scheduler.Change) {
+	from := -1
+	if sess, ok := sn.sessions.Get(nodeID); ok {
+		from = sess.SlotID
+	}
+	msg := protocol.ScheduleChange{
+		At:           ch.Layout.Epoch,
+		TotalSlots:   ch.Layout.TotalSlots,
+		SlotDuration: ch.Layout.SlotDuration,
+		From:         from,
+		To:           ch.Target(from),
+	}
+	sn.reply(conn, nodeID, 0, msg.Encode())
+}
+
+// 变更生效前入网或恢复会话的地面站同样需要通告
+func (sn *SatelliteNode) announcePending(conn net.Conn, nodeID string) {
+	if ch, ok := sn.scheduler.PendingChange(); ok {
+		sn.announceLayout(conn, nodeID, ch)
+	}
+}
+
+// 在变更的生效时刻切换帧结构，会话改用迁移后的时隙
+// 失去时隙的节点保留会话，下一次心跳时重新分配
+// 由定时器或生效后收到的第一帧触发，变更未到生效时刻或已切换时不做处理
+func (sn *SatelliteNode) applyLayout() {
+	sn.layoutMu.Lock()
+	defer sn.layoutMu.Unlock()
+	if !sn.layoutDue() {
+		return
+	}
+	ch, moves, err := sn.scheduler.ApplyChange()
+	if err != nil {
+		sn.log.Warn("帧结构变更失败", "err", err)
+		return
+	}
+	for _, m := range moves {
+		if sess, ok := sn.sessions.Get(m.NodeID); ok && sess.SlotID == m.From {
+			sn.sessions.SetSlot(m.NodeID, m.To)
+		}
+		if m.To != m.From {
+			sn.slotLost(m.NodeID, m.From)
+		}
+		sn.log.Info("帧结构变更迁移时隙", logging.KEY_PEER, m.NodeID, "old_slot_id", m.From, logging.KEY_SLOT_ID, m.To)
+	}
+	sn.activity.reset(ch.Layout)
+	sn.capture.SetLayout(ch.Layout)
+	sn.metrics.layoutChanges.Inc()
+	sn.log.Info("帧结构变更生效", "slots", ch.Layout.TotalSlots, "slot_duration", ch.Layout.SlotDuration, "moved", len(moves))
+	sn.recordSchedule()
+}
+
+// 是否有已到生效时刻的帧结构变更
+// 生效时刻之后Current已按新帧结构计算，调度表须同时切换，否则迁移了时隙的节点会被判为冒用
+func (sn *SatelliteNode) layoutDue() bool {
+	ch, ok := sn.scheduler.PendingChange()
+	return ok && !sn.clock.Now().Before(ch.Layout.Epoch)
+}
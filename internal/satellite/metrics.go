@@ -0,0 +1,90 @@
+package satellite
+
+import (
+	"tdma-network/internal/metrics"
+	"tdma-network/internal/session"
+)
+
+// 卫星节点指标
+type satelliteMetrics struct {
+	registry *metrics.Registry
+
+	framesReceived *metrics.CounterVec // 按地面站
+	framesSent     *metrics.CounterVec // 按地面站
+	crcFailures    *metrics.Counter
+	slotMismatches *metrics.Counter
+	staleFrames    *metrics.CounterVec // 按原因
+	allocFailures  *metrics.Counter
+	invisibleDrops *metrics.Counter
+	authFailures   *metrics.CounterVec // 按原因
+	tlsFailures    *metrics.Counter
+	aclDenials     *metrics.CounterVec // 按原因
+
+	violations      *metrics.CounterVec // 按违规类型
+	quarantines     *metrics.Counter
+	quarantineDrops *metrics.Counter
+
+	layoutChanges *metrics.Counter
+
+	islFrames   *metrics.CounterVec // 按邻居卫星
+	islRejected *metrics.CounterVec // 按原因
+	islDropped  *metrics.CounterVec // 按原因
+	forwarded   *metrics.Counter
+	unreachable *metrics.Counter
+
+	sessions *metrics.GaugeVec
+	islPeers *metrics.Gauge
+}
+
+// 创建并注册卫星节点指标
+func newSatelliteMetrics(sn *SatelliteNode) *satelliteMetrics {
+	r := metrics.NewRegistry()
+	m := &satelliteMetrics{
+		registry: r,
+
+		framesReceived: r.CounterVec("tdma_frames_received_total", "通过校验的上行帧数", "node_id"),
+		framesSent:     r.CounterVec("tdma_frames_sent_total", "发出的下行帧数", "node_id"),
+		crcFailures:    r.Counter("tdma_crc_failures_total", "校验失败的帧数"),
+		slotMismatches: r.Counter("tdma_slot_mismatches_total", "不在所属时隙内到达的数据帧数"),
+		staleFrames:    r.CounterVec("tdma_stale_frames_total", "绝对时隙号未标注、超前或过期而丢弃的帧数", "reason"),
+		allocFailures:  r.Counter("tdma_slot_allocation_failures_total", "时隙分配失败次数"),
+		invisibleDrops: r.Counter("tdma_invisible_frames_dropped_total", "来自不可见地面站而丢弃的帧数"),
+		authFailures:   r.CounterVec("tdma_auth_failures_total", "认证失败而拒绝的帧数", "reason"),
+		tlsFailures:    r.Counter("tdma_tls_handshake_failures_total", "TLS握手失败的连接数"),
+		aclDenials:     r.CounterVec("tdma_acl_denials_total", "访问控制策略拒绝的请求数", "reason"),
+
+		violations:      r.CounterVec("tdma_security_violations_total", "安全违规次数", "kind"),
+		quarantines:     r.Counter("tdma_quarantines_total", "因多次违规被隔离的次数"),
+		quarantineDrops: r.Counter("tdma_quarantine_frames_dropped_total", "来自隔离中节点而丢弃的帧数"),
+
+		layoutChanges: r.Counter("tdma_layout_changes_total", "生效的帧结构变更次数"),
+
+		islFrames:   r.CounterVec("tdma_isl_frames_received_total", "星间链路收到的帧数", "peer"),
+		islRejected: r.CounterVec("tdma_isl_rejected_total", "拒绝的星间链路建链请求与地面站连接上的星间链路消息数", "reason"),
+		islDropped:  r.CounterVec("tdma_isl_dropped_total", "已建立的星间链路上丢弃的帧数", "reason"),
+		forwarded:   r.Counter("tdma_isl_frames_forwarded_total", "经星间链路转发的地面站数据帧数"),
+		unreachable: r.Counter("tdma_unreachable_total", "目的地面站不可达的数据帧数"),
+
+		sessions: r.GaugeVec("tdma_sessions", "各状态的地面站会话数", "state"),
+		islPeers: r.Gauge("tdma_isl_peers", "在线的星间链路数"),
+	}
+
+	r.GaugeFunc("tdma_slot_utilisation", "已分配时隙占总时隙的比例", func() float64 {
+		return float64(len(sn.scheduler.GetSchedule())) / float64(sn.scheduler.TotalSlots())
+	})
+	r.GaugeFunc("tdma_routes", "可达的卫星数（含本星）", func() float64 {
+		return float64(len(sn.routes.Routes()))
+	})
+
+	r.OnScrape(func() {
+		counts := map[string]int{session.STATE_ACTIVE: 0, session.STATE_DEGRADED: 0, session.STATE_DETACHED: 0}
+		for _, sess := range sn.sessions.List() {
+			counts[sess.State]++
+		}
+		for state, n := range counts {
+			m.sessions.With(state).Set(float64(n))
+		}
+		m.islPeers.Set(float64(len(sn.peerList())))
+	})
+	return m
+}
@@ -0,0 +1,1383 @@
+package satellite
+
+import (
+	"bufio"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"tdma-network/internal/acl"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/capture"
+	"tdma-network/internal/channel"
+	"tdma-network/internal/clock"
+	"tdma-network/internal/config"
+	"tdma-network/internal/logging"
+	"tdma-network/internal/metrics"
+	"tdma-network/internal/network"
+	"tdma-network/internal/orbit"
+	"tdma-network/internal/replay"
+	"tdma-network/internal/routing"
+	"tdma-network/internal/scheduler"
+	"tdma-network/internal/security"
+	"tdma-network/internal/session"
+	"tdma-network/internal/traffic"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 卫星节点
+type SatelliteNode struct {
+	nodeID    string
+	scheduler *scheduler.TDMAScheduler
+	network   *network.NetworkInterface
+	listener  net.Listener
+	running   bool
+	sessions  *session.Manager
+	clock     clock.Clock
+	auth      *auth.Authenticator // 节点认证，nil表示不要求认证
+	sequences *auth.Sequencer     // 不要求认证时未签名帧的防重放窗口，nil表示不检查（回放）
+
+	resumeTimeout time.Duration // 连接断开后会话保留时长
+	changeLead    time.Duration // 帧结构变更距通告的最短时长
+	layoutMu      sync.Mutex    // 串行化帧结构切换
+
+	// 重新加载配置时读取时隙配置，nil表示不支持热更新帧结构
+	schedulerConfig func() (scheduler.Config, error)
+
+	guard     *security.Guard // 安全违规统计与节点隔离
+	tlsServer *tls.Config     // 监听端口的TLS配置，nil表示明文TCP
+	tlsClient *tls.Config     // 主动建立星间链路的TLS配置
+	bindCert  bool            // 要求帧的节点ID与客户端证书主体一致
+
+	formerMu    sync.Mutex
+	formerSlots map[formerSlot]time.Time // 节点刚失去的时隙 -> 宽限期结束时刻
+
+	configMu    sync.RWMutex
+	channelFile string              // 信道配置文件，重新加载时读取
+	orbitFile   string              // 可见性配置文件，重新加载时读取
+	aclFile     string              // 访问控制策略文件，重新加载时读取
+	channels    *channel.FileConfig // 下行信道配置，nil表示理想链路
+	visibility  *orbit.Model        // 可见性模型，nil表示所有地面站始终可见
+	policy      *acl.Policy         // 访问控制策略，nil表示不限制
+
+	linksMu sync.Mutex
+	links   map[string]*channel.Channel // 节点ID -> 下行信道
+
+	inView map[string]bool // 地面站上一次检查时的可见状态
+
+	peerEntries []peerEntry // 配置的星间链路邻居
+	peersMu     sync.Mutex
+	peers       map[string]*peer // 卫星ID -> 在线星间链路
+	routes      *routing.Table   // 星座链路状态路由
+	advertiseMu sync.Mutex
+	stationsMu  sync.Mutex
+	stations    map[string]net.Conn // 接入本星的地面站 -> 连接
+
+	traffic  *traffic.Receiver // 地面站上行业务统计
+	metrics  *satelliteMetrics
+	activity *slotActivity    // 各时隙上行数据统计，供仪表盘显示
+	errors   *logging.Ring    // 最近的警告与错误日志，供仪表盘显示
+	capture  *capture.Writer  // 收发帧抓包，nil表示不抓包
+	record   *replay.Recorder // 回放日志，nil表示不记录
+
+	log    *slog.Logger
+	islLog *slog.Logger
+}
+
+// 卫星节点配置，由命令行、环境变量与配置文件确定
+type satelliteConfig struct {
+	NodeID        string
+	Port          int
+	Scheduler     scheduler.Config
+	ResumeTimeout time.Duration // 连接断开后会话保留时长
+	ChangeLead    time.Duration // 帧结构变更距通告的最短时长，留给地面站接收通告
+}
+
+// 默认配置，回放早期的日志时使用
+func defaultSatelliteConfig(nodeID string) satelliteConfig {
+	return satelliteConfig{NodeID: nodeID, Scheduler: scheduler.DefaultConfig(), ResumeTimeout: 30 * time.Second, ChangeLead: 5 * time.Second}
+}
+
+// 检查配置是否有效，port为false时不检查端口（回放）
+func (c satelliteConfig) validate(port bool) error {
+	if c.NodeID == "" || len(c.NodeID) > len(protocol.TDMAFrame{}.NodeID) {
+		return fmt.Errorf("节点ID %q 长度应为 1-%d 字节", c.NodeID, len(protocol.TDMAFrame{}.NodeID))
+	}
+	if port && (c.Port < 1 || c.Port > 65535) {
+		return fmt.Errorf("端口 %d 超出范围 1-65535", c.Port)
+	}
+	if err := c.Scheduler.Validate(); err != nil {
+		return err
+	}
+	if c.ResumeTimeout <= 0 {
+		return fmt.Errorf("会话保留时长须大于0")
+	}
+	if c.ChangeLead <= 0 {
+		return fmt.Errorf("帧结构变更提前时长须大于0")
+	}
+	return nil
+}
+
+// 按配置创建卫星节点，配置须已通过检查
+func NewSatelliteNode(cfg satelliteConfig) *SatelliteNode {
+	nodeID := cfg.NodeID
+	errors := logging.NewRing(32)
+	sn := &SatelliteNode{
+		nodeID:        nodeID,
+		scheduler:     scheduler.NewTDMAScheduler(cfg.Scheduler.TotalSlots, cfg.Scheduler.SlotDuration),
+		resumeTimeout: cfg.ResumeTimeout,
+		changeLead:    cfg.ChangeLead,
+		network:       network.NewNetworkInterface(),
+		sessions:      session.NewManager(cfg.ResumeTimeout),
+		clock:         clock.Real{},
+		guard:         security.NewGuard(security.DefaultPolicy, clock.Real{}),
+		links:         make(map[string]*channel.Channel),
+		inView:        make(map[string]bool),
+		peers:         make(map[string]*peer),
+		routes:        routing.NewTable(nodeID),
+		stations:      make(map[string]net.Conn),
+		formerSlots:   make(map[formerSlot]time.Time),
+		traffic:       traffic.NewReceiver(),
+		activity:      newSlotActivity(cfg.Scheduler.TotalSlots),
+		errors:        errors,
+
+		log:    slog.New(errors.Wrap(logging.For("satellite").Handler(), slog.LevelWarn)).With(logging.KEY_NODE_ID, nodeID),
+		islLog: slog.New(errors.Wrap(logging.For("isl").Handler(), slog.LevelWarn)).With(logging.KEY_NODE_ID, nodeID),
+	}
+
+	sn.metrics = newSatelliteMetrics(sn)
+
+	// 心跳续约时隙租约，租约在判定节点失效前不会过期
+	if err := sn.sessions.SetLiveness(protocol.HEARTBEAT_INTERVAL, protocol.HEARTBEAT_MISS_LIMIT); err != nil {
+		panic(err) // 心跳参数为协议常量
+	}
+	sn.scheduler.SetLeaseDuration(protocol.HEARTBEAT_INTERVAL * (protocol.HEARTBEAT_MISS_LIMIT + 1))
+	// 会话事件按事件时刻标注绝对时隙号
+	sn.sessions.SetSlotClock(sn.absSlot)
+
+	return sn
+}
+
+// 设置时钟，回放时使用虚拟时钟
+func (sn *SatelliteNode) SetClock(c clock.Clock) {
+	sn.clock = c
+	sn.scheduler.SetClock(c)
+	sn.sessions.SetClock(c)
+	sn.guard.SetClock(c)
+}
+
+// 启动卫星节点
+func (sn *SatelliteNode) Start(port int) error {
+	// 启动调度器
+	started := sn.clock.Now()
+	err := sn.scheduler.Start()
+	if err != nil {
+		return fmt.Errorf("启动调度器失败: %v", err)
+	}
+	cfg := sn.scheduler.Config()
+	sn.record.Start(sn.nodeID, started, cfg.TotalSlots, cfg.SlotDuration, sn.resumeTimeout)
+
+	// 启动网络监听
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("启动监听失败: %v", err)
+	}
+	if sn.tlsServer != nil {
+		listener = tls.NewListener(listener, sn.tlsServer)
+	}
+	sn.listener = listener
+	sn.running = true
+
+	sn.log.Info("卫星节点启动", "port", port)
+
+	// 启动接收循环
+	go sn.receiveLoop()
+
+	// 启动调度状态记录
+	go sn.statusLoop()
+
+	// 启动可见性检测
+	if sn.aclFile != "" {
+		sn.scheduler.SetAdmission(sn.admit)
+	}
+	if sn.visibility != nil {
+		sn.scheduler.SetVisibility(sn.isVisible)
+		go sn.visibilityLoop()
+	}
+
+	// 启动心跳检测与会话清理
+	go sn.livenessLoop()
+	go sn.eventLoop(sn.sessions.Subscribe())
+
+	// 启动星间链路
+	go sn.islLoop()
+	for _, entry := range sn.peerEntries {
+		go sn.dialLoop(entry)
+	}
+
+	return nil
+}
+
+// 停止卫星节点
+func (sn *SatelliteNode) Stop() error {
+	sn.running = false
+
+	if sn.listener != nil {
+		sn.listener.Close()
+	}
+
+	sn.scheduler.Stop()
+	sn.network.Disconnect()
+	sn.capture.Close()
+	sn.record.Close()
+
+	sn.log.Info("卫星节点已停止")
+	return nil
+}
+
+// 接收循环
+func (sn *SatelliteNode) receiveLoop() {
+	for sn.running {
+		conn, err := sn.listener.Accept()
+		if err != nil {
+			if sn.running {
+				sn.log.Warn("接受连接失败", "err", err)
+			}
+			continue
+		}
+
+		// 为每个连接启动一个处理协程
+		go sn.handleConnection(conn)
+	}
+}
+
+// 处理连接
+func (sn *SatelliteNode) handleConnection(conn net.Conn) {
+	// TLS连接先完成握手，取出客户端证书绑定的节点ID
+	var certNode string
+	if tc, ok := conn.(*tls.Conn); ok {
+		node, err := auth.AcceptTLS(tc)
+		if err != nil {
+			sn.log.Warn("接受连接失败", "addr", conn.RemoteAddr().String(), "err", err)
+			sn.metrics.tlsFailures.Inc()
+			conn.Close()
+			return
+		}
+		certNode = node
+	}
+
+	// 星间链路不经过地面站下行信道
+	raw := conn
+
+	// 下行方向经过信道损伤，链路参数在识别出节点后确定
+	var downlink *channel.Channel
+	if channels := sn.channelConfig(); channels != nil {
+		downlink = channel.New(channels.Default)
+		conn = channel.NewConn(conn, downlink)
+	}
+	conn = &lockedConn{Conn: conn}
+	defer conn.Close()
+
+	sn.log.Info("接受连接", "addr", conn.RemoteAddr().String())
+
+	// 模拟网络接口连接
+	sn.network.Connect(conn.RemoteAddr().String())
+
+	// 记录该连接上出现过的节点，断开时挂起其会话
+	nodes := make(map[string]bool)
+	defer sn.closeConnection(conn, nodes)
+
+	for sn.running {
+		frame, err := protocol.ReadFrame(conn)
+		if err != nil {
+			if err == io.EOF {
+				sn.log.Info("连接已关闭", "addr", conn.RemoteAddr().String())
+			} else {
+				sn.log.Warn("读取帧失败", "addr", conn.RemoteAddr(), "err", err)
+			}
+			break
+		}
+		sn.capture.Frame(capture.DIR_INBOUND, conn, frame.GetNodeID(), frame)
+		if sn.bindCert {
+			if err := auth.MatchCertNode(certNode, frame.GetNodeID()); err != nil {
+				sn.authFailure(conn, frame.GetNodeID(), err)
+				continue
+			}
+		}
+		// 其他卫星发起的星间链路，只能是连接上的第一帧
+		if strings.HasPrefix(string(frame.Data), protocol.MSG_ISL_PREFIX) {
+			if len(nodes) == 0 {
+				sn.acceptPeer(raw, frame, certNode)
+				return
+			}
+			sn.log.Warn("地面站连接上的星间链路消息，丢弃帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID())...)
+			sn.metrics.islRejected.With(islRejectStation).Inc()
+			continue
+		}
+		sn.receiveFrame(frame, conn, nodes, downlink)
+		sn.recordSchedule()
+	}
+}
+
+// 处理地面站连接上收到的一帧
+// nodes为该连接上通过认证的节点，downlink为该连接的下行信道（nil表示理想链路）
+func (sn *SatelliteNode) receiveFrame(frame *protocol.TDMAFrame, conn net.Conn, nodes map[string]bool, downlink *channel.Channel) {
+	// 不在可见窗口内的地面站没有无线链路，丢弃其帧
+	if !sn.isVisible(frame.GetNodeID()) {
+		sn.log.Debug("地面站不可见，丢弃帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID())...)
+		sn.metrics.invisibleDrops.Inc()
+		return
+	}
+	sn.log.Debug("接收帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID())...)
+	// 验证帧
+	if err := frame.Validate(); err != nil {
+		sn.log.Warn("帧验证失败", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID(), "err", err)...)
+		sn.metrics.crcFailures.Inc()
+		return
+	}
+	// 隔离中的节点的帧一律丢弃，包括入网与认证握手
+	if until, ok := sn.guard.Quarantined(frame.GetNodeID()); ok {
+		sn.log.Debug("节点处于隔离中，丢弃帧", logging.FrameArgs(frame, logging.KEY_PEER, frame.GetNodeID(), "until", until)...)
+		sn.metrics.quarantineDrops.Inc()
+		return
+	}
+	if !sn.authenticate(frame, conn) {
+		return
+	}
+	// 回放日志记录通过认证后的帧，回放时不需要密钥
+	sn.record.Recv(conn, frame)
+
+	if nodeID := frame.GetNodeID(); !nodes[nodeID] {
+		nodes[nodeID] = true
+		sn.attachStation(nodeID, conn)
+		if downlink != nil {
+			cfg := sn.channelConfig().Link(nodeID)
+			downlink.SetConfig(cfg)
+			if link, ok := sn.orbitModel().Link(sn.nodeID, nodeID, cfg.CarrierHz); ok && cfg.Geometry {
+				downlink.SetPropagation(link)
+			}
+			sn.linksMu.Lock()
+			sn.links[nodeID] = downlink
+			sn.linksMu.Unlock()
+		}
+	}
+	// 处理帧
+	sn.processFrame(frame, conn)
+}
+
+// 连接断开，挂起该连接上各节点的会话
+func (sn *SatelliteNode) closeConnection(conn net.Conn, nodes map[string]bool) {
+	for nodeID := range nodes {
+		sn.detachStation(nodeID, conn)
+		if sn.sessions.Detach(nodeID, conn.RemoteAddr().String()) {
+			sn.log.Info("连接断开，会话保留等待恢复", logging.KEY_PEER, nodeID, "timeout", sn.resumeTimeout)
+		}
+	}
+	sn.record.Closed(conn)
+}
+
+// 处理TDMA帧
+func (sn *SatelliteNode) processFrame(frame *protocol.TDMAFrame, conn net.Conn) {
+	nodeID := frame.GetNodeID()
+	sn.metrics.framesReceived.With(nodeID).Inc()
+	data := string(frame.Data)
+	// 定时器触发前收到生效时刻之后的帧时立即切换帧结构
+	if sn.layoutDue() {
+		sn.applyLayout()
+	}
+	cur := sn.scheduler.Current()
+	// 未标注、超前或迟到一个超帧以上的帧视为伪造或重放，不刷新会话
+	if reason := absSlotReason(frame.AbsSlot, cur, sn.scheduler.Config().TotalSlots); reason != "" {
+		sn.staleFrame(frame, nodeID, cur, reason)
+		return
+	}
+	sn.sessions.Touch(nodeID)
+
+	switch {
+	case data == protocol.MSG_GET_CURRENT_SLOT:
+		// 检查是否为获取时隙请求
+		currentSlot := sn.globalSlot()
+		// 发送当前时隙响应
+		sn.reply(conn, nodeID, currentSlot, fmt.Sprintf("%s%d", protocol.MSG_CURRENT_SLOT, currentSlot))
+		return
+
+	case data == protocol.MSG_JOIN:
+		sn.handleJoin(nodeID, conn)
+		return
+
+	case strings.HasPrefix(data, protocol.MSG_RESUME):
+		sn.handleResume(nodeID, strings.TrimPrefix(data, protocol.MSG_RESUME), conn)
+		return
+
+	case data == protocol.MSG_HEARTBEAT:
+		sn.handleHeartbeat(nodeID, conn)
+		return
+
+	case data == protocol.MSG_LEAVE:
+		sn.handleLeave(nodeID)
+		return
+	}
+
+	// 用全局统一时钟判断slotID
+	if int(frame.SlotID) != cur.Slot {
+		sn.log.Warn("时隙不匹配", logging.FrameArgs(frame, logging.KEY_PEER, nodeID, "expected", cur.Slot)...)
+		sn.metrics.slotMismatches.Inc()
+		return
+	}
+	// 时隙号相同但属于之前超帧的数据帧同样过期，数据帧不适用控制帧的超前容差
+	if abs := int64(frame.AbsSlot); abs != cur.Abs {
+		reason := staleExpired
+		if abs > cur.Abs {
+			reason = staleFuture
+		}
+		sn.staleFrame(frame, nodeID, cur, reason)
+		return
+	}
+	// 数据帧只能在本节点持有的时隙内发送，写入空闲时隙或其他节点的时隙都视为冒用
+	// 时隙只经入网、会话恢复与心跳分配，这里只为持有者续约
+	if owner := sn.scheduler.GetSchedule()[int(frame.SlotID)]; owner != nodeID {
+		// 时隙刚被释放或改派，地面站收到通知前仍按原时隙发送，不计违规
+		if sn.formerSlot(nodeID, int(frame.SlotID)) {
+			sn.log.Info("节点按刚失去的时隙发送，丢弃帧", logging.FrameArgs(frame, logging.KEY_PEER, nodeID, "owner", owner)...)
+			return
+		}
+		detail := fmt.Sprintf("时隙 %d 空闲", frame.SlotID)
+		if owner != "" {
+			detail = fmt.Sprintf("时隙 %d 属于 %s", frame.SlotID, owner)
+		}
+		sn.violation(conn, nodeID, int(frame.SlotID), security.VIOLATION_SLOT_SPOOF, detail)
+		return
+	}
+	sn.activity.mark(cur.Abs)
+	slotID, err := sn.allocateForNode(nodeID)
+	if err != nil {
+		if sn.deny(conn, nodeID, err) {
+			return
+		}
+		sn.log.Warn("分配时隙失败", logging.KEY_PEER, nodeID, "err", err)
+		sn.metrics.allocFailures.Inc()
+		return
+	}
+	sn.log.Debug("确认时隙", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	sn.reply(conn, nodeID, slotID, fmt.Sprintf("%s%d", protocol.MSG_ACK_SLOT, slotID))
+
+	if strings.HasPrefix(data, protocol.MSG_DATA_TO) {
+		sn.handleDataTo(nodeID, data, conn)
+	} else if m, ok := traffic.Decode(frame.Data); ok {
+		sn.traffic.Observe(m, sn.clock.Now())
+	}
+}
+
+// 绝对时隙号检查不通过的原因，作为 tdma_stale_frames_total 的 reason 标签
+const (
+	staleMissing = "missing" // 未标注绝对时隙号
+	staleFuture  = "future"  // 控制帧超前当前时隙一个以上，或数据帧晚于当前时隙
+	staleExpired = "expired" // 早于当前时隙一个超帧以上，或数据帧不在当前时隙
+)
+
+// 控制帧允许超前当前时隙的时隙数，容忍时隙边界附近地面站与卫星的时钟偏差
+const absSlotTolerance = 1
+
+// 检查帧标注的绝对时隙号，返回丢弃的原因，有效时返回空串
+// 控制帧不受时隙限制，只要求超前不超过absSlotTolerance且不早于当前时隙一个超帧以上
+func absSlotReason(abs uint64, cur scheduler.SlotTime, totalSlots int) string {
+	switch {
+	case abs == 0:
+		return staleMissing
+	case abs > math.MaxInt64 || int64(abs) > cur.Abs+absSlotTolerance:
+		return staleFuture
+	case cur.Abs-int64(abs) >= int64(totalSlots):
+		return staleExpired
+	}
+	return ""
+}
+
+// 丢弃绝对时隙号无效的帧
+func (sn *SatelliteNode) staleFrame(frame *protocol.TDMAFrame, nodeID string, cur scheduler.SlotTime, reason string) {
+	sn.log.Warn("丢弃绝对时隙号无效的帧", logging.FrameArgs(frame, logging.KEY_PEER, nodeID, "reason", reason, "expected_abs_slot", cur.Abs)...)
+	sn.metrics.staleFrames.With(reason).Inc()
+}
+
+// 当前全局时隙
+func (sn *SatelliteNode) globalSlot() int {
+	return sn.scheduler.Current().Slot
+}
+
+// 处理入网请求
+func (sn *SatelliteNode) handleJoin(nodeID string, conn net.Conn) {
+	if err := sn.accessPolicy().Check(nodeID, sn.clock.Now()); err != nil {
+		sn.deny(conn, nodeID, err)
+		return
+	}
+	slotID, err := sn.scheduler.AllocateTimeSlot(nodeID, 1)
+	if err != nil {
+		if sn.deny(conn, nodeID, err) {
+			return
+		}
+		sn.log.Warn("入网分配时隙失败", logging.KEY_PEER, nodeID, "err", err)
+		sn.metrics.allocFailures.Inc()
+		return
+	}
+	sess, err := sn.sessions.Create(nodeID, slotID, conn.RemoteAddr().String())
+	if err != nil {
+		sn.log.Warn("创建会话失败", logging.KEY_PEER, nodeID, "err", err)
+		return
+	}
+	sn.log.Info("地面站入网", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	sn.reply(conn, nodeID, slotID, fmt.Sprintf("%s%d_%s", protocol.MSG_JOIN_ACK, slotID, sess.Token))
+	sn.announcePending(conn, nodeID)
+}
+
+// 处理会话恢复请求
+func (sn *SatelliteNode) handleResume(nodeID, token string, conn net.Conn) {
+	sess, err := sn.sessions.Resume(token, nodeID, conn.RemoteAddr().String())
+	if err != nil {
+		sn.log.Warn("会话恢复失败", logging.KEY_PEER, nodeID, "err", err)
+		sn.reply(conn, nodeID, 0, protocol.MSG_RESUME_REJECT)
+		return
+	}
+
+	slotID, err := sn.allocateForNode(nodeID)
+	if err != nil {
+		if sn.deny(conn, nodeID, err) {
+			return
+		}
+		sn.log.Warn("恢复时隙失败", logging.KEY_PEER, nodeID, "err", err)
+		sn.reply(conn, nodeID, 0, protocol.MSG_RESUME_REJECT)
+		return
+	}
+	if slotID != sess.SlotID {
+		sn.log.Info("原时隙已失效，重新分配", logging.KEY_PEER, nodeID, "old_slot_id", sess.SlotID, logging.KEY_SLOT_ID, slotID)
+	}
+	sn.log.Info("会话恢复", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	sn.reply(conn, nodeID, slotID, fmt.Sprintf("%s%d", protocol.MSG_RESUME_ACK, slotID))
+	sn.announcePending(conn, nodeID)
+}
+
+// 为节点续约或分配时隙，已有会话的节点优先保持原时隙
+// 不再满足访问控制策略（如时间窗口结束）的节点不能续约
+func (sn *SatelliteNode) allocateForNode(nodeID string) (int, error) {
+	if err := sn.accessPolicy().Check(nodeID, sn.clock.Now()); err != nil {
+		return -1, err
+	}
+	if sess, ok := sn.sessions.Get(nodeID); ok {
+		if err := sn.scheduler.RenewTimeSlot(sess.SlotID, nodeID); err == nil {
+			return sess.SlotID, nil
+		}
+	}
+
+	slotID, err := sn.scheduler.AllocateTimeSlot(nodeID, 1)
+	if err != nil {
+		return -1, err
+	}
+	sn.sessions.SetSlot(nodeID, slotID)
+	return slotID, nil
+}
+
+// 发送响应帧，节点有认证会话时附加认证尾部
+func (sn *SatelliteNode) reply(conn net.Conn, nodeID string, slotID int, data string) {
+	respFrame := sn.newFrame(slotID, data)
+	sn.record.Send(conn, nodeID, respFrame)
+	sn.send(conn, nodeID, respFrame, sn.auth.Session(nodeID))
+}
+
+// 构造发往地面站的帧，标注发送时的绝对时隙号
+func (sn *SatelliteNode) newFrame(slotID int, data string) *protocol.TDMAFrame {
+	frame := protocol.NewTDMAFrame(uint32(slotID), sn.nodeID, []byte(data))
+	frame.SetAbsSlot(uint64(sn.scheduler.Current().Abs))
+	return frame
+}
+
+// 写出一帧，as非nil时以该认证会话签名
+func (sn *SatelliteNode) send(conn net.Conn, nodeID string, frame *protocol.TDMAFrame, as *auth.Session) {
+	write := func(f *protocol.TDMAFrame) error {
+		sn.capture.Frame(capture.DIR_OUTBOUND, conn, nodeID, f)
+		return protocol.WriteFrame(conn, f)
+	}
+	var err error
+	if as != nil {
+		err = as.Send(frame, write)
+	} else {
+		err = write(frame)
+	}
+	if err != nil {
+		sn.log.Warn("发送响应帧失败", logging.FrameArgs(frame, logging.KEY_PEER, nodeID, "err", err)...)
+		return
+	}
+	sn.metrics.framesSent.With(nodeID).Inc()
+	sn.log.Debug("发送帧", logging.FrameArgs(frame, logging.KEY_PEER, nodeID)...)
+}
+
+// 认证收到的帧，返回是否继续处理
+// 通过认证的帧去掉认证尾部；入网握手完成时帧替换为入网请求，之后按未认证时的流程处理
+func (sn *SatelliteNode) authenticate(frame *protocol.TDMAFrame, conn net.Conn) bool {
+	if sn.auth == nil {
+		return sn.checkSequence(frame, conn)
+	}
+	nodeID := frame.GetNodeID()
+	remote := conn.RemoteAddr().String()
+
+	// 握手消息在建立认证会话前发出，带明文序号尾部，由挑战响应防重放
+	if frame.IsSequenced() {
+		frame.TakeSequence()
+	}
+
+	if !frame.IsAuthenticated() {
+		switch data := string(frame.Data); {
+		case data == protocol.MSG_JOIN:
+			challenge, err := sn.auth.Challenge(nodeID, remote)
+			if err != nil {
+				sn.authFailure(conn, nodeID, err)
+				return false
+			}
+			sn.log.Debug("发起认证挑战", logging.KEY_PEER, nodeID)
+			sn.send(conn, nodeID, sn.newFrame(0, challenge), nil)
+			return false
+
+		case strings.HasPrefix(data, protocol.MSG_AUTH_RESPONSE):
+			if err := sn.auth.Complete(nodeID, remote, strings.TrimPrefix(data, protocol.MSG_AUTH_RESPONSE)); err != nil {
+				sn.authFailure(conn, nodeID, err)
+				return false
+			}
+			sn.log.Info("节点认证成功", logging.KEY_PEER, nodeID)
+			frame.Data = []byte(protocol.MSG_JOIN)
+			frame.Length = uint32(len(frame.Data))
+			frame.CRC = frame.CalculateCRC()
+			return true
+		}
+	}
+
+	if err := sn.auth.Open(nodeID, frame); err != nil {
+		sn.authFailure(conn, nodeID, err)
+		return false
+	}
+	return true
+}
+
+// 不要求认证时校验未签名帧的序号尾部并去掉尾部，返回是否继续处理
+// 回放日志记录去掉尾部后的帧，回放时不检查，重放违规另行记录
+func (sn *SatelliteNode) checkSequence(frame *protocol.TDMAFrame, conn net.Conn) bool {
+	if sn.sequences == nil {
+		if frame.IsSequenced() {
+			frame.TakeSequence()
+		}
+		return true
+	}
+	if err := sn.sequences.Open(frame.GetNodeID(), frame); err != nil {
+		sn.authFailure(conn, frame.GetNodeID(), err)
+		return false
+	}
+	return true
+}
+
+// 记录认证失败；握手失败与缺少会话时回复拒绝原因，地面站据此重新入网
+// 其余失败（未签名、标签错误、重放）静默丢弃
+func (sn *SatelliteNode) authFailure(conn net.Conn, nodeID string, err error) {
+	reason := auth.Reason(err)
+	if reason == "" {
+		sn.log.Warn("认证处理失败", logging.KEY_PEER, nodeID, "err", err)
+		return
+	}
+	sn.metrics.authFailures.With(reason).Inc()
+	sn.log.Warn("认证失败，拒绝帧", logging.KEY_PEER, nodeID, "addr", conn.RemoteAddr().String(), "reason", reason, "err", err)
+
+	switch reason {
+	case auth.REASON_UNKNOWN_NODE, auth.REASON_NO_CHALLENGE, auth.REASON_BAD_RESPONSE, auth.REASON_NO_SESSION, auth.REASON_CERT_MISMATCH:
+		sn.send(conn, nodeID, sn.newFrame(0, protocol.MSG_AUTH_REJECT+reason), nil)
+
+	case auth.REASON_REPLAY:
+		// 被拒绝的帧不进入回放日志，单独记录违规以便回放时重现隔离
+		sn.record.Violation(conn, nodeID, security.VIOLATION_REPLAY)
+		sn.violation(conn, nodeID, -1, security.VIOLATION_REPLAY, err.Error())
+	}
+}
+
+// 记录一次安全违规并发布安全事件
+// 只有来自节点当前连接的违规累计到隔离阈值，其他连接上的违规（如截获后在另一连接上重放）
+// 只记录事件，避免攻击者借此隔离正常节点
+func (sn *SatelliteNode) violation(conn net.Conn, nodeID string, slotID int, kind, detail string) {
+	sn.metrics.violations.With(kind).Inc()
+	sn.log.Warn("安全违规", logging.KEY_PEER, nodeID, "kind", kind, "addr", conn.RemoteAddr().String(), "detail", detail)
+	sn.sessions.Publish(session.Event{Type: session.EVENT_SECURITY, NodeID: nodeID, SlotID: slotID, Time: sn.clock.Now(), Detail: kind + ": " + detail})
+
+	if own, ok := sn.stationConn(nodeID); !ok || own != conn {
+		return
+	}
+	if sn.guard.Violation(nodeID, kind) {
+		sn.quarantine(conn, nodeID)
+	}
+}
+
+// 隔离节点：回复 JOIN_REJECT_quarantined，删除其会话与认证会话并释放时隙
+// 隔离期间节点的帧一律丢弃，隔离结束后节点可以重新入网
+func (sn *SatelliteNode) quarantine(conn net.Conn, nodeID string) {
+	until, _ := sn.guard.Quarantined(nodeID)
+	sn.metrics.quarantines.Inc()
+	sn.log.Warn("隔离节点", logging.KEY_PEER, nodeID, "until", until)
+	sn.reply(conn, nodeID, 0, protocol.MSG_JOIN_REJECT+security.REASON_QUARANTINED)
+
+	slotID := -1
+	if sess, ok := sn.sessions.Remove(nodeID); ok {
+		slotID = sess.SlotID
+	}
+	sn.auth.Remove(nodeID)
+	sn.releaseNodeSlots(nodeID)
+	sn.sessions.Publish(session.Event{Type: session.EVENT_QUARANTINED, NodeID: nodeID, SlotID: slotID, Time: sn.clock.Now(), Detail: "至 " + until.Format(time.RFC3339)})
+	sn.recordSchedule()
+}
+
+// 访问控制策略拒绝时回复 JOIN_REJECT_<原因> 并返回true，err不是访问被拒绝时返回false
+// 节点不再被允许接入时释放其已持有的时隙
+func (sn *SatelliteNode) deny(conn net.Conn, nodeID string, err error) bool {
+	reason := acl.Reason(err)
+	if reason == "" {
+		return false
+	}
+	sn.metrics.aclDenials.With(reason).Inc()
+	sn.log.Warn("访问被拒绝", logging.KEY_PEER, nodeID, "reason", reason, "err", err)
+	if reason == acl.REASON_NOT_ALLOWED || reason == acl.REASON_OUTSIDE_WINDOW {
+		sn.releaseNodeSlots(nodeID)
+	}
+	sn.reply(conn, nodeID, 0, protocol.MSG_JOIN_REJECT+reason)
+	return true
+}
+
+// 处理心跳，续约节点时隙
+func (sn *SatelliteNode) handleHeartbeat(nodeID string, conn net.Conn) {
+	if _, ok := sn.sessions.Heartbeat(nodeID); !ok {
+		sn.log.Info("心跳没有有效会话", logging.KEY_PEER, nodeID)
+		sn.reply(conn, nodeID, 0, protocol.MSG_HEARTBEAT_REJECT)
+		return
+	}
+
+	slotID, err := sn.allocateForNode(nodeID)
+	if err != nil {
+		if sn.deny(conn, nodeID, err) {
+			return
+		}
+		sn.log.Warn("续约时隙失败", logging.KEY_PEER, nodeID, "err", err)
+		return
+	}
+	// 附带发送时刻，地面站据此测量传播时延并调整定时提前量
+	sn.reply(conn, nodeID, slotID, fmt.Sprintf("%s%d_%d", protocol.MSG_HEARTBEAT_ACK, slotID, sn.clock.Now().UnixNano()))
+}
+
+// 处理离网请求（如地面站切换到其他卫星），立即释放其时隙
+func (sn *SatelliteNode) handleLeave(nodeID string) {
+	slotID := -1
+	if sess, ok := sn.sessions.Remove(nodeID); ok {
+		slotID = sess.SlotID
+	}
+	sn.auth.Remove(nodeID)
+	sn.releaseNodeSlots(nodeID)
+	sn.sessions.Publish(session.Event{Type: session.EVENT_LEFT, NodeID: nodeID, SlotID: slotID, Time: sn.clock.Now()})
+}
+
+// 心跳检测循环，释放失效节点与恢复超时会话的时隙
+func (sn *SatelliteNode) livenessLoop() {
+	ticker := time.NewTicker(protocol.HEARTBEAT_INTERVAL / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !sn.running {
+			return
+		}
+		sn.checkLiveness()
+	}
+}
+
+// 释放失效节点与恢复超时会话的时隙
+func (sn *SatelliteNode) checkLiveness() {
+	for _, sess := range sn.sessions.CheckLiveness() {
+		sn.auth.Remove(sess.NodeID)
+		sn.releaseNodeSlots(sess.NodeID)
+	}
+	for _, sess := range sn.sessions.Expire() {
+		sn.auth.Remove(sess.NodeID)
+		sn.releaseNodeSlots(sess.NodeID)
+	}
+	sn.recordSchedule()
+}
+
+// 调度表变化时写入回放日志
+func (sn *SatelliteNode) recordSchedule() {
+	if sn.record != nil {
+		sn.record.Schedule(sn.scheduler.GetSchedule())
+	}
+}
+
+// 会话事件循环
+func (sn *SatelliteNode) eventLoop(events <-chan session.Event) {
+	for e := range events {
+		sn.handleEvent(e)
+	}
+}
+
+// 处理会话事件
+func (sn *SatelliteNode) handleEvent(e session.Event) {
+	sn.log.Info("会话事件", "event", e.Type, logging.KEY_PEER, e.NodeID, logging.KEY_SLOT_ID, e.SlotID, logging.KEY_ABS_SLOT, e.AbsSlot, "detail", e.Detail)
+	sn.stationEvent(e)
+}
+
+// 时隙变更后的宽限期：地面站最迟在下一次心跳确认时获知新的时隙
+const slotChangeGrace = protocol.HEARTBEAT_INTERVAL
+
+// 节点刚失去的时隙
+type formerSlot struct {
+	nodeID string
+	slotID int
+}
+
+// 记录节点失去时隙，宽限期内该节点在原时隙发送的数据帧只丢弃不计违规
+// 只用于释放或改派后节点仍保留会话的情况，离网、踢出与隔离不给宽限期
+func (sn *SatelliteNode) slotLost(nodeID string, slotID int) {
+	if nodeID == "" {
+		return
+	}
+	now := sn.clock.Now()
+	sn.formerMu.Lock()
+	defer sn.formerMu.Unlock()
+	for k, until := range sn.formerSlots {
+		if !now.Before(until) {
+			delete(sn.formerSlots, k)
+		}
+	}
+	sn.formerSlots[formerSlot{nodeID, slotID}] = now.Add(slotChangeGrace)
+}
+
+// 节点是否处于失去时隙slotID后的宽限期内
+func (sn *SatelliteNode) formerSlot(nodeID string, slotID int) bool {
+	sn.formerMu.Lock()
+	defer sn.formerMu.Unlock()
+	until, ok := sn.formerSlots[formerSlot{nodeID, slotID}]
+	return ok && sn.clock.Now().Before(until)
+}
+
+// 释放节点持有的全部时隙
+func (sn *SatelliteNode) releaseNodeSlots(nodeID string) {
+	for slotID, owner := range sn.scheduler.GetSchedule() {
+		if owner != nodeID {
+			continue
+		}
+		if err := sn.scheduler.ReleaseTimeSlot(slotID); err != nil {
+			sn.log.Warn("释放时隙失败", logging.KEY_SLOT_ID, slotID, "err", err)
+			continue
+		}
+		sn.log.Info("释放时隙", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	}
+}
+
+// 判断地面站当前是否可见
+func (sn *SatelliteNode) isVisible(nodeID string) bool {
+	return sn.orbitModel().Visible(sn.nodeID, nodeID, sn.clock.Now())
+}
+
+// 可见性检测循环，地面站出境(LOS)时回收其时隙
+func (sn *SatelliteNode) visibilityLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !sn.running {
+			return
+		}
+		sn.checkVisibility()
+	}
+}
+
+// 更新各地面站可见状态，回收出境地面站的时隙
+func (sn *SatelliteNode) checkVisibility() {
+	now := sn.clock.Now()
+	model := sn.orbitModel()
+	for nodeID := range model.Stations {
+		sn.updateInView(model, nodeID, now)
+	}
+	for nodeID := range model.Passes[sn.nodeID] {
+		sn.updateInView(model, nodeID, now)
+	}
+
+	for slotID, nodeID := range sn.scheduler.ReclaimInvisible() {
+		sn.slotLost(nodeID, slotID)
+		sn.log.Info("地面站出境，回收时隙", logging.KEY_PEER, nodeID, logging.KEY_SLOT_ID, slotID)
+	}
+	sn.recordSchedule()
+}
+
+// 更新地面站可见状态并发布AOS/LOS事件
+func (sn *SatelliteNode) updateInView(model *orbit.Model, nodeID string, now time.Time) {
+	if !model.Constrained(sn.nodeID, nodeID) {
+		return
+	}
+	visible := model.Visible(sn.nodeID, nodeID, now)
+	prev, known := sn.inView[nodeID]
+	sn.inView[nodeID] = visible
+	if known && prev == visible {
+		return
+	}
+
+	e := session.Event{Type: session.EVENT_LOS, NodeID: nodeID, SlotID: -1, Time: now}
+	if visible {
+		e.Type = session.EVENT_AOS
+	}
+	if sess, ok := sn.sessions.Get(nodeID); ok {
+		e.SlotID = sess.SlotID
+	}
+	if known || visible {
+		sn.sessions.Publish(e)
+	}
+}
+
+// 打印地面站下一次过境的时延曲线
+func (sn *SatelliteNode) printPass(nodeID string) {
+	model := sn.orbitModel()
+	link, ok := model.Link(sn.nodeID, nodeID, sn.channelConfig().Link(nodeID).CarrierHz)
+	if !ok {
+		fmt.Printf("未配置节点 %s 的轨道几何\n", nodeID)
+		return
+	}
+	p, ok := model.NextPass(sn.nodeID, nodeID, sn.clock.Now(), 24*time.Hour, 10*time.Second)
+	if !ok {
+		fmt.Println("24小时内没有过境")
+		return
+	}
+	orbit.PrintCurve(link.Curve(p, 30*time.Second))
+}
+
+// 当前下行信道配置
+func (sn *SatelliteNode) channelConfig() *channel.FileConfig {
+	sn.configMu.RLock()
+	defer sn.configMu.RUnlock()
+	return sn.channels
+}
+
+// 当前可见性模型
+func (sn *SatelliteNode) orbitModel() *orbit.Model {
+	sn.configMu.RLock()
+	defer sn.configMu.RUnlock()
+	return sn.visibility
+}
+
+// 当前访问控制策略
+func (sn *SatelliteNode) accessPolicy() *acl.Policy {
+	sn.configMu.RLock()
+	defer sn.configMu.RUnlock()
+	return sn.policy
+}
+
+// 调度器的准入检查，按当前访问控制策略
+func (sn *SatelliteNode) admit(nodeID string, priority, slots int, now time.Time) error {
+	if p := sn.accessPolicy(); p != nil {
+		return p.Admit(nodeID, priority, slots, now)
+	}
+	return nil
+}
+
+// 加载信道、可见性与访问控制配置文件
+func (sn *SatelliteNode) loadConfig() error {
+	var channels *channel.FileConfig
+	var visibility *orbit.Model
+	var policy *acl.Policy
+	var err error
+	if sn.channelFile != "" {
+		channels, err = channel.LoadConfig(sn.channelFile)
+		if err != nil {
+			return fmt.Errorf("加载信道配置失败: %v", err)
+		}
+	}
+	if sn.orbitFile != "" {
+		visibility, err = orbit.LoadModel(sn.orbitFile)
+		if err != nil {
+			return fmt.Errorf("加载可见性配置失败: %v", err)
+		}
+	}
+	if sn.aclFile != "" {
+		policy, err = acl.LoadPolicy(sn.aclFile)
+		if err != nil {
+			return fmt.Errorf("加载访问控制策略失败: %v", err)
+		}
+	}
+
+	sn.configMu.Lock()
+	sn.channels = channels
+	sn.visibility = visibility
+	sn.policy = policy
+	sn.configMu.Unlock()
+	return nil
+}
+
+// 重新加载启动时指定的配置文件，已建立的下行信道立即使用新的链路参数
+// 任一文件无效时保持原配置；时隙配置变化时计划帧结构变更，返回计划的变更
+func (sn *SatelliteNode) reloadConfig() (*scheduler.Change, error) {
+	if err := sn.loadConfig(); err != nil {
+		return nil, err
+	}
+
+	if channels := sn.channelConfig(); channels != nil {
+		sn.linksMu.Lock()
+		for nodeID, ch := range sn.links {
+			ch.SetConfig(channels.Link(nodeID))
+		}
+		sn.linksMu.Unlock()
+	}
+	sn.log.Info("配置已重新加载", "channel", sn.channelFile, "orbit", sn.orbitFile, "acl", sn.aclFile)
+
+	ch, err := sn.reloadLayout()
+	if err != nil {
+		return nil, fmt.Errorf("时隙配置无法生效: %v", err)
+	}
+	return ch, nil
+}
+
+// 打印下行信道统计
+func (sn *SatelliteNode) printLinkStatus() {
+	sn.linksMu.Lock()
+	defer sn.linksMu.Unlock()
+
+	if len(sn.links) == 0 {
+		return
+	}
+	fmt.Println("下行信道:")
+	for nodeID, ch := range sn.links {
+		st := ch.Stats()
+		fmt.Printf("  %s: 帧 %d, 丢失 %d, 误码帧 %d (比特 %d), 乱序 %d, 传播时延 %v, 多普勒 %.1fHz\n",
+			nodeID, st.Frames, st.Dropped, st.Corrupted, st.BitErrors, st.Reordered,
+			st.PropDelay.Round(time.Microsecond), st.DopplerHz)
+	}
+}
+
+// 状态循环，定期记录调度器状态（debug级别）
+func (sn *SatelliteNode) statusLoop() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sn.log.Debug("调度状态", logging.KEY_SLOT_ID, sn.scheduler.GetCurrentSlot(),
+			"assigned", len(sn.scheduler.GetSchedule()), "total", sn.scheduler.TotalSlots())
+	}
+}
+
+// 命令行交互
+func (sn *SatelliteNode) commandLoop() {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Println("卫星节点命令:")
+	fmt.Println("  status - 显示状态")
+	fmt.Println("  schedule - 显示调度表")
+	fmt.Println("  pass <节点ID> - 显示地面站下一次过境的时延曲线")
+	fmt.Println("  routes - 显示星间链路与路由表")
+	fmt.Println("  watch - 实时仪表盘，回车返回")
+	fmt.Println("  quit - 退出")
+
+	for sn.running {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			break
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command := fields[0]
+
+		switch command {
+		case "status":
+			fmt.Printf("节点ID: %s\n", sn.nodeID)
+			fmt.Printf("运行状态: %v\n", sn.running)
+			status := sn.network.GetConnectionStatus()
+			fmt.Printf("连接状态: %v\n", status)
+			fmt.Println("会话:")
+			for _, sess := range sn.sessions.List() {
+				fmt.Printf("  %s: 时隙 %d, 状态 %s, 地址 %s, 最近活动 %s\n",
+					sess.NodeID, sess.SlotID, sess.State, sess.RemoteAddr, sess.LastSeen.Format(time.RFC3339))
+			}
+			sn.printLinkStatus()
+			sn.traffic.Print()
+
+		case "schedule":
+			schedule := sn.scheduler.GetSchedule()
+			fmt.Println("当前调度表:")
+			for slotID, nodeID := range schedule {
+				fmt.Printf("  时隙 %d: %s\n", slotID, nodeID)
+			}
+
+		case "pass":
+			if len(fields) < 2 {
+				fmt.Println("用法: pass <节点ID>")
+				continue
+			}
+			sn.printPass(fields[1])
+
+		case "routes":
+			sn.printRoutes()
+
+		case "watch":
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				sn.watch(os.Stdout, stop)
+				close(done)
+			}()
+			scanner.Scan()
+			close(stop)
+			<-done
+
+		case "quit":
+			sn.Stop()
+			return
+
+		default:
+			fmt.Println("未知命令")
+		}
+	}
+}
+
+// Main 运行卫星节点命令行程序，cmd/satellite 只是它的入口
+func Main() {
+	defaults := defaultSatelliteConfig("SATELLITE_001")
+	flag.String(config.FLAG_CONFIG, "", "配置文件，JSON或每行一个 选项名=值（默认读取TDMA_CONFIG）")
+	nodeID := flag.String("id", defaults.NodeID, "卫星节点ID")
+	port := flag.Int("port", 0, "监听端口，也可作为位置参数给出")
+	totalSlots := flag.Int("slots", defaults.Scheduler.TotalSlots, "每个超帧的时隙数，须与地面站一致")
+	slotDuration := flag.Duration("slot-duration", defaults.Scheduler.SlotDuration, "时隙持续时间，须与地面站一致")
+	resumeTimeout := flag.Duration("resume-timeout", defaults.ResumeTimeout, "连接断开后会话保留时长")
+	changeLead := flag.Duration("change-lead", defaults.ChangeLead, "重新加载后帧结构变更距通告的最短时长，变更在此后的第一个超帧边界生效")
+	channelFile := flag.String("channel", "", "信道损伤配置文件(JSON)")
+	orbitFile := flag.String("orbit", "", "轨道与可见性配置文件(JSON)")
+	aclFile := flag.String("acl", "", "访问控制策略文件(JSON)，只允许其中列出的节点入网")
+	peerList := flag.String("peers", "", "星间链路邻居列表: [卫星ID@]地址:端口[,...]，只接受其中写明ID的卫星发起的建链")
+	metricsAddr := flag.String("metrics", "", "指标服务监听地址，如 :9100，为空时不启动")
+	adminAddr := flag.String("admin", "", "管理接口监听地址，如 127.0.0.1:9200，为空时不启动")
+	adminToken := flag.String("admin-token", "", "管理接口修改类操作的Bearer令牌（默认读取TDMA_ADMIN_TOKEN）")
+	keystoreFile := flag.String("keystore", "", "地面站预共享密钥库(JSON)，配置后地面站须认证入网且每帧签名、加密；未配置时只按明文序号防重放")
+	rekeyFrames := flag.Uint64("rekey-frames", auth.DefaultRekeyPolicy.Frames, "每个方向加密该数量的帧后更新会话密钥，0表示不按帧数更新")
+	rekeyInterval := flag.Duration("rekey-interval", auth.DefaultRekeyPolicy.Interval, "会话密钥使用超过该时长后更新，0表示不按时间更新")
+	quarantineThreshold := flag.Int("quarantine-threshold", security.DefaultPolicy.Threshold, "窗口内违规达到该次数的节点被隔离，0表示不隔离")
+	quarantineWindow := flag.Duration("quarantine-window", security.DefaultPolicy.Window, "累计违规次数的时间窗口")
+	quarantineDuration := flag.Duration("quarantine-duration", security.DefaultPolicy.Duration, "隔离时长")
+	tlsCert := flag.String("tls-cert", "", "TLS证书(PEM)，配置后监听端口与星间链路使用TLS 1.3")
+	tlsKey := flag.String("tls-key", "", "TLS私钥(PEM)")
+	tlsCA := flag.String("tls-ca", "", "校验客户端证书与邻居卫星证书的CA(PEM)")
+	tlsVerifyClient := flag.Bool("tls-verify-client", false, "要求并校验客户端证书")
+	tlsBindNode := flag.Bool("tls-bind-node", false, "要求帧的节点ID与客户端证书主体CN一致（隐含-tls-verify-client）")
+	captureFile := flag.String("capture", "", "收发帧抓包文件(pcapng)")
+	recordFile := flag.String("record", "", "记录收到的帧与响应的回放日志文件")
+	replayFile := flag.String("replay", "", "回放日志文件，回放后比较响应与调度表并退出")
+	watch := flag.Bool("watch", false, "以实时仪表盘代替命令行交互（日志建议重定向到文件）")
+	logLevel := flag.String("log-level", "", "日志级别，如 info,isl=debug（默认读取LOG_LEVEL）")
+	logFormat := flag.String("log-format", "", "日志格式 text|json（默认读取LOG_FORMAT）")
+	flag.Parse()
+
+	// 命令行之外的选项取自环境变量与配置文件
+	loader := config.NewLoader(flag.CommandLine)
+	if err := loader.Apply(); err != nil {
+		fmt.Printf("配置无效: %v\n", err)
+		os.Exit(1)
+	}
+	if flag.NArg() > 1 {
+		fmt.Printf("多余的参数: %v\n", flag.Args()[1:])
+		os.Exit(1)
+	}
+	if flag.NArg() == 1 {
+		p, err := strconv.Atoi(flag.Arg(0))
+		if err != nil {
+			fmt.Printf("无效的端口号: %s\n", flag.Arg(0))
+			os.Exit(1)
+		}
+		*port = p
+	}
+	cfg := satelliteConfig{
+		NodeID:        *nodeID,
+		Port:          *port,
+		Scheduler:     scheduler.Config{TotalSlots: *totalSlots, SlotDuration: *slotDuration},
+		ResumeTimeout: *resumeTimeout,
+		ChangeLead:    *changeLead,
+	}
+
+	if err := logging.Setup(*logLevel, *logFormat); err != nil {
+		fmt.Printf("日志参数无效: %v\n", err)
+		os.Exit(1)
+	}
+	logger := logging.For("satellite")
+
+	if *replayFile != "" {
+		var visibility *orbit.Model
+		if *orbitFile != "" {
+			var err error
+			visibility, err = orbit.LoadModel(*orbitFile)
+			if err != nil {
+				logging.Fatal(logger, "加载可见性配置失败", "err", err)
+			}
+		}
+		var policy *acl.Policy
+		if *aclFile != "" {
+			var err error
+			policy, err = acl.LoadPolicy(*aclFile)
+			if err != nil {
+				logging.Fatal(logger, "加载访问控制策略失败", "err", err)
+			}
+		}
+		quarantine := security.Policy{Threshold: *quarantineThreshold, Window: *quarantineWindow, Duration: *quarantineDuration}
+		diffs, err := replayLog(*replayFile, visibility, policy, quarantine)
+		if err != nil {
+			logging.Fatal(logger, "回放失败", "err", err)
+		}
+		if diffs > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.Port == 0 {
+		fmt.Println("用法: satellite [-config 配置文件] [-id 卫星ID] [-slots 时隙数] [-slot-duration 时长] [-resume-timeout 时长] [-change-lead 时长] [-channel 配置文件] [-orbit 配置文件] [-acl 策略文件] [-peers 邻居列表] [-metrics 地址] [-admin 地址] [-keystore 密钥库] [-rekey-frames 帧数] [-rekey-interval 时长] [-quarantine-threshold 次数] [-quarantine-window 时长] [-quarantine-duration 时长] [-tls-cert 证书 -tls-key 私钥] [-tls-ca CA] [-tls-verify-client] [-tls-bind-node] [-capture 抓包文件] [-record 回放日志] [-watch] [-log-level 级别] [-log-format 格式] [-port] <端口>")
+		fmt.Println("      satellite -replay 回放日志 [-orbit 配置文件] [-acl 策略文件] [-quarantine-threshold 次数] [-log-level 级别]")
+		fmt.Println("除 -config 外的选项都可以写在配置文件中，或用环境变量 TDMA_<选项名> 设置，如 TDMA_SLOT_DURATION=500ms")
+		os.Exit(1)
+	}
+	if err := cfg.validate(true); err != nil {
+		logging.Fatal(logger, "配置无效", "err", err)
+	}
+
+	// 创建卫星节点
+	satellite := NewSatelliteNode(cfg)
+	logger.Info("创建卫星节点", logging.KEY_NODE_ID, cfg.NodeID, "port", cfg.Port, "slots", cfg.Scheduler.TotalSlots, "slot_duration", cfg.Scheduler.SlotDuration)
+
+	// 重新加载时再次读取配置文件与环境变量中的时隙配置，其他选项只在启动时生效
+	// 读入新的FlagSet，不修改其他协程正在读取的命令行选项
+	satellite.schedulerConfig = func() (scheduler.Config, error) {
+		fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+		totalSlots := fs.Int("slots", defaults.Scheduler.TotalSlots, "")
+		slotDuration := fs.Duration("slot-duration", defaults.Scheduler.SlotDuration, "")
+		if err := loader.ApplyTo(fs); err != nil {
+			return scheduler.Config{}, err
+		}
+		return scheduler.Config{TotalSlots: *totalSlots, SlotDuration: *slotDuration}, nil
+	}
+	satellite.channelFile = *channelFile
+	satellite.orbitFile = *orbitFile
+	satellite.aclFile = *aclFile
+	if err := satellite.loadConfig(); err != nil {
+		logging.Fatal(logger, "加载配置失败", "err", err)
+	}
+
+	var err error
+	satellite.peerEntries, err = parsePeers(*peerList)
+	if err != nil {
+		logging.Fatal(logger, "邻居列表参数无效", "err", err)
+	}
+
+	if *keystoreFile != "" {
+		keys, err := auth.LoadKeystore(*keystoreFile)
+		if err != nil {
+			logging.Fatal(logger, "加载密钥库失败", "err", err)
+		}
+		satellite.auth = auth.NewAuthenticator(keys, satellite.clock)
+		satellite.auth.SetRekeyPolicy(auth.RekeyPolicy{Frames: *rekeyFrames, Interval: *rekeyInterval})
+		logger.Info("启用节点认证", "file", *keystoreFile, "nodes", len(keys.Nodes()))
+	} else {
+		satellite.sequences = auth.NewSequencer()
+	}
+
+	satellite.guard.SetPolicy(security.Policy{Threshold: *quarantineThreshold, Window: *quarantineWindow, Duration: *quarantineDuration})
+
+	if *tlsCert != "" || *tlsKey != "" {
+		cfg := auth.TLSConfig{Cert: *tlsCert, Key: *tlsKey, CA: *tlsCA, VerifyClient: *tlsVerifyClient || *tlsBindNode}
+		if cfg.VerifyClient && cfg.CA == "" {
+			logging.Fatal(logger, "-tls-verify-client 与 -tls-bind-node 需要用 -tls-ca 指定签发客户端证书的CA")
+		}
+		if satellite.tlsServer, err = cfg.Server(); err != nil {
+			logging.Fatal(logger, "TLS配置无效", "err", err)
+		}
+		if satellite.tlsClient, err = cfg.Client(); err != nil {
+			logging.Fatal(logger, "TLS配置无效", "err", err)
+		}
+		satellite.bindCert = *tlsBindNode
+		logger.Info("启用TLS", "cert", *tlsCert, "verify_client", cfg.VerifyClient, "bind_node", satellite.bindCert)
+	} else if *tlsBindNode || *tlsVerifyClient {
+		logging.Fatal(logger, "-tls-verify-client 与 -tls-bind-node 需要同时配置 -tls-cert 与 -tls-key")
+	}
+
+	if *captureFile != "" {
+		satellite.capture, err = capture.Create(*captureFile, cfg.NodeID)
+		if err != nil {
+			logging.Fatal(logger, "创建抓包文件失败", "err", err)
+		}
+		satellite.capture.SetLayout(satellite.scheduler.Layout())
+		logger.Info("抓包", "file", *captureFile)
+	}
+
+	if *recordFile != "" {
+		satellite.record, err = replay.Create(*recordFile, satellite.clock)
+		if err != nil {
+			logging.Fatal(logger, "创建回放日志失败", "err", err)
+		}
+		logger.Info("记录回放日志", "file", *recordFile)
+	}
+
+	if *metricsAddr != "" {
+		if _, err := metrics.Serve(*metricsAddr, satellite.metrics.registry); err != nil {
+			logging.Fatal(logger, "启动指标服务失败", "err", err)
+		}
+		logger.Info("指标服务", "url", "http://"+*metricsAddr+"/metrics")
+	}
+
+	if *adminAddr != "" {
+		token := *adminToken
+		if _, err := satellite.serveAdmin(*adminAddr, token); err != nil {
+			logging.Fatal(logger, "启动管理接口失败", "err", err)
+		}
+		if token == "" {
+			logger.Warn("未配置管理令牌，管理接口只提供查询")
+		}
+		logger.Info("管理接口", "url", "http://"+*adminAddr+"/api/status")
+	}
+
+	// 启动卫星节点
+	err = satellite.Start(cfg.Port)
+	if err != nil {
+		logging.Fatal(logger, "启动卫星节点失败", "err", err)
+	}
+
+	// 收到SIGHUP时重新加载配置，与管理接口的 /api/reload 相同
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := satellite.reloadConfig(); err != nil {
+				logger.Warn("重新加载配置失败", "err", err)
+			}
+		}
+	}()
+
+	sig := make(chan os.Signal, 1)
+
+	// 仪表盘模式，收到退出信号时恢复终端
+	if *watch {
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		stop := make(chan struct{})
+		go func() {
+			<-sig
+			close(stop)
+		}()
+		satellite.watch(os.Stdout, stop)
+		satellite.Stop()
+		return
+	}
+
+	// 启动命令行交互，标准输入关闭（以守护进程运行）后等待退出信号
+	satellite.commandLoop()
+	if satellite.running {
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		satellite.Stop()
+	}
+}
@@ -0,0 +1,441 @@
+package satellite
+
+import (
+	"strconv"
+	"strings"
+	"tdma-network/internal/auth"
+	"tdma-network/internal/clock"
+	"tdma-network/internal/scheduler"
+	"tdma-network/internal/security"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+var testSchedulerConfig = scheduler.Config{TotalSlots: 8, SlotDuration: 100 * time.Millisecond}
+
+// 在虚拟时钟下运行、不监听端口的卫星节点
+func newTestSatellite(t *testing.T) (*SatelliteNode, *clock.Virtual) {
+	t.Helper()
+	cfg := defaultSatelliteConfig("SAT_T")
+	cfg.Scheduler = testSchedulerConfig
+	sn := NewSatelliteNode(cfg)
+	clk := clock.NewVirtual(protocol.TDMA_EPOCH.Add(1000*time.Hour + 10*time.Millisecond))
+	sn.SetClock(clk)
+	sn.running = true
+	if err := sn.scheduler.Start(); err != nil {
+		t.Fatalf("启动调度器失败: %v", err)
+	}
+	return sn, clk
+}
+
+func newTestConn(nodeID string) *replayConn {
+	return &replayConn{remote: nodeID, nodes: make(map[string]bool)}
+}
+
+// 按地面站的方式发送一帧：标注当前绝对时隙号
+func sendFrame(sn *SatelliteNode, conn *replayConn, nodeID string, slotID int, data string) {
+	sendStamped(sn, conn, nodeID, slotID, data, uint64(sn.scheduler.Current().Abs))
+}
+
+// 发送标注了指定绝对时隙号的帧
+func sendStamped(sn *SatelliteNode, conn *replayConn, nodeID string, slotID int, data string, abs uint64) {
+	frame := protocol.NewTDMAFrame(uint32(slotID), nodeID, []byte(data))
+	frame.SetAbsSlot(abs)
+	sn.receiveFrame(frame, conn, conn.nodes, nil)
+}
+
+// 发送带明文序号尾部的帧，seq为0时不带序号
+func sendSequenced(sn *SatelliteNode, conn *replayConn, nodeID string, slotID int, data string, seq uint64) {
+	frame := protocol.NewTDMAFrame(uint32(slotID), nodeID, []byte(data))
+	frame.SetAbsSlot(uint64(sn.scheduler.Current().Abs))
+	if seq > 0 {
+		frame.SetSequence(seq)
+	}
+	sn.receiveFrame(frame, conn, conn.nodes, nil)
+}
+
+// 取出连接上收到的全部响应
+func takeReplies(conn *replayConn) []string {
+	var out []string
+	for _, f := range conn.pending {
+		out = append(out, string(f.Data))
+	}
+	conn.pending = nil
+	return out
+}
+
+// 入网并返回分配的时隙
+func joinStation(t *testing.T, sn *SatelliteNode, conn *replayConn, nodeID string) int {
+	t.Helper()
+	sendFrame(sn, conn, nodeID, 0, protocol.MSG_JOIN)
+	for _, r := range takeReplies(conn) {
+		if rest, ok := strings.CutPrefix(r, protocol.MSG_JOIN_ACK); ok {
+			slotID, err := strconv.Atoi(strings.SplitN(rest, "_", 2)[0])
+			if err != nil {
+				t.Fatalf("无效的入网确认 %q", r)
+			}
+			return slotID
+		}
+	}
+	t.Fatalf("%s 未收到入网确认", nodeID)
+	return -1
+}
+
+// 推进虚拟时钟到下一次进入slotID的时刻（时隙开始后1毫秒）
+func advanceToSlot(sn *SatelliteNode, clk *clock.Virtual, slotID int) {
+	for sn.scheduler.Current().Slot != slotID {
+		clk.Run(sn.scheduler.Current().End.Add(time.Millisecond))
+	}
+}
+
+// 数据帧只能在本节点持有的时隙内发送，空闲时隙同样视为冒用
+func TestDataFrameSlotOwnership(t *testing.T) {
+	tests := []struct {
+		name     string
+		slot     func(own, other int) int
+		accepted bool
+	}{
+		{"own slot", func(own, other int) int { return own }, true},
+		{"other node's slot", func(own, other int) int { return other }, false},
+		{"free slot", func(own, other int) int {
+			for s := 0; s < testSchedulerConfig.TotalSlots; s++ {
+				if s != own && s != other {
+					return s
+				}
+			}
+			return -1
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn, clk := newTestSatellite(t)
+			gs1, gs2 := newTestConn("GS1"), newTestConn("GS2")
+			own := joinStation(t, sn, gs1, "GS1")
+			other := joinStation(t, sn, gs2, "GS2")
+			slotID := tt.slot(own, other)
+			before := sn.scheduler.GetSchedule()
+
+			advanceToSlot(sn, clk, slotID)
+			sendFrame(sn, gs1, "GS1", slotID, protocol.MSG_DATA_TO+"GS2:hello")
+
+			spoofs := sn.metrics.violations.With(security.VIOLATION_SLOT_SPOOF).Value()
+			acked := false
+			for _, r := range takeReplies(gs1) {
+				acked = acked || strings.HasPrefix(r, protocol.MSG_ACK_SLOT)
+			}
+			forwarded := false
+			for _, r := range takeReplies(gs2) {
+				forwarded = forwarded || strings.Contains(r, "hello")
+			}
+
+			if tt.accepted {
+				if spoofs != 0 || !acked || !forwarded {
+					t.Fatalf("spoofs=%v acked=%v forwarded=%v, want frame accepted", spoofs, acked, forwarded)
+				}
+				return
+			}
+			if spoofs != 1 || acked || forwarded {
+				t.Fatalf("spoofs=%v acked=%v forwarded=%v, want frame dropped as slot spoof", spoofs, acked, forwarded)
+			}
+			if after := sn.scheduler.GetSchedule(); len(after) != len(before) || after[slotID] != before[slotID] {
+				t.Fatalf("schedule changed from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+// 时隙被释放后，地面站在收到通知前仍按原时隙发送，宽限期内不计违规，不会被隔离
+func TestReleasedStationNotQuarantined(t *testing.T) {
+	sn, clk := newTestSatellite(t)
+	conn := newTestConn("GS1")
+	slotID := joinStation(t, sn, conn, "GS1")
+	if _, err := sn.releaseSlot(slotID); err != nil {
+		t.Fatalf("releaseSlot: %v", err)
+	}
+	takeReplies(conn)
+
+	advanceToSlot(sn, clk, slotID)
+	for i := 0; i < 2*security.DefaultPolicy.Threshold; i++ {
+		sendFrame(sn, conn, "GS1", slotID, "payload")
+	}
+	if spoofs := sn.metrics.violations.With(security.VIOLATION_SLOT_SPOOF).Value(); spoofs != 0 {
+		t.Fatalf("%v slot spoof violations within the grace period", spoofs)
+	}
+	if _, ok := sn.guard.Quarantined("GS1"); ok {
+		t.Fatalf("released station quarantined")
+	}
+	for _, r := range takeReplies(conn) {
+		if strings.HasPrefix(r, protocol.MSG_ACK_SLOT) {
+			t.Fatalf("frame in the released slot acknowledged: %q", r)
+		}
+	}
+
+	// 宽限期过后仍在原时隙发送视为冒用
+	clk.Run(clk.Now().Add(slotChangeGrace))
+	advanceToSlot(sn, clk, slotID)
+	sendFrame(sn, conn, "GS1", slotID, "payload")
+	if spoofs := sn.metrics.violations.With(security.VIOLATION_SLOT_SPOOF).Value(); spoofs != 1 {
+		t.Fatalf("%v slot spoof violations after the grace period, want 1", spoofs)
+	}
+}
+
+func TestAbsSlotReason(t *testing.T) {
+	cur := scheduler.SlotTime{Abs: 1000, Slot: 0}
+	total := testSchedulerConfig.TotalSlots
+	tests := []struct {
+		name string
+		abs  uint64
+		want string
+	}{
+		{"current", 1000, ""},
+		{"previous slot", 999, ""},
+		{"just within superframe", uint64(1000 - total + 1), ""},
+		{"zero", 0, staleMissing},
+		{"one superframe old", uint64(1000 - total), staleExpired},
+		{"long ago", 1, staleExpired},
+		{"next slot", 1001, ""},
+		{"two slots ahead", 1002, staleFuture},
+		{"overflows int64", 1 << 63, staleFuture},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := absSlotReason(tt.abs, cur, total); got != tt.want {
+				t.Fatalf("absSlotReason(%d) = %q, want %q", tt.abs, got, tt.want)
+			}
+		})
+	}
+}
+
+// 绝对时隙号无效的控制帧与数据帧都被丢弃并按原因计数
+func TestFramesWithInvalidAbsSlotDropped(t *testing.T) {
+	sn, clk := newTestSatellite(t)
+	conn := newTestConn("GS1")
+	slotID := joinStation(t, sn, conn, "GS1")
+	advanceToSlot(sn, clk, slotID)
+	cur := sn.scheduler.Current()
+	total := int64(testSchedulerConfig.TotalSlots)
+
+	tests := []struct {
+		name   string
+		data   string
+		abs    uint64
+		reason string
+	}{
+		{"unstamped heartbeat", protocol.MSG_HEARTBEAT, 0, staleMissing},
+		{"future heartbeat", protocol.MSG_HEARTBEAT, uint64(cur.Abs + 2), staleFuture},
+		{"replayed heartbeat", protocol.MSG_HEARTBEAT, uint64(cur.Abs - total), staleExpired},
+		{"unstamped data", "payload", 0, staleMissing},
+		{"future data", "payload", uint64(cur.Abs + total), staleFuture},
+		// 时隙号相同，但属于上一个超帧
+		{"replayed data", "payload", uint64(cur.Abs - total), staleExpired},
+		{"late data in same superframe", "payload", uint64(cur.Abs - 1), staleExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := sn.metrics.staleFrames.With(tt.reason).Value()
+			sendStamped(sn, conn, "GS1", slotID, tt.data, tt.abs)
+			if got := sn.metrics.staleFrames.With(tt.reason).Value() - before; got != 1 {
+				t.Fatalf("tdma_stale_frames_total{reason=%q} += %v, want 1", tt.reason, got)
+			}
+			if replies := takeReplies(conn); len(replies) != 0 {
+				t.Fatalf("dropped frame answered with %q", replies)
+			}
+		})
+	}
+
+	// 正确标注的帧照常处理
+	sendFrame(sn, conn, "GS1", slotID, "payload")
+	if replies := takeReplies(conn); len(replies) != 1 || !strings.HasPrefix(replies[0], protocol.MSG_ACK_SLOT) {
+		t.Fatalf("valid data frame answered with %q", replies)
+	}
+}
+
+// 心跳丢失或断开后恢复超时的节点，时隙被释放
+func TestLivenessReleasesSlot(t *testing.T) {
+	tests := []struct {
+		name   string
+		detach bool
+		wait   time.Duration
+		freed  bool
+	}{
+		{"heartbeat within limit", false, protocol.HEARTBEAT_INTERVAL * protocol.HEARTBEAT_MISS_LIMIT, false},
+		{"heartbeats missed", false, protocol.HEARTBEAT_INTERVAL*protocol.HEARTBEAT_MISS_LIMIT + time.Millisecond, true},
+		{"detached within resume timeout", true, 30 * time.Second, false},
+		{"resume timeout passed", true, 30*time.Second + time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn, clk := newTestSatellite(t)
+			conn := newTestConn("GS1")
+			slotID := joinStation(t, sn, conn, "GS1")
+			if tt.detach && !sn.sessions.Detach("GS1", conn.RemoteAddr().String()) {
+				t.Fatalf("Detach = false")
+			}
+
+			clk.Run(clk.Now().Add(tt.wait))
+			sn.checkLiveness()
+
+			owner := sn.scheduler.GetSchedule()[slotID]
+			_, hasSession := sn.sessions.Get("GS1")
+			if tt.freed != (owner == "") || tt.freed == hasSession {
+				t.Fatalf("slot %d owner %q, session %v, want freed=%v", slotID, owner, hasSession, tt.freed)
+			}
+		})
+	}
+}
+
+// 时隙边界前地面站时钟略快：控制帧标注下一时隙仍被接受，数据帧只能在当前时隙发送
+func TestAbsSlotToleranceAtSlotBoundary(t *testing.T) {
+	sn, clk := newTestSatellite(t)
+	conn := newTestConn("GS1")
+	slotID := joinStation(t, sn, conn, "GS1")
+	advanceToSlot(sn, clk, slotID)
+	cur := sn.scheduler.Current()
+	clk.Run(cur.End.Add(-time.Millisecond))
+	if now := sn.scheduler.Current(); now.Abs != cur.Abs {
+		t.Fatalf("left slot %d before the boundary", cur.Abs)
+	}
+	next := uint64(cur.Abs + 1)
+
+	sendStamped(sn, conn, "GS1", slotID, protocol.MSG_HEARTBEAT, next)
+	if replies := takeReplies(conn); len(replies) != 1 || !strings.HasPrefix(replies[0], protocol.MSG_HEARTBEAT_ACK) {
+		t.Fatalf("heartbeat stamped with the next slot answered with %q", replies)
+	}
+
+	before := sn.metrics.staleFrames.With(staleFuture).Value()
+	sendStamped(sn, conn, "GS1", slotID, "payload", next)
+	if got := sn.metrics.staleFrames.With(staleFuture).Value() - before; got != 1 {
+		t.Fatalf("tdma_stale_frames_total{reason=%q} += %v, want 1", staleFuture, got)
+	}
+	if replies := takeReplies(conn); len(replies) != 0 {
+		t.Fatalf("data frame stamped with the next slot answered with %q", replies)
+	}
+
+	// 越过边界后同一标注成为当前时隙，数据帧被接受
+	clk.Run(cur.End.Add(time.Millisecond))
+	if now := sn.scheduler.Current(); now.Abs != cur.Abs+1 {
+		t.Fatalf("current slot %d, want %d", now.Abs, cur.Abs+1)
+	}
+	sendStamped(sn, conn, "GS1", sn.scheduler.Current().Slot, protocol.MSG_HEARTBEAT, next)
+	if replies := takeReplies(conn); len(replies) != 1 {
+		t.Fatalf("heartbeat after the boundary answered with %q", replies)
+	}
+}
+
+// 帧结构变更生效时刻之后、定时器切换调度表之前，按迁移后的时隙发送的数据帧不是冒用
+func TestDataFrameAfterLayoutEpoch(t *testing.T) {
+	newCfg := scheduler.Config{TotalSlots: 4, SlotDuration: 50 * time.Millisecond}
+	tests := []struct {
+		name   string
+		nodeID string
+	}{
+		{"slot kept", "GS1"},
+		{"slot remapped", "GS2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sn, clk := newTestSatellite(t)
+			conns := map[string]*replayConn{"GS1": newTestConn("GS1"), "GS2": newTestConn("GS2")}
+			// GS1持有时隙1，GS2持有超出新时隙数的时隙6
+			for nodeID, slotID := range map[string]int{"GS1": 1, "GS2": 6} {
+				sn.scheduler.ReleaseTimeSlot(joinStation(t, sn, conns[nodeID], nodeID))
+				if _, err := sn.scheduler.AssignTimeSlot(slotID, nodeID); err != nil {
+					t.Fatalf("AssignTimeSlot: %v", err)
+				}
+				sn.sessions.SetSlot(nodeID, slotID)
+			}
+
+			// 直接计划变更，不设置生效时刻的定时器
+			at := sn.scheduler.Layout().NextSuperframe(clk.Now().Add(time.Second))
+			ch, err := sn.scheduler.PlanChange(newCfg, at)
+			if err != nil {
+				t.Fatalf("PlanChange: %v", err)
+			}
+			sess, _ := sn.sessions.Get(tt.nodeID)
+			target := ch.Target(sess.SlotID)
+			if target < 0 {
+				t.Fatalf("slot %d has no target in %v", sess.SlotID, ch.Remap)
+			}
+
+			clk.Run(at.Add(time.Duration(target)*newCfg.SlotDuration + time.Millisecond))
+			if cur := sn.scheduler.Current(); cur.Slot != target {
+				t.Fatalf("current slot %d, want %d", cur.Slot, target)
+			}
+			conn := conns[tt.nodeID]
+			takeReplies(conn)
+			sendFrame(sn, conn, tt.nodeID, target, "payload")
+
+			if spoofs := sn.metrics.violations.With(security.VIOLATION_SLOT_SPOOF).Value(); spoofs != 0 {
+				t.Fatalf("%v slot spoof violations", spoofs)
+			}
+			acked := false
+			for _, r := range takeReplies(conn) {
+				acked = acked || r == protocol.MSG_ACK_SLOT+strconv.Itoa(target)
+			}
+			if !acked {
+				t.Fatalf("no %s%d", protocol.MSG_ACK_SLOT, target)
+			}
+			if _, ok := sn.scheduler.PendingChange(); ok {
+				t.Fatalf("change still pending after the first frame past the epoch")
+			}
+			if owner := sn.scheduler.GetSchedule()[target]; owner != tt.nodeID {
+				t.Fatalf("slot %d owned by %q, want %s", target, owner, tt.nodeID)
+			}
+			if sess, _ := sn.sessions.Get(tt.nodeID); sess.SlotID != target {
+				t.Fatalf("session slot %d, want %d", sess.SlotID, target)
+			}
+		})
+	}
+}
+
+// 不配置密钥库时按明文序号防重放：原样重发的帧记为重放违规，没有序号的帧被丢弃
+func TestPlaintextReplayWindow(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	sn.sequences = auth.NewSequencer()
+	conn := newTestConn("GS1")
+
+	sendSequenced(sn, conn, "GS1", 0, protocol.MSG_JOIN, 100)
+	if replies := takeReplies(conn); len(replies) == 0 || !strings.HasPrefix(replies[0], protocol.MSG_JOIN_ACK) {
+		t.Fatalf("sequenced join answered with %q", replies)
+	}
+
+	tests := []struct {
+		name     string
+		seq      uint64
+		accepted bool
+		reason   string // 拒绝时的认证失败原因
+	}{
+		{"heartbeat", 110, true, ""},
+		{"replayed heartbeat", 110, false, auth.REASON_REPLAY},
+		{"replayed join", 100, false, auth.REASON_REPLAY},
+		{"reordered within window", 105, true, ""},
+		{"unsequenced", 0, false, auth.REASON_UNSIGNED},
+		{"next heartbeat", 120, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replays := sn.metrics.violations.With(security.VIOLATION_REPLAY).Value()
+			failures := sn.metrics.authFailures.With(tt.reason).Value()
+			sendSequenced(sn, conn, "GS1", 0, protocol.MSG_HEARTBEAT, tt.seq)
+
+			replies := takeReplies(conn)
+			if acked := len(replies) == 1 && strings.HasPrefix(replies[0], protocol.MSG_HEARTBEAT_ACK); acked != tt.accepted {
+				t.Fatalf("heartbeat answered with %q, want accepted=%v", replies, tt.accepted)
+			}
+			if tt.accepted {
+				return
+			}
+			if got := sn.metrics.authFailures.With(tt.reason).Value() - failures; got != 1 {
+				t.Fatalf("tdma_auth_failures_total{reason=%q} += %v, want 1", tt.reason, got)
+			}
+			wantReplays := 0.0
+			if tt.reason == auth.REASON_REPLAY {
+				wantReplays = 1
+			}
+			if got := sn.metrics.violations.With(security.VIOLATION_REPLAY).Value() - replays; got != wantReplays {
+				t.Fatalf("replay violations += %v, want %v", got, wantReplays)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package satellite
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"sort"
+	"strings"
+	"tdma-network/internal/acl"
+	"tdma-network/internal/clock"
+	"tdma-network/internal/orbit"
+	"tdma-network/internal/replay"
+	"tdma-network/internal/scheduler"
+	"tdma-network/internal/security"
+	"tdma-network/internal/session"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 回放用的连接，收集卫星写入的响应帧
+type replayConn struct {
+	id      uint32
+	remote  string
+	discard bool            // 星间链路的连接，写入的帧不参与比较
+	nodes   map[string]bool // 该连接上出现过的节点
+	pending []*protocol.TDMAFrame
+}
+
+func (c *replayConn) Read(b []byte) (int, error) { return 0, io.EOF }
+
+func (c *replayConn) Write(b []byte) (int, error) {
+	if c.discard {
+		return len(b), nil
+	}
+	frame, err := protocol.DeserializeTDMAFrame(b)
+	if err != nil {
+		return 0, err
+	}
+	c.pending = append(c.pending, frame)
+	return len(b), nil
+}
+
+func (c *replayConn) Close() error                     { return nil }
+func (c *replayConn) LocalAddr() net.Addr              { return replayAddr("replay") }
+func (c *replayConn) RemoteAddr() net.Addr             { return replayAddr(c.remote) }
+func (c *replayConn) SetDeadline(time.Time) error      { return nil }
+func (c *replayConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *replayConn) SetWriteDeadline(time.Time) error { return nil }
+
+type replayAddr string
+
+func (a replayAddr) Network() string { return "replay" }
+func (a replayAddr) String() string  { return string(a) }
+
+// 回放过程状态
+type replayer struct {
+	sn     *SatelliteNode
+	start  time.Time
+	conns  map[uint32]*replayConn
+	tokens map[string]string // 记录中的会话令牌 -> 回放时生成的令牌
+	events <-chan session.Event
+
+	frames, responses, schedules, diffs int
+}
+
+// 将回放日志输入新的卫星节点，比较回放产生的响应与调度表和记录是否一致
+// 时间由虚拟时钟按记录的时刻推进，返回不一致之处的数量
+func replayLog(path string, visibility *orbit.Model, policy *acl.Policy, quarantine security.Policy) (int, error) {
+	entries, err := replay.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 || entries[0].Type != replay.ENTRY_START {
+		return 0, fmt.Errorf("回放日志缺少启动记录")
+	}
+	start := entries[0]
+
+	cfg := defaultSatelliteConfig(start.NodeID)
+	if start.Slots > 0 {
+		cfg.Scheduler = scheduler.Config{TotalSlots: start.Slots, SlotDuration: start.SlotDuration}
+	}
+	if start.ResumeTimeout > 0 {
+		cfg.ResumeTimeout = start.ResumeTimeout
+	}
+	if err := cfg.validate(false); err != nil {
+		return 0, fmt.Errorf("启动记录中的配置无效: %v", err)
+	}
+	sn := NewSatelliteNode(cfg)
+	sn.visibility = visibility
+	sn.policy = policy
+	sn.guard.SetPolicy(quarantine)
+	clk := clock.NewVirtual(start.Time)
+	sn.SetClock(clk)
+	sn.running = true
+	if err := sn.scheduler.Start(); err != nil {
+		return 0, fmt.Errorf("启动调度器失败: %v", err)
+	}
+
+	r := &replayer{
+		sn:     sn,
+		start:  start.Time,
+		conns:  make(map[uint32]*replayConn),
+		tokens: make(map[string]string),
+		events: sn.sessions.Subscribe(),
+	}
+
+	// 与运行时相同节拍的周期检查
+	every(clk, protocol.HEARTBEAT_INTERVAL/2, sn.checkLiveness)
+	if policy != nil {
+		sn.scheduler.SetAdmission(sn.admit)
+	}
+	if visibility != nil {
+		sn.scheduler.SetVisibility(sn.isVisible)
+		every(clk, time.Second, sn.checkVisibility)
+	}
+
+	for i, e := range entries[1:] {
+		line, e := i+2, e
+		clk.AfterFunc(e.Time.Sub(start.Time), func() {
+			r.apply(line, e)
+			r.drainEvents()
+		})
+	}
+	end := entries[len(entries)-1].Time
+	clk.Run(end)
+	r.finish()
+
+	fmt.Printf("回放 %s: 卫星 %s, 时长 %v, 接收帧 %d, 响应 %d, 调度表快照 %d, 不一致 %d 处\n",
+		path, start.NodeID, end.Sub(start.Time).Round(time.Millisecond), r.frames, r.responses, r.schedules, r.diffs)
+	return r.diffs, nil
+}
+
+// 按固定节拍重复执行f
+func every(clk clock.Clock, d time.Duration, f func()) {
+	next := clk.Now().Add(d)
+	var tick func()
+	tick = func() {
+		f()
+		next = next.Add(d)
+		clk.AfterFunc(next.Sub(clk.Now()), tick)
+	}
+	clk.AfterFunc(d, tick)
+}
+
+// 执行一条记录，line为记录在日志中的行号
+func (r *replayer) apply(line int, e replay.Entry) {
+	switch e.Type {
+	case replay.ENTRY_RECV:
+		frame, err := protocol.DeserializeTDMAFrame(e.Frame)
+		if err != nil {
+			r.diff(e, "第 %d 行的帧无效: %v", line, err)
+			return
+		}
+		r.frames++
+		conn := r.conn(e)
+		r.resumeToken(frame)
+		r.sn.receiveFrame(frame, conn, conn.nodes, nil)
+
+	case replay.ENTRY_SEND:
+		r.compareResponse(line, e)
+
+	case replay.ENTRY_CLOSE:
+		conn, ok := r.conns[e.Conn]
+		if !ok {
+			return
+		}
+		r.unmatched(conn)
+		r.sn.closeConnection(conn, conn.nodes)
+		delete(r.conns, e.Conn)
+
+	case replay.ENTRY_ISL:
+		frame, err := protocol.DeserializeTDMAFrame(e.Frame)
+		if err != nil {
+			r.diff(e, "第 %d 行的帧无效: %v", line, err)
+			return
+		}
+		p := r.sn.getPeer(e.NodeID)
+		if p == nil {
+			p = r.sn.addPeer(e.NodeID, &replayConn{remote: e.NodeID, discard: true}, false, nil)
+		}
+		if p != nil {
+			r.sn.handleISL(p, string(frame.Data))
+		}
+
+	case replay.ENTRY_ISL_DOWN:
+		if p := r.sn.getPeer(e.NodeID); p != nil {
+			r.sn.removePeer(p)
+		}
+
+	case replay.ENTRY_VIOLATION:
+		conn := r.conn(e)
+		r.sn.violation(conn, e.NodeID, -1, e.Kind, "回放")
+
+	case replay.ENTRY_LAYOUT:
+		if e.At == nil {
+			r.diff(e, "第 %d 行的帧结构变更缺少生效时刻", line)
+			return
+		}
+		cfg := scheduler.Config{TotalSlots: e.Slots, SlotDuration: e.SlotDuration}
+		if _, err := r.sn.planLayout(cfg, *e.At); err != nil {
+			r.diff(e, "帧结构变更无法计划: %v", err)
+		}
+
+	case replay.ENTRY_SCHEDULE:
+		r.schedules++
+		if got := r.sn.scheduler.GetSchedule(); !maps.Equal(got, e.Schedule) {
+			r.diff(e, "调度表不一致: 记录 %s, 回放 %s", formatSchedule(e.Schedule), formatSchedule(got))
+		}
+
+	default:
+		r.sn.log.Warn("忽略未知的回放记录", "line", line, "type", e.Type)
+	}
+}
+
+// 记录中的连接对应的回放连接
+func (r *replayer) conn(e replay.Entry) *replayConn {
+	conn, ok := r.conns[e.Conn]
+	if !ok {
+		conn = &replayConn{id: e.Conn, remote: e.Remote, nodes: make(map[string]bool)}
+		r.conns[e.Conn] = conn
+	}
+	return conn
+}
+
+// 与记录的响应比较回放产生的下一个响应
+func (r *replayer) compareResponse(line int, e replay.Entry) {
+	want, err := protocol.DeserializeTDMAFrame(e.Frame)
+	if err != nil {
+		r.diff(e, "第 %d 行的帧无效: %v", line, err)
+		return
+	}
+	r.responses++
+
+	conn, ok := r.conns[e.Conn]
+	if !ok || len(conn.pending) == 0 {
+		r.diff(e, "缺少发往 %s 的响应: 记录 %s", e.NodeID, describeResponse(want))
+		return
+	}
+	got := conn.pending[0]
+	conn.pending = conn.pending[1:]
+
+	if want.SlotID != got.SlotID || normalizeResponse(string(want.Data)) != normalizeResponse(string(got.Data)) {
+		r.diff(e, "发往 %s 的响应不一致: 记录 %s, 回放 %s", e.NodeID, describeResponse(want), describeResponse(got))
+		return
+	}
+
+	// 记住令牌的对应关系，之后用记录的令牌恢复会话时替换为回放生成的令牌
+	if wantToken, ok := joinToken(string(want.Data)); ok {
+		if gotToken, ok := joinToken(string(got.Data)); ok {
+			r.tokens[wantToken] = gotToken
+		}
+	}
+}
+
+// 将会话恢复请求中记录的令牌替换为回放时生成的令牌
+func (r *replayer) resumeToken(frame *protocol.TDMAFrame) {
+	token, ok := strings.CutPrefix(string(frame.Data), protocol.MSG_RESUME)
+	if !ok {
+		return
+	}
+	if mapped, ok := r.tokens[token]; ok {
+		frame.Data = []byte(protocol.MSG_RESUME + mapped)
+		frame.Length = uint32(len(frame.Data))
+		frame.CRC = frame.CalculateCRC()
+	}
+}
+
+// 报告连接上回放多产生的响应
+func (r *replayer) unmatched(conn *replayConn) {
+	for _, f := range conn.pending {
+		r.diff(replay.Entry{Time: r.sn.clock.Now(), Conn: conn.id}, "多余的响应: 回放 %s", describeResponse(f))
+	}
+	conn.pending = nil
+}
+
+// 回放结束，检查尚未比较的响应
+func (r *replayer) finish() {
+	ids := make([]uint32, 0, len(r.conns))
+	for id := range r.conns {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		r.unmatched(r.conns[id])
+	}
+}
+
+// 处理回放期间产生的会话事件
+func (r *replayer) drainEvents() {
+	for {
+		select {
+		case e := <-r.events:
+			r.sn.handleEvent(e)
+		default:
+			return
+		}
+	}
+}
+
+// 报告一处不一致
+func (r *replayer) diff(e replay.Entry, format string, args ...any) {
+	r.diffs++
+	prefix := fmt.Sprintf("  ! +%v", e.Time.Sub(r.start).Round(time.Microsecond))
+	if e.Conn != 0 {
+		prefix += fmt.Sprintf(" 连接 #%d", e.Conn)
+	}
+	fmt.Printf(prefix+": "+format+"\n", args...)
+}
+
+// 去掉响应中每次运行都不同的部分：入网令牌与心跳发送时刻
+func normalizeResponse(data string) string {
+	for _, prefix := range []string{protocol.MSG_JOIN_ACK, protocol.MSG_HEARTBEAT_ACK} {
+		if rest, ok := strings.CutPrefix(data, prefix); ok {
+			slot, _, _ := strings.Cut(rest, "_")
+			return prefix + slot
+		}
+	}
+	return data
+}
+
+// 入网确认中的会话令牌
+func joinToken(data string) (string, bool) {
+	rest, ok := strings.CutPrefix(data, protocol.MSG_JOIN_ACK)
+	if !ok {
+		return "", false
+	}
+	_, token, ok := strings.Cut(rest, "_")
+	return token, ok
+}
+
+func describeResponse(f *protocol.TDMAFrame) string {
+	return fmt.Sprintf("时隙 %d %q", f.SlotID, f.Data)
+}
+
+// 按时隙顺序格式化调度表
+func formatSchedule(schedule map[int]string) string {
+	slots := make([]int, 0, len(schedule))
+	for slotID := range schedule {
+		slots = append(slots, slotID)
+	}
+	sort.Ints(slots)
+
+	parts := make([]string, 0, len(slots))
+	for _, slotID := range slots {
+		parts = append(parts, fmt.Sprintf("%d:%s", slotID, schedule[slotID]))
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,111 @@
+package satellite
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"tdma-network/internal/replay"
+	"tdma-network/internal/security"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+// 在虚拟时钟下记录一次入网、心跳、离网的交互，返回回放日志的各条记录
+func recordExchange(t *testing.T) []replay.Entry {
+	t.Helper()
+	sn, clk := newTestSatellite(t)
+	var buf bytes.Buffer
+	sn.record = replay.NewRecorder(&buf, clk)
+	cfg := sn.scheduler.Config()
+	sn.record.Start(sn.nodeID, clk.Now(), cfg.TotalSlots, cfg.SlotDuration, sn.resumeTimeout)
+
+	gs := newTestConn("GS1")
+	slotID := joinStation(t, sn, gs, "GS1")
+	clk.Run(clk.Now().Add(protocol.HEARTBEAT_INTERVAL / 2))
+	advanceToSlot(sn, clk, slotID)
+	sendFrame(sn, gs, "GS1", slotID, protocol.MSG_HEARTBEAT)
+	clk.Run(clk.Now().Add(protocol.HEARTBEAT_INTERVAL / 2))
+	advanceToSlot(sn, clk, slotID)
+	sendFrame(sn, gs, "GS1", slotID, protocol.MSG_LEAVE)
+	sn.record.Closed(gs)
+
+	entries, err := replay.Read(&buf)
+	if err != nil {
+		t.Fatalf("读取回放日志失败: %v", err)
+	}
+	return entries
+}
+
+func writeReplayLog(t *testing.T, entries []replay.Entry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	path := filepath.Join(t.TempDir(), "replay.log")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 记录时刻取自节点的虚拟时钟，回放与记录一致；改动一条响应后恰好报告一处不一致
+func TestReplayRecordedExchange(t *testing.T) {
+	entries := recordExchange(t)
+
+	start := entries[0].Time
+	for _, e := range entries {
+		if e.Time.Before(start) || e.Time.Sub(start) > time.Minute {
+			t.Fatalf("记录 %s 的时刻 %v 不在虚拟时间轴上（启动于 %v）", e.Type, e.Time, start)
+		}
+	}
+
+	diffs, err := replayLog(writeReplayLog(t, entries), nil, nil, security.DefaultPolicy)
+	if err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if diffs != 0 {
+		t.Fatalf("replay of an unmodified log reported %d diffs, want 0", diffs)
+	}
+
+	// 把心跳确认改成拒绝
+	changed := false
+	for i, e := range entries {
+		if e.Type != replay.ENTRY_SEND {
+			continue
+		}
+		frame, err := protocol.DeserializeTDMAFrame(e.Frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(frame.Data), protocol.MSG_HEARTBEAT_ACK) {
+			continue
+		}
+		frame.Data = []byte(protocol.MSG_HEARTBEAT_REJECT)
+		frame.Length = uint32(len(frame.Data))
+		frame.CRC = frame.CalculateCRC()
+		if entries[i].Frame, err = frame.Serialize(); err != nil {
+			t.Fatal(err)
+		}
+		changed = true
+		break
+	}
+	if !changed {
+		t.Fatal("no heartbeat ack recorded")
+	}
+
+	diffs, err = replayLog(writeReplayLog(t, entries), nil, nil, security.DefaultPolicy)
+	if err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if diffs != 1 {
+		t.Fatalf("replay with one changed response reported %d diffs, want 1", diffs)
+	}
+}
@@ -0,0 +1,117 @@
+package satellite
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"tdma-network/internal/channel"
+	"tdma-network/internal/traffic"
+	"time"
+)
+
+// 仿真场景
+//
+//	{
+//	  "duration": "1h",
+//	  "slot_duration": "1s",
+//	  "total_slots": 10,
+//	  "bitrate": 9600,
+//	  "seed": 1,
+//	  "channel": {"default": {"delay": "20ms"}},
+//	  "stations": [
+//	    {"id": "GS", "count": 4, "traffic": {"type": "poisson", "interval": "2s", "size": 64}}
+//	  ]
+//	}
+type Scenario struct {
+	Duration     channel.Duration   `json:"duration"`
+	SlotDuration channel.Duration   `json:"slot_duration"` // 默认1s
+	TotalSlots   int                `json:"total_slots"`   // 默认10
+	Bitrate      int                `json:"bitrate"`       // 上行速率(bit/s)，决定每个时隙可发送的字节数，默认9600
+	Guard        channel.Duration   `json:"guard"`         // 时隙末尾保护间隔，默认50ms
+	Seed         int64              `json:"seed"`          // 随机种子，相同种子结果可复现
+	Channel      channel.FileConfig `json:"channel"`       // 上下行信道，按地面站ID配置
+	Stations     []StationSpec      `json:"stations"`
+}
+
+// 地面站配置
+type StationSpec struct {
+	ID      string           `json:"id"`
+	Count   int              `json:"count"`   // 大于1时生成 ID_001 ... ID_n
+	Start   channel.Duration `json:"start"`   // 入网时刻
+	Traffic traffic.Config   `json:"traffic"` // 与地面站业务源配置相同，type为空表示不产生业务
+}
+
+// 加载仿真场景
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取场景文件失败: %v", err)
+	}
+
+	sc := &Scenario{
+		SlotDuration: channel.Duration(time.Second),
+		TotalSlots:   10,
+		Bitrate:      9600,
+		Guard:        channel.Duration(50 * time.Millisecond),
+		Seed:         1,
+	}
+	if err := json.Unmarshal(data, sc); err != nil {
+		return nil, fmt.Errorf("解析场景文件失败: %v", err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// 检查场景参数
+func (sc *Scenario) Validate() error {
+	if sc.Duration <= 0 {
+		return fmt.Errorf("duration 必须大于0")
+	}
+	if sc.SlotDuration <= 0 || sc.TotalSlots <= 0 {
+		return fmt.Errorf("slot_duration 与 total_slots 必须大于0")
+	}
+	if sc.Bitrate <= 0 {
+		return fmt.Errorf("bitrate 必须大于0")
+	}
+	if sc.Guard < 0 || sc.Guard >= sc.SlotDuration {
+		return fmt.Errorf("guard 应在[0, slot_duration)内")
+	}
+	if err := sc.Channel.Validate(); err != nil {
+		return fmt.Errorf("channel.%v", err)
+	}
+	if len(sc.Stations) == 0 {
+		return fmt.Errorf("至少需要一个地面站")
+	}
+	for i, st := range sc.Stations {
+		if st.ID == "" {
+			return fmt.Errorf("stations[%d]: id 不能为空", i)
+		}
+		if st.Traffic.Type == "" {
+			continue
+		}
+		if err := st.Traffic.Validate(); err != nil {
+			return fmt.Errorf("stations[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// 展开地面站列表
+func (sc *Scenario) ExpandStations() []StationSpec {
+	var specs []StationSpec
+	for _, st := range sc.Stations {
+		if st.Count <= 1 {
+			specs = append(specs, st)
+			continue
+		}
+		for i := 1; i <= st.Count; i++ {
+			s := st
+			s.ID = fmt.Sprintf("%s_%03d", st.ID, i)
+			s.Count = 1
+			specs = append(specs, s)
+		}
+	}
+	return specs
+}
@@ -0,0 +1,472 @@
+package satellite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"tdma-network/internal/channel"
+	"tdma-network/internal/clock"
+	"tdma-network/internal/scheduler"
+	"tdma-network/internal/traffic"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 仿真中的卫星节点ID
+const simSatelliteID = "SIM_SAT"
+
+// 地面站发送队列上限
+const maxOutboxSize = 100
+
+// 仿真开始时刻：TDMA纪元后一天，帧上标注的绝对时隙号不为0
+var simStart = protocol.TDMA_EPOCH.Add(24 * time.Hour)
+
+// Simulate 运行场景文件描述的仿真并打印报告，duration与seed非0时覆盖场景中的取值
+func Simulate(path string, duration time.Duration, seed int64) error {
+	sc, err := LoadScenario(path)
+	if err != nil {
+		return err
+	}
+	if duration > 0 {
+		sc.Duration = channel.Duration(duration)
+	}
+	if seed != 0 {
+		sc.Seed = seed
+	}
+
+	fmt.Printf("仿真场景: %s, 地面站 %d, 时长 %v, 时隙 %d x %v, 种子 %d\n",
+		path, len(sc.ExpandStations()), time.Duration(sc.Duration),
+		sc.TotalSlots, time.Duration(sc.SlotDuration), sc.Seed)
+
+	s, err := newSim(sc)
+	if err != nil {
+		return err
+	}
+	s.run().Print()
+	return nil
+}
+
+// 离散事件仿真
+// 卫星是真实的卫星节点，地面站经内存中的连接与上下行信道与之收发帧，时间由虚拟时钟驱动
+type sim struct {
+	sc           *Scenario
+	clk          *clock.Virtual
+	sn           *SatelliteNode
+	slotDuration time.Duration
+	capacity     time.Duration // 每个时隙可用于发送的时长（扣除保护间隔）
+
+	stations []*station
+	report   *report
+
+	airing        []*transmission // 最近经过卫星接收机的数据帧，用于判断冲突
+	lastCollision int64           // 最近一次冲突所在的绝对时隙
+	lastUsed      int64           // 最近一个承载数据的绝对时隙
+}
+
+// 一个数据帧占用卫星接收机的时段
+type transmission struct {
+	st         *station
+	start, end time.Time
+}
+
+// 创建仿真
+func newSim(sc *Scenario) (*sim, error) {
+	cfg := defaultSatelliteConfig(simSatelliteID)
+	cfg.Scheduler = scheduler.Config{TotalSlots: sc.TotalSlots, SlotDuration: time.Duration(sc.SlotDuration)}
+	if err := cfg.validate(false); err != nil {
+		return nil, fmt.Errorf("场景中的时隙配置无效: %v", err)
+	}
+	sn := NewSatelliteNode(cfg)
+	clk := clock.NewVirtual(simStart)
+	sn.SetClock(clk)
+
+	s := &sim{
+		sc:            sc,
+		clk:           clk,
+		sn:            sn,
+		slotDuration:  cfg.Scheduler.SlotDuration,
+		capacity:      time.Duration(sc.SlotDuration - sc.Guard),
+		report:        &report{},
+		lastCollision: -1,
+		lastUsed:      -1,
+	}
+	for i, spec := range sc.ExpandStations() {
+		st, err := newStation(s, spec, sc.Seed*1000+int64(i)*3)
+		if err != nil {
+			return nil, fmt.Errorf("地面站 %s: %v", spec.ID, err)
+		}
+		s.stations = append(s.stations, st)
+	}
+	return s, nil
+}
+
+// 运行仿真至场景时长结束
+func (s *sim) run() *report {
+	wall := time.Now()
+
+	// 与回放相同：卫星节点不监听端口，周期检查由虚拟时钟驱动
+	s.sn.running = true
+	s.sn.scheduler.Start()
+	every(s.clk, protocol.HEARTBEAT_INTERVAL/2, s.sn.checkLiveness)
+
+	for _, st := range s.stations {
+		st.begin()
+	}
+	s.clk.AfterFunc(s.sn.scheduler.Current().End.Sub(simStart), s.slotTick)
+
+	end := simStart.Add(time.Duration(s.sc.Duration))
+	s.report.Events = s.clk.Run(end)
+	s.report.Duration = s.clk.Since(simStart)
+	s.report.Wall = time.Since(wall)
+	s.report.Bitrate = s.sc.Bitrate
+	s.report.SlotDuration = s.slotDuration
+	s.report.Stations = s.stations
+	s.report.satellite(s.sn)
+	return s.report
+}
+
+// 当前时隙，与真实节点一样由卫星的调度器按全局时钟计算
+func (s *sim) current() scheduler.SlotTime {
+	return s.sn.scheduler.Current()
+}
+
+// 时隙边界：统计新时隙是否已分配，各地面站在新时隙内发送
+func (s *sim) slotTick() {
+	cur := s.current()
+	s.report.SlotsTotal++
+	if _, ok := s.sn.scheduler.GetSchedule()[cur.Slot]; ok {
+		s.report.SlotsAssigned++
+	}
+	for _, st := range s.stations {
+		st.onSlot(cur)
+	}
+	s.clk.AfterFunc(cur.End.Sub(s.clk.Now()), s.slotTick)
+}
+
+// 按上行速率计算帧的发送时长
+func (s *sim) airtime(bytes int) time.Duration {
+	return time.Duration(int64(bytes) * 8 * int64(time.Second) / int64(s.sc.Bitrate))
+}
+
+// 接收地面站的上行帧，无法解析时计为CRC错误
+func (s *sim) receive(st *station, raw []byte) {
+	frame, err := protocol.DeserializeTDMAFrame(raw)
+	if err != nil {
+		s.report.CRCErrors++
+		return
+	}
+	s.deliver(st, frame)
+}
+
+// 将上行帧交给卫星节点，卫星的响应经下行信道发回
+// 返回卫星是否以时隙确认接受了数据帧
+func (s *sim) deliver(st *station, frame *protocol.TDMAFrame) bool {
+	s.sn.receiveFrame(frame, st.conn, st.conn.nodes, nil)
+
+	accepted := false
+	for _, f := range st.conn.pending {
+		accepted = accepted || strings.HasPrefix(string(f.Data), protocol.MSG_ACK_SLOT)
+		st.downlinkFrame(f)
+	}
+	st.conn.pending = nil
+	return accepted
+}
+
+// 数据帧开始到达卫星接收机
+func (s *sim) beginTransmission(tx *transmission) {
+	// 仍在接收的帧至多在一个时隙的发送时长之前开始
+	kept := s.airing[:0]
+	for _, other := range s.airing {
+		if other.end.After(tx.start.Add(-s.capacity)) {
+			kept = append(kept, other)
+		}
+	}
+	s.airing = append(kept, tx)
+}
+
+// 数据帧接收完成：与其他地面站的帧在时间上重叠时双方都丢失，否则交给卫星节点
+func (s *sim) endTransmission(tx *transmission, raw []byte) {
+	r := s.report
+	for _, other := range s.airing {
+		if other.st != tx.st && other.start.Before(tx.end) && other.end.After(tx.start) {
+			r.CollidedFrames++
+			if abs := s.current().Abs; abs != s.lastCollision {
+				r.Collisions++
+				s.lastCollision = abs
+			}
+			return
+		}
+	}
+
+	frame, err := protocol.DeserializeTDMAFrame(raw)
+	if err != nil {
+		r.CRCErrors++
+		return
+	}
+	// 消息由地面站产生，卫星接受后按产生时刻统计时延
+	m, ok := traffic.Decode(frame.Data)
+	if !s.deliver(tx.st, frame) {
+		return
+	}
+	if abs := s.current().Abs; abs != s.lastUsed {
+		r.SlotsUsed++
+		s.lastUsed = abs
+	}
+	r.Airtime += tx.end.Sub(tx.start)
+	if !ok {
+		return
+	}
+	latency := s.clk.Now().Sub(m.Timestamp)
+	r.Latencies = append(r.Latencies, latency)
+	r.DeliveredBytes += int64(len(m.Payload))
+	tx.st.stats.Delivered++
+	tx.st.stats.LatencySum += latency
+}
+
+// 地面站统计
+type stationStats struct {
+	Generated   int64
+	Sent        int64
+	Delivered   int64
+	QueueDrops  int64
+	UplinkLost  int64
+	Rejoins     int64
+	LatencySum  time.Duration
+	DownlinkErr int64
+}
+
+// 仿真中的地面站
+type station struct {
+	sim      *sim
+	id       string
+	spec     StationSpec
+	conn     *replayConn // 与卫星节点之间的连接，收集卫星写入的响应
+	uplink   *channel.Channel
+	downlink *channel.Channel
+	gen      traffic.Generator // nil表示不产生业务
+	flow     string
+
+	joined  bool
+	slotID  int
+	lastAck time.Time
+	seq     uint64
+	outbox  []traffic.Message
+
+	stats stationStats
+}
+
+func newStation(s *sim, spec StationSpec, seed int64) (*station, error) {
+	st := &station{
+		sim:    s,
+		id:     spec.ID,
+		spec:   spec,
+		conn:   newSimConn(spec.ID),
+		slotID: -1,
+	}
+
+	if spec.Traffic.Type != "" {
+		cfg := spec.Traffic
+		if cfg.Seed == 0 {
+			cfg.Seed = seed
+		}
+		gen, err := traffic.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		st.gen = gen
+		st.flow = cfg.FlowName(0)
+	}
+
+	// 未指定种子的链路由场景种子派生，保证结果可复现
+	cfg := s.sc.Channel.Link(spec.ID)
+	up, down := cfg, cfg
+	if cfg.Seed == 0 {
+		up.Seed, down.Seed = seed+1, seed+2
+	}
+	st.uplink = channel.New(up)
+	st.downlink = channel.New(down)
+	st.uplink.SetClock(s.clk)
+	st.downlink.SetClock(s.clk)
+	return st, nil
+}
+
+// 地面站与卫星节点之间的内存连接
+func newSimConn(nodeID string) *replayConn {
+	return &replayConn{remote: nodeID, nodes: make(map[string]bool)}
+}
+
+// 在配置的时刻入网并启动业务
+func (st *station) begin() {
+	clk := st.sim.clk
+	start := time.Duration(st.spec.Start)
+	clk.AfterFunc(start, st.join)
+	clk.AfterFunc(start+protocol.HEARTBEAT_INTERVAL, st.heartbeatTick)
+	if st.gen != nil {
+		clk.AfterFunc(start, st.generate)
+	}
+}
+
+// 构造标注当前绝对时隙号的帧
+func (st *station) newFrame(slotID int, data []byte) *protocol.TDMAFrame {
+	frame := protocol.NewTDMAFrame(uint32(slotID), st.id, data)
+	frame.SetAbsSlot(uint64(st.sim.current().Abs))
+	return frame
+}
+
+// 发送入网请求，未收到确认时在一个心跳间隔后重试
+func (st *station) join() {
+	st.sendControl(protocol.MSG_JOIN)
+	st.sim.clk.AfterFunc(protocol.HEARTBEAT_INTERVAL, func() {
+		if !st.joined {
+			st.join()
+		}
+	})
+}
+
+// 心跳，超时未收到确认时重新入网
+func (st *station) heartbeatTick() {
+	clk := st.sim.clk
+	if st.joined {
+		if clk.Since(st.lastAck) > protocol.HEARTBEAT_INTERVAL*protocol.HEARTBEAT_MISS_LIMIT {
+			st.rejoin()
+		} else {
+			st.sendControl(protocol.MSG_HEARTBEAT)
+		}
+	}
+	clk.AfterFunc(protocol.HEARTBEAT_INTERVAL, st.heartbeatTick)
+}
+
+// 丢弃当前时隙重新入网
+func (st *station) rejoin() {
+	st.joined = false
+	st.slotID = -1
+	st.stats.Rejoins++
+	st.join()
+}
+
+// 按业务源的间隔产生下一条消息，业务结束后不再安排
+func (st *station) generate() {
+	wait, payload, ok := st.gen.Next()
+	if !ok {
+		return
+	}
+	st.sim.clk.AfterFunc(wait, func() {
+		st.seq++
+		st.stats.Generated++
+		if len(st.outbox) >= maxOutboxSize {
+			st.outbox = st.outbox[1:]
+			st.stats.QueueDrops++
+		}
+		st.outbox = append(st.outbox, traffic.Message{
+			Source:    st.id,
+			Flow:      st.flow,
+			Seq:       st.seq,
+			Timestamp: st.sim.clk.Now(),
+			Payload:   payload,
+		})
+		st.generate()
+	})
+}
+
+// 时隙开始：在自己的时隙内按上行速率连续发送队列中的消息
+func (st *station) onSlot(cur scheduler.SlotTime) {
+	if !st.joined || cur.Slot != st.slotID {
+		return
+	}
+
+	var offset time.Duration
+	for len(st.outbox) > 0 {
+		m := st.outbox[0]
+		raw, _ := st.newFrame(st.slotID, m.Encode()).Serialize()
+		airtime := st.sim.airtime(len(raw))
+		if offset+airtime > st.sim.capacity {
+			break
+		}
+		st.outbox = st.outbox[1:]
+		offset += airtime
+		st.stats.Sent++
+		if !st.sendData(raw, offset, airtime) {
+			st.stats.UplinkLost++
+		}
+	}
+}
+
+// 发送控制帧；控制帧不占用时隙，不参与冲突
+func (st *station) sendControl(data string) {
+	raw, _ := st.newFrame(0, []byte(data)).Serialize()
+	out, delay, dropped := st.uplink.Transmit(raw)
+	if dropped {
+		return
+	}
+	st.sim.clk.AfterFunc(delay, func() { st.sim.receive(st, out) })
+}
+
+// 经上行信道发送数据帧，offset为帧发送完成前的时长；返回帧是否未被信道丢失
+func (st *station) sendData(raw []byte, offset, airtime time.Duration) bool {
+	out, delay, dropped := st.uplink.Transmit(raw)
+	if dropped {
+		return false
+	}
+	clk := st.sim.clk
+	end := offset + delay
+	tx := &transmission{st: st, start: clk.Now().Add(end - airtime), end: clk.Now().Add(end)}
+	clk.AfterFunc(end-airtime, func() { st.sim.beginTransmission(tx) })
+	clk.AfterFunc(end, func() { st.sim.endTransmission(tx, out) })
+	return true
+}
+
+// 经下行信道发送卫星的响应帧
+func (st *station) downlinkFrame(frame *protocol.TDMAFrame) {
+	raw, err := frame.Serialize()
+	if err != nil {
+		return
+	}
+	out, delay, dropped := st.downlink.Transmit(raw)
+	if dropped {
+		return
+	}
+	st.sim.clk.AfterFunc(delay, func() { st.receive(out) })
+}
+
+// 接收下行帧
+func (st *station) receive(raw []byte) {
+	frame, err := protocol.DeserializeTDMAFrame(raw)
+	if err != nil || frame.Validate() != nil {
+		st.stats.DownlinkErr++
+		return
+	}
+	msg := string(frame.Data)
+
+	switch {
+	case strings.HasPrefix(msg, protocol.MSG_JOIN_ACK):
+		parts := strings.SplitN(strings.TrimPrefix(msg, protocol.MSG_JOIN_ACK), "_", 2)
+		if slotID, err := strconv.Atoi(parts[0]); err == nil {
+			st.joined = true
+			st.slotID = slotID
+			st.lastAck = st.sim.clk.Now()
+		}
+
+	case strings.HasPrefix(msg, protocol.MSG_JOIN_REJECT):
+		// 被隔离或拒绝接入，入网请求按心跳间隔重试
+		if st.joined {
+			st.rejoin()
+		}
+
+	case strings.HasPrefix(msg, protocol.MSG_HEARTBEAT_ACK):
+		parts := strings.SplitN(strings.TrimPrefix(msg, protocol.MSG_HEARTBEAT_ACK), "_", 2)
+		if slotID, err := strconv.Atoi(parts[0]); err == nil && st.joined {
+			st.slotID = slotID
+			st.lastAck = st.sim.clk.Now()
+		}
+
+	case msg == protocol.MSG_HEARTBEAT_REJECT:
+		if st.joined {
+			st.rejoin()
+		}
+
+	case strings.HasPrefix(msg, protocol.MSG_ACK_SLOT):
+		if slotID, err := strconv.Atoi(strings.TrimPrefix(msg, protocol.MSG_ACK_SLOT)); err == nil && st.joined {
+			st.slotID = slotID
+		}
+	}
+}
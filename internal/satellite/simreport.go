@@ -0,0 +1,120 @@
+package satellite
+
+import (
+	"fmt"
+	"sort"
+	"tdma-network/internal/security"
+	"time"
+)
+
+// 仿真结果
+type report struct {
+	Duration     time.Duration // 仿真（虚拟）时长
+	Wall         time.Duration // 实际耗时
+	Events       int
+	Bitrate      int
+	SlotDuration time.Duration
+	Stations     []*station
+
+	SlotsTotal    int64         // 经过的时隙数
+	SlotsAssigned int64         // 已分配给地面站的时隙数
+	SlotsUsed     int64         // 成功承载数据的时隙数
+	Airtime       time.Duration // 成功接收的帧占用的发送时长
+
+	DeliveredBytes int64
+	Latencies      []time.Duration
+
+	Collisions     int64 // 发生冲突的时隙数
+	CollidedFrames int64 // 因冲突丢失的帧数
+	SlotMismatches int64 // 到达时已不在所属时隙的帧数
+	CRCErrors      int64 // 上行校验失败的帧数
+	AllocFailures  int64 // 没有可用时隙导致的分配失败
+	Violations     int64 // 卫星记录的安全违规，如在其他节点的时隙内发送
+	Quarantines    int64
+}
+
+// 取出卫星节点指标中的统计
+func (r *report) satellite(sn *SatelliteNode) {
+	m := sn.metrics
+	r.SlotMismatches += int64(m.slotMismatches.Value() + m.staleFrames.With(staleExpired).Value() + m.staleFrames.With(staleFuture).Value())
+	r.CRCErrors += int64(m.crcFailures.Value())
+	r.AllocFailures += int64(m.allocFailures.Value())
+	for _, kind := range []string{security.VIOLATION_SLOT_SPOOF, security.VIOLATION_REPLAY} {
+		r.Violations += int64(m.violations.With(kind).Value())
+	}
+	r.Quarantines += int64(m.quarantines.Value())
+}
+
+// 时延分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+// 百分比，分母为0时返回0
+func ratio(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// 打印仿真报告
+func (r *report) Print() {
+	fmt.Printf("=== 仿真报告 ===\n")
+	fmt.Printf("仿真时长: %v (实际耗时 %v, 事件 %d)\n", r.Duration, r.Wall.Round(time.Millisecond), r.Events)
+
+	var generated, sent, delivered, queueDrops, uplinkLost int64
+	fmt.Printf("地面站:\n")
+	for _, st := range r.Stations {
+		s := st.stats
+		generated += s.Generated
+		sent += s.Sent
+		delivered += s.Delivered
+		queueDrops += s.QueueDrops
+		uplinkLost += s.UplinkLost
+
+		var avg time.Duration
+		if s.Delivered > 0 {
+			avg = s.LatencySum / time.Duration(s.Delivered)
+		}
+		fmt.Printf("  %s: 时隙 %d, 产生 %d, 发送 %d, 送达 %d (%.1f%%), 队列丢弃 %d, 信道丢失 %d, 平均时延 %v, 重新入网 %d\n",
+			st.id, st.slotID, s.Generated, s.Sent, s.Delivered, ratio(s.Delivered, s.Generated),
+			s.QueueDrops, s.UplinkLost, avg.Round(time.Millisecond), s.Rejoins)
+	}
+
+	seconds := r.Duration.Seconds()
+	if seconds > 0 {
+		bps := float64(r.DeliveredBytes*8) / seconds
+		fmt.Printf("吞吐量: %.1f bit/s (上行速率的 %.1f%%)\n", bps, bps*100/float64(r.Bitrate))
+	}
+	fmt.Printf("消息: 产生 %d, 发送 %d, 送达 %d (%.1f%%), 队列丢弃 %d, 信道丢失 %d\n",
+		generated, sent, delivered, ratio(delivered, generated), queueDrops, uplinkLost)
+
+	if len(r.Latencies) > 0 {
+		sorted := append([]time.Duration(nil), r.Latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var sum time.Duration
+		for _, l := range sorted {
+			sum += l
+		}
+		fmt.Printf("时延: 平均 %v, P50 %v, P95 %v, P99 %v, 最大 %v\n",
+			(sum / time.Duration(len(sorted))).Round(time.Millisecond),
+			percentile(sorted, 0.50).Round(time.Millisecond),
+			percentile(sorted, 0.95).Round(time.Millisecond),
+			percentile(sorted, 0.99).Round(time.Millisecond),
+			sorted[len(sorted)-1].Round(time.Millisecond))
+	}
+
+	capacity := time.Duration(r.SlotsTotal) * r.SlotDuration
+	fmt.Printf("时隙: 共 %d, 已分配 %.1f%%, 承载数据 %.1f%%, 容量利用率 %.1f%%\n",
+		r.SlotsTotal, ratio(r.SlotsAssigned, r.SlotsTotal), ratio(r.SlotsUsed, r.SlotsTotal),
+		ratio(int64(r.Airtime), int64(capacity)))
+	fmt.Printf("冲突: 时隙 %d, 丢失帧 %d\n", r.Collisions, r.CollidedFrames)
+	fmt.Printf("时隙错位: %d, 上行CRC错误: %d, 时隙分配失败: %d\n", r.SlotMismatches, r.CRCErrors, r.AllocFailures)
+	fmt.Printf("安全违规: %d, 隔离: %d\n", r.Violations, r.Quarantines)
+	fmt.Printf("================\n")
+}
@@ -0,0 +1,168 @@
+package satellite
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"tdma-network/internal/auth"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+// 由内存中的测试CA签发主体CN为cn的证书，ca为nil时生成自签名的CA证书
+func issueCert(t *testing.T, ca *tls.Certificate, cn string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"sat.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, signer := tmpl, any(key)
+	if ca == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent, signer = ca.Leaf, ca.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// 按-tls-bind-node配置卫星的TLS，返回签发证书的CA与对应的证书池
+func enableBindCert(t *testing.T, sn *SatelliteNode) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	ca := issueCert(t, nil, "TDMA Test CA")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	sn.tlsServer = &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{issueCert(t, &ca, "SAT_T")},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	sn.bindCert = true
+	return ca, pool
+}
+
+// 在本地端口上以TLS接受一个连接并交给handleConnection，处理结束时关闭done
+func serveTLSOnce(t *testing.T, sn *SatelliteNode) (addr string, done <-chan struct{}) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		sn.handleConnection(tls.Server(conn, sn.tlsServer))
+	}()
+	return ln.Addr().String(), ch
+}
+
+// 以cfg建立到addr的TLS连接
+func dialTLS(t *testing.T, addr string, cfg *tls.Config) (net.Conn, error) {
+	t.Helper()
+	raw, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := auth.DialTLS(raw, "", cfg)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, err
+}
+
+// 在TLS连接上发送一帧并读取一个响应
+func exchange(t *testing.T, sn *SatelliteNode, conn net.Conn, nodeID, data string) string {
+	t.Helper()
+	frame := protocol.NewTDMAFrame(0, nodeID, []byte(data))
+	frame.SetAbsSlot(uint64(sn.scheduler.Current().Abs))
+	if err := protocol.WriteFrame(conn, frame); err != nil {
+		t.Fatalf("WriteFrame: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := protocol.ReadFrame(conn)
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	return string(reply.Data)
+}
+
+func TestBindCertRejectsOtherNodeID(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	ca, pool := enableBindCert(t, sn)
+	addr, _ := serveTLSOnce(t, sn)
+	conn, err := dialTLS(t, addr, &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		RootCAs:      pool,
+		ServerName:   "sat.test",
+		Certificates: []tls.Certificate{issueCert(t, &ca, "GS1")},
+	})
+	if err != nil {
+		t.Fatalf("DialTLS: %v", err)
+	}
+
+	// 帧的节点ID与证书CN不一致时拒绝，不建立会话
+	if got := exchange(t, sn, conn, "GS2", protocol.MSG_JOIN); got != protocol.MSG_AUTH_REJECT+auth.REASON_CERT_MISMATCH {
+		t.Fatalf("reply to GS2 = %q, want %q", got, protocol.MSG_AUTH_REJECT+auth.REASON_CERT_MISMATCH)
+	}
+	if _, ok := sn.sessions.Get("GS2"); ok {
+		t.Fatalf("GS2 joined over a connection certified as GS1")
+	}
+	if got := sn.metrics.authFailures.With(auth.REASON_CERT_MISMATCH).Value(); got != 1 {
+		t.Fatalf("cert_mismatch failures = %v, want 1", got)
+	}
+
+	// 同一连接上证书对应的节点可以正常入网
+	if got := exchange(t, sn, conn, "GS1", protocol.MSG_JOIN); !strings.HasPrefix(got, protocol.MSG_JOIN_ACK) {
+		t.Fatalf("reply to GS1 = %q, want %s...", got, protocol.MSG_JOIN_ACK)
+	}
+}
+
+func TestBindCertRequiresClientCertificate(t *testing.T) {
+	sn, _ := newTestSatellite(t)
+	_, pool := enableBindCert(t, sn)
+	addr, done := serveTLSOnce(t, sn)
+
+	conn, err := dialTLS(t, addr, &tls.Config{MinVersion: tls.VersionTLS13, RootCAs: pool, ServerName: "sat.test"})
+	if err == nil {
+		// TLS 1.3中服务端在客户端握手完成后才校验客户端证书
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err = conn.Read(make([]byte, 1)); err == nil {
+			t.Fatalf("connection without client certificate was served")
+		}
+	}
+	<-done
+	if got := sn.metrics.tlsFailures.Value(); got != 1 {
+		t.Fatalf("TLS handshake failures = %v, want 1", got)
+	}
+}
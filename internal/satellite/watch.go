@@ -0,0 +1,306 @@
+package satellite
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"tdma-network/internal/scheduler"
+	"time"
+	"unicode/utf8"
+)
+
+// 终端控制序列
+const (
+	ansiReset      = "\x1b[0m"
+	ansiBold       = "\x1b[1m"
+	ansiDim        = "\x1b[2m"
+	ansiReverse    = "\x1b[7m"
+	ansiRed        = "\x1b[31m"
+	ansiGreen      = "\x1b[32m"
+	ansiYellow     = "\x1b[33m"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiClearBelow = "\x1b[J"
+	ansiAltScreen  = "\x1b[?1049h\x1b[?25l" // 切换到备用屏幕并隐藏光标
+	ansiMainScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// 仪表盘参数
+const (
+	activityWindow = 10              // 时隙利用率统计的超帧数
+	rateWindow     = 5 * time.Second // 收发速率的统计窗口
+	wheelCells     = 15              // 时隙轮最多显示的时隙数
+	errorLines     = 8               // 显示的最近错误条数
+	errorWidth     = 110             // 错误行的最大显示宽度
+)
+
+// 绝对时隙号，自TDMA纪元起经过的时隙数，帧结构变更前后连续
+func (sn *SatelliteNode) absSlot(t time.Time) int64 {
+	return sn.scheduler.SlotAt(t).Abs
+}
+
+// 记录各时隙是否收到过上行数据，用于计算时隙利用率
+type slotActivity struct {
+	mu    sync.Mutex
+	total int
+	base  int64          // 帧结构起点的绝对时隙号
+	seen  map[int64]bool // 绝对时隙号
+}
+
+func newSlotActivity(total int) *slotActivity {
+	return &slotActivity{total: total, seen: make(map[int64]bool)}
+}
+
+// 帧结构变更后重新开始统计
+func (a *slotActivity) reset(l scheduler.Layout) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.total = l.TotalSlots
+	a.base = l.Base
+	a.seen = make(map[int64]bool)
+}
+
+// 记录abs时隙内收到了上行数据
+func (a *slotActivity) mark(abs int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.seen[abs] = true
+	keep := int64(a.total * activityWindow)
+	if len(a.seen) > 2*int(keep) {
+		for s := range a.seen {
+			if s < abs-keep {
+				delete(a.seen, s)
+			}
+		}
+	}
+}
+
+// 时隙slot在current之前最近activityWindow次出现中收到上行数据的比例
+func (a *slotActivity) utilisation(slot int, current int64) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	total := int64(a.total)
+	last := current - 1 - ((current-1-a.base-int64(slot))%total+total)%total
+	used := 0
+	for k := int64(0); k < activityWindow; k++ {
+		if a.seen[last-k*total] {
+			used++
+		}
+	}
+	return float64(used) / activityWindow
+}
+
+// 收发计数采样
+type rateSample struct {
+	at     time.Time
+	rx, tx float64
+}
+
+// 终端仪表盘
+type dashboard struct {
+	sn    *SatelliteNode
+	out   io.Writer
+	rates map[string][]rateSample
+}
+
+// 在out上显示仪表盘，每个时隙边界刷新一次，直到stop关闭
+func (sn *SatelliteNode) watch(out io.Writer, stop <-chan struct{}) {
+	d := &dashboard{sn: sn, out: out, rates: make(map[string][]rateSample)}
+
+	io.WriteString(out, ansiAltScreen)
+	defer io.WriteString(out, ansiMainScreen)
+
+	for {
+		now := sn.clock.Now()
+		d.render(now)
+
+		// 对齐到下一个时隙边界
+		wait := sn.scheduler.SlotAt(now).End.Sub(now)
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// 绘制一帧画面，从左上角覆盖上一帧
+func (d *dashboard) render(now time.Time) {
+	sn := d.sn
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString(ansiClearLine + "\n")
+	}
+
+	// 按已生效的帧结构显示，与调度表的时隙数一致
+	slots := sn.scheduler.GetSlots()
+	total := len(slots)
+	st := sn.scheduler.Layout().At(now)
+	abs, current := st.Abs, st.Slot
+	if current >= total {
+		current = total - 1
+	}
+	assigned := 0
+	for _, s := range slots {
+		if s.Status == "ASSIGNED" {
+			assigned++
+		}
+	}
+
+	b.WriteString(ansiHome)
+	line("%sTDMA %s%s  %s  时隙 %d/%d  超帧 %d  已分配 %d/%d (%.0f%%)", ansiBold, sn.nodeID, ansiReset,
+		now.Format("2006-01-02 15:04:05"), current, total, st.Superframe, assigned, total, 100*float64(assigned)/float64(total))
+	line("")
+
+	// 时隙轮，当前时隙居中
+	cells := min(total, wheelCells)
+	var wheel, marker strings.Builder
+	for k := 0; k < cells; k++ {
+		slot := ((current-cells/2+k)%total + total) % total
+		cell := fmt.Sprintf(" %2d ", slot)
+		switch {
+		case slot == current:
+			wheel.WriteString(ansiReverse + ansiBold + cell + ansiReset)
+			marker.WriteString("  ▲ ")
+		case slots[slot].Status == "ASSIGNED":
+			wheel.WriteString(ansiGreen + cell + ansiReset)
+			marker.WriteString("    ")
+		default:
+			wheel.WriteString(ansiDim + cell + ansiReset)
+			marker.WriteString("    ")
+		}
+	}
+	line("  %s", wheel.String())
+	line("  %s", marker.String())
+	line("")
+
+	// 各时隙归属与利用率
+	line("%s  %s  %s %s 利用率(最近%d超帧)%s", ansiBold, pad("时隙", 4), pad("归属", 16), pad("状态", 9), activityWindow, ansiReset)
+	for _, s := range slots {
+		u := sn.activity.utilisation(s.SlotID, abs)
+		row := fmt.Sprintf("  %4d  %-16s %-9s %s %3.0f%%", s.SlotID, s.NodeID, s.Status, bar(u, 10), 100*u)
+		switch {
+		case s.SlotID == current:
+			line("%s%s%s", ansiReverse, row, ansiReset)
+		case s.Status != "ASSIGNED":
+			line("%s%s%s", ansiDim, row, ansiReset)
+		default:
+			line("%s", row)
+		}
+	}
+	line("")
+
+	// 会话收发速率
+	sessions := sn.sessions.List()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].NodeID < sessions[j].NodeID })
+	line("%s  %s %s  %s %s %s %s %s  %s%s", ansiBold, pad("会话", 16), padLeft("时隙", 4), pad("状态", 9),
+		padLeft("RX帧/s", 8), padLeft("TX帧/s", 8), padLeft("RX总计", 8), padLeft("TX总计", 8), "最近活动", ansiReset)
+	if len(sessions) == 0 {
+		line("%s  (无)%s", ansiDim, ansiReset)
+	}
+	active := make(map[string]bool, len(sessions))
+	for _, sess := range sessions {
+		active[sess.NodeID] = true
+		rx := sn.metrics.framesReceived.With(sess.NodeID).Value()
+		tx := sn.metrics.framesSent.With(sess.NodeID).Value()
+		rxRate, txRate := d.rate(sess.NodeID, now, rx, tx)
+		line("  %-16s %4d  %-9s %8.1f %8.1f %8.0f %8.0f  %s前", sess.NodeID, sess.SlotID, sess.State,
+			rxRate, txRate, rx, tx, now.Sub(sess.LastSeen).Round(time.Second))
+	}
+	for nodeID := range d.rates {
+		if !active[nodeID] {
+			delete(d.rates, nodeID)
+		}
+	}
+	line("")
+
+	// 最近错误
+	line("%s  最近错误%s", ansiBold, ansiReset)
+	entries := sn.errors.Entries()
+	if len(entries) > errorLines {
+		entries = entries[len(entries)-errorLines:]
+	}
+	if len(entries) == 0 {
+		line("%s  (无)%s", ansiDim, ansiReset)
+	}
+	for _, e := range entries {
+		color := ansiYellow
+		if e.Level >= slog.LevelError {
+			color = ansiRed
+		}
+		text := truncate(fmt.Sprintf("%s %-5s %s %s", e.Time.Format("15:04:05.000"), e.Level, e.Message, e.Attrs), errorWidth)
+		line("  %s%s%s", color, text, ansiReset)
+	}
+
+	b.WriteString(ansiClearBelow)
+	io.WriteString(d.out, b.String())
+}
+
+// 根据统计窗口内的计数变化计算收发速率（帧/秒）
+func (d *dashboard) rate(nodeID string, now time.Time, rx, tx float64) (float64, float64) {
+	hist := append(d.rates[nodeID], rateSample{at: now, rx: rx, tx: tx})
+	for len(hist) > 2 && now.Sub(hist[1].at) >= rateWindow {
+		hist = hist[1:]
+	}
+	d.rates[nodeID] = hist
+
+	first := hist[0]
+	dt := now.Sub(first.at).Seconds()
+	if dt <= 0 {
+		return 0, 0
+	}
+	return (rx - first.rx) / dt, (tx - first.tx) / dt
+}
+
+// 比例条
+func bar(frac float64, width int) string {
+	n := int(frac*float64(width) + 0.5)
+	return strings.Repeat("█", n) + strings.Repeat("░", width-n)
+}
+
+// 终端显示宽度，中日韩文字与全角符号占两列
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		switch {
+		case r >= 0x1100 && r <= 0x115F, r >= 0x2E80 && r <= 0xA4CF, r >= 0xAC00 && r <= 0xD7A3,
+			r >= 0xF900 && r <= 0xFAFF, r >= 0xFE30 && r <= 0xFE4F, r >= 0xFF00 && r <= 0xFF60, r >= 0xFFE0 && r <= 0xFFE6:
+			w += 2
+		default:
+			w++
+		}
+	}
+	return w
+}
+
+// 按显示宽度左对齐
+func pad(s string, width int) string {
+	if n := width - displayWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// 按显示宽度右对齐
+func padLeft(s string, width int) string {
+	if n := width - displayWidth(s); n > 0 {
+		return strings.Repeat(" ", n) + s
+	}
+	return s
+}
+
+// 按字符截断
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
@@ -0,0 +1,283 @@
+package scheduler
+
+import (
+	"fmt"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 帧结构：自Epoch起按SlotDuration划分时隙，每TotalSlots个时隙为一个超帧
+// 绝对时隙号跨帧结构变更连续，Epoch处的时隙号为Base
+type Layout struct {
+	Epoch time.Time
+	Base  int64
+	Config
+}
+
+// 以全局TDMA纪元为起点的帧结构
+func NewLayout(cfg Config) Layout {
+	return Layout{Epoch: protocol.TDMA_EPOCH, Config: cfg}
+}
+
+// t时刻所在时隙的绝对时隙号（不按超帧取模）
+func (l Layout) Abs(t time.Time) int64 {
+	elapsed := t.Sub(l.Epoch)
+	n := int64(elapsed / l.SlotDuration)
+	if elapsed < 0 && elapsed%l.SlotDuration != 0 {
+		n--
+	}
+	return l.Base + n
+}
+
+// t时刻所在的时隙
+func (l Layout) Slot(t time.Time) int {
+	return l.SlotOf(l.Abs(t))
+}
+
+// 绝对时隙号为abs的时隙在超帧中的序号
+func (l Layout) SlotOf(abs int64) int {
+	total := int64(l.TotalSlots)
+	return int(((abs-l.Base)%total + total) % total)
+}
+
+// 绝对时隙号为abs的时隙的开始时刻
+func (l Layout) SlotStart(abs int64) time.Time {
+	return l.Epoch.Add(time.Duration(abs-l.Base) * l.SlotDuration)
+}
+
+// 在at时刻切换到cfg的帧结构，at处的绝对时隙号保持连续
+func (l Layout) Next(cfg Config, at time.Time) Layout {
+	return Layout{Epoch: at, Base: l.Abs(at), Config: cfg}
+}
+
+// 超帧时长
+func (l Layout) SuperframeDuration() time.Duration {
+	return time.Duration(l.TotalSlots) * l.SlotDuration
+}
+
+// 一个时隙在时间轴上的位置
+type SlotTime struct {
+	Abs        int64     // 绝对时隙号
+	Superframe int64     // 自帧结构起点的超帧序号
+	Slot       int       // 超帧内的时隙
+	Start, End time.Time // 时隙的开始时刻与下一时隙的开始时刻
+}
+
+// t时刻所在的时隙
+func (l Layout) At(t time.Time) SlotTime {
+	abs := l.Abs(t)
+	n, total := abs-l.Base, int64(l.TotalSlots)
+	superframe := n / total
+	if n%total < 0 {
+		superframe--
+	}
+	return SlotTime{
+		Abs:        abs,
+		Superframe: superframe,
+		Slot:       l.SlotOf(abs),
+		Start:      l.SlotStart(abs),
+		End:        l.SlotStart(abs + 1),
+	}
+}
+
+// 不早于t的第一个超帧边界
+func (l Layout) NextSuperframe(t time.Time) time.Time {
+	frame := l.SuperframeDuration()
+	n := t.Sub(l.Epoch) / frame
+	at := l.Epoch.Add(n * frame)
+	if at.Before(t) {
+		at = at.Add(frame)
+	}
+	return at
+}
+
+// t是否为超帧边界
+func (l Layout) IsSuperframeBoundary(t time.Time) bool {
+	return t.Sub(l.Epoch)%l.SuperframeDuration() == 0
+}
+
+// 帧结构变更，在原帧结构的超帧边界生效
+type Change struct {
+	Layout Layout      // 新的帧结构，Epoch为生效时刻
+	Remap  map[int]int // 计划时已分配的时隙 -> 新帧结构中的时隙，-1表示没有可用的时隙
+}
+
+// 时隙from在新帧结构中的时隙：计划时已分配的按迁移表，其他时隙序号不变，超出新时隙数时为-1
+func (c Change) Target(from int) int {
+	if to, ok := c.Remap[from]; ok {
+		return to
+	}
+	if from >= 0 && from < c.Layout.TotalSlots {
+		return from
+	}
+	return -1
+}
+
+// 帧结构变更生效时时隙发生变化的节点，To为-1表示新帧结构中没有可用的时隙，时隙被释放
+type Move struct {
+	NodeID   string
+	From, To int
+}
+
+// 当前生效的帧结构
+func (s *TDMAScheduler) Layout() Layout {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.layout()
+}
+
+// 调用方需持有锁
+func (s *TDMAScheduler) layout() Layout {
+	return Layout{Epoch: s.epoch, Base: s.base, Config: Config{TotalSlots: s.totalSlots, SlotDuration: s.slotDuration}}
+}
+
+// t时刻的帧结构：已计划的变更在生效时刻之后即按新帧结构计算，不必等待ApplyChange
+func (s *TDMAScheduler) LayoutAt(t time.Time) Layout {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.layoutAt(t)
+}
+
+// 调用方需持有锁
+func (s *TDMAScheduler) layoutAt(t time.Time) Layout {
+	if s.pending != nil && !t.Before(s.pending.Layout.Epoch) {
+		return s.pending.Layout
+	}
+	return s.layout()
+}
+
+// t时刻所在的时隙，是卫星与地面站判断时隙的唯一依据
+func (s *TDMAScheduler) SlotAt(t time.Time) SlotTime {
+	return s.LayoutAt(t).At(t)
+}
+
+// 调度器时钟当前所在的时隙
+func (s *TDMAScheduler) Current() SlotTime {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.clock.Now()
+	return s.layoutAt(now).At(now)
+}
+
+// 尚未生效的帧结构变更
+func (s *TDMAScheduler) PendingChange() (Change, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.pending == nil {
+		return Change{}, false
+	}
+	return *s.pending, true
+}
+
+// 计划在at时刻切换到新的帧结构，at须为当前帧结构的超帧边界且晚于当前时刻
+// 已分配的时隙按序号保留，超出新时隙数的时隙依次迁移到最小的空闲时隙；
+// 变更生效前新分配的时隙只使用新帧结构中仍然有效且未被迁移占用的时隙
+func (s *TDMAScheduler) PlanChange(cfg Config, at time.Time) (Change, error) {
+	if err := cfg.Validate(); err != nil {
+		return Change{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending != nil {
+		return Change{}, fmt.Errorf("已有 %s 生效的帧结构变更", s.pending.Layout.Epoch.Format(time.RFC3339Nano))
+	}
+	if !at.After(s.clock.Now()) {
+		return Change{}, fmt.Errorf("生效时刻 %s 已过去", at.Format(time.RFC3339Nano))
+	}
+	if !s.layout().IsSuperframeBoundary(at) {
+		return Change{}, fmt.Errorf("生效时刻 %s 不是超帧边界", at.Format(time.RFC3339Nano))
+	}
+
+	remap := make(map[int]int)
+	reserved := make(map[int]bool)
+	var moving []int
+	for i := 0; i < s.totalSlots; i++ {
+		if s.slots[i].Status != "ASSIGNED" {
+			continue
+		}
+		if i < cfg.TotalSlots {
+			remap[i] = i
+			reserved[i] = true
+		} else {
+			moving = append(moving, i)
+		}
+	}
+	next := 0
+	for _, from := range moving {
+		for next < cfg.TotalSlots && reserved[next] {
+			next++
+		}
+		if next < cfg.TotalSlots {
+			remap[from] = next
+			reserved[next] = true
+		} else {
+			remap[from] = -1
+		}
+	}
+
+	s.pending = &Change{Layout: s.layout().Next(cfg, at), Remap: remap}
+	s.reserved = reserved
+	return *s.pending, nil
+}
+
+// 切换到已计划的帧结构，在变更的生效时刻调用，返回时隙发生变化的节点
+// 计划之后新分配的时隙保持序号不变；目标时隙已被占用（如管理接口强制分配）的节点失去时隙
+func (s *TDMAScheduler) ApplyChange() (Change, []Move, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == nil {
+		return Change{}, nil, fmt.Errorf("没有待生效的帧结构变更")
+	}
+	ch := *s.pending
+	cfg := ch.Layout.Config
+
+	slots := make(map[int]*SlotStatus, cfg.TotalSlots)
+	for i := 0; i < cfg.TotalSlots; i++ {
+		slots[i] = &SlotStatus{SlotID: i, Status: "FREE", Duration: cfg.SlotDuration}
+	}
+
+	var moves []Move
+	for from := 0; from < s.totalSlots; from++ {
+		old := s.slots[from]
+		if old.Status != "ASSIGNED" {
+			continue
+		}
+		to := ch.Target(from)
+		if to >= 0 && slots[to].Status != "FREE" {
+			to = -1
+		}
+		if to >= 0 {
+			slots[to].NodeID = old.NodeID
+			slots[to].Status = "ASSIGNED"
+			slots[to].StartTime = old.StartTime
+			slots[to].FragmentID = old.FragmentID
+		}
+		if to != from {
+			moves = append(moves, Move{NodeID: old.NodeID, From: from, To: to})
+		}
+	}
+
+	s.slots = slots
+	s.totalSlots = cfg.TotalSlots
+	s.slotDuration = cfg.SlotDuration
+	s.epoch = ch.Layout.Epoch
+	s.base = ch.Layout.Base
+	s.pending = nil
+	s.reserved = nil
+	return ch, moves, nil
+}
+
+// 时隙是否可以分配给新节点（调用方需持有锁）
+// 帧结构变更生效前不分配新帧结构中无效或已被迁移占用的时隙，也不分配调度表中尚不存在的时隙
+func (s *TDMAScheduler) free(slotID int) bool {
+	if slotID >= s.totalSlots || s.slots[slotID].Status != "FREE" {
+		return false
+	}
+	if s.pending == nil {
+		return true
+	}
+	return slotID < s.pending.Layout.TotalSlots && !s.reserved[slotID]
+}
@@ -1,8 +1,12 @@
 package scheduler
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
 	"time"
 )
 
@@ -22,8 +26,21 @@ type TDMAScheduler struct {
 	slots        map[int]*SlotStatus
 	totalSlots   int
 	slotDuration time.Duration
-	currentSlot  int
-	startTime    time.Time
+	epoch        time.Time // 帧结构的起点，当前时隙由时钟相对它计算
+	base         int64     // epoch处的绝对时隙号
+
+	pending  *Change      // 尚未生效的帧结构变更
+	reserved map[int]bool // 变更生效后被已分配时隙占用的新时隙
+
+	leaseDuration time.Duration // 时隙租约有效期，需通过续约保持
+
+	priorities map[string]int // 节点优先级
+
+	visible func(nodeID string) bool // 节点可见性判断，nil表示始终可见
+
+	admit func(nodeID string, priority, slots int, now time.Time) error // 准入检查，nil表示不限制
+
+	clock clock.Clock
 }
 
 // 创建新的TDMA调度器
@@ -32,8 +49,13 @@ func NewTDMAScheduler(totalSlots int, slotDuration time.Duration) *TDMAScheduler
 		slots:        make(map[int]*SlotStatus),
 		totalSlots:   totalSlots,
 		slotDuration: slotDuration,
-		currentSlot:  0,
-		startTime:    time.Now(),
+		epoch:        protocol.TDMA_EPOCH,
+
+		leaseDuration: slotDuration * 10,
+
+		priorities: make(map[string]int),
+
+		clock: clock.Real{},
 	}
 
 	// 初始化所有时隙为FREE状态
@@ -53,53 +75,70 @@ func (s *TDMAScheduler) AllocateTimeSlot(nodeID string, priority int) (slotID in
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if !s.isVisible(nodeID) {
+		return -1, fmt.Errorf("节点 %s 当前不可见", nodeID)
+	}
+
 	// 首先检查节点是否已经有分配的时隙
+	held := -1
 	for i := 0; i < s.totalSlots; i++ {
 		if s.slots[i].NodeID == nodeID && s.slots[i].Status == "ASSIGNED" {
 			// 如果已分配的时隙仍然有效，直接返回
-			if time.Since(s.slots[i].StartTime) < s.slotDuration*10 {
-				return i, nil
+			if s.clock.Since(s.slots[i].StartTime) < s.leaseDuration {
+				held = i
+				break
 			}
 			// 如果时隙已过期，释放它
 			s.slots[i].Status = "FREE"
 			s.slots[i].NodeID = ""
 		}
 	}
+	if held >= 0 {
+		if err := s.checkAdmission(nodeID, priority, s.countSlots(nodeID)); err != nil {
+			return -1, err
+		}
+		return held, nil
+	}
+	if err := s.checkAdmission(nodeID, priority, s.countSlots(nodeID)+1); err != nil {
+		return -1, err
+	}
 
-	// 优先分配当前时隙或下一个时隙
-	currentSlot := s.currentSlot
-	nextSlot := (currentSlot + 1) % s.totalSlots
+	// 优先分配当前时隙或下一个时隙，时隙号按当前时刻生效的帧结构计算，与Current一致
+	now := s.clock.Now()
+	l := s.layoutAt(now)
+	currentSlot := l.Slot(now)
+	nextSlot := (currentSlot + 1) % l.TotalSlots
 
 	// 检查当前时隙是否可用
-	if s.slots[currentSlot].Status == "FREE" {
+	if s.free(currentSlot) {
 		s.slots[currentSlot].NodeID = nodeID
 		s.slots[currentSlot].Status = "ASSIGNED"
-		s.slots[currentSlot].StartTime = time.Now()
+		s.slots[currentSlot].StartTime = s.clock.Now()
 		return currentSlot, nil
 	}
 
 	// 检查下一个时隙是否可用
-	if s.slots[nextSlot].Status == "FREE" {
+	if s.free(nextSlot) {
 		s.slots[nextSlot].NodeID = nodeID
 		s.slots[nextSlot].Status = "ASSIGNED"
-		s.slots[nextSlot].StartTime = time.Now()
+		s.slots[nextSlot].StartTime = s.clock.Now()
 		return nextSlot, nil
 	}
 
 	// 如果当前和下一个时隙都不可用，查找最近的可用时隙
-	for offset := 2; offset < s.totalSlots; offset++ {
-		slotID := (currentSlot + offset) % s.totalSlots
-		if s.slots[slotID].Status == "FREE" {
+	for offset := 2; offset < l.TotalSlots; offset++ {
+		slotID := (currentSlot + offset) % l.TotalSlots
+		if s.free(slotID) {
 			s.slots[slotID].NodeID = nodeID
 			s.slots[slotID].Status = "ASSIGNED"
-			s.slots[slotID].StartTime = time.Now()
+			s.slots[slotID].StartTime = s.clock.Now()
 			return slotID, nil
 		}
 	}
 
 	// 如果没有可用时隙，尝试重用最旧的已分配时隙
 	oldestSlot := -1
-	oldestTime := time.Now()
+	oldestTime := s.clock.Now()
 	for i := 0; i < s.totalSlots; i++ {
 		if s.slots[i].Status == "ASSIGNED" {
 			if s.slots[i].StartTime.Before(oldestTime) {
@@ -109,28 +148,110 @@ func (s *TDMAScheduler) AllocateTimeSlot(nodeID string, priority int) (slotID in
 		}
 	}
 
-	if oldestSlot != -1 && time.Since(oldestTime) > s.slotDuration*5 {
+	// 只回收租约已过期的时隙，不挤占其他节点的有效租约
+	if oldestSlot != -1 && s.clock.Since(oldestTime) >= s.leaseDuration {
 		s.slots[oldestSlot].NodeID = nodeID
 		s.slots[oldestSlot].Status = "ASSIGNED"
-		s.slots[oldestSlot].StartTime = time.Now()
+		s.slots[oldestSlot].StartTime = s.clock.Now()
 		return oldestSlot, nil
 	}
 
 	return -1, fmt.Errorf("没有可用的时隙")
 }
 
+// 设置时隙租约有效期
+func (s *TDMAScheduler) SetLeaseDuration(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaseDuration = d
+}
+
+// 设置时钟，用于在虚拟时间下运行
+func (s *TDMAScheduler) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// 设置节点可见性判断，不可见的节点不会被分配时隙
+func (s *TDMAScheduler) SetVisibility(visible func(nodeID string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.visible = visible
+}
+
+// 设置准入检查，节点以priority优先级持有slots个时隙不被允许时返回错误
+// 分配时隙与更新优先级时调用（更新优先级时slots为0），错误原样返回给调用方
+func (s *TDMAScheduler) SetAdmission(admit func(nodeID string, priority, slots int, now time.Time) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admit = admit
+}
+
+// 准入检查（调用方需持有锁）
+func (s *TDMAScheduler) checkAdmission(nodeID string, priority, slots int) error {
+	if s.admit == nil {
+		return nil
+	}
+	return s.admit(nodeID, priority, slots, s.clock.Now())
+}
+
+// 节点持有的时隙数（调用方需持有锁）
+func (s *TDMAScheduler) countSlots(nodeID string) int {
+	n := 0
+	for i := 0; i < s.totalSlots; i++ {
+		if s.slots[i].Status == "ASSIGNED" && s.slots[i].NodeID == nodeID {
+			n++
+		}
+	}
+	return n
+}
+
+// 判断节点是否可见（调用方需持有锁）
+func (s *TDMAScheduler) isVisible(nodeID string) bool {
+	return s.visible == nil || s.visible(nodeID)
+}
+
+// 回收已不可见（LOS）节点的时隙，返回被回收的时隙及其原节点
+func (s *TDMAScheduler) ReclaimInvisible() map[int]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reclaimed := make(map[int]string)
+	if s.visible == nil {
+		return reclaimed
+	}
+	for i := 0; i < s.totalSlots; i++ {
+		slot := s.slots[i]
+		if slot.Status == "ASSIGNED" && !s.visible(slot.NodeID) {
+			reclaimed[i] = slot.NodeID
+			slot.Status = "FREE"
+			slot.NodeID = ""
+			slot.FragmentID = 0
+		}
+	}
+	return reclaimed
+}
+
 // 分配连续时隙
 func (s *TDMAScheduler) AllocateConsecutiveSlots(nodeID string, count int) ([]int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if !s.isVisible(nodeID) {
+		return nil, fmt.Errorf("节点 %s 当前不可见", nodeID)
+	}
+	if err := s.checkAdmission(nodeID, s.priorities[nodeID], s.countSlots(nodeID)+count); err != nil {
+		return nil, err
+	}
+
 	var slotIDs []int
 
 	// 查找连续可用时隙
 	for i := 0; i <= s.totalSlots-count; i++ {
 		available := true
 		for j := 0; j < count; j++ {
-			if s.slots[i+j].Status != "FREE" {
+			if !s.free(i + j) {
 				available = false
 				break
 			}
@@ -140,7 +261,7 @@ func (s *TDMAScheduler) AllocateConsecutiveSlots(nodeID string, count int) ([]in
 			for j := 0; j < count; j++ {
 				s.slots[i+j].NodeID = nodeID
 				s.slots[i+j].Status = "ASSIGNED"
-				s.slots[i+j].StartTime = time.Now()
+				s.slots[i+j].StartTime = s.clock.Now()
 				slotIDs = append(slotIDs, i+j)
 			}
 			return slotIDs, nil
@@ -166,6 +287,62 @@ func (s *TDMAScheduler) ReleaseTimeSlot(slotID int) error {
 	return nil
 }
 
+// 时隙已被其他节点占用
+var ErrSlotTaken = errors.New("时隙已被其他节点占用")
+
+// 将空闲时隙强制分配给节点，并释放节点原先持有的其他时隙，返回被释放的时隙
+// 时隙归属其他节点时返回ErrSlotTaken，节点原有时隙保持不变
+// 不检查可见性，不可见节点的时隙仍会在下一次回收时释放
+func (s *TDMAScheduler) AssignTimeSlot(slotID int, nodeID string) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slotID < 0 || slotID >= s.totalSlots {
+		return nil, fmt.Errorf("无效的时隙ID")
+	}
+	if nodeID == "" {
+		return nil, fmt.Errorf("节点ID为空")
+	}
+	if owner := s.slots[slotID].NodeID; owner != "" && owner != nodeID {
+		return nil, fmt.Errorf("%w: 时隙 %d 归属 %s", ErrSlotTaken, slotID, owner)
+	}
+
+	var released []int
+	for id, slot := range s.slots {
+		if id == slotID || slot.NodeID != nodeID {
+			continue
+		}
+		slot.Status = "FREE"
+		slot.NodeID = ""
+		slot.FragmentID = 0
+		released = append(released, id)
+	}
+	sort.Ints(released)
+
+	s.slots[slotID].NodeID = nodeID
+	s.slots[slotID].Status = "ASSIGNED"
+	s.slots[slotID].StartTime = s.clock.Now()
+	s.slots[slotID].FragmentID = 0
+	return released, nil
+}
+
+// 续约时隙，仅当时隙仍归属该节点时成功
+func (s *TDMAScheduler) RenewTimeSlot(slotID int, nodeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slotID < 0 || slotID >= s.totalSlots {
+		return fmt.Errorf("无效的时隙ID")
+	}
+
+	if s.slots[slotID].Status != "ASSIGNED" || s.slots[slotID].NodeID != nodeID {
+		return fmt.Errorf("时隙 %d 不属于节点 %s", slotID, nodeID)
+	}
+
+	s.slots[slotID].StartTime = s.clock.Now()
+	return nil
+}
+
 // 获取调度表
 func (s *TDMAScheduler) GetSchedule() map[int]string {
 	s.mu.RLock()
@@ -185,14 +362,44 @@ func (s *TDMAScheduler) GetSchedule() map[int]string {
 func (s *TDMAScheduler) UpdatePriority(nodeID string, newPriority int) error {
 	// 这里可以实现基于优先级的时隙重新分配
 	// 简化实现，只记录优先级
+	if nodeID == "" {
+		return fmt.Errorf("节点ID为空")
+	}
+	if newPriority < 0 {
+		return fmt.Errorf("无效的优先级: %d", newPriority)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkAdmission(nodeID, newPriority, 0); err != nil {
+		return err
+	}
+	s.priorities[nodeID] = newPriority
 	return nil
 }
 
+// 获取各节点的优先级
+func (s *TDMAScheduler) GetPriorities() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	priorities := make(map[string]int, len(s.priorities))
+	for nodeID, p := range s.priorities {
+		priorities[nodeID] = p
+	}
+	return priorities
+}
+
 // 获取当前时隙
 func (s *TDMAScheduler) GetCurrentSlot() int {
+	return s.Current().Slot
+}
+
+// 获取总时隙数
+func (s *TDMAScheduler) TotalSlots() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.currentSlot
+	return s.totalSlots
 }
 
 // 获取时隙状态
@@ -207,37 +414,43 @@ func (s *TDMAScheduler) GetSlotStatus(slotID int) (*SlotStatus, error) {
 	return s.slots[slotID], nil
 }
 
+// 获取全部时隙状态的副本，按时隙顺序
+func (s *TDMAScheduler) GetSlots() []SlotStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	slots := make([]SlotStatus, s.totalSlots)
+	for i := 0; i < s.totalSlots; i++ {
+		slots[i] = *s.slots[i]
+	}
+	return slots
+}
+
+// 获取时隙持续时间
+func (s *TDMAScheduler) SlotDuration() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slotDuration
+}
+
 // 启动调度器
+// 当前时隙随时由时钟与帧结构计算，不需要推进时隙的调度循环
 func (s *TDMAScheduler) Start() error {
-	go s.scheduleLoop()
 	return nil
 }
 
 // 停止调度器
 func (s *TDMAScheduler) Stop() error {
-	// 停止调度循环
 	return nil
 }
 
-// 调度循环
-func (s *TDMAScheduler) scheduleLoop() {
-	ticker := time.NewTicker(s.slotDuration)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		s.mu.Lock()
-		s.currentSlot = (s.currentSlot + 1) % s.totalSlots
-		s.mu.Unlock()
-	}
-}
-
 // 获取下一个可用时隙
 func (s *TDMAScheduler) GetNextAvailableSlot() (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for i := 0; i < s.totalSlots; i++ {
-		if s.slots[i].Status == "FREE" {
+		if s.free(i) {
 			return i, nil
 		}
 	}
@@ -250,8 +463,9 @@ func (s *TDMAScheduler) PrintStatus() {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := s.clock.Now()
 	fmt.Printf("=== TDMA调度器状态 ===\n")
-	fmt.Printf("当前时隙: %d\n", s.currentSlot)
+	fmt.Printf("当前时隙: %d\n", s.layoutAt(now).Slot(now))
 	fmt.Printf("总时隙数: %d\n", s.totalSlots)
 	fmt.Printf("时隙持续时间: %v\n", s.slotDuration)
 	fmt.Printf("调度表:\n")
@@ -266,3 +480,46 @@ func (s *TDMAScheduler) PrintStatus() {
 // 默认时隙持续时间和总时隙数
 var DefaultSlotDuration = time.Second
 var DefaultTotalSlots = 10
+
+// 时隙数与时隙持续时间的允许范围
+const (
+	MaxTotalSlots   = 4096
+	MinSlotDuration = 10 * time.Millisecond
+)
+
+// 调度器配置，卫星与地面站须使用相同的配置
+type Config struct {
+	TotalSlots   int           // 每个超帧的时隙数
+	SlotDuration time.Duration // 时隙持续时间
+}
+
+// 默认配置
+func DefaultConfig() Config {
+	return Config{TotalSlots: DefaultTotalSlots, SlotDuration: DefaultSlotDuration}
+}
+
+// 检查配置是否有效
+func (c Config) Validate() error {
+	if c.TotalSlots < 1 || c.TotalSlots > MaxTotalSlots {
+		return fmt.Errorf("时隙数 %d 超出范围 1-%d", c.TotalSlots, MaxTotalSlots)
+	}
+	if c.SlotDuration < MinSlotDuration {
+		return fmt.Errorf("时隙持续时间 %v 不能小于 %v", c.SlotDuration, MinSlotDuration)
+	}
+	return nil
+}
+
+// 按配置创建调度器
+func New(cfg Config) (*TDMAScheduler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return NewTDMAScheduler(cfg.TotalSlots, cfg.SlotDuration), nil
+}
+
+// 调度器的配置
+func (s *TDMAScheduler) Config() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Config{TotalSlots: s.totalSlots, SlotDuration: s.slotDuration}
+}
@@ -0,0 +1,288 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"tdma-network/internal/clock"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+// 按TDMA纪元独立计算的时隙，作为期望值
+func epochSlot(t time.Time, cfg Config) (abs int64, slot int) {
+	abs = int64(t.Sub(protocol.TDMA_EPOCH) / cfg.SlotDuration)
+	return abs, int(abs % int64(cfg.TotalSlots))
+}
+
+func newVirtual(t *testing.T, cfg Config, start time.Time) (*TDMAScheduler, *clock.Virtual) {
+	t.Helper()
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	clk := clock.NewVirtual(start)
+	s.SetClock(clk)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return s, clk
+}
+
+func checkCurrent(t *testing.T, s *TDMAScheduler, now time.Time, cfg Config) {
+	t.Helper()
+	wantAbs, wantSlot := epochSlot(now, cfg)
+	cur := s.Current()
+	if cur.Abs != wantAbs || cur.Slot != wantSlot {
+		t.Fatalf("at %v: current = abs %d slot %d, want abs %d slot %d", now, cur.Abs, cur.Slot, wantAbs, wantSlot)
+	}
+	if s.GetCurrentSlot() != cur.Slot {
+		t.Fatalf("at %v: GetCurrentSlot = %d, Current().Slot = %d", now, s.GetCurrentSlot(), cur.Slot)
+	}
+	if now.Before(cur.Start) || !now.Before(cur.End) || cur.End.Sub(cur.Start) != cfg.SlotDuration {
+		t.Fatalf("at %v: slot window [%v, %v) does not contain now", now, cur.Start, cur.End)
+	}
+	if want := wantAbs / int64(cfg.TotalSlots); cur.Superframe != want {
+		t.Fatalf("at %v: superframe = %d, want %d", now, cur.Superframe, want)
+	}
+}
+
+// 进程在时隙中间启动时，当前时隙仍与全局时钟一致，而不是从0开始计数
+func TestCurrentSlotStartsMidSlot(t *testing.T) {
+	cfg := Config{TotalSlots: 8, SlotDuration: 250 * time.Millisecond}
+	start := protocol.TDMA_EPOCH.Add(1000*time.Hour + 5*cfg.SlotDuration + 37*time.Millisecond)
+	s, _ := newVirtual(t, cfg, start)
+	checkCurrent(t, s, start, cfg)
+	if s.GetCurrentSlot() != 5 {
+		t.Fatalf("GetCurrentSlot = %d, want 5", s.GetCurrentSlot())
+	}
+}
+
+// 长时间运行后调度器与全局时钟不会漂移
+func TestCurrentSlotNoDrift(t *testing.T) {
+	cfg := Config{TotalSlots: 10, SlotDuration: 30 * time.Millisecond}
+	start := protocol.TDMA_EPOCH.Add(123456789 * time.Microsecond)
+	s, clk := newVirtual(t, cfg, start)
+
+	// 以与时隙不同步的步长采样十万个时隙
+	step := 7 * time.Millisecond
+	for now := start; now.Before(start.Add(100000 * cfg.SlotDuration)); now = now.Add(step) {
+		clk.Run(now)
+		checkCurrent(t, s, now, cfg)
+	}
+}
+
+// 时钟跳变（如虚拟时钟直接推进、系统挂起后恢复）后当前时隙立即正确
+func TestCurrentSlotAfterClockJump(t *testing.T) {
+	cfg := Config{TotalSlots: 16, SlotDuration: 100 * time.Millisecond}
+	start := protocol.TDMA_EPOCH.Add(time.Hour)
+	s, clk := newVirtual(t, cfg, start)
+
+	now := start.Add(36*time.Hour + 3*time.Millisecond)
+	clk.Run(now)
+	checkCurrent(t, s, now, cfg)
+}
+
+// 分配时隙与判断当前时隙使用同一时基
+func TestAllocatePrefersCurrentSlot(t *testing.T) {
+	cfg := Config{TotalSlots: 8, SlotDuration: time.Second}
+	start := protocol.TDMA_EPOCH.Add(3*cfg.SlotDuration + 500*time.Millisecond)
+	s, clk := newVirtual(t, cfg, start)
+	clk.Run(start.Add(24*time.Hour + 2*cfg.SlotDuration))
+
+	slotID, err := s.AllocateTimeSlot("GS1", 1)
+	if err != nil {
+		t.Fatalf("AllocateTimeSlot: %v", err)
+	}
+	if want := s.GetCurrentSlot(); slotID != want {
+		t.Fatalf("allocated slot %d, current slot %d", slotID, want)
+	}
+	if slotID != 5 {
+		t.Fatalf("allocated slot %d, want 5", slotID)
+	}
+}
+
+// 帧结构变更前后的时隙与调度器一致，绝对时隙号连续
+func TestSlotAtAcrossLayoutChange(t *testing.T) {
+	cfg := Config{TotalSlots: 8, SlotDuration: 500 * time.Millisecond}
+	start := protocol.TDMA_EPOCH.Add(10 * time.Hour)
+	s, clk := newVirtual(t, cfg, start)
+
+	at := s.Layout().NextSuperframe(start.Add(2 * time.Second))
+	next := Config{TotalSlots: 5, SlotDuration: 200 * time.Millisecond}
+	if _, err := s.PlanChange(next, at); err != nil {
+		t.Fatalf("PlanChange: %v", err)
+	}
+
+	before := s.SlotAt(at.Add(-time.Nanosecond))
+	after := s.SlotAt(at)
+	if before.Slot != cfg.TotalSlots-1 || before.End != at {
+		t.Fatalf("last slot before change = %+v", before)
+	}
+	if after.Abs != before.Abs+1 || after.Slot != 0 || after.Superframe != 0 || after.Start != at {
+		t.Fatalf("first slot after change = %+v, previous abs %d", after, before.Abs)
+	}
+
+	clk.AfterFunc(at.Sub(start), func() {
+		if _, _, err := s.ApplyChange(); err != nil {
+			t.Errorf("ApplyChange: %v", err)
+		}
+	})
+	for k := 0; k < 1000; k++ {
+		now := at.Add(time.Duration(k) * 13 * time.Millisecond)
+		clk.Run(now)
+		n := int64(now.Sub(at) / next.SlotDuration)
+		cur := s.Current()
+		if cur.Abs != after.Abs+n || cur.Slot != int(n%int64(next.TotalSlots)) || cur.Superframe != n/int64(next.TotalSlots) {
+			t.Fatalf("at +%v: current = %+v", now.Sub(at), cur)
+		}
+		if cur != s.SlotAt(now) {
+			t.Fatalf("at +%v: Current %+v != SlotAt %+v", now.Sub(at), cur, s.SlotAt(now))
+		}
+	}
+	if s.Config() != next {
+		t.Fatalf("config = %+v, want %+v", s.Config(), next)
+	}
+}
+
+// 纪元之前的时刻按向下取整计算
+func TestLayoutAtBeforeEpoch(t *testing.T) {
+	l := NewLayout(Config{TotalSlots: 4, SlotDuration: time.Second})
+	st := l.At(protocol.TDMA_EPOCH.Add(-500 * time.Millisecond))
+	if st.Abs != -1 || st.Slot != 3 || st.Superframe != -1 || st.End != protocol.TDMA_EPOCH {
+		t.Fatalf("At(epoch-0.5s) = %+v", st)
+	}
+}
+
+// 变更生效时刻之后、ApplyChange之前，分配时隙与Current使用同一帧结构
+func TestAllocateDuringSwitchover(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new Config
+	}{
+		{"shrink", Config{TotalSlots: 8, SlotDuration: 500 * time.Millisecond}, Config{TotalSlots: 5, SlotDuration: 200 * time.Millisecond}},
+		{"grow", Config{TotalSlots: 4, SlotDuration: 500 * time.Millisecond}, Config{TotalSlots: 8, SlotDuration: 100 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := protocol.TDMA_EPOCH.Add(10 * time.Hour)
+			s, clk := newVirtual(t, tt.old, start)
+			at := s.Layout().NextSuperframe(start.Add(time.Second))
+			if _, err := s.PlanChange(tt.new, at); err != nil {
+				t.Fatalf("PlanChange: %v", err)
+			}
+
+			// 逐个时隙推进但不调用ApplyChange
+			for k := 0; k < tt.new.TotalSlots; k++ {
+				now := at.Add(time.Duration(k)*tt.new.SlotDuration + time.Millisecond)
+				clk.Run(now)
+				cur := s.Current()
+				slotID, err := s.AllocateTimeSlot(fmt.Sprintf("GS%d", k), 1)
+				if err != nil {
+					t.Fatalf("at slot %d: AllocateTimeSlot: %v", cur.Slot, err)
+				}
+				if slotID >= tt.old.TotalSlots || slotID >= tt.new.TotalSlots {
+					t.Fatalf("at slot %d: allocated slot %d outside old/new layout", cur.Slot, slotID)
+				}
+				if cur.Slot < tt.old.TotalSlots && slotID != cur.Slot {
+					t.Fatalf("allocated slot %d, current slot %d", slotID, cur.Slot)
+				}
+				s.ReleaseTimeSlot(slotID)
+			}
+		})
+	}
+}
+
+// 已弃用的 protocol.GetGlobalSlotIDAt 与未经变更的帧结构一致
+func TestGetGlobalSlotIDAtMatchesLayout(t *testing.T) {
+	cfg := Config{TotalSlots: 7, SlotDuration: 300 * time.Millisecond}
+	l := NewLayout(cfg)
+	for _, d := range []time.Duration{-time.Hour - 1, -1, 0, 1, 299 * time.Millisecond, 300 * time.Millisecond, 1000*time.Hour + 17} {
+		at := protocol.TDMA_EPOCH.Add(d)
+		if got, want := protocol.GetGlobalSlotIDAt(at, cfg.SlotDuration, cfg.TotalSlots), l.Slot(at); got != want {
+			t.Errorf("epoch%+v: GetGlobalSlotIDAt = %d, Layout.Slot = %d", d, got, want)
+		}
+	}
+}
+
+func TestAssignTimeSlot(t *testing.T) {
+	cfg := Config{TotalSlots: 8, SlotDuration: 100 * time.Millisecond}
+	s, _ := newVirtual(t, cfg, protocol.TDMA_EPOCH.Add(time.Hour))
+
+	if _, err := s.AssignTimeSlot(1, "GS1"); err != nil {
+		t.Fatalf("AssignTimeSlot(1): %v", err)
+	}
+	if _, err := s.AssignTimeSlot(3, "GS2"); err != nil {
+		t.Fatalf("AssignTimeSlot(3): %v", err)
+	}
+
+	// 改派到空闲时隙时释放节点原有的时隙
+	released, err := s.AssignTimeSlot(5, "GS1")
+	if err != nil {
+		t.Fatalf("AssignTimeSlot(5): %v", err)
+	}
+	if fmt.Sprint(released) != "[1]" {
+		t.Fatalf("released = %v, want [1]", released)
+	}
+
+	// 时隙归属其他节点时失败，不改变调度表
+	if _, err := s.AssignTimeSlot(3, "GS1"); !errors.Is(err, ErrSlotTaken) {
+		t.Fatalf("AssignTimeSlot(3) = %v, want ErrSlotTaken", err)
+	}
+	want := map[int]string{3: "GS2", 5: "GS1"}
+	if got := s.GetSchedule(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("schedule = %v, want %v", got, want)
+	}
+}
+
+// 时隙全部分配且租约有效时拒绝新节点，不挤占持有者；租约过期后回收最旧的时隙
+func TestAllocateFullScheduleKeepsLiveLeases(t *testing.T) {
+	cfg := Config{TotalSlots: 8, SlotDuration: 100 * time.Millisecond}
+	s, clk := newVirtual(t, cfg, protocol.TDMA_EPOCH.Add(time.Hour))
+	lease := 8 * time.Second
+	s.SetLeaseDuration(lease)
+
+	holders := make(map[int]string)
+	for i := 0; i < cfg.TotalSlots; i++ {
+		nodeID := fmt.Sprintf("GS%d", i)
+		slotID, err := s.AllocateTimeSlot(nodeID, 1)
+		if err != nil {
+			t.Fatalf("AllocateTimeSlot(%s): %v", nodeID, err)
+		}
+		holders[slotID] = nodeID
+	}
+
+	// 持有者续约期间，新节点在任何时刻都分配不到时隙
+	for _, d := range []time.Duration{600 * time.Millisecond, 5 * time.Second, 10 * time.Second} {
+		clk.Run(protocol.TDMA_EPOCH.Add(time.Hour + d))
+		if slotID, err := s.AllocateTimeSlot("GS_NEW", 1); err == nil {
+			t.Fatalf("after %v: newcomer got slot %d held by %s", d, slotID, holders[slotID])
+		}
+		if got := s.GetSchedule(); fmt.Sprint(got) != fmt.Sprint(holders) {
+			t.Fatalf("after %v: schedule = %v, want %v", d, got, holders)
+		}
+		for slotID, nodeID := range holders {
+			if err := s.RenewTimeSlot(slotID, nodeID); err != nil {
+				t.Fatalf("RenewTimeSlot(%d, %s): %v", slotID, nodeID, err)
+			}
+		}
+	}
+
+	// GS0之外的节点续约，GS0的租约过期后其时隙可被回收
+	var gs0 int
+	for slotID, nodeID := range holders {
+		if nodeID == "GS0" {
+			gs0 = slotID
+		}
+	}
+	clk.Run(clk.Now().Add(lease / 2))
+	for slotID, nodeID := range holders {
+		if nodeID != "GS0" {
+			s.RenewTimeSlot(slotID, nodeID)
+		}
+	}
+	clk.Run(clk.Now().Add(lease / 2))
+	if slotID, err := s.AllocateTimeSlot("GS_NEW", 1); err != nil || slotID != gs0 {
+		t.Fatalf("AllocateTimeSlot after GS0's lease expired = %d, %v, want %d", slotID, err, gs0)
+	}
+}
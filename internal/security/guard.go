@@ -0,0 +1,148 @@
+package security
+
+import (
+	"sort"
+	"sync"
+	"tdma-network/internal/clock"
+	"time"
+)
+
+// 隔离中的节点入网时回复 JOIN_REJECT_quarantined
+const REASON_QUARANTINED = "quarantined"
+
+// 违规类型，同时用作安全事件详情与指标标签
+const (
+	VIOLATION_REPLAY     = "replay"     // 重放：序号重复或落后于防重放窗口
+	VIOLATION_SLOT_SPOOF = "slot_spoof" // 帧的时隙不属于发送节点（空闲或属于其他节点）
+)
+
+// 隔离策略：Window内累计Threshold次违规的节点被隔离Duration
+type Policy struct {
+	Threshold int
+	Window    time.Duration
+	Duration  time.Duration
+}
+
+// 默认隔离策略
+var DefaultPolicy = Policy{Threshold: 5, Window: time.Minute, Duration: 5 * time.Minute}
+
+// 节点的违规记录
+type Status struct {
+	NodeID     string           `json:"node_id"`
+	Violations map[string]int64 `json:"violations"`      // 按类型累计的违规次数
+	LastAt     time.Time        `json:"last_at"`         // 最近一次违规时刻
+	Until      *time.Time       `json:"until,omitempty"` // 隔离结束时刻，未隔离时为空
+}
+
+type record struct {
+	recent []time.Time // Window内的违规时刻
+	total  map[string]int64
+	lastAt time.Time
+	until  time.Time
+}
+
+// 按节点统计违规并隔离多次违规的节点
+type Guard struct {
+	policy Policy
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	nodes map[string]*record
+}
+
+// 创建按policy隔离的Guard
+func NewGuard(policy Policy, clk clock.Clock) *Guard {
+	return &Guard{policy: policy, clock: clk, nodes: make(map[string]*record)}
+}
+
+// 设置隔离策略，已隔离的节点按原结束时刻解除
+func (g *Guard) SetPolicy(p Policy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.policy = p
+}
+
+// 设置时钟，回放时使用虚拟时钟
+func (g *Guard) SetClock(c clock.Clock) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.clock = c
+}
+
+// 记录节点的一次违规，返回节点是否因此进入隔离
+func (g *Guard) Violation(nodeID, kind string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+	r, ok := g.nodes[nodeID]
+	if !ok {
+		r = &record{total: make(map[string]int64)}
+		g.nodes[nodeID] = r
+	}
+	r.total[kind]++
+	r.lastAt = now
+
+	// 只保留窗口内的违规
+	recent := r.recent[:0]
+	for _, t := range r.recent {
+		if now.Sub(t) < g.policy.Window {
+			recent = append(recent, t)
+		}
+	}
+	r.recent = append(recent, now)
+
+	if now.Before(r.until) || g.policy.Threshold <= 0 || len(r.recent) < g.policy.Threshold {
+		return false
+	}
+	r.until = now.Add(g.policy.Duration)
+	r.recent = nil
+	return true
+}
+
+// 节点是否处于隔离中，返回隔离结束时刻
+func (g *Guard) Quarantined(nodeID string) (time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.nodes[nodeID]
+	if !ok || !g.clock.Now().Before(r.until) {
+		return time.Time{}, false
+	}
+	return r.until, true
+}
+
+// 提前解除节点的隔离，返回节点是否处于隔离中
+func (g *Guard) Release(nodeID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.nodes[nodeID]
+	if !ok || !g.clock.Now().Before(r.until) {
+		return false
+	}
+	r.until = time.Time{}
+	return true
+}
+
+// 有违规记录的节点，按节点ID排序
+func (g *Guard) List() []Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+	list := make([]Status, 0, len(g.nodes))
+	for nodeID, r := range g.nodes {
+		s := Status{NodeID: nodeID, Violations: make(map[string]int64, len(r.total)), LastAt: r.lastAt}
+		for k, n := range r.total {
+			s.Violations[k] = n
+		}
+		if now.Before(r.until) {
+			until := r.until
+			s.Until = &until
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].NodeID < list[j].NodeID })
+	return list
+}
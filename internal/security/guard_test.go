@@ -0,0 +1,116 @@
+package security
+
+import (
+	"tdma-network/internal/clock"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{Threshold: 3, Window: time.Minute, Duration: 5 * time.Minute}
+
+func TestGuardQuarantine(t *testing.T) {
+	// 每一步先推进时钟wait，violate时记录一次违规，然后检查节点是否处于隔离中
+	type step struct {
+		wait        time.Duration
+		node        string
+		violate     bool
+		quarantines bool // 本次违规使节点进入隔离
+		quarantined bool
+	}
+	v := func(wait time.Duration, quarantines, quarantined bool) step {
+		return step{wait: wait, node: "GS1", violate: true, quarantines: quarantines, quarantined: quarantined}
+	}
+	check := func(wait time.Duration, node string, quarantined bool) step {
+		return step{wait: wait, node: node, quarantined: quarantined}
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		steps  []step
+	}{
+		{"below threshold", testPolicy, []step{
+			v(0, false, false), v(time.Second, false, false),
+		}},
+		{"reaches threshold", testPolicy, []step{
+			v(0, false, false), v(time.Second, false, false), v(time.Second, true, true),
+		}},
+		{"violations expire from window", testPolicy, []step{
+			v(0, false, false), v(30*time.Second, false, false),
+			// 第一次违规已超过1分钟
+			v(31*time.Second, false, false),
+			v(time.Second, true, true),
+		}},
+		{"quarantine expires", testPolicy, []step{
+			v(0, false, false), v(0, false, false), v(0, true, true),
+			check(5*time.Minute-time.Millisecond, "GS1", true),
+			check(time.Millisecond, "GS1", false),
+			// 进入隔离时清空计数，解除后重新累计
+			v(0, false, false), v(0, false, false), v(0, true, true),
+		}},
+		{"violations while quarantined do not extend it", testPolicy, []step{
+			v(0, false, false), v(0, false, false), v(0, true, true),
+			v(time.Minute, false, true), v(0, false, true), v(0, false, true),
+			check(4*time.Minute, "GS1", false),
+		}},
+		{"other nodes unaffected", testPolicy, []step{
+			v(0, false, false), v(0, false, false), v(0, true, true),
+			check(0, "GS2", false),
+		}},
+		{"zero threshold disables quarantine", Policy{Window: time.Minute, Duration: time.Minute}, []step{
+			v(0, false, false), v(0, false, false), v(0, false, false), v(0, false, false),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewVirtual(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+			g := NewGuard(tt.policy, clk)
+			for i, s := range tt.steps {
+				clk.Run(clk.Now().Add(s.wait))
+				if s.violate {
+					if got := g.Violation(s.node, VIOLATION_REPLAY); got != s.quarantines {
+						t.Fatalf("step %d: Violation = %v, want %v", i, got, s.quarantines)
+					}
+				}
+				until, ok := g.Quarantined(s.node)
+				if ok != s.quarantined {
+					t.Fatalf("step %d: Quarantined = %v, want %v", i, ok, s.quarantined)
+				}
+				if s.quarantines && !until.Equal(clk.Now().Add(tt.policy.Duration)) {
+					t.Fatalf("step %d: quarantined until %v, want %v", i, until, clk.Now().Add(tt.policy.Duration))
+				}
+			}
+		})
+	}
+}
+
+func TestGuardReleaseAndList(t *testing.T) {
+	clk := clock.NewVirtual(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	g := NewGuard(testPolicy, clk)
+	g.Violation("GS2", VIOLATION_SLOT_SPOOF)
+	for i := 0; i < testPolicy.Threshold-1; i++ {
+		g.Violation("GS1", VIOLATION_REPLAY)
+	}
+	g.Violation("GS1", VIOLATION_SLOT_SPOOF)
+
+	list := g.List()
+	if len(list) != 2 || list[0].NodeID != "GS1" || list[1].NodeID != "GS2" {
+		t.Fatalf("List = %+v", list)
+	}
+	if got := list[0].Violations; got[VIOLATION_REPLAY] != 2 || got[VIOLATION_SLOT_SPOOF] != 1 || list[0].Until == nil {
+		t.Fatalf("GS1 = %+v", list[0])
+	}
+	if list[1].Until != nil {
+		t.Fatalf("GS2 quarantined: %+v", list[1])
+	}
+
+	if !g.Release("GS1") {
+		t.Fatalf("Release(GS1) = false")
+	}
+	if _, ok := g.Quarantined("GS1"); ok {
+		t.Fatalf("GS1 still quarantined after release")
+	}
+	if g.Release("GS1") || g.Release("GS2") || g.Release("GS9") {
+		t.Fatalf("Release of a node not in quarantine returned true")
+	}
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// 会话事件类型
+const (
+	EVENT_JOINED      = "JOINED"      // 节点入网
+	EVENT_RESUMED     = "RESUMED"     // 会话恢复
+	EVENT_DETACHED    = "DETACHED"    // 连接断开
+	EVENT_DEGRADED    = "DEGRADED"    // 心跳迟到
+	EVENT_RECOVERED   = "RECOVERED"   // 心跳恢复
+	EVENT_DEAD        = "DEAD"        // 心跳丢失，节点失效
+	EVENT_EXPIRED     = "EXPIRED"     // 断开后恢复超时
+	EVENT_LEFT        = "LEFT"        // 节点主动离网
+	EVENT_KICKED      = "KICKED"      // 被管理员踢出
+	EVENT_AOS         = "AOS"         // 地面站进入可见窗口
+	EVENT_LOS         = "LOS"         // 地面站离开可见窗口，时隙被回收
+	EVENT_SECURITY    = "SECURITY"    // 安全违规（重放、冒用时隙），Detail为 <违规类型>: <说明>
+	EVENT_QUARANTINED = "QUARANTINED" // 多次违规，节点被隔离
+)
+
+// 会话事件
+type Event struct {
+	Type    string
+	NodeID  string
+	SlotID  int
+	AbsSlot int64 // 事件发生时的绝对时隙号
+	Time    time.Time
+	Detail  string
+}
+
+func (e Event) String() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s 节点 %s (时隙 %d)", e.Type, e.NodeID, e.SlotID)
+	}
+	return fmt.Sprintf("%s 节点 %s (时隙 %d): %s", e.Type, e.NodeID, e.SlotID, e.Detail)
+}
+
+// 订阅会话事件，订阅者处理不及时的事件将被丢弃
+func (m *Manager) Subscribe() <-chan Event {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	ch := make(chan Event, 64)
+	m.subscribers = append(m.subscribers, ch)
+	return ch
+}
+
+// 发布外部事件（如可见性变化）
+func (m *Manager) Publish(e Event) {
+	m.emit(e)
+}
+
+// 发布事件
+func (m *Manager) emit(e Event) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	if e.AbsSlot == 0 && m.slotClock != nil {
+		e.AbsSlot = m.slotClock(e.Time)
+	}
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
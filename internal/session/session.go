@@ -0,0 +1,307 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"tdma-network/internal/clock"
+	"time"
+)
+
+// 会话状态
+const (
+	STATE_ACTIVE   = "ACTIVE"   // 连接在线
+	STATE_DEGRADED = "DEGRADED" // 心跳迟到
+	STATE_DETACHED = "DETACHED" // 连接已断开，等待恢复
+)
+
+// 地面站会话
+type Session struct {
+	NodeID     string
+	Token      string
+	SlotID     int
+	State      string
+	RemoteAddr string
+	CreatedAt  time.Time
+	LastSeen   time.Time
+	DetachedAt time.Time
+
+	LastHeartbeat time.Time
+	Heartbeats    int64
+}
+
+// 会话管理器
+type Manager struct {
+	mu            sync.RWMutex
+	byToken       map[string]*Session
+	byNode        map[string]*Session
+	resumeTimeout time.Duration
+
+	heartbeatInterval time.Duration
+	missLimit         int
+
+	subMu       sync.Mutex
+	subscribers []chan Event
+	slotClock   func(time.Time) int64
+
+	clock clock.Clock
+}
+
+// 创建新的会话管理器
+// resumeTimeout为连接断开后保留会话（及其时隙）的时长
+func NewManager(resumeTimeout time.Duration) *Manager {
+	return &Manager{
+		byToken:       make(map[string]*Session),
+		byNode:        make(map[string]*Session),
+		resumeTimeout: resumeTimeout,
+		clock:         clock.Real{},
+	}
+}
+
+// 设置时钟，用于在虚拟时间下运行
+func (m *Manager) SetClock(c clock.Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = c
+}
+
+// 设置事件时间戳使用的绝对时隙时钟，返回t时刻的绝对时隙号
+func (m *Manager) SetSlotClock(f func(time.Time) int64) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	m.slotClock = f
+}
+
+// 设置心跳检测参数，两者都须大于0，否则全部会话会在下一次检测时被判定失效
+// 超过1.5个心跳间隔未收到心跳进入DEGRADED，连续丢失missLimit个心跳判定失效
+func (m *Manager) SetLiveness(interval time.Duration, missLimit int) error {
+	if interval <= 0 {
+		return fmt.Errorf("心跳间隔须大于0: %v", interval)
+	}
+	if missLimit <= 0 {
+		return fmt.Errorf("心跳丢失次数须大于0: %d", missLimit)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.heartbeatInterval = interval
+	m.missLimit = missLimit
+	return nil
+}
+
+// 创建会话，同一节点的旧会话将被替换
+func (m *Manager) Create(nodeID string, slotID int, remoteAddr string) (*Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, fmt.Errorf("生成会话令牌失败: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if old, ok := m.byNode[nodeID]; ok {
+		delete(m.byToken, old.Token)
+	}
+
+	now := m.clock.Now()
+	s := &Session{
+		NodeID:     nodeID,
+		Token:      token,
+		SlotID:     slotID,
+		State:      STATE_ACTIVE,
+		RemoteAddr: remoteAddr,
+		CreatedAt:  now,
+		LastSeen:   now,
+
+		LastHeartbeat: now,
+	}
+	m.byToken[token] = s
+	m.byNode[nodeID] = s
+
+	m.emit(Event{Type: EVENT_JOINED, NodeID: nodeID, SlotID: slotID, Time: now})
+	return s, nil
+}
+
+// 使用令牌恢复会话
+func (m *Manager) Resume(token, nodeID, remoteAddr string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.byToken[token]
+	if !ok {
+		return nil, fmt.Errorf("未知的会话令牌")
+	}
+	if s.NodeID != nodeID {
+		return nil, fmt.Errorf("会话令牌与节点不匹配")
+	}
+
+	now := m.clock.Now()
+	s.State = STATE_ACTIVE
+	s.RemoteAddr = remoteAddr
+	s.LastSeen = now
+	s.LastHeartbeat = now
+	s.DetachedAt = time.Time{}
+
+	m.emit(Event{Type: EVENT_RESUMED, NodeID: nodeID, SlotID: s.SlotID, Time: now})
+	return s, nil
+}
+
+// 标记会话连接断开，返回是否存在在线会话
+// 会话已在其他连接上恢复时（remoteAddr不同）不做处理
+func (m *Manager) Detach(nodeID, remoteAddr string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.byNode[nodeID]
+	if !ok || s.State == STATE_DETACHED || s.RemoteAddr != remoteAddr {
+		return false
+	}
+	s.State = STATE_DETACHED
+	s.DetachedAt = m.clock.Now()
+
+	m.emit(Event{Type: EVENT_DETACHED, NodeID: nodeID, SlotID: s.SlotID, Time: s.DetachedAt})
+	return true
+}
+
+// 记录心跳，DEGRADED状态的会话恢复为ACTIVE
+func (m *Manager) Heartbeat(nodeID string) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.byNode[nodeID]
+	if !ok || s.State == STATE_DETACHED {
+		return Session{}, false
+	}
+
+	now := m.clock.Now()
+	s.LastHeartbeat = now
+	s.LastSeen = now
+	s.Heartbeats++
+	if s.State == STATE_DEGRADED {
+		s.State = STATE_ACTIVE
+		m.emit(Event{Type: EVENT_RECOVERED, NodeID: nodeID, SlotID: s.SlotID, Time: now})
+	}
+	return *s, true
+}
+
+// 检查心跳，更新DEGRADED状态，取出并删除已失效的会话
+func (m *Manager) CheckLiveness() []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.heartbeatInterval <= 0 {
+		return nil
+	}
+
+	now := m.clock.Now()
+	degradedAfter := m.heartbeatInterval * 3 / 2
+	deadAfter := m.heartbeatInterval * time.Duration(m.missLimit)
+
+	var dead []Session
+	for nodeID, s := range m.byNode {
+		if s.State == STATE_DETACHED {
+			continue
+		}
+		silence := now.Sub(s.LastHeartbeat)
+		switch {
+		case silence > deadAfter:
+			dead = append(dead, *s)
+			delete(m.byToken, s.Token)
+			delete(m.byNode, nodeID)
+			m.emit(Event{Type: EVENT_DEAD, NodeID: nodeID, SlotID: s.SlotID, Time: now,
+				Detail: fmt.Sprintf("%v 未收到心跳", silence.Truncate(time.Millisecond))})
+
+		case silence > degradedAfter && s.State == STATE_ACTIVE:
+			s.State = STATE_DEGRADED
+			m.emit(Event{Type: EVENT_DEGRADED, NodeID: nodeID, SlotID: s.SlotID, Time: now,
+				Detail: fmt.Sprintf("心跳迟到 %v", silence.Truncate(time.Millisecond))})
+		}
+	}
+	return dead
+}
+
+// 更新会话时隙
+func (m *Manager) SetSlot(nodeID string, slotID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.byNode[nodeID]; ok {
+		s.SlotID = slotID
+	}
+}
+
+// 记录会话活动
+func (m *Manager) Touch(nodeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.byNode[nodeID]; ok {
+		s.LastSeen = m.clock.Now()
+	}
+}
+
+// 按节点获取会话副本
+func (m *Manager) Get(nodeID string) (Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.byNode[nodeID]
+	if !ok {
+		return Session{}, false
+	}
+	return *s, true
+}
+
+// 删除会话，返回被删除的会话
+func (m *Manager) Remove(nodeID string) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.byNode[nodeID]
+	if !ok {
+		return Session{}, false
+	}
+	delete(m.byToken, s.Token)
+	delete(m.byNode, nodeID)
+	return *s, true
+}
+
+// 取出并删除恢复超时的会话
+func (m *Manager) Expire() []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []Session
+	for nodeID, s := range m.byNode {
+		if s.State == STATE_DETACHED && m.clock.Since(s.DetachedAt) > m.resumeTimeout {
+			expired = append(expired, *s)
+			delete(m.byToken, s.Token)
+			delete(m.byNode, nodeID)
+			m.emit(Event{Type: EVENT_EXPIRED, NodeID: nodeID, SlotID: s.SlotID, Time: m.clock.Now()})
+		}
+	}
+	return expired
+}
+
+// 获取全部会话副本
+func (m *Manager) List() []Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]Session, 0, len(m.byNode))
+	for _, s := range m.byNode {
+		sessions = append(sessions, *s)
+	}
+	return sessions
+}
+
+// 生成随机会话令牌
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
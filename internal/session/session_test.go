@@ -0,0 +1,229 @@
+package session
+
+import (
+	"tdma-network/internal/clock"
+	"testing"
+	"time"
+)
+
+const (
+	testInterval  = time.Second
+	testMissLimit = 3
+	testResume    = 10 * time.Second
+)
+
+func newTestManager() (*Manager, *clock.Virtual) {
+	clk := clock.NewVirtual(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	m := NewManager(testResume)
+	m.SetClock(clk)
+	if err := m.SetLiveness(testInterval, testMissLimit); err != nil {
+		panic(err)
+	}
+	return m, clk
+}
+
+// 取出已发布的全部事件类型
+func drain(events <-chan Event) []string {
+	var out []string
+	for {
+		select {
+		case e := <-events:
+			out = append(out, e.Type)
+		default:
+			return out
+		}
+	}
+}
+
+func TestLivenessTransitions(t *testing.T) {
+	// 每一步先推进时钟wait，heartbeat时收到一次心跳，然后检查心跳
+	type step struct {
+		wait      time.Duration
+		heartbeat bool
+		state     string // 检查后的会话状态，空表示会话已删除
+		events    []string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"heartbeats keep session active", []step{
+			{testInterval, true, STATE_ACTIVE, nil},
+			{testInterval, true, STATE_ACTIVE, nil},
+			{testInterval * 3 / 2, false, STATE_ACTIVE, nil},
+		}},
+		{"late heartbeat degrades", []step{
+			{testInterval*3/2 + time.Millisecond, false, STATE_DEGRADED, []string{EVENT_DEGRADED}},
+			// 已DEGRADED时不重复发布
+			{testInterval / 2, false, STATE_DEGRADED, nil},
+		}},
+		{"heartbeat recovers degraded session", []step{
+			{2 * testInterval, false, STATE_DEGRADED, []string{EVENT_DEGRADED}},
+			{0, true, STATE_ACTIVE, []string{EVENT_RECOVERED}},
+			{testInterval, false, STATE_ACTIVE, nil},
+		}},
+		{"missed heartbeats expire session", []step{
+			{2 * testInterval, false, STATE_DEGRADED, []string{EVENT_DEGRADED}},
+			{testInterval, false, STATE_DEGRADED, nil},
+			{time.Millisecond, false, "", []string{EVENT_DEAD}},
+		}},
+		{"dead directly from active", []step{
+			{testMissLimit*testInterval + time.Millisecond, false, "", []string{EVENT_DEAD}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, clk := newTestManager()
+			events := m.Subscribe()
+			if _, err := m.Create("GS1", 3, "remote"); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			drain(events)
+
+			for i, s := range tt.steps {
+				clk.Run(clk.Now().Add(s.wait))
+				if s.heartbeat {
+					if _, ok := m.Heartbeat("GS1"); !ok {
+						t.Fatalf("step %d: Heartbeat found no session", i)
+					}
+				}
+				dead := m.CheckLiveness()
+
+				sess, ok := m.Get("GS1")
+				if s.state == "" {
+					// 失效的会话连同时隙一起取出，由调用方释放
+					if ok || len(dead) != 1 || dead[0].NodeID != "GS1" || dead[0].SlotID != 3 {
+						t.Fatalf("step %d: session %v, dead %+v, want GS1 removed with slot 3", i, ok, dead)
+					}
+				} else if !ok || sess.State != s.state || len(dead) != 0 {
+					t.Fatalf("step %d: state %q (found %v), dead %+v, want %q", i, sess.State, ok, dead, s.state)
+				}
+				if got := drain(events); len(got) != len(s.events) || (len(got) > 0 && got[0] != s.events[0]) {
+					t.Fatalf("step %d: events %v, want %v", i, got, s.events)
+				}
+			}
+		})
+	}
+}
+
+// 非正的心跳参数被拒绝，保留原有设置，不会把会话全部判定失效
+func TestSetLivenessRejectsNonPositive(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		missLimit int
+	}{
+		{"zero interval", 0, testMissLimit},
+		{"negative interval", -time.Second, testMissLimit},
+		{"zero miss limit", testInterval, 0},
+		{"negative miss limit", testInterval, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, clk := newTestManager()
+			if _, err := m.Create("GS1", 5, "remote"); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if err := m.SetLiveness(tt.interval, tt.missLimit); err == nil {
+				t.Fatalf("SetLiveness(%v, %d) succeeded", tt.interval, tt.missLimit)
+			}
+
+			clk.Run(clk.Now().Add(testInterval))
+			if dead := m.CheckLiveness(); len(dead) != 0 {
+				t.Fatalf("CheckLiveness = %+v after one interval, want none", dead)
+			}
+			clk.Run(clk.Now().Add(testInterval * testMissLimit))
+			if dead := m.CheckLiveness(); len(dead) != 1 {
+				t.Fatalf("CheckLiveness = %+v, want GS1 dead under the previous settings", dead)
+			}
+		})
+	}
+}
+
+// 断开的会话不做心跳检测，恢复超时后取出
+func TestDetachedSessionExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		resume  time.Duration // 断开后多久恢复，0表示不恢复
+		wait    time.Duration // 断开后多久检查
+		expired bool
+	}{
+		{"within resume timeout", 0, testResume, false},
+		{"resume timeout passed", 0, testResume + time.Millisecond, true},
+		{"resumed before timeout", testResume / 2, testResume + time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, clk := newTestManager()
+			s, err := m.Create("GS1", 5, "remote")
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if !m.Detach("GS1", "remote") {
+				t.Fatalf("Detach = false")
+			}
+			detached := clk.Now()
+			if tt.resume > 0 {
+				clk.Run(detached.Add(tt.resume))
+				if _, err := m.Resume(s.Token, "GS1", "remote2"); err != nil {
+					t.Fatalf("Resume: %v", err)
+				}
+				// 恢复后照常按心跳检测，这里持续收到心跳
+				for clk.Now().Before(detached.Add(tt.wait)) {
+					clk.Run(clk.Now().Add(testInterval))
+					m.Heartbeat("GS1")
+				}
+			}
+			clk.Run(detached.Add(tt.wait))
+
+			if dead := m.CheckLiveness(); len(dead) != 0 {
+				t.Fatalf("CheckLiveness = %+v, want none", dead)
+			}
+			expired := m.Expire()
+			if tt.expired != (len(expired) == 1) {
+				t.Fatalf("Expire = %+v, want expired=%v", expired, tt.expired)
+			}
+			if tt.expired && expired[0].SlotID != 5 {
+				t.Fatalf("expired session slot %d, want 5", expired[0].SlotID)
+			}
+			if _, ok := m.Get("GS1"); ok == tt.expired {
+				t.Fatalf("session present = %v after expiry check", ok)
+			}
+		})
+	}
+}
+
+func TestResumeAndDetach(t *testing.T) {
+	m, _ := newTestManager()
+	s, err := m.Create("GS1", 1, "a")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := m.Resume("bogus", "GS1", "b"); err == nil {
+		t.Fatalf("Resume with unknown token succeeded")
+	}
+	if _, err := m.Resume(s.Token, "GS2", "b"); err == nil {
+		t.Fatalf("Resume with another node's token succeeded")
+	}
+	if _, err := m.Resume(s.Token, "GS1", "b"); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	// 旧连接断开时会话已在新连接上恢复
+	if m.Detach("GS1", "a") {
+		t.Fatalf("Detach from stale connection = true")
+	}
+	if !m.Detach("GS1", "b") || m.Detach("GS1", "b") {
+		t.Fatalf("Detach from current connection should succeed once")
+	}
+	if _, ok := m.Heartbeat("GS1"); ok {
+		t.Fatalf("Heartbeat accepted on detached session")
+	}
+
+	// 重新入网替换旧会话，旧令牌失效
+	if _, err := m.Create("GS1", 2, "c"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := m.Resume(s.Token, "GS1", "d"); err == nil {
+		t.Fatalf("Resume with replaced token succeeded")
+	}
+}
@@ -0,0 +1,209 @@
+package traffic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 业务源
+// Next返回距上一条消息（首次调用时为业务开始）的间隔与下一条消息的内容，ok为false表示业务结束
+// 业务源本身不计时，由调用方按真实时间或虚拟时间驱动
+type Generator interface {
+	Next() (wait time.Duration, payload []byte, ok bool)
+}
+
+// 按配置创建业务源
+func New(cfg Config) (Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	interval := time.Duration(cfg.Interval)
+
+	switch cfg.Type {
+	case TYPE_CBR:
+		return &cbr{interval: interval, payload: filler(cfg.Size)}, nil
+
+	case TYPE_POISSON:
+		return &poisson{mean: interval, payload: filler(cfg.Size), rng: rng}, nil
+
+	case TYPE_ONOFF:
+		g := &onOff{interval: interval, on: time.Duration(cfg.On), off: time.Duration(cfg.Off), payload: filler(cfg.Size), rng: rng}
+		g.remaining = g.exp(g.on)
+		return g, nil
+
+	case TYPE_TRACE:
+		entries, err := loadTrace(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &trace{entries: entries, loop: cfg.Loop}, nil
+
+	case TYPE_FILE:
+		size := cfg.Size
+		if size == 0 {
+			size = 512
+		}
+		f, err := os.Open(cfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件失败: %v", err)
+		}
+		return &fileSource{r: f, chunk: size, interval: interval}, nil
+	}
+	return nil, fmt.Errorf("未知的业务类型: %s", cfg.Type)
+}
+
+// 填充内容
+func filler(size int) []byte {
+	return bytes.Repeat([]byte{'.'}, size)
+}
+
+// 固定速率
+type cbr struct {
+	interval time.Duration
+	payload  []byte
+}
+
+func (g *cbr) Next() (time.Duration, []byte, bool) {
+	return g.interval, g.payload, true
+}
+
+// 泊松到达
+type poisson struct {
+	mean    time.Duration
+	payload []byte
+	rng     *rand.Rand
+}
+
+func (g *poisson) Next() (time.Duration, []byte, bool) {
+	return time.Duration(g.rng.ExpFloat64() * float64(g.mean)), g.payload, true
+}
+
+// ON/OFF突发
+type onOff struct {
+	interval  time.Duration
+	on, off   time.Duration
+	remaining time.Duration // 当前ON期剩余时长
+	payload   []byte
+	rng       *rand.Rand
+}
+
+func (g *onOff) exp(mean time.Duration) time.Duration {
+	return time.Duration(g.rng.ExpFloat64() * float64(mean))
+}
+
+func (g *onOff) Next() (time.Duration, []byte, bool) {
+	if g.remaining >= g.interval {
+		g.remaining -= g.interval
+		return g.interval, g.payload, true
+	}
+	// 本次突发结束：等待ON期剩余时长与一个OFF期，然后开始新的突发
+	wait := g.remaining + g.exp(g.off)
+	g.remaining = g.exp(g.on)
+	return wait, g.payload, true
+}
+
+// 业务轨迹中的一条记录
+type traceEntry struct {
+	at   time.Duration // 相对轨迹开始的时刻
+	size int
+}
+
+// 加载业务轨迹：每行 "<时刻(秒)> <字节数>"，#开头为注释，时刻需递增
+func loadTrace(path string) ([]traceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开业务轨迹失败: %v", err)
+	}
+	defer f.Close()
+
+	var entries []traceEntry
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("业务轨迹第 %d 行格式错误", line)
+		}
+		sec, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("业务轨迹第 %d 行时刻无效: %v", line, err)
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("业务轨迹第 %d 行字节数无效", line)
+		}
+		at := time.Duration(sec * float64(time.Second))
+		if n := len(entries); n > 0 && at < entries[n-1].at {
+			return nil, fmt.Errorf("业务轨迹第 %d 行时刻未递增", line)
+		}
+		entries = append(entries, traceEntry{at: at, size: size})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取业务轨迹失败: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("业务轨迹为空")
+	}
+	return entries, nil
+}
+
+// 轨迹回放
+type trace struct {
+	entries []traceEntry
+	next    int
+	last    time.Duration
+	loop    bool
+}
+
+func (g *trace) Next() (time.Duration, []byte, bool) {
+	if g.next >= len(g.entries) {
+		if !g.loop {
+			return 0, nil, false
+		}
+		g.next, g.last = 0, 0
+	}
+	e := g.entries[g.next]
+	g.next++
+	wait := e.at - g.last
+	g.last = e.at
+	return wait, filler(e.size), true
+}
+
+// 文件分块发送
+type fileSource struct {
+	r        io.ReadCloser
+	chunk    int
+	interval time.Duration
+}
+
+func (g *fileSource) Next() (time.Duration, []byte, bool) {
+	buf := make([]byte, g.chunk)
+	n, err := io.ReadFull(g.r, buf)
+	if n == 0 {
+		g.r.Close()
+		return 0, nil, false
+	}
+	if err != nil {
+		// 最后一块，下次调用返回结束
+		buf = buf[:n]
+	}
+	return g.interval, buf, true
+}
@@ -0,0 +1,192 @@
+package traffic
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"tdma-network/internal/channel"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newGenerator(t *testing.T, cfg Config) Generator {
+	t.Helper()
+	g, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New(%+v): %v", cfg, err)
+	}
+	return g
+}
+
+// 取出n条消息的间隔
+func waits(t *testing.T, g Generator, n int) []time.Duration {
+	t.Helper()
+	out := make([]time.Duration, n)
+	for i := range out {
+		wait, _, ok := g.Next()
+		if !ok {
+			t.Fatalf("generator ended after %d messages", i)
+		}
+		out[i] = wait
+	}
+	return out
+}
+
+// 相同种子产生相同的到达序列，不同种子不同
+func TestSeededGeneratorsDeterministic(t *testing.T) {
+	const n = 200
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"poisson", Config{Type: TYPE_POISSON, Interval: channel.Duration(time.Second), Size: 8}},
+		{"onoff", Config{Type: TYPE_ONOFF, Interval: channel.Duration(100 * time.Millisecond), Size: 8,
+			On: channel.Duration(time.Second), Off: channel.Duration(3 * time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Seed = 42
+			a := waits(t, newGenerator(t, tt.cfg), n)
+			b := waits(t, newGenerator(t, tt.cfg), n)
+			for i := range a {
+				if a[i] != b[i] {
+					t.Fatalf("message %d: wait %v vs %v with the same seed", i, a[i], b[i])
+				}
+			}
+
+			tt.cfg.Seed = 43
+			c := waits(t, newGenerator(t, tt.cfg), n)
+			same := true
+			for i := range a {
+				if a[i] != c[i] {
+					same = false
+					break
+				}
+			}
+			if same {
+				t.Fatal("different seeds produced the same sequence")
+			}
+
+			var total time.Duration
+			for _, w := range a {
+				if w < 0 {
+					t.Fatalf("negative wait %v", w)
+				}
+				total += w
+			}
+			if total == 0 {
+				t.Fatal("all waits are zero")
+			}
+		})
+	}
+}
+
+// ON期间按固定间隔发送，突发之间的间隔包含OFF期
+func TestOnOffBursts(t *testing.T) {
+	interval := 100 * time.Millisecond
+	cfg := Config{Type: TYPE_ONOFF, Interval: channel.Duration(interval), Size: 8,
+		On: channel.Duration(time.Second), Off: channel.Duration(10 * time.Second), Seed: 1}
+	regular, gaps := 0, 0
+	for _, w := range waits(t, newGenerator(t, cfg), 500) {
+		switch {
+		case w == interval:
+			regular++
+		case w > interval:
+			gaps++
+		}
+	}
+	if regular == 0 || gaps == 0 {
+		t.Fatalf("regular %d, gaps %d: want both bursts and off periods", regular, gaps)
+	}
+}
+
+func TestTraceLoop(t *testing.T) {
+	path := writeTestFile(t, "trace.txt", []byte("# 时刻 字节数\n0.5 10\n\n1.5 20\n2 0\n"))
+	want := []struct {
+		wait time.Duration
+		size int
+	}{
+		{500 * time.Millisecond, 10},
+		{time.Second, 20},
+		{500 * time.Millisecond, 0},
+	}
+
+	for _, loop := range []bool{false, true} {
+		g := newGenerator(t, Config{Type: TYPE_TRACE, Path: path, Loop: loop})
+		for round := 0; round < 2; round++ {
+			for i, w := range want {
+				wait, payload, ok := g.Next()
+				if round == 1 && !loop {
+					if ok {
+						t.Fatalf("trace without loop continued after the last entry")
+					}
+					break
+				}
+				if !ok || wait != w.wait || len(payload) != w.size {
+					t.Fatalf("loop=%v round %d entry %d: (%v, %d bytes, %v), want (%v, %d bytes)",
+						loop, round, i, wait, len(payload), ok, w.wait, w.size)
+				}
+			}
+		}
+	}
+}
+
+func TestTraceErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"empty", "# 只有注释\n"},
+		{"missing size", "1.0\n"},
+		{"bad time", "soon 10\n"},
+		{"negative size", "1.0 -5\n"},
+		{"time goes back", "2.0 10\n1.0 10\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "trace.txt", []byte(tt.data))
+			if _, err := New(Config{Type: TYPE_TRACE, Path: path}); err == nil {
+				t.Fatalf("New accepted trace %q", tt.data)
+			}
+		})
+	}
+}
+
+// 文件按块发送，最后不足一块的部分单独发送一次后结束
+func TestFileSourceChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25) // 250字节
+	path := writeTestFile(t, "data.bin", data)
+	interval := 200 * time.Millisecond
+	g := newGenerator(t, Config{Type: TYPE_FILE, Path: path, Size: 100, Interval: channel.Duration(interval)})
+
+	var got []byte
+	var sizes []int
+	for {
+		wait, payload, ok := g.Next()
+		if !ok {
+			break
+		}
+		if wait != interval {
+			t.Fatalf("wait = %v, want %v", wait, interval)
+		}
+		sizes = append(sizes, len(payload))
+		got = append(got, payload...)
+	}
+	if len(sizes) != 3 || sizes[0] != 100 || sizes[1] != 100 || sizes[2] != 50 {
+		t.Fatalf("chunk sizes = %v, want [100 100 50]", sizes)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("reassembled chunks differ from the file")
+	}
+	if _, _, ok := g.Next(); ok {
+		t.Fatal("file source continued after the end")
+	}
+}
@@ -0,0 +1,195 @@
+package traffic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 业务消息前缀
+const MESSAGE_PREFIX = "TRF:"
+
+// 业务消息: TRF:<源节点长度>:<流长度>:<序号>:<产生时刻UnixNano>:<源节点><流><内容>
+// 源节点与流按长度切分，可以包含任意字符；接收端据此统计每条流的时延、丢失与乱序
+type Message struct {
+	Source    string
+	Flow      string
+	Seq       uint64
+	Timestamp time.Time
+	Payload   []byte
+}
+
+// 编码消息
+func (m Message) Encode() []byte {
+	header := fmt.Sprintf("%s%d:%d:%d:%d:%s%s", MESSAGE_PREFIX, len(m.Source), len(m.Flow), m.Seq, m.Timestamp.UnixNano(), m.Source, m.Flow)
+	return append([]byte(header), m.Payload...)
+}
+
+// 解码消息，不是业务消息时返回false
+func Decode(data []byte) (Message, bool) {
+	s, ok := strings.CutPrefix(string(data), MESSAGE_PREFIX)
+	if !ok {
+		return Message{}, false
+	}
+	parts := strings.SplitN(s, ":", 5)
+	if len(parts) != 5 {
+		return Message{}, false
+	}
+	srcLen, err := strconv.Atoi(parts[0])
+	if err != nil || srcLen < 0 {
+		return Message{}, false
+	}
+	flowLen, err := strconv.Atoi(parts[1])
+	if err != nil || flowLen < 0 {
+		return Message{}, false
+	}
+	seq, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return Message{}, false
+	}
+	ts, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return Message{}, false
+	}
+	rest := parts[4]
+	if srcLen+flowLen > len(rest) {
+		return Message{}, false
+	}
+	return Message{
+		Source:    rest[:srcLen],
+		Flow:      rest[srcLen : srcLen+flowLen],
+		Seq:       seq,
+		Timestamp: time.Unix(0, ts),
+		Payload:   []byte(rest[srcLen+flowLen:]),
+	}, true
+}
+
+// 判断重复所保留的序号窗口
+const seqWindow = 1024
+
+// 单条流的接收统计
+type FlowStats struct {
+	Source     string
+	Flow       string
+	Received   int64 // 收到的不重复消息数
+	Duplicates int64
+	Reordered  int64 // 晚于更大序号到达的消息数
+	Bytes      int64
+	MaxSeq     uint64
+
+	LatencySum time.Duration
+	LatencyMin time.Duration
+	LatencyMax time.Duration
+
+	seen map[uint64]bool
+}
+
+// 丢失的消息数：序号空缺，迟到的消息到达后会相应减少
+func (f *FlowStats) Lost() int64 {
+	if lost := int64(f.MaxSeq) - f.Received; lost > 0 {
+		return lost
+	}
+	return 0
+}
+
+// 平均时延
+func (f *FlowStats) LatencyAvg() time.Duration {
+	if f.Received == 0 {
+		return 0
+	}
+	return f.LatencySum / time.Duration(f.Received)
+}
+
+// 业务接收端
+type Receiver struct {
+	mu    sync.Mutex
+	flows map[flowKey]*FlowStats
+}
+
+// 源节点与流名称都可以包含任意字符，按二者组成的键区分流
+type flowKey struct {
+	source, flow string
+}
+
+// 创建业务接收端
+func NewReceiver() *Receiver {
+	return &Receiver{flows: make(map[flowKey]*FlowStats)}
+}
+
+// 记录在at时刻收到的消息
+func (r *Receiver) Observe(m Message, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := flowKey{m.Source, m.Flow}
+	f, ok := r.flows[key]
+	if !ok {
+		f = &FlowStats{Source: m.Source, Flow: m.Flow, seen: make(map[uint64]bool)}
+		r.flows[key] = f
+	}
+
+	// 窗口之外的旧序号无法判断是否重复，按重复处理
+	if f.seen[m.Seq] || (f.MaxSeq >= seqWindow && m.Seq <= f.MaxSeq-seqWindow) {
+		f.Duplicates++
+		return
+	}
+	f.seen[m.Seq] = true
+	if m.Seq < f.MaxSeq {
+		f.Reordered++
+	} else {
+		f.MaxSeq = m.Seq
+		for seq := range f.seen {
+			if seq+seqWindow <= f.MaxSeq {
+				delete(f.seen, seq)
+			}
+		}
+	}
+
+	latency := at.Sub(m.Timestamp)
+	f.Received++
+	f.Bytes += int64(len(m.Payload))
+	f.LatencySum += latency
+	if f.Received == 1 || latency < f.LatencyMin {
+		f.LatencyMin = latency
+	}
+	if latency > f.LatencyMax {
+		f.LatencyMax = latency
+	}
+}
+
+// 获取各条流统计的副本，按源节点与流排序
+func (r *Receiver) Stats() []FlowStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]FlowStats, 0, len(r.flows))
+	for _, f := range r.flows {
+		s := *f
+		s.seen = nil
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Source != stats[j].Source {
+			return stats[i].Source < stats[j].Source
+		}
+		return stats[i].Flow < stats[j].Flow
+	})
+	return stats
+}
+
+// 打印接收统计
+func (r *Receiver) Print() {
+	stats := r.Stats()
+	if len(stats) == 0 {
+		return
+	}
+	fmt.Println("业务接收:")
+	for _, f := range stats {
+		fmt.Printf("  %s/%s: 收到 %d, 丢失 %d, 重复 %d, 乱序 %d, %d 字节, 时延 平均 %v 最小 %v 最大 %v\n",
+			f.Source, f.Flow, f.Received, f.Lost(), f.Duplicates, f.Reordered, f.Bytes,
+			f.LatencyAvg().Round(time.Millisecond), f.LatencyMin.Round(time.Millisecond), f.LatencyMax.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,99 @@
+package traffic
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"plain", Message{Source: "GS1", Flow: "cbr0", Seq: 1, Timestamp: ts, Payload: []byte("hello")}},
+		{"colon in source", Message{Source: "GS:1", Flow: "poisson0", Seq: 42, Timestamp: ts, Payload: []byte("x")}},
+		{"colon in flow and payload", Message{Source: "GS1", Flow: "a:b", Seq: 7, Timestamp: ts, Payload: []byte("k:v:w")}},
+		{"empty payload", Message{Source: "GS1", Flow: "f", Seq: 0, Timestamp: ts}},
+		{"digits in source", Message{Source: "12:34", Flow: "56", Seq: 1<<63 + 1, Timestamp: ts, Payload: []byte("78")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Decode(tt.msg.Encode())
+			if !ok {
+				t.Fatalf("Decode(%q) failed", tt.msg.Encode())
+			}
+			if got.Source != tt.msg.Source || got.Flow != tt.msg.Flow || got.Seq != tt.msg.Seq ||
+				!got.Timestamp.Equal(tt.msg.Timestamp) || !bytes.Equal(got.Payload, tt.msg.Payload) {
+				t.Fatalf("Decode(Encode(%+v)) = %+v", tt.msg, got)
+			}
+		})
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no prefix", "DATA:hello"},
+		{"missing fields", "TRF:3:4:1"},
+		{"bad source length", "TRF:x:4:1:0:GS1cbr0"},
+		{"negative length", "TRF:-1:4:1:0:GS1cbr0"},
+		{"bad seq", "TRF:3:4:one:0:GS1cbr0"},
+		{"bad timestamp", "TRF:3:4:1:now:GS1cbr0"},
+		{"lengths exceed data", "TRF:3:9:1:0:GS1cbr0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, ok := Decode([]byte(tt.data)); ok {
+				t.Fatalf("Decode(%q) = %+v, want failure", tt.data, m)
+			}
+		})
+	}
+}
+
+func TestReceiverCounts(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	msg := func(seq uint64) Message {
+		return Message{Source: "GS:1", Flow: "f", Seq: seq, Timestamp: base.Add(time.Duration(seq) * time.Second), Payload: []byte("abcd")}
+	}
+
+	r := NewReceiver()
+	// 1 2 4 3 3 6：5丢失，3乱序，第二个3重复
+	for _, seq := range []uint64{1, 2, 4, 3, 3, 6} {
+		r.Observe(msg(seq), base.Add(time.Duration(seq)*time.Second+100*time.Millisecond))
+	}
+	// 另一条流单独统计
+	r.Observe(Message{Source: "GT1", Flow: "f", Seq: 1, Timestamp: base}, base.Add(time.Second))
+
+	stats := r.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("got %d flows, want 2", len(stats))
+	}
+	f := stats[0]
+	if f.Source != "GS:1" || f.Flow != "f" {
+		t.Fatalf("first flow = %s/%s, want GS:1/f", f.Source, f.Flow)
+	}
+	if f.Received != 5 || f.Lost() != 1 || f.Duplicates != 1 || f.Reordered != 1 || f.MaxSeq != 6 || f.Bytes != 20 {
+		t.Fatalf("stats = received %d lost %d dup %d reordered %d max %d bytes %d, want 5 1 1 1 6 20",
+			f.Received, f.Lost(), f.Duplicates, f.Reordered, f.MaxSeq, f.Bytes)
+	}
+	if f.LatencyAvg() != 100*time.Millisecond || f.LatencyMin != 100*time.Millisecond || f.LatencyMax != 100*time.Millisecond {
+		t.Fatalf("latency avg %v min %v max %v, want 100ms", f.LatencyAvg(), f.LatencyMin, f.LatencyMax)
+	}
+
+	// 迟到的5到达后不再计为丢失
+	r.Observe(msg(5), base.Add(6*time.Second))
+	if f := r.Stats()[0]; f.Lost() != 0 || f.Reordered != 2 {
+		t.Fatalf("after late seq 5: lost %d reordered %d, want 0 2", f.Lost(), f.Reordered)
+	}
+
+	// 窗口之外的旧序号按重复处理
+	r.Observe(msg(seqWindow+10), base)
+	r.Observe(msg(2), base)
+	if f := r.Stats()[0]; f.Duplicates != 2 {
+		t.Fatalf("old seq outside window: duplicates %d, want 2", f.Duplicates)
+	}
+}
@@ -0,0 +1,163 @@
+package traffic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"tdma-network/internal/channel"
+	"tdma-network/pkg/protocol"
+	"time"
+)
+
+// 业务类型
+const (
+	TYPE_CBR     = "cbr"     // 固定间隔、固定长度
+	TYPE_POISSON = "poisson" // 泊松到达，interval为平均间隔
+	TYPE_ONOFF   = "onoff"   // 突发：ON期间按interval发送，ON/OFF时长服从指数分布
+	TYPE_TRACE   = "trace"   // 按记录的业务轨迹回放
+	TYPE_FILE    = "file"    // 分块发送磁盘文件
+)
+
+// 业务源配置
+type Config struct {
+	Type     string           `json:"type"`
+	Name     string           `json:"name"`     // 流名称，默认为类型加序号
+	Interval channel.Duration `json:"interval"` // 发送间隔（poisson为平均间隔）
+	Size     int              `json:"size"`     // 消息内容字节数（file为分块大小）
+
+	On  channel.Duration `json:"on"`  // onoff: 平均ON时长
+	Off channel.Duration `json:"off"` // onoff: 平均OFF时长
+
+	Path string `json:"path"` // trace/file: 轨迹或文件路径
+	Loop bool   `json:"loop"` // trace: 回放结束后从头开始
+
+	Destination string `json:"destination"` // 目的地面站，为空时发往卫星
+	Seed        int64  `json:"seed"`        // 随机种子，0表示按时间取种
+}
+
+// 业务配置文件
+//
+//	{
+//	  "sources": [
+//	    {"type": "poisson", "interval": "2s", "size": 64},
+//	    {"type": "file", "path": "data.bin", "size": 512, "interval": "200ms", "destination": "GS2"}
+//	  ]
+//	}
+type FileConfig struct {
+	Sources []Config `json:"sources"`
+}
+
+// 加载业务配置文件
+func LoadConfig(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取业务配置失败: %v", err)
+	}
+
+	var fc FileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("解析业务配置失败: %v", err)
+	}
+	for i, cfg := range fc.Sources {
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("sources[%d]: %v", i, err)
+		}
+	}
+	return fc.Sources, nil
+}
+
+// 解析命令行业务描述："类型,键=值,..."，如 "poisson,interval=2s,size=64"
+func ParseSpec(spec string) (Config, error) {
+	fields := strings.Split(spec, ",")
+	cfg := Config{Type: strings.TrimSpace(fields[0])}
+
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return Config{}, fmt.Errorf("无效的参数: %s", field)
+		}
+		key, value := kv[0], kv[1]
+
+		var err error
+		switch key {
+		case "name":
+			cfg.Name = value
+		case "interval":
+			err = parseDuration(value, &cfg.Interval)
+		case "on":
+			err = parseDuration(value, &cfg.On)
+		case "off":
+			err = parseDuration(value, &cfg.Off)
+		case "size":
+			cfg.Size, err = strconv.Atoi(value)
+		case "path":
+			cfg.Path = value
+		case "loop":
+			cfg.Loop, err = strconv.ParseBool(value)
+		case "dest", "destination":
+			cfg.Destination = value
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return Config{}, fmt.Errorf("未知的参数: %s", key)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("参数 %s 无效: %v", key, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func parseDuration(s string, d *channel.Duration) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = channel.Duration(v)
+	return nil
+}
+
+// 检查参数合法性
+func (c Config) Validate() error {
+	switch c.Type {
+	case TYPE_CBR, TYPE_POISSON:
+		if c.Interval <= 0 || c.Size <= 0 {
+			return fmt.Errorf("%s: interval 与 size 必须大于0", c.Type)
+		}
+	case TYPE_ONOFF:
+		if c.Interval <= 0 || c.Size <= 0 || c.On <= 0 || c.Off <= 0 {
+			return fmt.Errorf("onoff: interval、size、on、off 必须大于0")
+		}
+	case TYPE_TRACE:
+		if c.Path == "" {
+			return fmt.Errorf("trace: 需要指定 path")
+		}
+	case TYPE_FILE:
+		if c.Path == "" || c.Interval <= 0 {
+			return fmt.Errorf("file: 需要指定 path 与 interval")
+		}
+		if c.Size < 0 || c.Size > protocol.MAX_FRAME_DATA/2 {
+			return fmt.Errorf("file: size 超出范围")
+		}
+	default:
+		return fmt.Errorf("未知的业务类型: %s", c.Type)
+	}
+	if c.Size < 0 {
+		return fmt.Errorf("size 不能为负")
+	}
+	return nil
+}
+
+// 流名称
+func (c Config) FlowName(index int) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%s%d", c.Type, index)
+}
@@ -0,0 +1,85 @@
+package traffic
+
+import (
+	"strings"
+	"tdma-network/internal/channel"
+	"tdma-network/pkg/protocol"
+	"testing"
+	"time"
+)
+
+func TestParseSpec(t *testing.T) {
+	got, err := ParseSpec("onoff, name=burst:1, interval=100ms, on=2s, off=5s, size=32, dest=GS2, seed=7")
+	if err != nil {
+		t.Fatalf("ParseSpec: %v", err)
+	}
+	want := Config{
+		Type: TYPE_ONOFF, Name: "burst:1", Size: 32, Destination: "GS2", Seed: 7,
+		Interval: channel.Duration(100 * time.Millisecond), On: channel.Duration(2 * time.Second), Off: channel.Duration(5 * time.Second),
+	}
+	if got != want {
+		t.Fatalf("ParseSpec = %+v, want %+v", got, want)
+	}
+
+	got, err = ParseSpec("trace,path=t.txt,loop=true")
+	if err != nil {
+		t.Fatalf("ParseSpec: %v", err)
+	}
+	if got.Type != TYPE_TRACE || got.Path != "t.txt" || !got.Loop {
+		t.Fatalf("ParseSpec trace = %+v", got)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string // 错误信息中应包含的内容
+	}{
+		{"", "未知的业务类型"},
+		{"bursty,interval=1s,size=8", "未知的业务类型"},
+		{"cbr,interval", "无效的参数"},
+		{"cbr,interval=1s,size=8,rate=5", "未知的参数: rate"},
+		{"cbr,interval=soon,size=8", "参数 interval 无效"},
+		{"cbr,interval=1s,size=big", "参数 size 无效"},
+		{"trace,path=t.txt,loop=maybe", "参数 loop 无效"},
+		{"poisson,interval=1s,size=8,seed=x", "参数 seed 无效"},
+		{"cbr,size=8", "interval 与 size 必须大于0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseSpec(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseSpec(%q) error = %v, want %q", tt.spec, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	second := channel.Duration(time.Second)
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{"cbr", Config{Type: TYPE_CBR, Interval: second, Size: 8}, true},
+		{"cbr zero size", Config{Type: TYPE_CBR, Interval: second}, false},
+		{"poisson zero interval", Config{Type: TYPE_POISSON, Size: 8}, false},
+		{"onoff", Config{Type: TYPE_ONOFF, Interval: second, Size: 8, On: second, Off: second}, true},
+		{"onoff missing off", Config{Type: TYPE_ONOFF, Interval: second, Size: 8, On: second}, false},
+		{"trace", Config{Type: TYPE_TRACE, Path: "t.txt"}, true},
+		{"trace without path", Config{Type: TYPE_TRACE}, false},
+		{"trace negative size", Config{Type: TYPE_TRACE, Path: "t.txt", Size: -1}, false},
+		{"file default chunk", Config{Type: TYPE_FILE, Path: "f.bin", Interval: second}, true},
+		{"file without interval", Config{Type: TYPE_FILE, Path: "f.bin"}, false},
+		{"file chunk too large", Config{Type: TYPE_FILE, Path: "f.bin", Interval: second, Size: protocol.MAX_FRAME_DATA}, false},
+		{"unknown type", Config{Type: "video"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err == nil) != tt.ok {
+				t.Fatalf("Validate(%+v) = %v, want ok=%v", tt.cfg, err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// 认证标志位：数据区末尾附加认证尾部 <序号(8字节)><HMAC-SHA256标签(32字节)>
+const FLAG_AUTH = 0x0010
+
+// 加密标志位：数据区为 <密钥代数(4字节)><AES-GCM密文与标签>，加密后再附加认证尾部
+const FLAG_ENCRYPTED = 0x0020
+
+// 密钥代数字段长度
+const KEY_EPOCH_LEN = 4
+
+// 认证尾部
+const (
+	AUTH_SEQ_LEN     = 8
+	AUTH_TAG_LEN     = sha256.Size
+	AUTH_TRAILER_LEN = AUTH_SEQ_LEN + AUTH_TAG_LEN
+)
+
+// 是否带认证尾部
+func (f *TDMAFrame) IsAuthenticated() bool {
+	return (f.Flags & FLAG_AUTH) != 0
+}
+
+// 用key为帧附加序号为seq的认证尾部，并更新Length与CRC
+// 标签覆盖帧头各字段（不含Length，含绝对时隙号）、序号与数据区
+func (f *TDMAFrame) Sign(key []byte, seq uint64) {
+	f.Flags |= FLAG_AUTH
+	payload := f.Data
+
+	data := make([]byte, 0, len(payload)+AUTH_TRAILER_LEN)
+	data = append(data, payload...)
+	data = binary.BigEndian.AppendUint64(data, seq)
+	data = append(data, f.authTag(key, seq, payload)...)
+
+	f.Data = data
+	f.Length = uint32(len(data))
+	f.CRC = calculateCRC(f)
+}
+
+// 用key校验认证尾部，成功时去掉尾部与认证标志（更新Length与CRC）并返回序号
+// 序号是否重复由调用方判断
+func (f *TDMAFrame) Verify(key []byte) (uint64, error) {
+	if !f.IsAuthenticated() {
+		return 0, fmt.Errorf("帧没有认证尾部")
+	}
+	if len(f.Data) < AUTH_TRAILER_LEN {
+		return 0, fmt.Errorf("认证尾部长度不足: %d", len(f.Data))
+	}
+	n := len(f.Data) - AUTH_TRAILER_LEN
+	payload := f.Data[:n]
+	seq := binary.BigEndian.Uint64(f.Data[n:])
+	tag := f.Data[n+AUTH_SEQ_LEN:]
+	if !hmac.Equal(tag, f.authTag(key, seq, payload)) {
+		return 0, fmt.Errorf("认证标签不匹配")
+	}
+
+	f.Flags &^= FLAG_AUTH
+	f.Data = payload
+	f.Length = uint32(len(payload))
+	f.CRC = calculateCRC(f)
+	return seq, nil
+}
+
+// 计算认证标签，Flags按带认证标志计算
+func (f *TDMAFrame) authTag(key []byte, seq uint64, payload []byte) []byte {
+	var hdr [8 + 4 + 32 + 4 + 2 + 2 + 2 + 8 + AUTH_SEQ_LEN]byte
+	b := append(hdr[:0], f.Header[:]...)
+	b = binary.BigEndian.AppendUint32(b, f.SlotID)
+	b = append(b, f.NodeID[:]...)
+	b = binary.BigEndian.AppendUint32(b, f.FragmentID)
+	b = binary.BigEndian.AppendUint16(b, f.TotalFrags)
+	b = binary.BigEndian.AppendUint16(b, f.FragIndex)
+	b = binary.BigEndian.AppendUint16(b, f.Flags|FLAG_AUTH)
+	b = binary.BigEndian.AppendUint64(b, f.AbsSlot)
+	b = binary.BigEndian.AppendUint64(b, seq)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// 数据区是否已加密
+func (f *TDMAFrame) IsEncrypted() bool {
+	return (f.Flags & FLAG_ENCRYPTED) != 0
+}
+
+// 用第epoch代密钥的aead加密数据区，nonce由密钥代数与序号seq组成，并更新Length与CRC
+// 同一密钥下序号不能重复
+func (f *TDMAFrame) Encrypt(aead cipher.AEAD, epoch uint32, seq uint64) {
+	data := make([]byte, KEY_EPOCH_LEN, KEY_EPOCH_LEN+len(f.Data)+aead.Overhead())
+	binary.BigEndian.PutUint32(data, epoch)
+	data = aead.Seal(data, gcmNonce(epoch, seq), f.Data, nil)
+
+	f.Flags |= FLAG_ENCRYPTED
+	f.Data = data
+	f.Length = uint32(len(data))
+	f.CRC = calculateCRC(f)
+}
+
+// 加密数据区使用的密钥代数
+func (f *TDMAFrame) KeyEpoch() (uint32, bool) {
+	if !f.IsEncrypted() || len(f.Data) < KEY_EPOCH_LEN {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(f.Data), true
+}
+
+// 用aead解密数据区，成功时去掉加密标志并更新Length与CRC，失败时帧不变
+func (f *TDMAFrame) Decrypt(aead cipher.AEAD, seq uint64) error {
+	epoch, ok := f.KeyEpoch()
+	if !ok {
+		return fmt.Errorf("帧没有加密数据")
+	}
+	plain, err := aead.Open(nil, gcmNonce(epoch, seq), f.Data[KEY_EPOCH_LEN:], nil)
+	if err != nil {
+		return fmt.Errorf("解密失败: %v", err)
+	}
+
+	f.Flags &^= FLAG_ENCRYPTED
+	f.Data = plain
+	f.Length = uint32(len(plain))
+	f.CRC = calculateCRC(f)
+	return nil
+}
+
+// 12字节GCM nonce：密钥代数(4字节) + 序号(8字节)
+func gcmNonce(epoch uint32, seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce, epoch)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
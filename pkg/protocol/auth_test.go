@@ -0,0 +1,119 @@
+package protocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+var testMACKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newSignedFrame() *TDMAFrame {
+	f := NewTDMAFrame(3, "GS1", []byte("DATA_TO:GS2:hello"))
+	f.FragmentID = 7
+	f.TotalFrags = 2
+	f.FragIndex = 1
+	f.SetAbsSlot(176534000)
+	f.Sign(testMACKey, 42)
+	return f
+}
+
+func TestSignVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    []byte
+		tamper func(f *TDMAFrame)
+		ok     bool
+	}{
+		{"unmodified", testMACKey, func(f *TDMAFrame) {}, true},
+		{"wrong key", []byte("fedcba9876543210fedcba9876543210"), func(f *TDMAFrame) {}, false},
+		{"tag", testMACKey, func(f *TDMAFrame) { f.Data[len(f.Data)-1] ^= 0x80 }, false},
+		{"sequence", testMACKey, func(f *TDMAFrame) { f.Data[len(f.Data)-AUTH_TRAILER_LEN+7] ^= 1 }, false},
+		{"payload", testMACKey, func(f *TDMAFrame) { f.Data[0] ^= 1 }, false},
+		{"header", testMACKey, func(f *TDMAFrame) { f.Header[7] ^= 1 }, false},
+		{"slot id", testMACKey, func(f *TDMAFrame) { f.SlotID ^= 1 }, false},
+		{"node id", testMACKey, func(f *TDMAFrame) { f.NodeID[31] ^= 1 }, false},
+		{"fragment id", testMACKey, func(f *TDMAFrame) { f.FragmentID ^= 1 }, false},
+		{"total frags", testMACKey, func(f *TDMAFrame) { f.TotalFrags ^= 1 }, false},
+		{"frag index", testMACKey, func(f *TDMAFrame) { f.FragIndex ^= 1 }, false},
+		{"flags", testMACKey, func(f *TDMAFrame) { f.Flags ^= FLAG_ENCRYPTED }, false},
+		{"abs slot", testMACKey, func(f *TDMAFrame) { f.AbsSlot ^= 1 }, false},
+		{"truncated", testMACKey, func(f *TDMAFrame) { f.Data = f.Data[:AUTH_TRAILER_LEN-1] }, false},
+		{"unsigned", testMACKey, func(f *TDMAFrame) { f.Flags &^= FLAG_AUTH }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSignedFrame()
+			tt.tamper(f)
+			seq, err := f.Verify(tt.key)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Verify = %v, want ok=%v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			if seq != 42 || string(f.Data) != "DATA_TO:GS2:hello" || f.IsAuthenticated() {
+				t.Fatalf("Verify = seq %d data %q flags %#x", seq, f.Data, f.Flags)
+			}
+			if err := f.Validate(); err != nil {
+				t.Fatalf("Validate after Verify: %v", err)
+			}
+		})
+	}
+}
+
+func newTestAEAD(t *testing.T, key []byte) cipher.AEAD {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("NewGCM: %v", err)
+	}
+	return aead
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	aead := newTestAEAD(t, testMACKey)
+	tests := []struct {
+		name   string
+		aead   cipher.AEAD
+		seq    uint64
+		tamper func(f *TDMAFrame)
+		ok     bool
+	}{
+		{"unmodified", aead, 9, func(f *TDMAFrame) {}, true},
+		{"wrong key", newTestAEAD(t, []byte("fedcba9876543210fedcba9876543210")), 9, func(f *TDMAFrame) {}, false},
+		{"wrong sequence", aead, 10, func(f *TDMAFrame) {}, false},
+		{"key epoch", aead, 9, func(f *TDMAFrame) { f.Data[KEY_EPOCH_LEN-1] ^= 1 }, false},
+		{"ciphertext", aead, 9, func(f *TDMAFrame) { f.Data[KEY_EPOCH_LEN] ^= 1 }, false},
+		{"gcm tag", aead, 9, func(f *TDMAFrame) { f.Data[len(f.Data)-1] ^= 1 }, false},
+		{"truncated", aead, 9, func(f *TDMAFrame) { f.Data = f.Data[:KEY_EPOCH_LEN-1] }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewTDMAFrame(3, "GS1", []byte("DATA_TO:GS2:hello"))
+			f.Encrypt(aead, 5, 9)
+			if epoch, ok := f.KeyEpoch(); !ok || epoch != 5 {
+				t.Fatalf("KeyEpoch = %d, %v", epoch, ok)
+			}
+			tt.tamper(f)
+			sealed := string(f.Data)
+			err := f.Decrypt(tt.aead, tt.seq)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Decrypt = %v, want ok=%v", err, tt.ok)
+			}
+			if !tt.ok {
+				if string(f.Data) != sealed || !f.IsEncrypted() {
+					t.Fatalf("failed Decrypt modified the frame")
+				}
+				return
+			}
+			if string(f.Data) != "DATA_TO:GS2:hello" || f.IsEncrypted() || f.Validate() != nil {
+				t.Fatalf("Decrypt = %q flags %#x", f.Data, f.Flags)
+			}
+		})
+	}
+}
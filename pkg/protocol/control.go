@@ -0,0 +1,128 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 控制消息（以数据区字符串区分）
+const (
+	MSG_GET_CURRENT_SLOT = "GET_CURRENT_SLOT"
+	MSG_CURRENT_SLOT     = "CURRENT_SLOT_"
+	MSG_ACK_SLOT         = "ACK_SLOT_"
+
+	// 入网: JOIN -> JOIN_ACK_<slotID>_<token>
+	// 访问控制策略拒绝入网、会话恢复、续约或时隙分配时回复 JOIN_REJECT_<原因>
+	MSG_JOIN        = "JOIN"
+	MSG_JOIN_ACK    = "JOIN_ACK_"
+	MSG_JOIN_REJECT = "JOIN_REJECT_"
+
+	// 会话恢复: RESUME_<token> -> RESUME_ACK_<slotID> 或 RESUME_REJECT
+	MSG_RESUME        = "RESUME_"
+	MSG_RESUME_ACK    = "RESUME_ACK_"
+	MSG_RESUME_REJECT = "RESUME_REJECT"
+
+	// 节点认证（卫星配置了密钥库时）: JOIN -> AUTH_CHALLENGE_<卫星随机数>_<卫星X25519公钥>
+	// -> AUTH_RESPONSE_<地面站随机数>_<地面站X25519公钥>_<响应MAC> -> 已签名并加密的JOIN_ACK；
+	// 认证失败回复 AUTH_REJECT_<原因>。随机数、公钥与MAC均为十六进制
+	MSG_AUTH_CHALLENGE = "AUTH_CHALLENGE_"
+	MSG_AUTH_RESPONSE  = "AUTH_RESPONSE_"
+	MSG_AUTH_REJECT    = "AUTH_REJECT_"
+
+	// 离网: 释放节点的时隙与会话，无响应
+	MSG_LEAVE = "LEAVE"
+
+	// 心跳: HEARTBEAT -> HEARTBEAT_ACK_<slotID>_<卫星发送时刻UnixNano> 或 HEARTBEAT_REJECT（会话不存在）
+	MSG_HEARTBEAT        = "HEARTBEAT"
+	MSG_HEARTBEAT_ACK    = "HEARTBEAT_ACK_"
+	MSG_HEARTBEAT_REJECT = "HEARTBEAT_REJECT"
+
+	// 地面站间数据: 地面站在自己的时隙发送 DATA_TO:<目的节点>:<内容>
+	// 卫星向目的地面站投递 DATA_FROM:<源节点>:<内容>，无法路由时向源地面站回复 DATA_UNREACHABLE:<目的节点>，
+	// 访问控制策略不允许发往该目的节点时回复 DATA_DENIED:<目的节点>:<原因>
+	MSG_DATA_TO          = "DATA_TO:"
+	MSG_DATA_FROM        = "DATA_FROM:"
+	MSG_DATA_UNREACHABLE = "DATA_UNREACHABLE:"
+	MSG_DATA_DENIED      = "DATA_DENIED:"
+
+	// 帧结构变更通告: SCHEDULE_CHANGE_<生效时刻UnixNano>_<时隙数>_<时隙持续时间纳秒>_<原时隙>_<新时隙>
+	// 卫星在变更生效前向接入的地面站通告，生效时刻起时隙从新的起点按新帧结构划分；
+	// 地面站届时若仍使用原时隙则改用新时隙，新时隙为-1表示需等待心跳确认重新分配
+	MSG_SCHEDULE_CHANGE = "SCHEDULE_CHANGE_"
+)
+
+// 帧结构变更通告的内容
+type ScheduleChange struct {
+	At           time.Time
+	TotalSlots   int
+	SlotDuration time.Duration
+	From, To     int
+}
+
+// 编码为 SCHEDULE_CHANGE 消息
+func (c ScheduleChange) Encode() string {
+	return fmt.Sprintf("%s%d_%d_%d_%d_%d", MSG_SCHEDULE_CHANGE, c.At.UnixNano(), c.TotalSlots, int64(c.SlotDuration), c.From, c.To)
+}
+
+// 解析 SCHEDULE_CHANGE 消息
+func ParseScheduleChange(msg string) (ScheduleChange, error) {
+	parts := strings.Split(strings.TrimPrefix(msg, MSG_SCHEDULE_CHANGE), "_")
+	if len(parts) != 5 {
+		return ScheduleChange{}, fmt.Errorf("帧结构变更通告格式错误: %q", msg)
+	}
+	var v [5]int64
+	for i, p := range parts {
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return ScheduleChange{}, fmt.Errorf("帧结构变更通告格式错误: %q", msg)
+		}
+		v[i] = n
+	}
+	return ScheduleChange{
+		At:           time.Unix(0, v[0]).UTC(),
+		TotalSlots:   int(v[1]),
+		SlotDuration: time.Duration(v[2]),
+		From:         int(v[3]),
+		To:           int(v[4]),
+	}, nil
+}
+
+// 星间链路消息，帧的NodeID为发送方卫星
+const (
+	MSG_ISL_PREFIX = "ISL_"
+
+	// 邻居发现与保活，建链后双方周期性发送
+	MSG_ISL_HELLO = "ISL_HELLO"
+
+	// 星间链路认证（配置了密钥库时）: 发起方 ISL_HELLO -> 接收方 ISL_CHALLENGE_<接收方随机数>
+	// -> 发起方 ISL_RESPONSE_<发起方随机数>_<响应MAC> -> 接收方 ISL_ACCEPT_<确认MAC>，之后双方发送 ISL_HELLO。
+	// MAC用发起方卫星的预共享密钥计算，随机数与MAC均为十六进制。
+	// 认证后链路上的每一帧都用握手导出的链路密钥附加认证尾部（FLAG_AUTH），序号逐帧递增
+	MSG_ISL_CHALLENGE = "ISL_CHALLENGE_"
+	MSG_ISL_RESPONSE  = "ISL_RESPONSE_"
+	MSG_ISL_ACCEPT    = "ISL_ACCEPT_"
+
+	// 链路状态通告: ISL_LSA:<通告编码>，收到更新的通告后向其他邻居泛洪
+	MSG_ISL_LSA = "ISL_LSA:"
+
+	// 转发数据: ISL_DATA:<剩余跳数>:<源节点长度>:<目的节点长度>:<源节点><目的节点><内容>
+	// 节点ID按字节长度切分，可以包含冒号
+	MSG_ISL_DATA = "ISL_DATA:"
+)
+
+// 星间链路参数
+const (
+	ISL_HELLO_INTERVAL   = 2 * time.Second          // 保活间隔
+	ISL_DEAD_INTERVAL    = 3 * ISL_HELLO_INTERVAL   // 超过该时长未收到邻居消息判定链路中断
+	ISL_REFRESH_INTERVAL = 10 * time.Second         // 本星链路状态通告刷新间隔
+	ISL_LSA_MAX_AGE      = 3 * ISL_REFRESH_INTERVAL // 超过该时长未刷新的通告被删除
+	ISL_MAX_HOPS         = 16                       // 转发数据的最大跳数
+)
+
+// 心跳参数
+const (
+	HEARTBEAT_INTERVAL   = 2 * time.Second // 地面站心跳间隔
+	HEARTBEAT_MISS_LIMIT = 3               // 连续丢失该数量心跳后判定节点失效
+)
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// 序号标志位：数据区末尾附加明文序号尾部 <序号(8字节)>
+// 地面站发出的未签名帧带序号尾部，卫星以此做防重放；签名帧的序号在认证尾部中
+const FLAG_SEQ = 0x0040
+
+// 序号尾部长度
+const SEQ_TRAILER_LEN = 8
+
+// 是否带序号尾部
+func (f *TDMAFrame) IsSequenced() bool {
+	return (f.Flags & FLAG_SEQ) != 0
+}
+
+// 为帧附加序号为seq的序号尾部，并更新Length与CRC
+// 序号没有密钥保护，只能识别原样重发的帧
+func (f *TDMAFrame) SetSequence(seq uint64) {
+	f.Flags |= FLAG_SEQ
+	f.Data = binary.BigEndian.AppendUint64(append([]byte(nil), f.Data...), seq)
+	f.Length = uint32(len(f.Data))
+	f.CRC = calculateCRC(f)
+}
+
+// 去掉序号尾部与序号标志（更新Length与CRC）并返回序号
+func (f *TDMAFrame) TakeSequence() (uint64, error) {
+	if !f.IsSequenced() {
+		return 0, fmt.Errorf("帧没有序号尾部")
+	}
+	if len(f.Data) < SEQ_TRAILER_LEN {
+		return 0, fmt.Errorf("序号尾部长度不足: %d", len(f.Data))
+	}
+	n := len(f.Data) - SEQ_TRAILER_LEN
+	seq := binary.BigEndian.Uint64(f.Data[n:])
+
+	f.Flags &^= FLAG_SEQ
+	f.Data = f.Data[:n]
+	f.Length = uint32(n)
+	f.CRC = calculateCRC(f)
+	return seq, nil
+}
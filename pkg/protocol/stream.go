@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 单帧数据区最大长度，防止异常Length导致超大内存分配
+const MAX_FRAME_DATA = 1 << 20
+
+// 帧头之后、数据区之前的固定字段长度
+const frameFixedLen = 4 + 32 + 4 + 4 + 2 + 2 + 2 + 8
+
+// 数据区之前的帧前缀长度（帧头 + 固定字段）
+const FRAME_PREFIX_LEN = 8 + frameFixedLen
+
+// 数据区之后的帧尾部长度（CRC + Footer）
+const FRAME_SUFFIX_LEN = 4 + 8
+
+// 从字节流中读取一个完整的TDMA帧
+// 遇到无效帧头时逐字节滑动重新同步；连接关闭时原样返回io.EOF
+func ReadFrame(r io.Reader) (*TDMAFrame, error) {
+	buf := make([]byte, FRAME_PREFIX_LEN)
+
+	if _, err := io.ReadFull(r, buf[:8]); err != nil {
+		return nil, err
+	}
+	for !bytes.Equal(buf[:8], FRAME_HEADER[:]) {
+		copy(buf, buf[1:8])
+		if _, err := io.ReadFull(r, buf[7:8]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := io.ReadFull(r, buf[8:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(buf[8+4+32:])
+	if length > MAX_FRAME_DATA {
+		return nil, fmt.Errorf("数据长度超出上限: %d", length)
+	}
+
+	// 数据区 + CRC + Footer
+	rest := make([]byte, int(length)+FRAME_SUFFIX_LEN)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+
+	return DeserializeTDMAFrame(append(buf, rest...))
+}
+
+// 将帧序列化后写入字节流
+func WriteFrame(w io.Writer, frame *TDMAFrame) error {
+	data, err := frame.Serialize()
+	if err != nil {
+		return fmt.Errorf("序列化失败: %v", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
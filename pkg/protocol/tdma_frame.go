@@ -3,6 +3,7 @@ package protocol
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"strings"
 	"time"
 )
@@ -17,6 +18,7 @@ type TDMAFrame struct {
 	TotalFrags uint16 // 总分片数
 	FragIndex  uint16 // 当前分片索引
 	Flags      uint16 // 分片标志位
+	AbsSlot    uint64 // 发送时的绝对时隙号（自TDMA_EPOCH起的时隙数，跨超帧连续），0表示未标注
 	Data       []byte
 	CRC        uint32
 	Footer     [8]byte
@@ -36,14 +38,29 @@ var (
 	FRAME_FOOTER = [8]byte{0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA}
 )
 
-// 全局TDMA时隙起点
+// 全局TDMA时隙起点，当前时隙统一由调度器的帧结构（scheduler.Layout）自此起点计算
 var TDMA_EPOCH = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 
 // 获取全局统一时钟下的slotID
+//
+// Deprecated: 不考虑运行中的帧结构变更，改用 scheduler.TDMAScheduler 的 Current 或 SlotAt。
 func GetGlobalSlotID(slotDuration time.Duration, totalSlots int) int {
-	now := time.Now().UTC()
-	elapsed := now.Sub(TDMA_EPOCH)
-	slot := int(elapsed/slotDuration) % totalSlots
+	return GetGlobalSlotIDAt(time.Now(), slotDuration, totalSlots)
+}
+
+// 获取全局统一时钟下t时刻的slotID，与自TDMA_EPOCH起、未经变更的 scheduler.Layout 计算结果相同
+//
+// Deprecated: 不考虑运行中的帧结构变更，改用 scheduler.TDMAScheduler 的 SlotAt 或 scheduler.Layout.Slot。
+func GetGlobalSlotIDAt(t time.Time, slotDuration time.Duration, totalSlots int) int {
+	elapsed := t.Sub(TDMA_EPOCH)
+	abs := int64(elapsed / slotDuration)
+	if elapsed < 0 && elapsed%slotDuration != 0 {
+		abs--
+	}
+	slot := int(abs % int64(totalSlots))
+	if slot < 0 {
+		slot += totalSlots
+	}
 	return slot
 }
 
@@ -106,9 +123,27 @@ func NewFragmentTDMAFrame(slotID uint32, nodeID string, fragmentID uint32,
 // 序列化TDMA帧
 func (f *TDMAFrame) Serialize() ([]byte, error) {
 	// 计算总长度
-	totalLen := 8 + 4 + 32 + 4 + 4 + 2 + 2 + 2 + len(f.Data) + 4 + 8
+	totalLen := FRAME_PREFIX_LEN + len(f.Data) + FRAME_SUFFIX_LEN
 
 	buf := make([]byte, totalLen)
+	offset := f.putPrefix(buf)
+
+	// 写入Data
+	copy(buf[offset:], f.Data)
+	offset += len(f.Data)
+
+	// 写入CRC
+	binary.BigEndian.PutUint32(buf[offset:], f.CRC)
+	offset += 4
+
+	// 写入Footer
+	copy(buf[offset:], f.Footer[:])
+
+	return buf, nil
+}
+
+// 写入数据区之前的帧前缀（帧头与固定字段），返回写入的字节数
+func (f *TDMAFrame) putPrefix(buf []byte) int {
 	offset := 0
 
 	// 写入Header
@@ -143,23 +178,16 @@ func (f *TDMAFrame) Serialize() ([]byte, error) {
 	binary.BigEndian.PutUint16(buf[offset:], f.Flags)
 	offset += 2
 
-	// 写入Data
-	copy(buf[offset:], f.Data)
-	offset += len(f.Data)
-
-	// 写入CRC
-	binary.BigEndian.PutUint32(buf[offset:], f.CRC)
-	offset += 4
-
-	// 写入Footer
-	copy(buf[offset:], f.Footer[:])
+	// 写入AbsSlot
+	binary.BigEndian.PutUint64(buf[offset:], f.AbsSlot)
+	offset += 8
 
-	return buf, nil
+	return offset
 }
 
 // 反序列化TDMA帧
 func DeserializeTDMAFrame(data []byte) (*TDMAFrame, error) {
-	if len(data) < 60 { // 最小长度变为60
+	if len(data) < FRAME_PREFIX_LEN+FRAME_SUFFIX_LEN {
 		return nil, fmt.Errorf("数据长度不足")
 	}
 
@@ -198,6 +226,10 @@ func DeserializeTDMAFrame(data []byte) (*TDMAFrame, error) {
 	frame.Flags = binary.BigEndian.Uint16(data[offset:])
 	offset += 2
 
+	// 读取AbsSlot
+	frame.AbsSlot = binary.BigEndian.Uint64(data[offset:])
+	offset += 8
+
 	// 读取Data
 	dataLen := int(frame.Length)
 	if offset+dataLen+12 > len(data) {
@@ -263,32 +295,31 @@ func (f *TDMAFrame) GetNodeID() string {
 	return strings.TrimRight(string(f.NodeID[:]), "\x00")
 }
 
+// 标注发送时的绝对时隙号并更新CRC，已签名或加密的帧须在签名前标注
+func (f *TDMAFrame) SetAbsSlot(abs uint64) {
+	f.AbsSlot = abs
+	f.CRC = calculateCRC(f)
+}
+
 // 计算CRC
+// 使用CRC-32(IEEE)覆盖CRC之前的全部字段（帧头、时隙、节点ID、长度、分片字段、绝对时隙号）与数据区，任意单比特错误均可检出
 func calculateCRC(frame *TDMAFrame) uint32 {
-	// 简化的CRC计算，实际应用中应使用更复杂的算法
-	var crc uint32 = 0xFFFFFFFF
-
-	// 计算Header的CRC
-	for _, b := range frame.Header {
-		crc = (crc << 1) ^ uint32(b)
-	}
-
-	// 计算其他字段的CRC
-	crc = (crc << 1) ^ frame.SlotID
-	crc = (crc << 1) ^ uint32(frame.TotalFrags)
-	crc = (crc << 1) ^ uint32(frame.FragIndex)
-	crc = (crc << 1) ^ uint32(frame.Flags)
+	var prefix [FRAME_PREFIX_LEN]byte
+	frame.putPrefix(prefix[:])
 
-	// 计算数据的CRC
-	for _, b := range frame.Data {
-		crc = (crc << 1) ^ uint32(b)
-	}
+	crc := crc32.Update(0, crc32.IEEETable, prefix[:])
+	crc = crc32.Update(crc, crc32.IEEETable, frame.Data)
 
 	return crc
 }
 
+// 按当前字段计算CRC，可与CRC字段比较
+func (f *TDMAFrame) CalculateCRC() uint32 {
+	return calculateCRC(f)
+}
+
 // 格式化输出帧信息
 func (f *TDMAFrame) String() string {
-	return fmt.Sprintf("TDMAFrame{SlotID:%d, NodeID:%s, Length:%d, FragmentID:%d, TotalFrags:%d, FragIndex:%d, Flags:0x%04X, DataLen:%d}",
-		f.SlotID, f.GetNodeID(), f.Length, f.FragmentID, f.TotalFrags, f.FragIndex, f.Flags, len(f.Data))
+	return fmt.Sprintf("TDMAFrame{SlotID:%d, NodeID:%s, Length:%d, FragmentID:%d, TotalFrags:%d, FragIndex:%d, Flags:0x%04X, AbsSlot:%d, DataLen:%d}",
+		f.SlotID, f.GetNodeID(), f.Length, f.FragmentID, f.TotalFrags, f.FragIndex, f.Flags, f.AbsSlot, len(f.Data))
 }
@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"testing"
+)
+
+// 反序列化并校验，返回第一个错误
+func decodeValid(raw []byte) error {
+	f, err := DeserializeTDMAFrame(raw)
+	if err != nil {
+		return err
+	}
+	return f.Validate()
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	f := NewTDMAFrame(3, "GS1", []byte("HEARTBEAT"))
+	f.FragmentID = 42
+	f.SetAbsSlot(176534000)
+	raw, err := f.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	if len(raw) != FRAME_PREFIX_LEN+len(f.Data)+FRAME_SUFFIX_LEN {
+		t.Fatalf("len = %d", len(raw))
+	}
+	got, err := DeserializeTDMAFrame(raw)
+	if err != nil {
+		t.Fatalf("Deserialize: %v", err)
+	}
+	if err := got.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got.String() != f.String() || string(got.Data) != string(f.Data) || got.CRC != f.CRC {
+		t.Fatalf("round trip = %v, want %v", got, f)
+	}
+}
+
+// CRC之前任意字段的单比特错误都能检出
+func TestCRCDetectsBitFlipInEveryField(t *testing.T) {
+	f := NewTDMAFrame(3, "GS1", []byte("DATA_TO:GS2:hello"))
+	f.FragmentID = 0x01020304
+	f.TotalFrags = 2
+	f.FragIndex = 1
+	f.SetAbsSlot(0x0102030405060708)
+	raw, err := f.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	if err := decodeValid(raw); err != nil {
+		t.Fatalf("unmodified frame: %v", err)
+	}
+
+	fields := []struct {
+		name      string
+		off, size int
+	}{
+		{"Header", 0, 8},
+		{"SlotID", 8, 4},
+		{"NodeID", 12, 32},
+		{"Length", 44, 4},
+		{"FragmentID", 48, 4},
+		{"TotalFrags", 52, 2},
+		{"FragIndex", 54, 2},
+		{"Flags", 56, 2},
+		{"AbsSlot", 58, 8},
+		{"Data", FRAME_PREFIX_LEN, len(f.Data)},
+		{"CRC", FRAME_PREFIX_LEN + len(f.Data), 4},
+		{"Footer", FRAME_PREFIX_LEN + len(f.Data) + 4, 8},
+	}
+	for _, field := range fields {
+		t.Run(field.name, func(t *testing.T) {
+			for i := field.off * 8; i < (field.off+field.size)*8; i++ {
+				b := append([]byte(nil), raw...)
+				b[i/8] ^= 1 << (i % 8)
+				if decodeValid(b) == nil {
+					t.Fatalf("bit %d of byte %d flipped but frame accepted", i%8, i/8-field.off)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+-- TDMA网络抓包解析器
+--
+-- 解析 satellite / groundstation 的 -capture 选项生成的pcapng文件
+-- （链路类型 LINKTYPE_USER0，格式见README“抓包”一节）。
+--
+-- 安装: 复制到Wireshark个人插件目录（帮助 -> 关于 -> 文件夹 -> 个人Lua插件），
+-- 或 wireshark -X lua_script:tools/wireshark/tdma.lua capture.pcapng
+
+local cap_proto = Proto("tdmacap", "TDMA Capture Header")
+local tdma_proto = Proto("tdma", "TDMA Frame")
+
+-- 抓包头
+local directions = { [1] = "接收", [2] = "发送" }
+
+local cf = {
+	version   = ProtoField.uint8("tdmacap.version", "Version"),
+	direction = ProtoField.uint8("tdmacap.direction", "Direction", base.DEC, directions),
+	hdr_len   = ProtoField.uint16("tdmacap.hdr_len", "Header Length"),
+	cur_slot  = ProtoField.uint32("tdmacap.current_slot", "Global Slot At Capture"),
+	conn_id   = ProtoField.uint32("tdmacap.conn_id", "Connection"),
+	reserved  = ProtoField.bytes("tdmacap.reserved", "Reserved"),
+	local_id  = ProtoField.string("tdmacap.local", "Local Node"),
+	peer      = ProtoField.string("tdmacap.peer", "Peer"),
+}
+cap_proto.fields = cf
+
+-- TDMA帧
+local FLAG_FRAGMENT   = 0x0001
+local FLAG_FIRST_FRAG = 0x0002
+local FLAG_LAST_FRAG  = 0x0004
+local FLAG_NEED_ACK   = 0x0008
+local FLAG_AUTH       = 0x0010
+local FLAG_ENCRYPTED  = 0x0020
+local FLAG_SEQ        = 0x0040
+local KEY_EPOCH_LEN   = 4
+local AUTH_SEQ_LEN    = 8
+local AUTH_TAG_LEN    = 32
+local SEQ_TRAILER_LEN = 8
+
+local tf = {
+	header      = ProtoField.bytes("tdma.header", "Header"),
+	slot_id     = ProtoField.uint32("tdma.slot_id", "Slot ID"),
+	node_id     = ProtoField.string("tdma.node_id", "Node ID"),
+	length      = ProtoField.uint32("tdma.length", "Length"),
+	fragment_id = ProtoField.uint32("tdma.fragment_id", "Fragment ID"),
+	total_frags = ProtoField.uint16("tdma.total_frags", "Total Fragments"),
+	frag_index  = ProtoField.uint16("tdma.frag_index", "Fragment Index"),
+	flags       = ProtoField.uint16("tdma.flags", "Flags", base.HEX),
+	flag_frag   = ProtoField.bool("tdma.flags.fragment", "Fragment", 16, nil, FLAG_FRAGMENT),
+	flag_first  = ProtoField.bool("tdma.flags.first", "First Fragment", 16, nil, FLAG_FIRST_FRAG),
+	flag_last   = ProtoField.bool("tdma.flags.last", "Last Fragment", 16, nil, FLAG_LAST_FRAG),
+	flag_ack    = ProtoField.bool("tdma.flags.need_ack", "Need ACK", 16, nil, FLAG_NEED_ACK),
+	flag_auth   = ProtoField.bool("tdma.flags.auth", "Authenticated", 16, nil, FLAG_AUTH),
+	flag_enc    = ProtoField.bool("tdma.flags.encrypted", "Encrypted", 16, nil, FLAG_ENCRYPTED),
+	flag_seq    = ProtoField.bool("tdma.flags.seq", "Sequenced", 16, nil, FLAG_SEQ),
+	abs_slot    = ProtoField.uint64("tdma.abs_slot", "Absolute Slot"),
+	data        = ProtoField.bytes("tdma.data", "Data"),
+	message     = ProtoField.string("tdma.message", "Message"),
+	key_epoch   = ProtoField.uint32("tdma.key_epoch", "Key Epoch"),
+	ciphertext  = ProtoField.bytes("tdma.ciphertext", "Ciphertext (AES-GCM)"),
+	auth_seq    = ProtoField.uint64("tdma.auth.seq", "Auth Sequence"),
+	auth_tag    = ProtoField.bytes("tdma.auth.tag", "Auth Tag (HMAC-SHA256)"),
+	seq         = ProtoField.uint64("tdma.seq", "Sequence (plaintext)"),
+	crc         = ProtoField.uint32("tdma.crc", "CRC", base.HEX),
+	crc_calc    = ProtoField.uint32("tdma.crc.calculated", "Calculated CRC", base.HEX),
+	crc_ok      = ProtoField.bool("tdma.crc.ok", "CRC OK"),
+	footer      = ProtoField.bytes("tdma.footer", "Footer"),
+}
+tdma_proto.fields = tf
+
+local ef = {
+	bad_header = ProtoExpert.new("tdma.bad_header", "无效的帧头", expert.group.MALFORMED, expert.severity.ERROR),
+	bad_footer = ProtoExpert.new("tdma.bad_footer", "无效的帧尾", expert.group.MALFORMED, expert.severity.ERROR),
+	bad_crc    = ProtoExpert.new("tdma.bad_crc", "CRC校验失败", expert.group.CHECKSUM, expert.severity.ERROR),
+	truncated  = ProtoExpert.new("tdma.truncated", "帧长度不足", expert.group.MALFORMED, expert.severity.ERROR),
+	slot_mismatch = ProtoExpert.new("tdma.slot_mismatch", "帧时隙与抓包时刻的全局时隙不同",
+		expert.group.SEQUENCE, expert.severity.NOTE),
+}
+tdma_proto.experts = ef
+
+local HEADER = "aa55aa55aa55aa55"
+local FOOTER = "55aa55aa55aa55aa"
+local FIXED_LEN = 8 + 4 + 32 + 4 + 4 + 2 + 2 + 2 + 8 -- Data之前的字节数
+local SUFFIX_LEN = 4 + 8                             -- CRC + Footer
+
+-- CRC-32(IEEE) 查表
+local crc_table = {}
+for i = 0, 255 do
+	local c = i
+	for _ = 1, 8 do
+		if bit.band(c, 1) == 1 then
+			c = bit.bxor(bit.rshift(c, 1), 0xEDB88320)
+		else
+			c = bit.rshift(c, 1)
+		end
+	end
+	crc_table[i] = c
+end
+
+local function crc_update(crc, range)
+	crc = bit.bnot(crc)
+	for i = 0, range:len() - 1 do
+		local b = range(i, 1):uint()
+		crc = bit.bxor(crc_table[bit.band(bit.bxor(crc, b), 0xFF)], bit.rshift(crc, 8))
+	end
+	return bit.bnot(crc)
+end
+
+-- 与 protocol.calculateCRC 相同：覆盖CRC之前的全部字段与数据区
+local function frame_crc(tvb, data_len)
+	local crc = crc_update(0, tvb(0, FIXED_LEN + data_len))
+	-- bit库返回有符号数，转为无符号
+	if crc < 0 then crc = crc + 0x100000000 end
+	return crc
+end
+
+local function nul_string(range)
+	local s = range:string()
+	return (s:gsub("%z.*$", ""))
+end
+
+local current_slot = nil
+
+function tdma_proto.dissector(tvb, pinfo, tree)
+	if tvb:len() < FIXED_LEN + SUFFIX_LEN then
+		local t = tree:add(tdma_proto, tvb())
+		t:add_proto_expert_info(ef.truncated)
+		return
+	end
+
+	pinfo.cols.protocol = "TDMA"
+	local length = tvb(44, 4):uint()
+	local data_len = math.min(length, tvb:len() - FIXED_LEN - SUFFIX_LEN)
+	local total = FIXED_LEN + data_len + SUFFIX_LEN
+	local t = tree:add(tdma_proto, tvb(0, total))
+
+	local h = t:add(tf.header, tvb(0, 8))
+	if tostring(tvb(0, 8):bytes()):lower() ~= HEADER then
+		h:add_proto_expert_info(ef.bad_header)
+	end
+	local slot_id = tvb(8, 4):uint()
+	local s = t:add(tf.slot_id, tvb(8, 4))
+	if current_slot ~= nil and current_slot ~= slot_id then
+		s:add_proto_expert_info(ef.slot_mismatch)
+	end
+	local node_id = nul_string(tvb(12, 32))
+	t:add(tf.node_id, tvb(12, 32), node_id)
+	t:add(tf.length, tvb(44, 4))
+	t:add(tf.fragment_id, tvb(48, 4))
+	t:add(tf.total_frags, tvb(52, 2))
+	t:add(tf.frag_index, tvb(54, 2))
+	local ft = t:add(tf.flags, tvb(56, 2))
+	ft:add(tf.flag_frag, tvb(56, 2))
+	ft:add(tf.flag_first, tvb(56, 2))
+	ft:add(tf.flag_last, tvb(56, 2))
+	ft:add(tf.flag_ack, tvb(56, 2))
+	ft:add(tf.flag_auth, tvb(56, 2))
+	ft:add(tf.flag_enc, tvb(56, 2))
+	ft:add(tf.flag_seq, tvb(56, 2))
+	t:add(tf.abs_slot, tvb(58, 8))
+
+	-- 认证尾部或明文序号尾部位于数据区末尾
+	local flags = tvb(56, 2):uint()
+	local payload_len = data_len
+	local seq_len = 0
+	if bit.band(flags, FLAG_AUTH) ~= 0 and data_len >= AUTH_SEQ_LEN + AUTH_TAG_LEN then
+		payload_len = data_len - AUTH_SEQ_LEN - AUTH_TAG_LEN
+	elseif bit.band(flags, FLAG_SEQ) ~= 0 and data_len >= SEQ_TRAILER_LEN then
+		seq_len = SEQ_TRAILER_LEN
+		payload_len = data_len - SEQ_TRAILER_LEN
+	end
+
+	local msg = ""
+	if bit.band(flags, FLAG_ENCRYPTED) ~= 0 and payload_len >= KEY_EPOCH_LEN then
+		-- 密文无法解析，只显示密钥代数
+		t:add(tf.key_epoch, tvb(FIXED_LEN, KEY_EPOCH_LEN))
+		if payload_len > KEY_EPOCH_LEN then
+			t:add(tf.ciphertext, tvb(FIXED_LEN + KEY_EPOCH_LEN, payload_len - KEY_EPOCH_LEN))
+		end
+		msg = "ENCRYPTED"
+	elseif payload_len > 0 then
+		local d = tvb(FIXED_LEN, payload_len)
+		local dt = t:add(tf.data, d)
+		msg = d:string()
+		dt:add(tf.message, d, msg)
+	end
+	if seq_len > 0 then
+		t:add(tf.seq, tvb(FIXED_LEN + payload_len, SEQ_TRAILER_LEN))
+	elseif payload_len < data_len then
+		local a = FIXED_LEN + payload_len
+		t:add(tf.auth_seq, tvb(a, AUTH_SEQ_LEN))
+		t:add(tf.auth_tag, tvb(a + AUTH_SEQ_LEN, AUTH_TAG_LEN))
+	end
+
+	local off = FIXED_LEN + data_len
+	local crc = tvb(off, 4):uint()
+	local calc = frame_crc(tvb, data_len)
+	local ct = t:add(tf.crc, tvb(off, 4))
+	ct:add(tf.crc_calc, tvb(off, 4), calc):set_generated()
+	ct:add(tf.crc_ok, tvb(off, 4), crc == calc):set_generated()
+	if crc ~= calc then
+		ct:add_proto_expert_info(ef.bad_crc)
+	end
+	local f = t:add(tf.footer, tvb(off + 4, 8))
+	if tostring(tvb(off + 4, 8):bytes()):lower() ~= FOOTER then
+		f:add_proto_expert_info(ef.bad_footer)
+	end
+
+	-- 控制消息取冒号或换行前的部分作为摘要
+	local summary = msg:match("^[%w_]+") or ""
+	pinfo.cols.info:append(string.format(" %s slot=%d %s", node_id, slot_id, summary))
+	if crc ~= calc then
+		pinfo.cols.info:append(" [CRC错误]")
+	end
+	return total
+end
+
+function cap_proto.dissector(tvb, pinfo, tree)
+	if tvb:len() < 4 then
+		return 0
+	end
+	local hdr_len = tvb(2, 2):uint()
+	local t = tree:add(cap_proto, tvb(0, hdr_len))
+	t:add(cf.version, tvb(0, 1))
+	t:add(cf.direction, tvb(1, 1))
+	t:add(cf.hdr_len, tvb(2, 2))
+	t:add(cf.cur_slot, tvb(4, 4))
+	t:add(cf.conn_id, tvb(8, 4))
+	t:add(cf.reserved, tvb(12, 4))
+	local local_id = nul_string(tvb(16, 32))
+	local peer = nul_string(tvb(48, 32))
+	t:add(cf.local_id, tvb(16, 32), local_id)
+	t:add(cf.peer, tvb(48, 32), peer)
+
+	local dir = tvb(1, 1):uint()
+	if dir == 1 then
+		pinfo.cols.src = peer
+		pinfo.cols.dst = local_id
+		pinfo.p2p_dir = P2P_DIR_RECV
+	else
+		pinfo.cols.src = local_id
+		pinfo.cols.dst = peer
+		pinfo.p2p_dir = P2P_DIR_SENT
+	end
+	pinfo.cols.info = string.format("%s #%d", directions[dir] or "?", tvb(8, 4):uint())
+
+	current_slot = tvb(4, 4):uint()
+	tdma_proto.dissector(tvb(hdr_len):tvb(), pinfo, tree)
+	current_slot = nil
+	return tvb:len()
+end
+
+local encap = wtap_encaps and wtap_encaps.USER0 or wtap.USER0
+DissectorTable.get("wtap_encap"):add(encap, cap_proto)